@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"path"
 	"regexp"
@@ -29,14 +30,19 @@ import (
 	"time"
 
 	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
 	"github.com/google/cadvisor/events"
 	httpMux "github.com/google/cadvisor/http/mux"
 	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/info/v1/pb"
 	"github.com/google/cadvisor/manager"
 )
 
 const (
 	apiResource = "/api/"
+
+	// Media type of protocol buffer encoded responses.
+	protobufContentType = "application/x-protobuf"
 )
 
 func RegisterHandlers(mux httpMux.Mux, m manager.Manager) error {
@@ -121,8 +127,20 @@ func handleRequest(supportedApiVersions map[string]ApiVersion, m manager.Manager
 
 }
 
-func writeResult(res interface{}, w http.ResponseWriter) error {
-	out, err := json.Marshal(res)
+// Writes the result as JSON, indented if the request has ?pretty=true and
+// compact otherwise. The CPU usage is reported as requested by ?cpu_usage= and
+// values are converted to the units requested by ?units=.
+func writeResult(res interface{}, w http.ResponseWriter, r *http.Request) error {
+	res, err := convertResult(res, r.URL.Query().Get("cpu_usage"), r.URL.Query().Get("units"))
+	if err != nil {
+		return err
+	}
+	var out []byte
+	if pretty, _ := strconv.ParseBool(r.URL.Query().Get("pretty")); pretty {
+		out, err = json.MarshalIndent(res, "", "  ")
+	} else {
+		out, err = json.Marshal(res)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to marshall response %+v with error: %s", res, err)
 	}
@@ -133,6 +151,72 @@ func writeResult(res interface{}, w http.ResponseWriter) error {
 
 }
 
+// Returns whether the request's Accept header lists the protocol buffer media
+// type.
+func acceptsProtobuf(r *http.Request) bool {
+	for _, accept := range r.Header["Accept"] {
+		for _, mediaRange := range strings.Split(accept, ",") {
+			mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+			if err == nil && mediaType == protobufContentType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Writes container information as a protocol buffer if the client accepts one
+// and as JSON otherwise. Lists and maps of containers are written as a
+// ContainerInfoList, the latter sorted by container name. Protocol buffers are
+// always in raw units with the cumulative CPU usage, so requests for others are
+// rejected.
+func writeContainerResult(res interface{}, w http.ResponseWriter, r *http.Request) error {
+	if !acceptsProtobuf(r) {
+		return writeResult(res, w, r)
+	}
+	for param, raw := range map[string]string{"units": unitsRaw, "cpu_usage": cpuUsageCumulative} {
+		if value := r.URL.Query().Get(param); value != "" && value != raw {
+			http.Error(w, fmt.Sprintf("%s=%s is only supported for JSON responses", param, value), http.StatusBadRequest)
+			return nil
+		}
+	}
+
+	var msg proto.Message
+	switch res := res.(type) {
+	case *info.ContainerInfo:
+		msg = pb.FromContainerInfo(res)
+	case []*info.ContainerInfo:
+		list := &pb.ContainerInfoList{}
+		for _, cont := range res {
+			list.Containers = append(list.Containers, pb.FromContainerInfo(cont))
+		}
+		msg = list
+	case map[string]info.ContainerInfo:
+		names := make([]string, 0, len(res))
+		for name := range res {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		list := &pb.ContainerInfoList{}
+		for _, name := range names {
+			cont := res[name]
+			list.Containers = append(list.Containers, pb.FromContainerInfo(&cont))
+		}
+		msg = list
+	default:
+		return fmt.Errorf("unable to encode %T as a protocol buffer", res)
+	}
+
+	out, err := proto.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshall response %+v with error: %s", res, err)
+	}
+
+	w.Header().Set("Content-Type", protobufContentType)
+	w.Write(out)
+	return nil
+}
+
 func streamResults(eventChannel *events.EventChannel, w http.ResponseWriter, r *http.Request, m manager.Manager) error {
 	cn, ok := w.(http.CloseNotifier)
 	if !ok {
@@ -153,7 +237,11 @@ func streamResults(eventChannel *events.EventChannel, w http.ResponseWriter, r *
 		case <-cn.CloseNotify():
 			m.CloseEventChannel(eventChannel.GetWatchId())
 			return nil
-		case ev := <-eventChannel.GetChannel():
+		case ev, ok := <-eventChannel.GetChannel():
+			if !ok {
+				// The watch was stopped, e.g. on shutdown.
+				return nil
+			}
 			err := enc.Encode(ev)
 			if err != nil {
 				glog.Errorf("error encoding message %+v for result stream: %v", ev, err)
@@ -163,6 +251,42 @@ func streamResults(eventChannel *events.EventChannel, w http.ResponseWriter, r *
 	}
 }
 
+func streamMachineStats(watchId int, statsChannel <-chan *info.MachineStats, w http.ResponseWriter, r *http.Request, m manager.Manager) error {
+	cn, ok := w.(http.CloseNotifier)
+	if !ok {
+		m.CloseMachineStatsChannel(watchId)
+		return errors.New("could not access http.CloseNotifier")
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		m.CloseMachineStatsChannel(watchId)
+		return errors.New("could not access http.Flusher")
+	}
+
+	w.Header().Set("Transfer-Encoding", "chunked")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case <-cn.CloseNotify():
+			m.CloseMachineStatsChannel(watchId)
+			return nil
+		case stats, ok := <-statsChannel:
+			if !ok {
+				// The watch was stopped, e.g. on shutdown.
+				return nil
+			}
+			err := enc.Encode(stats)
+			if err != nil {
+				glog.Errorf("error encoding machine stats %+v for result stream: %v", stats, err)
+			}
+			flusher.Flush()
+		}
+	}
+}
+
 func getContainerInfoRequest(body io.ReadCloser) (*info.ContainerInfoRequest, error) {
 	query := info.DefaultContainerInfoRequest()
 	decoder := json.NewDecoder(body)
@@ -223,6 +347,18 @@ func getEventRequest(r *http.Request) (*events.Request, bool, error) {
 			query.EventType[info.EventContainerDeletion] = newBool
 		}
 	}
+	if val, ok := urlMap["pid_migration_events"]; ok {
+		newBool, err := strconv.ParseBool(val[0])
+		if err == nil {
+			query.EventType[info.EventPidMigration] = newBool
+		}
+	}
+	if val, ok := urlMap["memory_pressure_events"]; ok {
+		newBool, err := strconv.ParseBool(val[0])
+		if err == nil {
+			query.EventType[info.EventMemoryPressure] = newBool
+		}
+	}
 	if val, ok := urlMap["max_events"]; ok {
 		newInt, err := strconv.Atoi(val[0])
 		if err == nil {
@@ -241,6 +377,16 @@ func getEventRequest(r *http.Request) (*events.Request, bool, error) {
 			query.EndTime = newTime
 		}
 	}
+	if val, ok := urlMap["label"]; ok {
+		query.Labels = make(map[string]string, len(val))
+		for _, label := range val {
+			kv := strings.SplitN(label, "=", 2)
+			if len(kv) != 2 {
+				return nil, false, fmt.Errorf("invalid label %q, expected <key>=<value>", label)
+			}
+			query.Labels[kv[0]] = kv[1]
+		}
+	}
 
 	return query, stream, nil
 }
@@ -0,0 +1,342 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/info/v2"
+)
+
+const (
+	// Raw values as collected: bytes and nanoseconds. The default.
+	unitsRaw = "raw"
+	// Values converted to MiB, seconds and millicores.
+	unitsHuman = "human"
+)
+
+const mib = 1 << 20
+
+// Conversion of a raw JSON value to a unit humans read more easily. The
+// converted value is stored under the name of the raw one with the suffix of
+// its unit.
+type unitConversion struct {
+	suffix  string
+	divisor float64
+}
+
+var (
+	bytesToMiB     = unitConversion{"_mib", mib}
+	nanosToSeconds = unitConversion{"_seconds", float64(time.Second)}
+)
+
+// Conversions of the fields of the structs of responses, by struct type and
+// field name. Conversions of struct fields apply to each of their numbers.
+// Fields of other structs are left as they are, whatever their JSON name.
+var unitConversions = map[reflect.Type]map[string]unitConversion{
+	reflect.TypeOf(info.CpuUsage{}): {
+		"Total":  nanosToSeconds,
+		"User":   nanosToSeconds,
+		"System": nanosToSeconds,
+		"PerCpu": nanosToSeconds,
+	},
+	reflect.TypeOf(info.MachineCpuStats{}): {
+		"Total":  nanosToSeconds,
+		"User":   nanosToSeconds,
+		"System": nanosToSeconds,
+		"Steal":  nanosToSeconds,
+	},
+	reflect.TypeOf(info.MemoryStats{}): {
+		"Usage":          bytesToMiB,
+		"Cache":          bytesToMiB,
+		"RSS":            bytesToMiB,
+		"MappedFile":     bytesToMiB,
+		"Swap":           bytesToMiB,
+		"WorkingSet":     bytesToMiB,
+		"KernelUsage":    bytesToMiB,
+		"KernelLimit":    bytesToMiB,
+		"KernelTCPUsage": bytesToMiB,
+		"KernelTCPLimit": bytesToMiB,
+	},
+	reflect.TypeOf(info.MachineMemoryStats{}): {
+		"Total":           bytesToMiB,
+		"Usage":           bytesToMiB,
+		"Available":       bytesToMiB,
+		"Free":            bytesToMiB,
+		"Buffers":         bytesToMiB,
+		"Cached":          bytesToMiB,
+		"SlabReclaimable": bytesToMiB,
+		"SwapTotal":       bytesToMiB,
+		"SwapFree":        bytesToMiB,
+	},
+	reflect.TypeOf(info.MemorySpec{}): {
+		"Limit":       bytesToMiB,
+		"Reservation": bytesToMiB,
+		"SwapLimit":   bytesToMiB,
+	},
+	reflect.TypeOf(v2.MemorySpec{}): {
+		"Limit":       bytesToMiB,
+		"Reservation": bytesToMiB,
+		"SwapLimit":   bytesToMiB,
+	},
+	reflect.TypeOf(info.FsStats{}): {
+		"Limit": bytesToMiB,
+		"Usage": bytesToMiB,
+	},
+	reflect.TypeOf(v2.FsInfo{}): {
+		"Capacity": bytesToMiB,
+		"Usage":    bytesToMiB,
+	},
+	reflect.TypeOf(info.FsInfo{}): {
+		"Capacity": bytesToMiB,
+	},
+	reflect.TypeOf(info.MachineInfo{}): {
+		"MemoryCapacity": bytesToMiB,
+	},
+	reflect.TypeOf(v2.Attributes{}): {
+		"MemoryCapacity": bytesToMiB,
+	},
+	reflect.TypeOf(v2.InstantUsage{}): {
+		"Memory": bytesToMiB,
+	},
+	reflect.TypeOf(v2.Usage{}): {
+		"Memory": bytesToMiB,
+	},
+}
+
+// Returns the generic JSON value of the response, keeping numbers as they are.
+func toJSONValue(res interface{}) (interface{}, error) {
+	out, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(out))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Calls visit with each struct of a Go value and the JSON object it was
+// encoded to, from the outermost one in. value is the generic JSON value of
+// the Go value. Values missing from the JSON value, e.g. left out as empty,
+// are skipped, and so are those not encoded as their kind is, e.g. times. The
+// MarshalJSON methods of the info structs encode their fields as they are.
+func walkJSON(v reflect.Value, value interface{}, visit func(v reflect.Value, fields map[string]interface{})) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		visit(v, fields)
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			name, ok := jsonName(field)
+			if !ok {
+				continue
+			}
+			// The fields of embedded structs are encoded in the object of
+			// the struct embedding them.
+			if name == "" {
+				walkJSON(v.Field(i), fields, visit)
+				continue
+			}
+			if child, ok := fields[name]; ok {
+				walkJSON(v.Field(i), child, visit)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		elements, ok := value.([]interface{})
+		if !ok || len(elements) != v.Len() {
+			return
+		}
+		for i, element := range elements {
+			walkJSON(v.Index(i), element, visit)
+		}
+	case reflect.Map:
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for _, key := range v.MapKeys() {
+			if entry, ok := entries[fmt.Sprint(key.Interface())]; ok {
+				walkJSON(v.MapIndex(key), entry, visit)
+			}
+		}
+	}
+}
+
+// Returns the name of the JSON member a struct field is encoded to, empty for
+// embedded structs without a name, false if the field is not encoded.
+func jsonName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name := strings.Split(tag, ",")[0]
+	if name != "" {
+		return name, true
+	}
+	if field.Anonymous {
+		t := field.Type
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Struct {
+			return "", true
+		}
+	}
+	return field.Name, true
+}
+
+// Converts the fields of a struct with known units in its JSON object from
+// bytes and nanoseconds to MiB and seconds. CPU stats are also given the total
+// of their usage rate in millicores as usage_millicores. Fields without a known
+// unit are left as they are.
+func humanize(v reflect.Value, fields map[string]interface{}) {
+	for fieldName, conversion := range unitConversions[v.Type()] {
+		field, ok := v.Type().FieldByName(fieldName)
+		if !ok {
+			continue
+		}
+		name, ok := jsonName(field)
+		if !ok {
+			continue
+		}
+		child, ok := fields[name]
+		if !ok {
+			continue
+		}
+		// The numbers of objects are converted in place.
+		if object, ok := child.(map[string]interface{}); ok {
+			conversion.applyToMembers(object)
+			continue
+		}
+		if converted, ok := conversion.apply(child); ok {
+			delete(fields, name)
+			fields[name+conversion.suffix] = converted
+		}
+	}
+	switch cpu := v.Interface().(type) {
+	case info.CpuStats:
+		fields["usage_millicores"] = cpu.UsageRate.Total
+	case info.MachineCpuStats:
+		fields["usage_millicores"] = cpu.UsageRate.Total
+	}
+}
+
+// Converts a number or an array of numbers, false if value is neither.
+func (self unitConversion) apply(value interface{}) (interface{}, bool) {
+	switch value := value.(type) {
+	case json.Number:
+		raw, err := value.Float64()
+		if err != nil {
+			return nil, false
+		}
+		return raw / self.divisor, true
+	case []interface{}:
+		converted := make([]interface{}, len(value))
+		for i, element := range value {
+			var ok bool
+			if converted[i], ok = self.apply(element); !ok {
+				return nil, false
+			}
+		}
+		return converted, true
+	}
+	return nil, false
+}
+
+// Converts the numbers of an object, renaming them with the suffix of the unit.
+func (self unitConversion) applyToMembers(object map[string]interface{}) {
+	for name, member := range object {
+		if _, ok := member.(json.Number); !ok {
+			continue
+		}
+		if converted, ok := self.apply(member); ok {
+			delete(object, name)
+			object[name+self.suffix] = converted
+		}
+	}
+}
+
+const (
+	// CPU usage reported as cumulative time along with its rate. The default.
+	cpuUsageCumulative = "cumulative"
+	// CPU usage only reported as its rate, for clients that cannot handle large
+	// cumulative counters.
+	cpuUsageRate = "rate"
+)
+
+// Returns the value of the response with the CPU usage requested by the
+// cpu_usage query parameter and converted to the units requested by the units
+// query parameter. The response is returned as it is when neither is requested.
+func convertResult(res interface{}, cpuUsage, units string) (interface{}, error) {
+	var rate, human bool
+	switch cpuUsage {
+	case "", cpuUsageCumulative:
+	case cpuUsageRate:
+		rate = true
+	default:
+		return nil, fmt.Errorf("unsupported CPU usage %q, expected %q or %q", cpuUsage, cpuUsageCumulative, cpuUsageRate)
+	}
+	switch units {
+	case "", unitsRaw:
+	case unitsHuman:
+		human = true
+	default:
+		return nil, fmt.Errorf("unsupported units %q, expected %q or %q", units, unitsRaw, unitsHuman)
+	}
+	if !rate && !human {
+		return res, nil
+	}
+
+	value, err := toJSONValue(res)
+	if err != nil {
+		return nil, err
+	}
+	if rate {
+		walkJSON(reflect.ValueOf(res), value, removeCumulativeCpuUsage)
+	}
+	if human {
+		walkJSON(reflect.ValueOf(res), value, humanize)
+	}
+	return value, nil
+}
+
+// Removes the cumulative usage from the CPU stats of containers.
+func removeCumulativeCpuUsage(v reflect.Value, fields map[string]interface{}) {
+	if v.Type() == reflect.TypeOf(info.CpuStats{}) {
+		delete(fields, "usage")
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/info/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteResultHumanUnits(t *testing.T) {
+	start := time.Date(2015, 3, 1, 0, 0, 0, 0, time.UTC)
+	cinfo := &info.ContainerInfo{
+		ContainerReference: info.ContainerReference{Name: "/test"},
+		Spec: info.ContainerSpec{
+			HasMemory: true,
+			Memory:    info.MemorySpec{Limit: 512 * mib},
+		},
+		Stats: []*info.ContainerStats{
+			{
+				Timestamp: start,
+				Cpu:       info.CpuStats{Usage: info.CpuUsage{Total: 1000000000, PerCpu: []uint64{1000000000}}},
+				Memory:    info.MemoryStats{Usage: 64 * mib, WorkingSet: 32 * mib},
+			}, {
+				// A quarter of a core over two seconds.
+				Timestamp: start.Add(2 * time.Second),
+				Cpu: info.CpuStats{
+					Usage:     info.CpuUsage{Total: 1500000000, PerCpu: []uint64{1500000000}},
+					UsageRate: info.CpuUsageRate{Total: 250},
+				},
+				Memory:     info.MemoryStats{Usage: 96 * mib, WorkingSet: 48 * mib},
+				Filesystem: []info.FsStats{{Device: "/dev/sda1", Limit: 1024 * mib, Usage: 256 * mib}},
+			},
+		},
+	}
+
+	w := httptest.NewRecorder()
+	assert.Nil(t, writeResult(cinfo, w, makeHTTPRequest("http://localhost:8080/api/v1.3/containers/test?units=human", t)))
+
+	var res struct {
+		Name string `json:"name"`
+		Spec struct {
+			Memory map[string]interface{} `json:"memory"`
+		} `json:"spec"`
+		Stats []struct {
+			Cpu struct {
+				Usage           map[string]interface{} `json:"usage"`
+				UsageMillicores *float64               `json:"usage_millicores"`
+			} `json:"cpu"`
+			Memory     map[string]interface{}   `json:"memory"`
+			Filesystem []map[string]interface{} `json:"filesystem"`
+		} `json:"stats"`
+	}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &res))
+
+	assert.Equal(t, "/test", res.Name)
+	assert.Equal(t, map[string]interface{}{"limit_mib": 512.0}, res.Spec.Memory)
+	assert.Len(t, res.Stats, 2)
+
+	first, second := res.Stats[0], res.Stats[1]
+	assert.Equal(t, 1.0, first.Cpu.Usage["total_seconds"])
+	assert.Equal(t, []interface{}{1.5}, second.Cpu.Usage["per_cpu_usage_seconds"])
+	_, ok := second.Cpu.Usage["total"]
+	assert.False(t, ok)
+	if assert.NotNil(t, first.Cpu.UsageMillicores) {
+		assert.Equal(t, 0.0, *first.Cpu.UsageMillicores)
+	}
+	if assert.NotNil(t, second.Cpu.UsageMillicores) {
+		assert.Equal(t, 250.0, *second.Cpu.UsageMillicores)
+	}
+	assert.Equal(t, 96.0, second.Memory["usage_mib"])
+	assert.Equal(t, 48.0, second.Memory["working_set_mib"])
+	_, ok = second.Memory["usage"]
+	assert.False(t, ok)
+	// Counts are not converted.
+	assert.Equal(t, 0.0, second.Memory["pgpgin"])
+	assert.Equal(t, 1024.0, second.Filesystem[0]["capacity_mib"])
+	assert.Equal(t, 256.0, second.Filesystem[0]["usage_mib"])
+	assert.Equal(t, "/dev/sda1", second.Filesystem[0]["device"])
+}
+
+func TestWriteResultHumanUnitsByType(t *testing.T) {
+	stats := &v2.DerivedStats{
+		LatestUsage: v2.InstantUsage{Cpu: 250, Memory: 64 * mib},
+		MinuteUsage: v2.Usage{
+			PercentComplete: 100,
+			Cpu:             v2.Percentiles{Present: true, Mean: 250, Max: 500, Ninety: 400},
+			Memory:          v2.Percentiles{Present: true, Mean: 32 * mib, Max: 64 * mib, Ninety: 48 * mib},
+		},
+	}
+	w := httptest.NewRecorder()
+	assert.Nil(t, writeResult(stats, w, makeHTTPRequest("http://localhost:8080/api/v2.0/summary?units=human", t)))
+	var res struct {
+		LatestUsage map[string]interface{} `json:"latest_usage"`
+		MinuteUsage struct {
+			Cpu    map[string]interface{} `json:"cpu"`
+			Memory map[string]interface{} `json:"memory"`
+		} `json:"minute_usage"`
+	}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, map[string]interface{}{"cpu": 250.0, "memory_mib": 64.0}, res.LatestUsage)
+	assert.Equal(t, map[string]interface{}{"present": true, "mean_mib": 32.0, "max_mib": 64.0, "ninety_mib": 48.0}, res.MinuteUsage.Memory)
+	// Millicores named like the converted memory fields are left as they are.
+	assert.Equal(t, map[string]interface{}{"present": true, "mean": 250.0, "max": 500.0, "ninety": 400.0}, res.MinuteUsage.Cpu)
+
+	// Fields are converted by the struct they belong to, not by their name.
+	cstats := &info.ContainerStats{Memory: info.MemoryStats{Usage: 64 * mib, Events: info.MemoryEvents{Max: 3}}}
+	w = httptest.NewRecorder()
+	assert.Nil(t, writeResult(cstats, w, makeHTTPRequest("http://localhost:8080/api/v1.3/containers?units=human", t)))
+	var cres struct {
+		Memory map[string]interface{} `json:"memory"`
+	}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &cres))
+	assert.Equal(t, 64.0, cres.Memory["usage_mib"])
+	assert.Equal(t, 3.0, cres.Memory["events"].(map[string]interface{})["max"])
+}
+
+func TestWriteResultRawUnits(t *testing.T) {
+	stats := &info.ContainerStats{Memory: info.MemoryStats{Usage: 64 * mib}}
+	for _, url := range []string{"http://localhost:8080/api/v1.3/containers", "http://localhost:8080/api/v1.3/containers?units=raw"} {
+		w := httptest.NewRecorder()
+		assert.Nil(t, writeResult(stats, w, makeHTTPRequest(url, t)))
+		var res struct {
+			Memory map[string]interface{} `json:"memory"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, float64(64*mib), res.Memory["usage"])
+	}
+
+	w := httptest.NewRecorder()
+	assert.NotNil(t, writeResult(stats, w, makeHTTPRequest("http://localhost:8080/api/v1.3/containers?units=metric", t)))
+}
+
+func TestWriteResultCpuUsageRate(t *testing.T) {
+	stats := []*info.ContainerStats{
+		{
+			Cpu:    info.CpuStats{Usage: info.CpuUsage{Total: 1000000000}},
+			Memory: info.MemoryStats{Usage: 64 * mib},
+		}, {
+			Cpu: info.CpuStats{
+				Usage:     info.CpuUsage{Total: 1500000000},
+				UsageRate: info.CpuUsageRate{Total: 250, User: 200, System: 50},
+			},
+			Memory: info.MemoryStats{Usage: 64 * mib},
+		},
+	}
+
+	w := httptest.NewRecorder()
+	assert.Nil(t, writeResult(stats, w, makeHTTPRequest("http://localhost:8080/api/v2.0/stats/test?cpu_usage=rate", t)))
+	var res []struct {
+		Cpu    map[string]interface{} `json:"cpu"`
+		Memory map[string]interface{} `json:"memory"`
+	}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Len(t, res, 2)
+	for _, sample := range res {
+		_, ok := sample.Cpu["usage"]
+		assert.False(t, ok)
+		assert.Equal(t, float64(64*mib), sample.Memory["usage"])
+	}
+	assert.Equal(t, map[string]interface{}{"total": 0.0, "user": 0.0, "system": 0.0}, res[0].Cpu["usage_rate"])
+	assert.Equal(t, map[string]interface{}{"total": 250.0, "user": 200.0, "system": 50.0}, res[1].Cpu["usage_rate"])
+
+	// The cumulative usage is kept by default.
+	w = httptest.NewRecorder()
+	assert.Nil(t, writeResult(stats, w, makeHTTPRequest("http://localhost:8080/api/v2.0/stats/test?cpu_usage=cumulative", t)))
+	var cumulative []struct {
+		Cpu map[string]interface{} `json:"cpu"`
+	}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &cumulative))
+	_, ok := cumulative[1].Cpu["usage"]
+	assert.True(t, ok)
+
+	w = httptest.NewRecorder()
+	assert.NotNil(t, writeResult(stats, w, makeHTTPRequest("http://localhost:8080/api/v2.0/stats/test?cpu_usage=delta", t)))
+}
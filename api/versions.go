@@ -15,12 +15,16 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"path"
 	"strconv"
+	"strings"
 
 	"github.com/golang/glog"
+	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/events"
 	info "github.com/google/cadvisor/info/v1"
 	"github.com/google/cadvisor/info/v2"
 	"github.com/google/cadvisor/manager"
@@ -38,6 +42,16 @@ const (
 	storageApi       = "storage"
 	attributesApi    = "attributes"
 	versionApi       = "version"
+	factoriesApi     = "factories"
+	treeApi          = "tree"
+	housekeepingApi  = "housekeeping"
+	thresholdApi     = "threshold"
+	eventPolicyApi   = "eventpolicy"
+	machineStatsApi  = "machinestats"
+	statusApi        = "status"
+	retentionApi     = "retention"
+	imageApi         = "image"
+	processTreeApi   = "processtree"
 )
 
 // Interface for a cAdvisor API version
@@ -88,7 +102,7 @@ func (self *version1_0) HandleRequest(requestType string, request []string, m ma
 			return err
 		}
 
-		err = writeResult(machineInfo, w)
+		err = writeResult(machineInfo, w, r)
 		if err != nil {
 			return err
 		}
@@ -108,8 +122,7 @@ func (self *version1_0) HandleRequest(requestType string, request []string, m ma
 			return fmt.Errorf("failed to get container %q with error: %s", containerName, err)
 		}
 
-		// Only output the container as JSON.
-		err = writeResult(cont, w)
+		err = writeContainerResult(cont, w, r)
 		if err != nil {
 			return err
 		}
@@ -158,8 +171,7 @@ func (self *version1_1) HandleRequest(requestType string, request []string, m ma
 			return fmt.Errorf("failed to get subcontainers for container %q with error: %s", containerName, err)
 		}
 
-		// Only output the containers as JSON.
-		err = writeResult(containers, w)
+		err = writeContainerResult(containers, w, r)
 		if err != nil {
 			return err
 		}
@@ -227,8 +239,7 @@ func (self *version1_2) HandleRequest(requestType string, request []string, m ma
 			return fmt.Errorf("unknown request for Docker container %v", request)
 		}
 
-		// Only output the containers as JSON.
-		err = writeResult(containers, w)
+		err = writeContainerResult(containers, w, r)
 		if err != nil {
 			return err
 		}
@@ -256,18 +267,79 @@ func (self *version1_3) Version() string {
 }
 
 func (self *version1_3) SupportedRequestTypes() []string {
-	return append(self.baseVersion.SupportedRequestTypes(), eventsApi)
+	return append(self.baseVersion.SupportedRequestTypes(), eventsApi, factoriesApi, statusApi, retentionApi, imageApi)
 }
 
 func (self *version1_3) HandleRequest(requestType string, request []string, m manager.Manager, w http.ResponseWriter, r *http.Request) error {
 	switch requestType {
 	case eventsApi:
 		return handleEventRequest(request, m, w, r)
+	case factoriesApi:
+		containerName := getContainerName(request)
+		glog.V(4).Infof("Api - Factories(%s)", containerName)
+		return writeResult(getFactoriesInfo(containerName), w, r)
+	case statusApi:
+		containerNames := r.URL.Query()["name"]
+		glog.V(4).Infof("Api - Status(%v)", containerNames)
+		status, err := m.GetContainerStatus(containerNames)
+		if err != nil {
+			return err
+		}
+		return writeResult(status, w, r)
+	case retentionApi:
+		containerNames := r.URL.Query()["name"]
+		glog.V(4).Infof("Api - Retention(%v)", containerNames)
+		retention, err := m.GetStatsRetention(containerNames)
+		if err != nil {
+			return err
+		}
+		return writeResult(retention, w, r)
+	case imageApi:
+		// Image references may hold "/", e.g. gcr.io/team/app:1.0.
+		image := strings.Join(request, "/")
+		glog.V(4).Infof("Api - Image(%s)", image)
+		if image == "" {
+			return fmt.Errorf("missing image in request")
+		}
+		query, err := getContainerInfoRequest(r.Body)
+		if err != nil {
+			return err
+		}
+		stats, err := m.GetStatsByImage(image, query)
+		if err != nil {
+			return fmt.Errorf("failed to get stats of image %q with error: %s", image, err)
+		}
+		return writeResult(stats, w, r)
 	default:
 		return self.baseVersion.HandleRequest(requestType, request, m, w, r)
 	}
 }
 
+// Registered container handler factories and which of them handles a container.
+type factoriesInfo struct {
+	// Names of the registered factories in detection order. Earlier factories take precedence.
+	Factories []string `json:"factories"`
+
+	// Container for which the handling factory was determined.
+	Container string `json:"container"`
+
+	// Name of the factory that handles the container, empty if none can.
+	HandledBy string `json:"handled_by,omitempty"`
+
+	// Whether the handling factory accepts the container. Containers that are not accepted are ignored.
+	Accepted bool `json:"accepted"`
+}
+
+func getFactoriesInfo(containerName string) factoriesInfo {
+	handledBy, accepted := container.FactoryForContainer(containerName)
+	return factoriesInfo{
+		Factories: container.ListFactories(),
+		Container: containerName,
+		HandledBy: handledBy,
+		Accepted:  accepted,
+	}
+}
+
 func handleEventRequest(request []string, m manager.Manager, w http.ResponseWriter, r *http.Request) error {
 	query, stream, err := getEventRequest(r)
 	if err != nil {
@@ -280,7 +352,7 @@ func handleEventRequest(request []string, m manager.Manager, w http.ResponseWrit
 		if err != nil {
 			return err
 		}
-		return writeResult(pastEvents, w)
+		return writeResult(pastEvents, w, r)
 	}
 	eventChannel, err := m.WatchForEvents(query)
 	if err != nil {
@@ -304,7 +376,7 @@ func (self *version2_0) Version() string {
 }
 
 func (self *version2_0) SupportedRequestTypes() []string {
-	return []string{versionApi, attributesApi, eventsApi, machineApi, summaryApi, statsApi, specApi, storageApi}
+	return []string{versionApi, attributesApi, eventsApi, machineApi, summaryApi, statsApi, specApi, storageApi, treeApi, processTreeApi, housekeepingApi, thresholdApi, eventPolicyApi, machineStatsApi}
 }
 
 func (self *version2_0) HandleRequest(requestType string, request []string, m manager.Manager, w http.ResponseWriter, r *http.Request) error {
@@ -319,7 +391,7 @@ func (self *version2_0) HandleRequest(requestType string, request []string, m ma
 		if err != nil {
 			return err
 		}
-		return writeResult(versionInfo.CadvisorVersion, w)
+		return writeResult(versionInfo.CadvisorVersion, w, r)
 	case attributesApi:
 		glog.V(4).Info("Api - Attributes")
 
@@ -332,7 +404,7 @@ func (self *version2_0) HandleRequest(requestType string, request []string, m ma
 			return err
 		}
 		info := v2.GetAttributes(machineInfo, versionInfo)
-		return writeResult(info, w)
+		return writeResult(info, w, r)
 	case machineApi:
 		glog.V(4).Info("Api - Machine")
 
@@ -341,7 +413,7 @@ func (self *version2_0) HandleRequest(requestType string, request []string, m ma
 		if err != nil {
 			return err
 		}
-		return writeResult(machineInfo, w)
+		return writeResult(machineInfo, w, r)
 	case summaryApi:
 		containerName := getContainerName(request)
 		glog.V(4).Infof("Api - Summary for container %q, options %+v", containerName, opt)
@@ -350,7 +422,7 @@ func (self *version2_0) HandleRequest(requestType string, request []string, m ma
 		if err != nil {
 			return err
 		}
-		return writeResult(stats, w)
+		return writeResult(stats, w, r)
 	case statsApi:
 		name := getContainerName(request)
 		glog.V(4).Infof("Api - Stats: Looking for stats for container %q, options %+v", name, opt)
@@ -362,7 +434,7 @@ func (self *version2_0) HandleRequest(requestType string, request []string, m ma
 		for name, cont := range conts {
 			contStats[name] = convertStats(cont)
 		}
-		return writeResult(contStats, w)
+		return writeResult(contStats, w, r)
 	case specApi:
 		containerName := getContainerName(request)
 		glog.V(4).Infof("Api - Spec for container %q, options %+v", containerName, opt)
@@ -370,7 +442,49 @@ func (self *version2_0) HandleRequest(requestType string, request []string, m ma
 		if err != nil {
 			return err
 		}
-		return writeResult(specs, w)
+		return writeResult(specs, w, r)
+	case treeApi:
+		containerName := getContainerName(request)
+		maxDepth := -1
+		if val := r.URL.Query().Get("max_depth"); len(val) != 0 {
+			n, err := strconv.ParseUint(val, 10, 32)
+			if err != nil {
+				return fmt.Errorf("failed to parse 'max_depth' option: %v", val)
+			}
+			maxDepth = int(n)
+		}
+		glog.V(4).Infof("Api - Tree for container %q, max depth %d", containerName, maxDepth)
+		tree, err := m.GetContainerTree(containerName, maxDepth)
+		if err != nil {
+			return err
+		}
+		return writeResult(tree, w, r)
+	case processTreeApi:
+		containerName := getContainerName(request)
+		glog.V(4).Infof("Api - Process tree for container %q", containerName)
+		tree, err := m.GetProcessTree(containerName)
+		if err != nil {
+			return err
+		}
+		return writeResult(tree, w, r)
+	case housekeepingApi:
+		return handleHousekeepingRequest(request, m, w, r)
+	case thresholdApi:
+		containerName := getContainerName(request)
+		metric := r.URL.Query().Get("metric")
+		if len(metric) == 0 {
+			return fmt.Errorf("missing 'metric' option")
+		}
+		threshold, err := strconv.ParseFloat(r.URL.Query().Get("threshold"), 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse 'threshold' option: %v", r.URL.Query().Get("threshold"))
+		}
+		glog.V(4).Infof("Api - Containers of %q with %s above %v, options %+v", containerName, metric, threshold, opt)
+		usage, err := m.GetContainersAboveThreshold(containerName, metric, threshold, opt)
+		if err != nil {
+			return err
+		}
+		return writeResult(usage, w, r)
 	case storageApi:
 		var err error
 		fi := []v2.FsInfo{}
@@ -388,14 +502,80 @@ func (self *version2_0) HandleRequest(requestType string, request []string, m ma
 				return err
 			}
 		}
-		return writeResult(fi, w)
+		return writeResult(fi, w, r)
 	case eventsApi:
 		return handleEventRequest(request, m, w, r)
+	case eventPolicyApi:
+		return handleEventPolicyRequest(m, w, r)
+	case machineStatsApi:
+		if r.URL.Query().Get("stream") == "true" {
+			glog.V(4).Infof("Api - Machine stats stream")
+			watchId, statsChannel, err := m.WatchMachineStats()
+			if err != nil {
+				return err
+			}
+			return streamMachineStats(watchId, statsChannel, w, r, m)
+		}
+		glog.V(4).Infof("Api - Machine stats, options %+v", opt)
+		stats, err := m.GetMachineStats(opt.Count)
+		if err != nil {
+			return err
+		}
+		return writeResult(stats, w, r)
 	default:
 		return fmt.Errorf("unknown request type %q", requestType)
 	}
 }
 
+// Reports the storage policy of events, replacing it first with the policy in
+// the body of POST requests.
+func handleEventPolicyRequest(m manager.Manager, w http.ResponseWriter, r *http.Request) error {
+	if r.Method == "POST" {
+		var policy events.StoragePolicy
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			return fmt.Errorf("failed to parse the event storage policy: %v", err)
+		}
+		glog.V(4).Infof("Api - Set event storage policy %+v", policy)
+		if err := m.SetEventStoragePolicy(policy); err != nil {
+			return err
+		}
+	}
+	return writeResult(m.GetEventStoragePolicy(), w, r)
+}
+
+// Whether the collection of stats is paused.
+type housekeepingStatus struct {
+	Paused bool `json:"paused"`
+}
+
+// Reports whether housekeeping is paused, pausing or resuming it first when
+// asked to with a POST to the pause or resume resource.
+func handleHousekeepingRequest(request []string, m manager.Manager, w http.ResponseWriter, r *http.Request) error {
+	if len(request) > 1 {
+		return fmt.Errorf("unknown housekeeping request %q", strings.Join(request, "/"))
+	}
+	if len(request) == 1 {
+		if r.Method != "POST" {
+			return fmt.Errorf("housekeeping can only be paused or resumed with a POST request, got %s", r.Method)
+		}
+		var err error
+		switch request[0] {
+		case "pause":
+			glog.V(4).Info("Api - Pause housekeeping")
+			err = m.Pause()
+		case "resume":
+			glog.V(4).Info("Api - Resume housekeeping")
+			err = m.Resume()
+		default:
+			return fmt.Errorf("unknown housekeeping request %q", request[0])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return writeResult(housekeepingStatus{Paused: m.Paused()}, w, r)
+}
+
 func convertStats(cont *info.ContainerInfo) []v2.ContainerStats {
 	stats := []v2.ContainerStats{}
 	for _, val := range cont.Stats {
@@ -424,6 +604,9 @@ func convertStats(cont *info.ContainerInfo) []v2.ContainerStats {
 			stat.DiskIo = val.DiskIo
 		}
 		// TODO(rjnagal): Handle load stats.
+		stat.DerivedMetrics = val.DerivedMetrics
+		stat.CollectionTime = val.CollectionTime
+		stat.SequenceNumber = val.SequenceNumber
 		stats = append(stats, stat)
 	}
 	return stats
@@ -433,6 +616,7 @@ func getRequestOptions(r *http.Request) (v2.RequestOptions, error) {
 	supportedTypes := map[string]bool{
 		v2.TypeName:   true,
 		v2.TypeDocker: true,
+		v2.TypeStable: true,
 	}
 	// fill in the defaults.
 	opt := v2.RequestOptions{
@@ -15,13 +15,17 @@
 package api
 
 import (
+	"encoding/json"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/google/cadvisor/events"
 	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/info/v1/pb"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -78,3 +82,71 @@ func TestGetEventRequestDoubleArgument(t *testing.T) {
 	assert.True(t, stream)
 	assert.Nil(t, err)
 }
+
+func TestWriteResultPretty(t *testing.T) {
+	res := housekeepingStatus{Paused: true}
+	tests := map[string]string{
+		"http://localhost:8080/api/v2.0/housekeeping":              `{"paused":true}`,
+		"http://localhost:8080/api/v2.0/housekeeping?pretty=false": `{"paused":true}`,
+		"http://localhost:8080/api/v2.0/housekeeping?pretty=true":  "{\n  \"paused\": true\n}",
+	}
+	for url, expected := range tests {
+		w := httptest.NewRecorder()
+		err := writeResult(res, w, makeHTTPRequest(url, t))
+		assert.Nil(t, err)
+		assert.Equal(t, expected, w.Body.String(), url)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	}
+}
+
+func TestWriteContainerResultProtobuf(t *testing.T) {
+	containers := map[string]info.ContainerInfo{
+		"/b": {ContainerReference: info.ContainerReference{Name: "/b"}},
+		"/a": {ContainerReference: info.ContainerReference{Name: "/a"}},
+	}
+	r := makeHTTPRequest("http://localhost:8080/api/v1.2/docker", t)
+	r.Header.Set("Accept", "application/json;q=0.5, application/x-protobuf")
+	w := httptest.NewRecorder()
+	err := writeContainerResult(containers, w, r)
+	assert.Nil(t, err)
+	assert.Equal(t, "application/x-protobuf", w.Header().Get("Content-Type"))
+
+	var list pb.ContainerInfoList
+	assert.Nil(t, proto.Unmarshal(w.Body.Bytes(), &list))
+	if assert.Len(t, list.Containers, 2) {
+		assert.Equal(t, "/a", list.Containers[0].GetReference().Name)
+		assert.Equal(t, "/b", list.Containers[1].GetReference().Name)
+	}
+}
+
+func TestWriteContainerResultProtobufRejectsConversions(t *testing.T) {
+	cont := &info.ContainerInfo{ContainerReference: info.ContainerReference{Name: "/"}}
+	for _, url := range []string{
+		"http://localhost:8080/api/v1.2/containers?units=human",
+		"http://localhost:8080/api/v1.2/containers?cpu_usage=rate",
+	} {
+		r := makeHTTPRequest(url, t)
+		r.Header.Set("Accept", "application/x-protobuf")
+		w := httptest.NewRecorder()
+		assert.Nil(t, writeContainerResult(cont, w, r))
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+	}
+
+	r := makeHTTPRequest("http://localhost:8080/api/v1.2/containers?units=raw", t)
+	r.Header.Set("Accept", "application/x-protobuf")
+	w := httptest.NewRecorder()
+	assert.Nil(t, writeContainerResult(cont, w, r))
+	assert.Equal(t, "application/x-protobuf", w.Header().Get("Content-Type"))
+}
+
+func TestWriteContainerResultDefaultsToJSON(t *testing.T) {
+	cont := &info.ContainerInfo{ContainerReference: info.ContainerReference{Name: "/"}}
+	r := makeHTTPRequest("http://localhost:8080/api/v1.0/containers", t)
+	w := httptest.NewRecorder()
+	err := writeContainerResult(cont, w, r)
+	assert.Nil(t, err)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var decoded info.ContainerInfo
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &decoded))
+	assert.Equal(t, "/", decoded.Name)
+}
@@ -15,6 +15,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"net/http"
@@ -22,11 +23,18 @@ import (
 	"os"
 	"os/signal"
 	"runtime"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/golang/glog"
+	"github.com/google/cadvisor/events"
+	"github.com/google/cadvisor/events/webhook"
 	cadvisorHttp "github.com/google/cadvisor/http"
+	info "github.com/google/cadvisor/info/v1"
 	"github.com/google/cadvisor/manager"
+	"github.com/google/cadvisor/storage/memory"
+	"github.com/google/cadvisor/storage/shared"
 	"github.com/google/cadvisor/utils/sysfs"
 	"github.com/google/cadvisor/version"
 )
@@ -35,7 +43,7 @@ var argIp = flag.String("listen_ip", "", "IP to listen on, defaults to all IPs")
 var argPort = flag.Int("port", 8080, "port to listen")
 var maxProcs = flag.Int("max_procs", 0, "max number of CPUs that can be used simultaneously. Less than 1 for default (number of cores).")
 
-var argDbDriver = flag.String("storage_driver", "", "storage driver to use. Data is always cached shortly in memory, this controls where data is pushed besides the local cache. Empty means none. Options are: <empty> (default), bigquery, and influxdb")
+var argDbDriver = flag.String("storage_driver", "", "comma-separated list of storage drivers to use. Data is always cached shortly in memory, this controls where data is pushed besides the local cache. Empty means none. Options are: <empty> (default), bigquery, influxdb, mqtt, shared, and any driver registered with storage.RegisterStorageDriver")
 var versionFlag = flag.Bool("version", false, "print cAdvisor version and exit")
 
 var httpAuthFile = flag.String("http_auth_file", "", "HTTP auth file for the web UI")
@@ -43,8 +51,31 @@ var httpAuthRealm = flag.String("http_auth_realm", "localhost", "HTTP auth realm
 var httpDigestFile = flag.String("http_digest_file", "", "HTTP digest file for the web UI")
 var httpDigestRealm = flag.String("http_digest_realm", "localhost", "HTTP digest file for the web UI")
 
+var serverAuthFile = flag.String("server_auth_file", "", "HTTP basic auth file required by every endpoint, the API included. Disabled if empty")
+var serverAuthRealm = flag.String("server_auth_realm", "localhost", "HTTP basic auth realm of every endpoint")
+var serverAuthExemptPaths = flag.String("server_auth_exempt_paths", "", "Comma-separated list of paths served without auth, e.g. /metrics,/healthz")
+var tlsCertFile = flag.String("tls_cert_file", "", "Certificate to serve HTTPS with. Plain HTTP is rejected when set along with --tls_key_file")
+var tlsKeyFile = flag.String("tls_key_file", "", "Key of the certificate to serve HTTPS with")
+
 var prometheusEndpoint = flag.String("prometheus_endpoint", "/metrics", "Endpoint to expose Prometheus metrics on")
 
+var enableHousekeepingJitter = flag.Bool("enable_housekeeping_jitter", false, "Whether to randomly move each container housekeeping to spread the collection of stats and the writes to storage")
+var maxHousekeepingJitter = flag.Float64("max_housekeeping_jitter", 0.1, "Largest fraction of the housekeeping interval by which a container housekeeping is moved earlier or later. Must be in [0, 1)")
+
+var minContainerAge = flag.Duration("min_container_age", 0, "Age containers must reach before they are monitored. Containers that disappear earlier are never monitored and generate no events")
+
+var eventWarmup = flag.Duration("event_warmup", 0, "Time after startup during which the containers found are taken as pre-existing and generate no creation event")
+
+var readOnlyStorageDir = flag.String("read_only_storage_dir", "", "Directory written by another cAdvisor instance with --storage_driver=shared. If set, its containers are served read-only instead of being monitored")
+
+var shutdownTimeout = flag.Duration("shutdown_timeout", 10*time.Second, "How long to wait on exit for the housekeeping to stop, the storage driver to be flushed and the event streams to be closed")
+
+var localTimestamps = flag.Bool("local_timestamps", false, "Marshal the timestamps of samples and events to JSON in the local time zone of the host, as before, rather than in UTC")
+
+var eventWebhookUrl = flag.String("event_webhook_url", "", "URL to which events are posted as JSON. Disabled if empty")
+var eventWebhookTypes = flag.String("event_webhook_types", "oom,oomKill,containerCreation,containerDeletion", "Comma-separated list of the types of events posted to the webhook")
+var eventWebhookQueueSize = flag.Int("event_webhook_queue_size", 100, "Number of events waiting to be posted to the webhook after which new events are dropped")
+
 func main() {
 	defer glog.Flush()
 	flag.Parse()
@@ -55,6 +86,7 @@ func main() {
 	}
 
 	setMaxProcs()
+	info.LocalTimestamps = *localTimestamps
 
 	memoryStorage, err := NewMemoryStorage(*argDbDriver)
 	if err != nil {
@@ -66,7 +98,14 @@ func main() {
 		glog.Fatalf("Failed to create a system interface: %s", err)
 	}
 
-	containerManager, err := manager.New(memoryStorage, sysFs)
+	options := manager.Options{
+		MinContainerAge: *minContainerAge,
+		EventWarmup:     *eventWarmup,
+	}
+	if *enableHousekeepingJitter {
+		options.MaxHousekeepingJitter = *maxHousekeepingJitter
+	}
+	containerManager, err := newManager(memoryStorage, sysFs, options)
 	if err != nil {
 		glog.Fatalf("Failed to create a Container Manager: %s", err)
 	}
@@ -84,13 +123,39 @@ func main() {
 		glog.Fatalf("Failed to start container manager: %v", err)
 	}
 
-	// Install signal handler.
+	// Post events to the webhook.
+	if *eventWebhookUrl != "" {
+		if err := startEventWebhook(containerManager); err != nil {
+			glog.Fatalf("Failed to start event webhook: %v", err)
+		}
+	}
+
+	// Install signal handlers.
 	installSignalHandler(containerManager)
+	installRefreshSignalHandler(containerManager)
 
 	glog.Infof("Starting cAdvisor version: %q on port %d", version.VERSION, *argPort)
 
 	addr := fmt.Sprintf("%s:%d", *argIp, *argPort)
-	glog.Fatal(http.ListenAndServe(addr, nil))
+	server := cadvisorHttp.NewServer(addr, mux, cadvisorHttp.ServerOptions{
+		AuthFile:        *serverAuthFile,
+		AuthRealm:       *serverAuthRealm,
+		AuthExemptPaths: splitList(*serverAuthExemptPaths),
+		TLSCertFile:     *tlsCertFile,
+		TLSKeyFile:      *tlsKeyFile,
+	})
+	glog.Fatal(server.ListenAndServe())
+}
+
+// Splits a comma-separated list, ignoring empty elements.
+func splitList(list string) []string {
+	var ret []string
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			ret = append(ret, s)
+		}
+	}
+	return ret
 }
 
 func setMaxProcs() {
@@ -111,6 +176,32 @@ func setMaxProcs() {
 	}
 }
 
+// Creates the manager, serving the containers of the shared storage read-only
+// if --read_only_storage_dir is set.
+func newManager(memoryStorage *memory.InMemoryStorage, sysFs sysfs.SysFs, options manager.Options) (manager.Manager, error) {
+	if *readOnlyStorageDir == "" {
+		return manager.New(memoryStorage, sysFs, options)
+	}
+	store, err := shared.New(*readOnlyStorageDir, shared.DefaultMaxStats, shared.DefaultMaxAge)
+	if err != nil {
+		return nil, err
+	}
+	glog.Infof("Serving the containers stored in %q read-only", *readOnlyStorageDir)
+	return manager.NewReadOnly(memoryStorage, sysFs, store)
+}
+
+func startEventWebhook(containerManager manager.Manager) error {
+	eventTypes, err := webhook.ParseEventTypes(*eventWebhookTypes)
+	if err != nil {
+		return err
+	}
+	request := events.NewRequest()
+	request.ContainerName = "/"
+	request.IncludeSubcontainers = true
+	request.EventType = eventTypes
+	return webhook.New(*eventWebhookUrl, *eventWebhookQueueSize).Start(containerManager, request)
+}
+
 func installSignalHandler(containerManager manager.Manager) {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, os.Kill, syscall.SIGTERM)
@@ -118,10 +209,28 @@ func installSignalHandler(containerManager manager.Manager) {
 	// Block until a signal is received.
 	go func() {
 		sig := <-c
-		if err := containerManager.Stop(); err != nil {
-			glog.Errorf("Failed to stop container manager: %v", err)
+		ctx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+		if err := containerManager.Shutdown(ctx); err != nil {
+			glog.Errorf("Failed to shut down container manager: %v", err)
 		}
+		cancel()
 		glog.Infof("Exiting given signal: %v", sig)
 		os.Exit(0)
 	}()
 }
+
+// Refreshes the machine info on SIGHUP, e.g. after CPUs or memory were
+// hot-added.
+func installRefreshSignalHandler(containerManager manager.Manager) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+
+	go func() {
+		for range c {
+			glog.Infof("Refreshing machine info given SIGHUP")
+			if err := containerManager.RefreshMachineInfo(); err != nil {
+				glog.Errorf("Failed to refresh machine info: %v", err)
+			}
+		}
+	}()
+}
@@ -33,6 +33,9 @@ type SubcontainerEventType int
 const (
 	SubcontainerAdd SubcontainerEventType = iota
 	SubcontainerDelete
+	// Subcontainers can no longer all be watched, e.g. because the inotify
+	// watches are exhausted, so they must be detected by polling.
+	SubcontainerWatchFailure
 )
 
 // SubcontainerEvent represents a
@@ -43,6 +43,8 @@ var DockerNamespace = "docker"
 var dockerRootDir = flag.String("docker_root", "/var/lib/docker", "Absolute path to the Docker state root directory (default: /var/lib/docker)")
 var dockerRunDir = flag.String("docker_run", "/var/run/docker", "Absolute path to the Docker run directory (default: /var/run/docker)")
 
+var volumeFsStats = flag.Bool("docker_volume_fs_stats", false, "Whether to report the filesystem usage of the volumes of Docker containers separately from the filesystem of the container")
+
 // TODO(vmarmol): Export run dir too for newer Dockers.
 // Directory holding Docker container state information.
 func DockerStateDir() string {
@@ -88,6 +90,9 @@ type dockerFactory struct {
 
 	// Information about mounted filesystems.
 	fsInfo fs.FsInfo
+
+	// Repository tags of the images of the containers.
+	images *imageCache
 }
 
 func (self *dockerFactory) String() string {
@@ -106,6 +111,7 @@ func (self *dockerFactory) NewContainerHandler(name string) (handler container.C
 		self.fsInfo,
 		self.usesAufsDriver,
 		&self.cgroupSubsystems,
+		self.images,
 	)
 	return
 }
@@ -168,8 +174,9 @@ func parseDockerVersion(full_version_string string) ([]int, error) {
 	return version_array, nil
 }
 
-// Register root container before running this function!
-func Register(factory info.MachineInfoFactory, fsInfo fs.FsInfo) error {
+// Register root container before running this function! Docker containers are
+// asked for before the factories of lower priority.
+func Register(factory info.MachineInfoFactory, fsInfo fs.FsInfo, priority int) error {
 	client, err := docker.NewClient(*ArgDockerEndpoint)
 	if err != nil {
 		return fmt.Errorf("unable to communicate with docker daemon: %v", err)
@@ -232,7 +239,8 @@ func Register(factory info.MachineInfoFactory, fsInfo fs.FsInfo) error {
 		usesAufsDriver:     usesAufsDriver,
 		cgroupSubsystems:   cgroupSubsystems,
 		fsInfo:             fsInfo,
+		images:             newImageCache(client),
 	}
-	container.RegisterContainerHandlerFactory(f)
+	container.RegisterContainerHandlerFactory(f, priority)
 	return nil
 }
@@ -16,9 +16,12 @@
 package docker
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"math"
 	"path"
+	"sort"
 	"strings"
 	"time"
 
@@ -26,11 +29,13 @@ import (
 	cgroup_fs "github.com/docker/libcontainer/cgroups/fs"
 	libcontainerConfigs "github.com/docker/libcontainer/configs"
 	"github.com/fsouza/go-dockerclient"
+	"github.com/golang/glog"
 	"github.com/google/cadvisor/container"
 	containerLibcontainer "github.com/google/cadvisor/container/libcontainer"
 	"github.com/google/cadvisor/fs"
 	info "github.com/google/cadvisor/info/v1"
 	"github.com/google/cadvisor/utils"
+	"github.com/google/cadvisor/utils/procfs"
 )
 
 // Path to aufs dir where all the files exist.
@@ -59,7 +64,11 @@ type dockerContainerHandler struct {
 	// (e.g.: "cpu" -> "/sys/fs/cgroup/cpu/test")
 	cgroupPaths map[string]string
 
-	// Manager of this container's cgroups.
+	// Paths of the subsystems bound to the cgroup v2 unified hierarchy, which
+	// cgroupManager does not read.
+	unifiedPaths map[string]string
+
+	// Manager of this container's cgroup v1 cgroups.
 	cgroupManager cgroups.Manager
 
 	usesAufsDriver bool
@@ -68,6 +77,26 @@ type dockerContainerHandler struct {
 
 	// Time at which this container was created.
 	creationTime time.Time
+
+	// ID of the image of this container and the time it was created.
+	imageID           string
+	imageCreationTime time.Time
+
+	// Registry, repository, tags and repository digest of the image of this
+	// container.
+	imageRegistry   string
+	imageRepository string
+	imageRepoTags   []string
+	imageRepoDigest string
+
+	// Volumes and bind mounts of this container.
+	mounts []info.Mount
+
+	// Labels of this container.
+	labels map[string]string
+
+	// Resource limits of the processes of this container.
+	ulimits []info.Ulimit
 }
 
 func newDockerContainerHandler(
@@ -77,6 +106,7 @@ func newDockerContainerHandler(
 	fsInfo fs.FsInfo,
 	usesAufsDriver bool,
 	cgroupSubsystems *containerLibcontainer.CgroupSubsystems,
+	images *imageCache,
 ) (container.ContainerHandler, error) {
 	// Create the cgroup paths.
 	cgroupPaths := make(map[string]string, len(cgroupSubsystems.MountPoints))
@@ -85,11 +115,12 @@ func newDockerContainerHandler(
 	}
 
 	// Generate the equivalent cgroup manager for this container.
+	v1Paths, unifiedPaths := cgroupSubsystems.SplitPaths(cgroupPaths)
 	cgroupManager := &cgroup_fs.Manager{
 		Cgroups: &libcontainerConfigs.Cgroup{
 			Name: name,
 		},
-		Paths: cgroupPaths,
+		Paths: v1Paths,
 	}
 
 	id := ContainerNameToDockerId(name)
@@ -99,6 +130,7 @@ func newDockerContainerHandler(
 		name:               name,
 		machineInfoFactory: machineInfoFactory,
 		cgroupPaths:        cgroupPaths,
+		unifiedPaths:       unifiedPaths,
 		cgroupManager:      cgroupManager,
 		usesAufsDriver:     usesAufsDriver,
 		fsInfo:             fsInfo,
@@ -112,6 +144,38 @@ func newDockerContainerHandler(
 	}
 	handler.creationTime = ctnr.Created
 
+	// The image may have been deleted since the container started, in which
+	// case only the image ID from the container is known.
+	handler.imageID = ctnr.Image
+	if ctnr.Image != "" {
+		image, err := client.InspectImage(ctnr.Image)
+		if err != nil {
+			glog.V(4).Infof("Unable to inspect image %q of container %q: %v", ctnr.Image, id, err)
+		} else {
+			handler.imageCreationTime = image.Created
+		}
+	}
+	handler.imageRepoTags = images.repoTagsOf(ctnr.Image)
+	if ctnr.Config != nil {
+		handler.imageRegistry, handler.imageRepository = imageRegistryAndRepository(ctnr.Config.Image, ctnr.Image, handler.imageRepoTags)
+		// Only the digest of a reference such as "redis@sha256:<hex>" is
+		// known, the client does not list the repository digests of images.
+		if i := strings.Index(ctnr.Config.Image, "@"); i >= 0 {
+			handler.imageRepoDigest = ctnr.Config.Image[i+1:]
+		}
+	}
+
+	handler.mounts = dockerMounts(ctnr)
+
+	// Labels are not exposed by the Docker client, read them from the on-disk state.
+	state, err := handler.readDockerState()
+	if err != nil {
+		glog.V(4).Infof("Unable to read labels of container %q: %v", id, err)
+	} else {
+		handler.labels = state.Config.Labels
+	}
+	handler.ulimits = handler.readUlimits(state)
+
 	// Add the name and bare ID as aliases of the container.
 	handler.aliases = append(handler.aliases, strings.TrimPrefix(ctnr.Name, "/"))
 	handler.aliases = append(handler.aliases, id)
@@ -124,9 +188,21 @@ func (self *dockerContainerHandler) ContainerReference() (info.ContainerReferenc
 		Name:      self.name,
 		Aliases:   self.aliases,
 		Namespace: DockerNamespace,
+		StableID:  self.stableID(),
 	}, nil
 }
 
+// Returns the identifier of the container persisting across its restarts. The
+// kubelet replaces restarted containers by new Docker containers, so those are
+// identified by their pod and container name. Other Docker containers keep
+// their ID when restarted.
+func (self *dockerContainerHandler) stableID() string {
+	if id := container.KubernetesStableID(self.labels); id != "" {
+		return id
+	}
+	return self.id
+}
+
 func (self *dockerContainerHandler) readLibcontainerConfig() (*libcontainerConfigs.Config, error) {
 	config, err := containerLibcontainer.ReadConfig(*dockerRootDir, *dockerRunDir, self.id)
 	if err != nil {
@@ -143,6 +219,144 @@ func (self *dockerContainerHandler) readLibcontainerConfig() (*libcontainerConfi
 	return config, nil
 }
 
+// Subset of the container state Docker persists in <docker_root>/containers/<id>/config.json.
+// Read from disk since the Docker client we depend on does not expose all of it.
+type dockerState struct {
+	State struct {
+		Pid       int  `json:"Pid"`
+		ExitCode  int  `json:"ExitCode"`
+		OOMKilled bool `json:"OOMKilled"`
+	} `json:"State"`
+	Config struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+}
+
+func (self *dockerContainerHandler) readDockerState() (*dockerState, error) {
+	out, err := ioutil.ReadFile(path.Join(DockerStateDir(), self.id, "config.json"))
+	if err != nil {
+		return nil, err
+	}
+	var state dockerState
+	err = json.Unmarshal(out, &state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Docker state of container %q: %v", self.id, err)
+	}
+	return &state, nil
+}
+
+// Returns the ulimits of the main process of the container, from
+// /proc/<pid>/limits. They are the ones the container was started with, the
+// defaults of the runtime for those not configured. Empty if they cannot be
+// read, e.g. when the container is not running.
+func (self *dockerContainerHandler) readUlimits(state *dockerState) []info.Ulimit {
+	ulimits := []info.Ulimit{}
+	if state == nil || state.State.Pid == 0 {
+		return ulimits
+	}
+	limits, err := procfs.GetLimits(state.State.Pid)
+	if err != nil {
+		glog.V(4).Infof("Unable to read the limits of the process of container %q: %v", self.id, err)
+		return ulimits
+	}
+	for _, limit := range limits {
+		ulimits = append(ulimits, info.Ulimit{
+			Name:      limit.Name,
+			SoftLimit: limit.Soft,
+			HardLimit: limit.Hard,
+		})
+	}
+	return ulimits
+}
+
+// Returns the volumes and bind mounts of the container, sorted by destination.
+func dockerMounts(ctnr *docker.Container) []info.Mount {
+	volumesDir := path.Join(*dockerRootDir, "vfs", "dir")
+	mounts := make([]info.Mount, 0, len(ctnr.Volumes))
+	for destination, source := range ctnr.Volumes {
+		mount := info.Mount{
+			Source:      source,
+			Destination: destination,
+			Mode:        "ro",
+			Type:        "bind",
+		}
+		if ctnr.VolumesRW[destination] {
+			mount.Mode = "rw"
+		}
+		if strings.HasPrefix(source, volumesDir) || strings.HasPrefix(source, path.Join(*dockerRootDir, "volumes")) {
+			mount.Type = "volume"
+		}
+		mounts = append(mounts, mount)
+	}
+	sort.Sort(mountsByDestination(mounts))
+	return mounts
+}
+
+// Returns whether ref is empty or refers to the image by ID, in full or
+// abbreviated, rather than by repository.
+func isImageId(ref, imageId string) bool {
+	if ref == "" {
+		return true
+	}
+	ref = strings.TrimPrefix(ref, "sha256:")
+	imageId = strings.TrimPrefix(imageId, "sha256:")
+	return imageId != "" && strings.HasPrefix(imageId, ref)
+}
+
+// Returns the registry and repository of an image from the reference the
+// container was created with or, for containers created from an image ID, from
+// the first tag of the image. Both are empty if neither names a repository.
+func imageRegistryAndRepository(ref, imageId string, repoTags []string) (string, string) {
+	if isImageId(ref, imageId) {
+		if len(repoTags) == 0 || strings.HasPrefix(repoTags[0], "<none>") {
+			return "", ""
+		}
+		ref = repoTags[0]
+	}
+	return ParseImageReference(ref)
+}
+
+// Registry of image references without an explicit registry host.
+const defaultImageRegistry = "docker.io"
+
+// Splits an image reference such as "registry:5000/team/app:1.0" or
+// "nginx@sha256:..." into its registry host and repository. The first
+// component is a registry host only when it contains a "." or ":" or is
+// "localhost"; otherwise the image is on Docker Hub, where official images
+// are in the "library/" namespace.
+func ParseImageReference(ref string) (registry, repository string) {
+	// Drop the digest then the tag, whose ":" follows the last "/".
+	if i := strings.Index(ref, "@"); i >= 0 {
+		ref = ref[:i]
+	}
+	if i := strings.LastIndex(ref, ":"); i >= 0 && !strings.Contains(ref[i:], "/") {
+		ref = ref[:i]
+	}
+
+	registry = defaultImageRegistry
+	repository = ref
+	if i := strings.Index(ref, "/"); i >= 0 {
+		host := ref[:i]
+		if strings.ContainsAny(host, ".:") || host == "localhost" {
+			registry = host
+			repository = ref[i+1:]
+		}
+	}
+	if registry == "index.docker.io" {
+		registry = defaultImageRegistry
+	}
+	if registry == defaultImageRegistry && !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+	return registry, repository
+}
+
+type mountsByDestination []info.Mount
+
+func (self mountsByDestination) Len() int           { return len(self) }
+func (self mountsByDestination) Swap(i, j int)      { self[i], self[j] = self[j], self[i] }
+func (self mountsByDestination) Less(i, j int) bool { return self[i].Destination < self[j].Destination }
+
 func libcontainerConfigToContainerSpec(config *libcontainerConfigs.Config, mi *info.MachineInfo) info.ContainerSpec {
 	var spec info.ContainerSpec
 	spec.HasMemory = true
@@ -169,6 +383,31 @@ func libcontainerConfigToContainerSpec(config *libcontainerConfigs.Config, mi *i
 	return spec
 }
 
+// Returns the device access rules Docker configured the container with.
+func configuredDeviceAccess(config *libcontainerConfigs.Config) []info.DeviceAccessRule {
+	if config.Cgroups == nil {
+		return nil
+	}
+	if config.Cgroups.AllowAllDevices {
+		return []info.DeviceAccessRule{{
+			Type:        "a",
+			Major:       info.DeviceWildcard,
+			Minor:       info.DeviceWildcard,
+			Permissions: "rwm",
+		}}
+	}
+	rules := make([]info.DeviceAccessRule, 0, len(config.Cgroups.AllowedDevices))
+	for _, device := range config.Cgroups.AllowedDevices {
+		rules = append(rules, info.DeviceAccessRule{
+			Type:        string(device.Type),
+			Major:       device.Major,
+			Minor:       device.Minor,
+			Permissions: device.Permissions,
+		})
+	}
+	return rules
+}
+
 func (self *dockerContainerHandler) GetSpec() (info.ContainerSpec, error) {
 	mi, err := self.machineInfoFactory.GetMachineInfo()
 	if err != nil {
@@ -181,11 +420,52 @@ func (self *dockerContainerHandler) GetSpec() (info.ContainerSpec, error) {
 
 	spec := libcontainerConfigToContainerSpec(libcontainerConfig, mi)
 	spec.CreationTime = self.creationTime
+	spec.ImageID = self.imageID
+	spec.ImageCreationTime = self.imageCreationTime
+	spec.ImageRegistry = self.imageRegistry
+	spec.ImageRepository = self.imageRepository
+	spec.ImageRepoTags = self.imageRepoTags
+	spec.ImageRepoDigest = self.imageRepoDigest
+	spec.Mounts = self.mounts
+	spec.Labels = self.labels
+	spec.Ulimits = self.ulimits
+	spec.Kubernetes = container.KubernetesMetadataFromLabels(self.labels)
+	spec.Cpu.Burst = containerLibcontainer.GetCpuBurst(self.cgroupPaths["cpu"])
+	spec.Cpu.Idle = containerLibcontainer.GetCpuIdle(self.cgroupPaths["cpu"])
+	_, unifiedCpuset := self.unifiedPaths["cpuset"]
+	spec.AllowedCpus, spec.AllowedMems = containerLibcontainer.GetCpuset(self.cgroupPaths["cpuset"], unifiedCpuset)
+	spec.IoMax = containerLibcontainer.GetIoMax(self.unifiedPaths["blkio"])
+	spec.DeviceAccess = containerLibcontainer.GetDeviceAccess(self.cgroupPaths["devices"])
+	if len(spec.DeviceAccess) == 0 {
+		// The rules of cgroup v2 cannot be read back, those Docker configured
+		// are reported instead.
+		spec.DeviceAccess = configuredDeviceAccess(libcontainerConfig)
+	}
+	spec.NetClsId = containerLibcontainer.GetNetClsId(self.cgroupPaths["net_cls"])
+	spec.NetPrioMap = containerLibcontainer.GetNetPrioMap(self.cgroupPaths["net_prio"])
+	spec.ControllersAvailable = containerLibcontainer.GetAvailableControllers(self.cgroupPaths, self.unifiedPaths)
 	if self.usesAufsDriver {
 		spec.HasFilesystem = true
 	}
 
-	return spec, err
+	// Containers that never exited report a zero exit code and no OOM kill.
+	state, err := self.readDockerState()
+	if err != nil {
+		glog.V(4).Infof("Unable to read Docker state of container %q: %v", self.name, err)
+	} else {
+		spec.LastExitCode = state.State.ExitCode
+		spec.LastOOMKilled = state.State.OOMKilled
+		if state.State.Pid != 0 {
+			adj, err := procfs.GetOomScoreAdj(state.State.Pid)
+			if err != nil {
+				glog.V(4).Infof("Unable to read the OOM score adjustment of container %q: %v", self.name, err)
+			} else {
+				spec.OomScoreAdj = &adj
+			}
+		}
+	}
+
+	return spec, nil
 }
 
 func (self *dockerContainerHandler) getFsStats(stats *info.ContainerStats) error {
@@ -231,6 +511,60 @@ func (self *dockerContainerHandler) getFsStats(stats *info.ContainerStats) error
 	return nil
 }
 
+// Appends the usage and IO counters of the filesystem backing each volume of
+// the container. Volumes whose filesystem cannot be read are skipped.
+func (self *dockerContainerHandler) getVolumeFsStats(stats *info.ContainerStats) {
+	for _, mount := range self.mounts {
+		if mount.Type != "volume" {
+			continue
+		}
+		volumeFs, err := self.getVolumeFs(mount.Source)
+		if err != nil {
+			glog.V(4).Infof("Unable to get the filesystem of volume %q of container %q: %v", mount.Destination, self.name, err)
+			continue
+		}
+		stats.Filesystem = append(stats.Filesystem, info.FsStats{
+			Device:          volumeFs.Device,
+			Volume:          mount.Destination,
+			Limit:           volumeFs.Capacity,
+			Usage:           volumeFs.Capacity - volumeFs.Free,
+			ReadsCompleted:  volumeFs.DiskStats.ReadsCompleted,
+			ReadsMerged:     volumeFs.DiskStats.ReadsMerged,
+			SectorsRead:     volumeFs.DiskStats.SectorsRead,
+			ReadTime:        volumeFs.DiskStats.ReadTime,
+			WritesCompleted: volumeFs.DiskStats.WritesCompleted,
+			WritesMerged:    volumeFs.DiskStats.WritesMerged,
+			SectorsWritten:  volumeFs.DiskStats.SectorsWritten,
+			WriteTime:       volumeFs.DiskStats.WriteTime,
+			IoInProgress:    volumeFs.DiskStats.IoInProgress,
+			IoTime:          volumeFs.DiskStats.IoTime,
+			WeightedIoTime:  volumeFs.DiskStats.WeightedIoTime,
+		})
+	}
+}
+
+// Returns the filesystem holding the directory.
+func (self *dockerContainerHandler) getVolumeFs(dir string) (*fs.Fs, error) {
+	deviceInfo, err := self.fsInfo.GetDirFsDevice(dir)
+	if err != nil {
+		return nil, err
+	}
+	mountpoint, err := self.fsInfo.GetMountpointForDevice(deviceInfo.Device)
+	if err != nil {
+		return nil, err
+	}
+	filesystems, err := self.fsInfo.GetFsInfoForPath(map[string]struct{}{mountpoint: {}})
+	if err != nil {
+		return nil, err
+	}
+	for i := range filesystems {
+		if filesystems[i].Device == deviceInfo.Device {
+			return &filesystems[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no filesystem found for device %q", deviceInfo.Device)
+}
+
 // TODO(vmarmol): Get from libcontainer API instead of cgroup manager when we don't have to support older Dockers.
 func (self *dockerContainerHandler) GetStats() (*info.ContainerStats, error) {
 	config, err := self.readLibcontainerConfig()
@@ -250,7 +584,7 @@ func (self *dockerContainerHandler) GetStats() (*info.ContainerStats, error) {
 			}
 		}
 	}
-	stats, err := containerLibcontainer.GetStats(self.cgroupManager, networkInterfaces)
+	stats, err := containerLibcontainer.GetStats(self.cgroupManager, self.unifiedPaths, networkInterfaces)
 	if err != nil {
 		return stats, err
 	}
@@ -273,6 +607,9 @@ func (self *dockerContainerHandler) GetStats() (*info.ContainerStats, error) {
 	if err != nil {
 		return stats, err
 	}
+	if *volumeFsStats {
+		self.getVolumeFsStats(stats)
+	}
 
 	return stats, nil
 }
@@ -0,0 +1,247 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	libcontainerConfigs "github.com/docker/libcontainer/configs"
+	"github.com/fsouza/go-dockerclient"
+	"github.com/google/cadvisor/fs"
+	info "github.com/google/cadvisor/info/v1"
+)
+
+func TestDockerMounts(t *testing.T) {
+	ctnr := &docker.Container{
+		Volumes: map[string]string{
+			"/logs": "/var/log/app",
+			"/data": "/var/lib/docker/vfs/dir/1234",
+		},
+		VolumesRW: map[string]bool{
+			"/data": true,
+		},
+	}
+	expected := []info.Mount{
+		{
+			Source:      "/var/lib/docker/vfs/dir/1234",
+			Destination: "/data",
+			Mode:        "rw",
+			Type:        "volume",
+		},
+		{
+			Source:      "/var/log/app",
+			Destination: "/logs",
+			Mode:        "ro",
+			Type:        "bind",
+		},
+	}
+	mounts := dockerMounts(ctnr)
+	if !reflect.DeepEqual(mounts, expected) {
+		t.Errorf("expected mounts %+v, got %+v", expected, mounts)
+	}
+
+	mounts = dockerMounts(&docker.Container{})
+	if mounts == nil || len(mounts) != 0 {
+		t.Errorf("expected an empty slice for a container without mounts, got %#v", mounts)
+	}
+}
+
+func TestConfiguredDeviceAccess(t *testing.T) {
+	config := &libcontainerConfigs.Config{
+		Cgroups: &libcontainerConfigs.Cgroup{
+			AllowedDevices: []*libcontainerConfigs.Device{
+				{Type: 'c', Major: 1, Minor: 3, Permissions: "rwm"},
+				{Type: 'c', Major: 136, Minor: libcontainerConfigs.Wildcard, Permissions: "rw"},
+			},
+		},
+	}
+	expected := []info.DeviceAccessRule{
+		{Type: "c", Major: 1, Minor: 3, Permissions: "rwm"},
+		{Type: "c", Major: 136, Minor: info.DeviceWildcard, Permissions: "rw"},
+	}
+	if rules := configuredDeviceAccess(config); !reflect.DeepEqual(rules, expected) {
+		t.Errorf("expected %+v, got %+v", expected, rules)
+	}
+
+	config.Cgroups.AllowAllDevices = true
+	expected = []info.DeviceAccessRule{{Type: "a", Major: info.DeviceWildcard, Minor: info.DeviceWildcard, Permissions: "rwm"}}
+	if rules := configuredDeviceAccess(config); !reflect.DeepEqual(rules, expected) {
+		t.Errorf("expected access to all devices, got %+v", rules)
+	}
+}
+
+func TestParseImageReference(t *testing.T) {
+	tests := []struct {
+		ref        string
+		registry   string
+		repository string
+	}{
+		{"nginx", "docker.io", "library/nginx"},
+		{"nginx:1.9", "docker.io", "library/nginx"},
+		{"google/cadvisor:latest", "docker.io", "google/cadvisor"},
+		{"docker.io/library/nginx", "docker.io", "library/nginx"},
+		{"index.docker.io/nginx", "docker.io", "library/nginx"},
+		{"gcr.io/google_containers/pause:2.0", "gcr.io", "google_containers/pause"},
+		{"registry.example.com:5000/team/app", "registry.example.com:5000", "team/app"},
+		{"registry.example.com:5000/team/app:1.0", "registry.example.com:5000", "team/app"},
+		{"localhost/app", "localhost", "app"},
+		{"localhost:5000/app:dev", "localhost:5000", "app"},
+		{"nginx@sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", "docker.io", "library/nginx"},
+		{"registry.example.com:5000/app:1.0@sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", "registry.example.com:5000", "app"},
+	}
+	for _, test := range tests {
+		registry, repository := ParseImageReference(test.ref)
+		if registry != test.registry || repository != test.repository {
+			t.Errorf("expected %q to be parsed as %q and %q, got %q and %q", test.ref, test.registry, test.repository, registry, repository)
+		}
+	}
+}
+
+func TestImageRegistryAndRepository(t *testing.T) {
+	imageId := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	tags := []string{"registry.example.com:5000/app:1.0", "app:latest"}
+	tests := []struct {
+		ref        string
+		tags       []string
+		registry   string
+		repository string
+	}{
+		{"gcr.io/app:2.0", tags, "gcr.io", "app"},
+		// Created from the image ID, attributed to the first tag.
+		{imageId, tags, "registry.example.com:5000", "app"},
+		{imageId[:12], tags, "registry.example.com:5000", "app"},
+		{"sha256:" + imageId, tags, "registry.example.com:5000", "app"},
+		{imageId, nil, "", ""},
+		{imageId, []string{"<none>:<none>"}, "", ""},
+		{"", nil, "", ""},
+	}
+	for _, test := range tests {
+		registry, repository := imageRegistryAndRepository(test.ref, imageId, test.tags)
+		if registry != test.registry || repository != test.repository {
+			t.Errorf("expected %q with tags %v to be %q and %q, got %q and %q", test.ref, test.tags, test.registry, test.repository, registry, repository)
+		}
+	}
+}
+
+func TestStableID(t *testing.T) {
+	const id = "2c4dee605d22e7b1fbc1ae1e4e6d6ab2c98cbcf8e1b0f6f5a19d3c0b3e1d4a7f"
+	handler := &dockerContainerHandler{
+		id: id,
+		labels: map[string]string{
+			"io.kubernetes.pod.uid":        "0f8e1d62-5b2a-11e5-9f9c-42010af00002",
+			"io.kubernetes.container.name": "nginx",
+		},
+	}
+	if stableID := handler.stableID(); stableID != "0f8e1d62-5b2a-11e5-9f9c-42010af00002/nginx" {
+		t.Errorf("expected the pod UID and container name as stable ID, got %q", stableID)
+	}
+
+	// Docker containers outside Kubernetes keep their ID across restarts.
+	handler.labels = nil
+	if stableID := handler.stableID(); stableID != id {
+		t.Errorf("expected the Docker ID as stable ID, got %q", stableID)
+	}
+}
+
+// Serves the filesystems of the directories it knows, by device.
+type fakeFsInfo struct {
+	fs.FsInfo
+	dirDevices  map[string]string
+	filesystems map[string]fs.Fs
+}
+
+func (self *fakeFsInfo) GetDirFsDevice(dir string) (*fs.DeviceInfo, error) {
+	device, ok := self.dirDevices[dir]
+	if !ok {
+		return nil, fmt.Errorf("unknown directory %q", dir)
+	}
+	return &fs.DeviceInfo{Device: device}, nil
+}
+
+func (self *fakeFsInfo) GetMountpointForDevice(device string) (string, error) {
+	return "/mnt" + device, nil
+}
+
+func (self *fakeFsInfo) GetFsInfoForPath(mountSet map[string]struct{}) ([]fs.Fs, error) {
+	var ret []fs.Fs
+	for device, filesystem := range self.filesystems {
+		if _, ok := mountSet["/mnt"+device]; ok {
+			ret = append(ret, filesystem)
+		}
+	}
+	return ret, nil
+}
+
+func TestVolumeFsStats(t *testing.T) {
+	sdb := fs.Fs{
+		DeviceInfo: fs.DeviceInfo{Device: "/dev/sdb1"},
+		Capacity:   1000,
+		Free:       400,
+		DiskStats: fs.DiskStats{
+			ReadsCompleted:  1,
+			ReadsMerged:     2,
+			SectorsRead:     3,
+			ReadTime:        4,
+			WritesCompleted: 5,
+			WritesMerged:    6,
+			SectorsWritten:  7,
+			WriteTime:       8,
+			IoInProgress:    9,
+			IoTime:          10,
+			WeightedIoTime:  11,
+		},
+	}
+	handler := &dockerContainerHandler{
+		name: "/docker/abcd",
+		fsInfo: &fakeFsInfo{
+			dirDevices: map[string]string{
+				"/var/lib/docker/volumes/data": "/dev/sdb1",
+				"/var/log/app":                 "/dev/sdb1",
+			},
+			filesystems: map[string]fs.Fs{"/dev/sdb1": sdb},
+		},
+		mounts: []info.Mount{
+			{Source: "/var/lib/docker/volumes/data", Destination: "/data", Type: "volume"},
+			// Bind mounts are not reported.
+			{Source: "/var/log/app", Destination: "/logs", Type: "bind"},
+			// Volumes whose filesystem is unknown are skipped.
+			{Source: "/var/lib/docker/volumes/gone", Destination: "/gone", Type: "volume"},
+		},
+	}
+	stats := &info.ContainerStats{}
+	handler.getVolumeFsStats(stats)
+	expected := []info.FsStats{{
+		Device:          "/dev/sdb1",
+		Volume:          "/data",
+		Limit:           1000,
+		Usage:           600,
+		ReadsCompleted:  1,
+		ReadsMerged:     2,
+		SectorsRead:     3,
+		ReadTime:        4,
+		WritesCompleted: 5,
+		WritesMerged:    6,
+		SectorsWritten:  7,
+		WriteTime:       8,
+		IoInProgress:    9,
+		IoTime:          10,
+		WeightedIoTime:  11,
+	}}
+	if !reflect.DeepEqual(stats.Filesystem, expected) {
+		t.Errorf("expected volume stats %+v, got %+v", expected, stats.Filesystem)
+	}
+}
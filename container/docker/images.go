@@ -0,0 +1,70 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/golang/glog"
+)
+
+// How long the listed images are used before being listed again.
+const imageCacheMaxAge = time.Minute
+
+// Repository tags of the images, listed once for all the containers created
+// at about the same time rather than once per container.
+type imageCache struct {
+	listImages func() ([]docker.APIImages, error)
+
+	lock     sync.Mutex
+	repoTags map[string][]string
+	listed   time.Time
+}
+
+func newImageCache(client *docker.Client) *imageCache {
+	return &imageCache{
+		listImages: func() ([]docker.APIImages, error) {
+			return client.ListImages(false)
+		},
+	}
+}
+
+// Returns the repository tags of the image with the specified ID, nil if they
+// could not be listed. The images are listed again once the cache expires or
+// if the image is missing from it, e.g. because it was just pulled.
+func (self *imageCache) repoTagsOf(imageId string) []string {
+	if imageId == "" {
+		return nil
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if tags, ok := self.repoTags[imageId]; ok && time.Since(self.listed) < imageCacheMaxAge {
+		return tags
+	}
+	images, err := self.listImages()
+	if err != nil {
+		glog.V(4).Infof("Unable to list images: %v", err)
+		return nil
+	}
+	self.repoTags = make(map[string][]string, len(images))
+	for _, image := range images {
+		self.repoTags[image.ID] = image.RepoTags
+	}
+	self.listed = time.Now()
+	return self.repoTags[imageId]
+}
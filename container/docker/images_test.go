@@ -0,0 +1,64 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+func TestImageCache(t *testing.T) {
+	lists := 0
+	images := []docker.APIImages{{ID: "a1", RepoTags: []string{"nginx:1.9", "nginx:latest"}}}
+	cache := &imageCache{
+		listImages: func() ([]docker.APIImages, error) {
+			lists++
+			return images, nil
+		},
+	}
+
+	// Containers of the same image share a single listing.
+	for i := 0; i < 3; i++ {
+		if tags := cache.repoTagsOf("a1"); !reflect.DeepEqual(tags, []string{"nginx:1.9", "nginx:latest"}) {
+			t.Errorf("expected the tags of nginx, got %v", tags)
+		}
+	}
+	if lists != 1 {
+		t.Errorf("expected the images to be listed once, got %d", lists)
+	}
+
+	// An image pulled since is listed again.
+	images = append(images, docker.APIImages{ID: "b2", RepoTags: []string{"redis:3.0"}})
+	if tags := cache.repoTagsOf("b2"); !reflect.DeepEqual(tags, []string{"redis:3.0"}) {
+		t.Errorf("expected the tags of redis, got %v", tags)
+	}
+	if lists != 2 {
+		t.Errorf("expected the images to be listed again for a new image, got %d listings", lists)
+	}
+
+	// Retagged images are picked up once the cache expires.
+	images[0].RepoTags = []string{"nginx:1.9"}
+	cache.listed = cache.listed.Add(-imageCacheMaxAge - time.Second)
+	if tags := cache.repoTagsOf("a1"); !reflect.DeepEqual(tags, []string{"nginx:1.9"}) {
+		t.Errorf("expected the new tags of nginx, got %v", tags)
+	}
+
+	if tags := cache.repoTagsOf(""); tags != nil {
+		t.Errorf("expected no tags without an image, got %v", tags)
+	}
+}
@@ -16,6 +16,7 @@ package container
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/golang/glog"
@@ -33,19 +34,29 @@ type ContainerHandlerFactory interface {
 }
 
 // TODO(vmarmol): Consider not making this global.
-// Global list of factories.
+// Global list of factories, sorted by decreasing priority.
 var (
 	factories     []ContainerHandlerFactory
+	priorities    []int
 	factoriesLock sync.RWMutex
 )
 
-// Register a ContainerHandlerFactory. These should be registered from least general to most general
-// as they will be asked in order whether they can handle a particular container.
-func RegisterContainerHandlerFactory(factory ContainerHandlerFactory) {
+// Register a ContainerHandlerFactory. Factories are asked whether they can handle a particular
+// container from the highest priority to the lowest, so more specific factories should have a
+// higher priority than general ones. Factories of the same priority are asked in registration order.
+func RegisterContainerHandlerFactory(factory ContainerHandlerFactory, priority int) {
 	factoriesLock.Lock()
 	defer factoriesLock.Unlock()
 
-	factories = append(factories, factory)
+	i := sort.Search(len(priorities), func(i int) bool {
+		return priorities[i] < priority
+	})
+	factories = append(factories, nil)
+	copy(factories[i+1:], factories[i:])
+	factories[i] = factory
+	priorities = append(priorities, 0)
+	copy(priorities[i+1:], priorities[i:])
+	priorities[i] = priority
 }
 
 // Returns whether there are any container handler factories registered.
@@ -83,10 +94,43 @@ func NewContainerHandler(name string) (ContainerHandler, bool, error) {
 	return nil, false, fmt.Errorf("no known factory can handle creation of container")
 }
 
+// Returns the names of the registered factories in the order in which they are
+// asked whether they can handle a container, i.e. by decreasing priority.
+// Earlier factories take precedence.
+func ListFactories() []string {
+	factoriesLock.RLock()
+	defer factoriesLock.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for _, factory := range factories {
+		names = append(names, factory.String())
+	}
+	return names
+}
+
+// Returns the name of the factory that handles the specified container and
+// whether it accepts it. The name is empty if no factory can handle the container.
+func FactoryForContainer(name string) (string, bool) {
+	factoriesLock.RLock()
+	defer factoriesLock.RUnlock()
+
+	for _, factory := range factories {
+		canHandle, canAccept, err := factory.CanHandleAndAccept(name)
+		if err != nil {
+			glog.V(4).Infof("Error trying to work out if we can handle %s: %v", name, err)
+		}
+		if canHandle {
+			return factory.String(), canAccept
+		}
+	}
+	return "", false
+}
+
 // Clear the known factories.
 func ClearContainerHandlerFactories() {
 	factoriesLock.Lock()
 	defer factoriesLock.Unlock()
 
 	factories = make([]ContainerHandlerFactory, 0, 4)
+	priorities = make([]int, 0, 4)
 }
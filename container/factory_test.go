@@ -53,7 +53,7 @@ func TestNewContainerHandler_FirstMatches(t *testing.T) {
 		CanHandleValue: true,
 		CanAcceptValue: true,
 	}
-	RegisterContainerHandlerFactory(allwaysYes)
+	RegisterContainerHandlerFactory(allwaysYes, 0)
 
 	// The yes factory should be asked to create the ContainerHandler.
 	mockContainer, err := mockFactory.NewContainerHandler(testContainerName)
@@ -80,13 +80,13 @@ func TestNewContainerHandler_SecondMatches(t *testing.T) {
 		CanHandleValue: false,
 		CanAcceptValue: true,
 	}
-	RegisterContainerHandlerFactory(allwaysNo)
+	RegisterContainerHandlerFactory(allwaysNo, 0)
 	allwaysYes := &mockContainerHandlerFactory{
 		Name:           "yes",
 		CanHandleValue: true,
 		CanAcceptValue: true,
 	}
-	RegisterContainerHandlerFactory(allwaysYes)
+	RegisterContainerHandlerFactory(allwaysYes, 0)
 
 	// The yes factory should be asked to create the ContainerHandler.
 	mockContainer, err := mockFactory.NewContainerHandler(testContainerName)
@@ -113,13 +113,13 @@ func TestNewContainerHandler_NoneMatch(t *testing.T) {
 		CanHandleValue: false,
 		CanAcceptValue: true,
 	}
-	RegisterContainerHandlerFactory(allwaysNo1)
+	RegisterContainerHandlerFactory(allwaysNo1, 0)
 	allwaysNo2 := &mockContainerHandlerFactory{
 		Name:           "no",
 		CanHandleValue: false,
 		CanAcceptValue: true,
 	}
-	RegisterContainerHandlerFactory(allwaysNo2)
+	RegisterContainerHandlerFactory(allwaysNo2, 0)
 
 	_, _, err := NewContainerHandler(testContainerName)
 	if err == nil {
@@ -136,13 +136,13 @@ func TestNewContainerHandler_Accept(t *testing.T) {
 		CanHandleValue: false,
 		CanAcceptValue: true,
 	}
-	RegisterContainerHandlerFactory(cannotHandle)
+	RegisterContainerHandlerFactory(cannotHandle, 0)
 	cannotAccept := &mockContainerHandlerFactory{
 		Name:           "no",
 		CanHandleValue: true,
 		CanAcceptValue: false,
 	}
-	RegisterContainerHandlerFactory(cannotAccept)
+	RegisterContainerHandlerFactory(cannotAccept, 0)
 
 	_, accept, err := NewContainerHandler(testContainerName)
 	if err != nil {
@@ -152,3 +152,69 @@ func TestNewContainerHandler_Accept(t *testing.T) {
 		t.Error("Expected NewContainerHandler to ignore the container.")
 	}
 }
+
+func TestFactoryForContainer(t *testing.T) {
+	ClearContainerHandlerFactories()
+
+	RegisterContainerHandlerFactory(&mockContainerHandlerFactory{
+		Name:           "no",
+		CanHandleValue: false,
+		CanAcceptValue: true,
+	}, 0)
+	RegisterContainerHandlerFactory(&mockContainerHandlerFactory{
+		Name:           "ignore",
+		CanHandleValue: true,
+		CanAcceptValue: false,
+	}, 0)
+	RegisterContainerHandlerFactory(&mockContainerHandlerFactory{
+		Name:           "yes",
+		CanHandleValue: true,
+		CanAcceptValue: true,
+	}, 0)
+
+	names := ListFactories()
+	if len(names) != 3 || names[0] != "no" || names[1] != "ignore" || names[2] != "yes" {
+		t.Errorf("expected factories [no ignore yes] in registration order, got %v", names)
+	}
+
+	// The first factory that can handle the container claims it.
+	name, accept := FactoryForContainer(testContainerName)
+	if name != "ignore" {
+		t.Errorf("expected factory %q to handle the container, got %q", "ignore", name)
+	}
+	if accept {
+		t.Errorf("expected the container to not be accepted")
+	}
+}
+
+func TestFactoryPriority(t *testing.T) {
+	ClearContainerHandlerFactories()
+
+	RegisterContainerHandlerFactory(&mockContainerHandlerFactory{
+		Name:           "raw",
+		CanHandleValue: true,
+		CanAcceptValue: true,
+	}, 0)
+	RegisterContainerHandlerFactory(&mockContainerHandlerFactory{
+		Name:           "runtime",
+		CanHandleValue: true,
+		CanAcceptValue: true,
+	}, 10)
+	RegisterContainerHandlerFactory(&mockContainerHandlerFactory{
+		Name:           "other",
+		CanHandleValue: false,
+		CanAcceptValue: true,
+	}, 10)
+
+	names := ListFactories()
+	if len(names) != 3 || names[0] != "runtime" || names[1] != "other" || names[2] != "raw" {
+		t.Errorf("expected factories [runtime other raw] by priority, got %v", names)
+	}
+
+	// The factory of higher priority claims the container even though it was
+	// registered last.
+	name, accept := FactoryForContainer(testContainerName)
+	if name != "runtime" || !accept {
+		t.Errorf("expected factory %q to handle and accept the container, got %q and %v", "runtime", name, accept)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import info "github.com/google/cadvisor/info/v1"
+
+// Labels set by the kubelet on the containers it runs.
+const (
+	kubernetesPodNameLabel       = "io.kubernetes.pod.name"
+	kubernetesPodNamespaceLabel  = "io.kubernetes.pod.namespace"
+	kubernetesPodUIDLabel        = "io.kubernetes.pod.uid"
+	kubernetesContainerNameLabel = "io.kubernetes.container.name"
+)
+
+// Returns the Kubernetes metadata of a container from its labels. The metadata
+// is empty for containers not managed by Kubernetes.
+func KubernetesMetadataFromLabels(labels map[string]string) info.KubernetesMetadata {
+	return info.KubernetesMetadata{
+		PodName:       labels[kubernetesPodNameLabel],
+		Namespace:     labels[kubernetesPodNamespaceLabel],
+		PodUID:        labels[kubernetesPodUIDLabel],
+		ContainerName: labels[kubernetesContainerNameLabel],
+	}
+}
+
+// Returns the identifier of a Kubernetes container that persists across its
+// restarts, <pod UID>/<container name>, from its labels. Empty for containers
+// not managed by Kubernetes.
+func KubernetesStableID(labels map[string]string) string {
+	uid, name := labels[kubernetesPodUIDLabel], labels[kubernetesContainerNameLabel]
+	if uid == "" || name == "" {
+		return ""
+	}
+	return uid + "/" + name
+}
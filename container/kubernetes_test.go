@@ -0,0 +1,78 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+func TestKubernetesMetadataFromLabels(t *testing.T) {
+	labels := map[string]string{
+		"io.kubernetes.pod.name":       "frontend-1",
+		"io.kubernetes.pod.namespace":  "payments",
+		"io.kubernetes.pod.uid":        "0f8e1d62-5b2a-11e5-9f9c-42010af00002",
+		"io.kubernetes.container.name": "nginx",
+		"team":                         "payments",
+	}
+	expected := info.KubernetesMetadata{
+		PodName:       "frontend-1",
+		Namespace:     "payments",
+		PodUID:        "0f8e1d62-5b2a-11e5-9f9c-42010af00002",
+		ContainerName: "nginx",
+	}
+	if metadata := KubernetesMetadataFromLabels(labels); metadata != expected {
+		t.Errorf("expected %+v, got %+v", expected, metadata)
+	}
+
+	if metadata := KubernetesMetadataFromLabels(map[string]string{"team": "payments"}); metadata != (info.KubernetesMetadata{}) {
+		t.Errorf("expected no Kubernetes metadata, got %+v", metadata)
+	}
+	if metadata := KubernetesMetadataFromLabels(nil); metadata != (info.KubernetesMetadata{}) {
+		t.Errorf("expected no Kubernetes metadata, got %+v", metadata)
+	}
+}
+
+func TestKubernetesStableIDPersistsAcrossRestarts(t *testing.T) {
+	// The kubelet replaces a restarted container by a new Docker container
+	// with a new name, so only the pod UID and container name are kept.
+	before := map[string]string{
+		"io.kubernetes.pod.name":       "frontend-1",
+		"io.kubernetes.pod.uid":        "0f8e1d62-5b2a-11e5-9f9c-42010af00002",
+		"io.kubernetes.container.name": "nginx",
+		"io.kubernetes.container.hash": "4c2a4f8a",
+	}
+	after := map[string]string{
+		"io.kubernetes.pod.name":       "frontend-1",
+		"io.kubernetes.pod.uid":        "0f8e1d62-5b2a-11e5-9f9c-42010af00002",
+		"io.kubernetes.container.name": "nginx",
+		"io.kubernetes.container.hash": "9d1e03b7",
+	}
+	const expected = "0f8e1d62-5b2a-11e5-9f9c-42010af00002/nginx"
+	if id := KubernetesStableID(before); id != expected {
+		t.Errorf("expected stable ID %q, got %q", expected, id)
+	}
+	if id := KubernetesStableID(after); id != expected {
+		t.Errorf("expected stable ID %q after the restart, got %q", expected, id)
+	}
+
+	if id := KubernetesStableID(map[string]string{"io.kubernetes.pod.uid": "0f8e1d62-5b2a-11e5-9f9c-42010af00002"}); id != "" {
+		t.Errorf("expected no stable ID without the container name, got %q", id)
+	}
+	if id := KubernetesStableID(nil); id != "" {
+		t.Errorf("expected no stable ID, got %q", id)
+	}
+}
@@ -16,12 +16,20 @@ package libcontainer
 
 import (
 	"fmt"
+	"io/ioutil"
+	"math"
 	"path"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/docker/docker/pkg/mount"
 	"github.com/docker/libcontainer"
 	"github.com/docker/libcontainer/cgroups"
 	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/utils"
+	"github.com/google/cadvisor/utils/procfs"
 	"github.com/google/cadvisor/utils/sysinfo"
 )
 
@@ -33,6 +41,17 @@ type CgroupSubsystems struct {
 	// Cgroup subsystem to their mount location.
 	// e.g.: "cpu" -> "/sys/fs/cgroup/cpu"
 	MountPoints map[string]string
+
+	// Cgroup subsystems bound to the cgroup v2 unified hierarchy rather than
+	// to a cgroup v1 one, as some are on hosts with the hybrid layout.
+	// e.g.: "memory" -> true
+	Unified map[string]bool
+
+	// Cgroup v1 subsystems only read for the containers found in the other
+	// ones. Their hierarchies are neither listed nor watched, so cgroups only
+	// present in them are not taken as containers.
+	// e.g.: "pids" -> true
+	Auxiliary map[string]bool
 }
 
 // Get information about the cgroup subsystems.
@@ -42,26 +61,163 @@ func GetCgroupSubsystems() (CgroupSubsystems, error) {
 	if err != nil {
 		return CgroupSubsystems{}, err
 	}
-	if len(allCgroups) == 0 {
+	unifiedMountpoint, unifiedControllers, err := getUnifiedHierarchy()
+	if err != nil {
+		return CgroupSubsystems{}, err
+	}
+	if len(allCgroups) == 0 && unifiedMountpoint == "" {
 		return CgroupSubsystems{}, fmt.Errorf("failed to find cgroup mounts")
 	}
+	return getCgroupSubsystems(allCgroups, unifiedMountpoint, unifiedControllers), nil
+}
+
+// Returns the mount point of the cgroup v2 unified hierarchy and the
+// controllers bound to it, or an empty mount point if it is not mounted.
+func getUnifiedHierarchy() (string, []string, error) {
+	mounts, err := mount.GetMounts()
+	if err != nil {
+		return "", nil, err
+	}
+	for _, m := range mounts {
+		if m.Fstype != "cgroup2" {
+			continue
+		}
+		out, err := ioutil.ReadFile(path.Join(m.Mountpoint, "cgroup.controllers"))
+		if err != nil {
+			return "", nil, err
+		}
+		return m.Mountpoint, strings.Fields(string(out)), nil
+	}
+	return "", nil, nil
+}
+
+// Cgroup v1 subsystems provided by each cgroup v2 controller.
+var unifiedControllerSubsystems = map[string][]string{
+	"cpu":    {"cpu", "cpuacct"},
+	"cpuset": {"cpuset"},
+	"memory": {"memory"},
+	"io":     {"blkio"},
+	"pids":   {"pids"},
+	"misc":   {"misc"},
+}
 
+// Maps the subsystems we care about to the cgroup v1 hierarchy they are
+// mounted on or, for those bound to it, to the cgroup v2 unified hierarchy.
+func getCgroupSubsystems(allCgroups []cgroups.Mount, unifiedMountpoint string, unifiedControllers []string) CgroupSubsystems {
 	// Trim the mounts to only the subsystems we care about.
-	supportedCgroups := make([]cgroups.Mount, 0, len(allCgroups))
+	supportedCgroups := make([]cgroups.Mount, 0, len(allCgroups)+1)
 	mountPoints := make(map[string]string, len(allCgroups))
+	auxiliary := make(map[string]bool)
 	for _, mount := range allCgroups {
 		for _, subsystem := range mount.Subsystems {
 			if _, ok := supportedSubsystems[subsystem]; ok {
 				supportedCgroups = append(supportedCgroups, mount)
 				mountPoints[subsystem] = mount.Mountpoint
+			} else if _, ok := auxiliarySubsystems[subsystem]; ok {
+				mountPoints[subsystem] = mount.Mountpoint
+				auxiliary[subsystem] = true
 			}
 		}
 	}
 
+	// A controller is bound to a single hierarchy, so those listed by the
+	// unified hierarchy are not mounted on cgroup v1. The cgroup v1 mounts are
+	// preferred should both have it.
+	unified := make(map[string]bool)
+	if unifiedMountpoint != "" {
+		unifiedMount := cgroups.Mount{Mountpoint: unifiedMountpoint}
+		for _, controller := range unifiedControllers {
+			for _, subsystem := range unifiedControllerSubsystems[controller] {
+				if _, ok := mountPoints[subsystem]; ok {
+					continue
+				}
+				mountPoints[subsystem] = unifiedMountpoint
+				unified[subsystem] = true
+				unifiedMount.Subsystems = append(unifiedMount.Subsystems, subsystem)
+			}
+		}
+		if len(unifiedMount.Subsystems) != 0 {
+			supportedCgroups = append(supportedCgroups, unifiedMount)
+		}
+	}
+
 	return CgroupSubsystems{
 		Mounts:      supportedCgroups,
 		MountPoints: mountPoints,
-	}, nil
+		Unified:     unified,
+		Auxiliary:   auxiliary,
+	}
+}
+
+// Splits the cgroup paths of a container between the cgroup v1 hierarchies,
+// the only ones read by the libcontainer cgroup manager, and the cgroup v2
+// unified hierarchy.
+func (self *CgroupSubsystems) SplitPaths(cgroupPaths map[string]string) (v1Paths map[string]string, unifiedPaths map[string]string) {
+	v1Paths = make(map[string]string, len(cgroupPaths))
+	unifiedPaths = make(map[string]string, len(self.Unified))
+	for subsystem, cgroupPath := range cgroupPaths {
+		if self.Unified[subsystem] {
+			unifiedPaths[subsystem] = cgroupPath
+		} else {
+			v1Paths[subsystem] = cgroupPath
+		}
+	}
+	return v1Paths, unifiedPaths
+}
+
+// Returns the cgroup paths of a container in the hierarchies looked for
+// containers, i.e. without those of the auxiliary subsystems.
+func (self *CgroupSubsystems) DiscoveryPaths(cgroupPaths map[string]string) map[string]string {
+	ret := make(map[string]string, len(cgroupPaths))
+	for subsystem, cgroupPath := range cgroupPaths {
+		if !self.Auxiliary[subsystem] {
+			ret[subsystem] = cgroupPath
+		}
+	}
+	return ret
+}
+
+// Returns the cgroup subsystems present for a container, sorted. Subsystems on
+// cgroup v1 are present if the cgroup of the container exists in their
+// hierarchy. Those bound to the cgroup v2 unified hierarchy must also be
+// enabled for the cgroup of the container, i.e. have their controller listed
+// in its cgroup.controllers.
+func GetAvailableControllers(cgroupPaths map[string]string, unifiedPaths map[string]string) []string {
+	// Controllers enabled per unified cgroup path.
+	enabled := make(map[string]map[string]bool)
+	available := make([]string, 0, len(cgroupPaths))
+	for subsystem, cgroupPath := range cgroupPaths {
+		if !utils.FileExists(cgroupPath) {
+			continue
+		}
+		if unifiedPath, ok := unifiedPaths[subsystem]; ok {
+			if _, ok := enabled[unifiedPath]; !ok {
+				enabled[unifiedPath] = readEnabledSubsystems(unifiedPath)
+			}
+			if !enabled[unifiedPath][subsystem] {
+				continue
+			}
+		}
+		available = append(available, subsystem)
+	}
+	sort.Strings(available)
+	return available
+}
+
+// Returns the cgroup v1 subsystems provided by the controllers enabled for a
+// cgroup v2 cgroup.
+func readEnabledSubsystems(cgroupPath string) map[string]bool {
+	subsystems := make(map[string]bool)
+	out, err := ioutil.ReadFile(path.Join(cgroupPath, "cgroup.controllers"))
+	if err != nil {
+		return subsystems
+	}
+	for _, controller := range strings.Fields(string(out)) {
+		for _, subsystem := range unifiedControllerSubsystems[controller] {
+			subsystems[subsystem] = true
+		}
+	}
+	return subsystems
 }
 
 // Cgroup subsystems we support listing (should be the minimal set we need stats from).
@@ -73,8 +229,19 @@ var supportedSubsystems map[string]struct{} = map[string]struct{}{
 	"blkio":   {},
 }
 
-// Get cgroup and networking stats of the specified container
-func GetStats(cgroupManager cgroups.Manager, networkInterfaces []string) (*info.ContainerStats, error) {
+// Cgroup v1 subsystems we only read from for the containers found in the
+// supported ones.
+var auxiliarySubsystems = map[string]struct{}{
+	"pids":     {},
+	"devices":  {},
+	"net_cls":  {},
+	"net_prio": {},
+}
+
+// Get cgroup and networking stats of the specified container. The cgroup
+// manager reads the cgroup v1 hierarchies, unifiedPaths holds the paths of the
+// subsystems bound to the cgroup v2 unified hierarchy.
+func GetStats(cgroupManager cgroups.Manager, unifiedPaths map[string]string, networkInterfaces []string) (*info.ContainerStats, error) {
 	cgroupStats, err := cgroupManager.GetStats()
 	if err != nil {
 		return nil, err
@@ -83,6 +250,23 @@ func GetStats(cgroupManager cgroups.Manager, networkInterfaces []string) (*info.
 		CgroupStats: cgroupStats,
 	}
 	stats := toContainerStats(libcontainerStats)
+	setMemoryStatsV2(unifiedPaths["memory"], &stats.Memory)
+	setDiskIoStatsV2(unifiedPaths["blkio"], &stats.DiskIo)
+	setMiscStatsV2(unifiedPaths["misc"], stats)
+
+	cgroupPaths := make(map[string]string, len(cgroupManager.GetPaths())+len(unifiedPaths))
+	for subsystem, cgroupPath := range cgroupManager.GetPaths() {
+		cgroupPaths[subsystem] = cgroupPath
+	}
+	for subsystem, cgroupPath := range unifiedPaths {
+		cgroupPaths[subsystem] = cgroupPath
+	}
+	setKernelMemoryStats(cgroupPaths["memory"], &stats.Memory)
+	setMemoryEvents(cgroupPaths["memory"], &stats.Memory)
+	setIoLatencyStats(cgroupPaths["blkio"], &stats.DiskIo)
+	setIoPressure(cgroupPaths["blkio"], &stats.DiskIo)
+	setCpuStatsV2(cgroupPaths["cpu"], &stats.Cpu)
+	setThreadCount(cgroupPaths, unifiedPaths, &stats.Processes)
 
 	if len(networkInterfaces) != 0 {
 		// ContainerStats only reports stat for one network device.
@@ -92,10 +276,79 @@ func GetStats(cgroupManager cgroups.Manager, networkInterfaces []string) (*info.
 		if err != nil {
 			return stats, err
 		}
+		setNetworkNamespaceStats(cgroupPaths, &stats.Network)
+		if sriovStats.enabled {
+			if pid, ok := getPid(cgroupPaths); ok {
+				stats.Network.Interfaces = sriovStats.get(pid)
+			}
+		}
 	}
 	return stats, nil
 }
 
+// Sets the number of threads in the container, which count against the limit
+// of its pids cgroup just like processes. Every hierarchy holds the same
+// threads, the cpu one is read on hosts without the pids cgroup. Left at zero
+// when unavailable.
+func setThreadCount(cgroupPaths map[string]string, unifiedPaths map[string]string, ret *info.ProcessStats) {
+	for _, subsystem := range []string{"pids", "cpu"} {
+		cgroupPath, ok := cgroupPaths[subsystem]
+		if !ok {
+			continue
+		}
+		var tids []int
+		var err error
+		if _, unified := unifiedPaths[subsystem]; unified {
+			tids, err = procfs.GetCgroupThreads(cgroupPath)
+		} else {
+			tids, err = procfs.GetCgroupTasks(cgroupPath)
+		}
+		if err != nil {
+			continue
+		}
+		ret.ThreadCount = uint64(len(tids))
+		return
+	}
+}
+
+// Returns a process of the container, used to find its namespaces.
+func getPid(cgroupPaths map[string]string) (int, bool) {
+	for _, subsystem := range []string{"cpu", "memory"} {
+		if cgroupPath, ok := cgroupPaths[subsystem]; ok {
+			pids, _ := procfs.GetCgroupPids(cgroupPath)
+			if len(pids) == 0 {
+				return 0, false
+			}
+			return pids[0], true
+		}
+	}
+	return 0, false
+}
+
+// Fills in the TCP memory usage and the tracked connections of the network
+// namespace of the container, and the corresponding limits of the kernel.
+// Containers sharing the network namespace of the host report the values of the
+// host. Left at zero when unavailable, e.g. when the container has no processes
+// or connection tracking is not loaded. The IP traffic per protocol is only
+// filled in when enabled. Containers sharing a network namespace share its
+// statistics, which are read once for all of them.
+func setNetworkNamespaceStats(cgroupPaths map[string]string, ret *info.NetworkStats) {
+	pid, ok := getPid(cgroupPaths)
+	if !ok {
+		return
+	}
+	netns := sharedNetnsStats.get(pid)
+	ret.TcpMemUsage = netns.tcpMemUsage
+	ret.ConntrackCount = netns.conntrackCount
+	ret.Protocols = netns.protocols
+	if v, err := procfs.GetTcpMemLimit(); err == nil {
+		ret.TcpMemLimit = v
+	}
+	if v, err := procfs.GetConntrackLimit(); err == nil {
+		ret.ConntrackLimit = v
+	}
+}
+
 func DockerStateDir(dockerRoot string) string {
 	return path.Join(dockerRoot, "containers")
 }
@@ -141,6 +394,720 @@ func DiskStatsCopy(blkio_stats []cgroups.BlkioStatEntry) (stat []info.PerDiskSta
 	return
 }
 
+// Keys of memory.stat holding the memory breakdown, in order of preference.
+type memoryStatKeys struct {
+	cache      []string
+	rss        []string
+	mappedFile []string
+	swap       []string
+	pgpgin     []string
+	pgpgout    []string
+	pswpin     []string
+	pswpout    []string
+}
+
+var (
+	// The "total_" entries of cgroup v1 include the memory of descendant
+	// cgroups. cgroup v2 only has the unprefixed entries, which are always
+	// hierarchical.
+	hierarchicalMemoryStatKeys = memoryStatKeys{
+		cache:      []string{"total_cache", "file"},
+		rss:        []string{"total_rss", "anon"},
+		mappedFile: []string{"total_mapped_file", "file_mapped"},
+		swap:       []string{"total_swap"},
+		pgpgin:     []string{"total_pgpgin", "pgpgin"},
+		pgpgout:    []string{"total_pgpgout", "pgpgout"},
+		pswpin:     []string{"total_pswpin", "pswpin"},
+		pswpout:    []string{"total_pswpout", "pswpout"},
+	}
+	// The unprefixed entries of cgroup v1 only count the memory of the cgroup
+	// itself.
+	localMemoryStatKeys = memoryStatKeys{
+		cache:      []string{"cache", "file"},
+		rss:        []string{"rss", "anon"},
+		mappedFile: []string{"mapped_file", "file_mapped"},
+		swap:       []string{"swap"},
+		pgpgin:     []string{"pgpgin"},
+		pgpgout:    []string{"pgpgout"},
+		pswpin:     []string{"pswpin"},
+		pswpout:    []string{"pswpout"},
+	}
+
+	memoryKeys = &hierarchicalMemoryStatKeys
+
+	// The working set is derived from the usage, which is always
+	// hierarchical, so the inactive file cache subtracted from it must be too.
+	memoryInactiveFileKeys = []string{"total_inactive_file", "inactive_file"}
+
+	// cgroup v2 only, v1 reports kernel memory in the memory.kmem.* files.
+	memoryKernelKeys    = []string{"slab", "kernel_stack", "sock"}
+	memoryKernelTCPKeys = []string{"sock"}
+)
+
+// Sets whether the memory breakdown of cgroup v1 containers includes the memory
+// of their descendant cgroups (the default) or only their own. Has no effect on
+// cgroup v2, whose stats are always hierarchical.
+func SetMemoryStatsHierarchical(hierarchical bool) {
+	if hierarchical {
+		memoryKeys = &hierarchicalMemoryStatKeys
+	} else {
+		memoryKeys = &localMemoryStatKeys
+	}
+}
+
+func memoryStat(stats map[string]uint64, keys []string) (uint64, bool) {
+	for _, key := range keys {
+		if v, ok := stats[key]; ok {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// Fills in the cache, RSS, mapped file and swap breakdown as well as the working
+// set from the entries of memory.stat. Usage must already be set.
+func setMemoryBreakdown(stats map[string]uint64, ret *info.MemoryStats) {
+	ret.Cache, _ = memoryStat(stats, memoryKeys.cache)
+	ret.RSS, _ = memoryStat(stats, memoryKeys.rss)
+	ret.MappedFile, _ = memoryStat(stats, memoryKeys.mappedFile)
+	ret.Swap, _ = memoryStat(stats, memoryKeys.swap)
+	for _, key := range memoryKernelKeys {
+		ret.KernelUsage += stats[key]
+	}
+	ret.KernelTCPUsage, _ = memoryStat(stats, memoryKernelTCPKeys)
+
+	// Working set is the usage minus the inactive file cache, which is the
+	// first memory to be reclaimed under pressure.
+	ret.WorkingSet = ret.Usage
+	if v, ok := memoryStat(stats, memoryInactiveFileKeys); ok {
+		if v < ret.WorkingSet {
+			ret.WorkingSet -= v
+		} else {
+			ret.WorkingSet = 0
+		}
+	}
+}
+
+// Fills in the page faults from memory.stat. The total_ prefixed stats of
+// cgroup v1 include the faults of descendant cgroups, otherwise the
+// hierarchical faults are the same as the container ones.
+func setPageFaults(stats map[string]uint64, ret *info.MemoryStats) {
+	ret.ContainerData.Pgfault = stats["pgfault"]
+	ret.ContainerData.Pgmajfault = stats["pgmajfault"]
+	ret.HierarchicalData = ret.ContainerData
+	if v, ok := stats["total_pgfault"]; ok {
+		ret.HierarchicalData.Pgfault = v
+	}
+	if v, ok := stats["total_pgmajfault"]; ok {
+		ret.HierarchicalData.Pgmajfault = v
+	}
+}
+
+// Fills in the pages charged, uncharged, swapped in and swapped out from
+// memory.stat, including those of descendant cgroups unless configured
+// otherwise.
+func setPagingStats(stats map[string]uint64, ret *info.MemoryStats) {
+	ret.Pgpgin, _ = memoryStat(stats, memoryKeys.pgpgin)
+	ret.Pgpgout, _ = memoryStat(stats, memoryKeys.pgpgout)
+	ret.Pswpin, _ = memoryStat(stats, memoryKeys.pswpin)
+	ret.Pswpout, _ = memoryStat(stats, memoryKeys.pswpout)
+}
+
+// Fills in the reclaim activity of the working set from memory.stat of cgroup
+// v2. Kernels since 5.9 split the counters between anonymous and file pages.
+func setWorkingSetEvents(stats map[string]uint64, ret *info.MemoryStats) {
+	ret.WorkingSetEvents.Refault = workingSetStat(stats, "workingset_refault")
+	ret.WorkingSetEvents.Activate = workingSetStat(stats, "workingset_activate")
+	ret.WorkingSetEvents.Restore = workingSetStat(stats, "workingset_restore")
+}
+
+func workingSetStat(stats map[string]uint64, key string) uint64 {
+	if v, ok := stats[key]; ok {
+		return v
+	}
+	return stats[key+"_anon"] + stats[key+"_file"]
+}
+
+// Reads a single integer from a cgroup file.
+func readCgroupUint64(dir, file string) (uint64, error) {
+	out, err := ioutil.ReadFile(path.Join(dir, file))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(out)), 10, 64)
+}
+
+// Fills in the kernel memory usage and limits from the memory.kmem.* files of
+// cgroup v1. Left untouched when kernel memory accounting is not available.
+func setKernelMemoryStats(memoryCgroupPath string, ret *info.MemoryStats) {
+	if memoryCgroupPath == "" {
+		return
+	}
+	if v, err := readCgroupUint64(memoryCgroupPath, "memory.kmem.usage_in_bytes"); err == nil {
+		ret.KernelUsage = v
+	}
+	if v, err := readCgroupUint64(memoryCgroupPath, "memory.kmem.limit_in_bytes"); err == nil {
+		ret.KernelLimit = v
+	}
+	if v, err := readCgroupUint64(memoryCgroupPath, "memory.kmem.tcp.usage_in_bytes"); err == nil {
+		ret.KernelTCPUsage = v
+	}
+	if v, err := readCgroupUint64(memoryCgroupPath, "memory.kmem.tcp.limit_in_bytes"); err == nil {
+		ret.KernelTCPLimit = v
+	}
+}
+
+// Fills in the memory usage, swap and breakdown from the memory.* files of
+// cgroup v2, which the libcontainer cgroup manager does not read.
+func setMemoryStatsV2(memoryCgroupPath string, ret *info.MemoryStats) {
+	if memoryCgroupPath == "" {
+		return
+	}
+	usage, err := readCgroupUint64(memoryCgroupPath, "memory.current")
+	if err != nil {
+		return
+	}
+	ret.Usage = usage
+	ret.WorkingSet = usage
+	if out, err := ioutil.ReadFile(path.Join(memoryCgroupPath, "memory.stat")); err == nil {
+		stats := parseFlatKeyed(string(out))
+		setPageFaults(stats, ret)
+		setPagingStats(stats, ret)
+		setMemoryBreakdown(stats, ret)
+		setWorkingSetEvents(stats, ret)
+	}
+	// memory.stat of cgroup v2 has no swap usage.
+	if v, err := readCgroupUint64(memoryCgroupPath, "memory.swap.current"); err == nil {
+		ret.Swap = v
+	}
+	// Missing when swap accounting is disabled.
+	if out, err := ioutil.ReadFile(path.Join(memoryCgroupPath, "memory.swap.events")); err == nil {
+		ret.SwapEvents = parseSwapEvents(string(out))
+	}
+}
+
+// Fills in the bytes and operations serviced per device from the io.stat file
+// of cgroup v2, which the libcontainer cgroup manager does not read.
+func setDiskIoStatsV2(blkioCgroupPath string, ret *info.DiskIoStats) {
+	if blkioCgroupPath == "" {
+		return
+	}
+	out, err := ioutil.ReadFile(path.Join(blkioCgroupPath, "io.stat"))
+	if err != nil {
+		return
+	}
+	ret.IoServiceBytes, ret.IoServiced = parseIoStat(string(out))
+}
+
+// Fills in the usage and limits of the misc controller from misc.current and
+// misc.max of cgroup v2. Left empty on hosts without the controller.
+func setMiscStatsV2(miscCgroupPath string, ret *info.ContainerStats) {
+	if miscCgroupPath == "" {
+		return
+	}
+	current, err := ioutil.ReadFile(path.Join(miscCgroupPath, "misc.current"))
+	if err != nil {
+		return
+	}
+	// The root cgroup has no limits.
+	max, _ := ioutil.ReadFile(path.Join(miscCgroupPath, "misc.max"))
+	ret.MiscStats = parseMiscStats(string(current), string(max))
+}
+
+// Parses the "<resource> <value>" lines of misc.current and misc.max. Resources
+// limited to "max" or missing from misc.max, as in the root cgroup, are
+// reported with a limit of math.MaxUint64. Only resources listed by
+// misc.current are returned, nil if there are none.
+func parseMiscStats(current, max string) map[string]info.MiscResourceStats {
+	usage := parseFlatKeyed(current)
+	if len(usage) == 0 {
+		return nil
+	}
+	limits := parseFlatKeyed(max)
+	ret := make(map[string]info.MiscResourceStats, len(usage))
+	for resource, value := range usage {
+		limit, ok := limits[resource]
+		if !ok {
+			limit = math.MaxUint64
+		}
+		ret[resource] = info.MiscResourceStats{
+			Current: value,
+			Max:     limit,
+		}
+	}
+	return ret
+}
+
+// Parses the bytes and operations read, written and discarded of the
+// "<major>:<minor> <key>=<value>..." lines of io.stat, keyed by operation like
+// the blkio stats of cgroup v1. The total includes discards, as it does on
+// cgroup v1. Malformed lines are ignored.
+func parseIoStat(content string) (serviceBytes []info.PerDiskStats, serviced []info.PerDiskStats) {
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		var major, minor uint64
+		if _, err := fmt.Sscanf(fields[0], "%d:%d", &major, &minor); err != nil {
+			continue
+		}
+		values := make(map[string]uint64, len(fields)-1)
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			v, err := strconv.ParseUint(kv[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			values[kv[0]] = v
+		}
+		// Devices only listed for their io.latency delay have no IO stats.
+		if _, ok := values["rbytes"]; !ok {
+			continue
+		}
+		serviceBytes = append(serviceBytes, info.PerDiskStats{
+			Major: major,
+			Minor: minor,
+			Stats: map[string]uint64{
+				"Read":    values["rbytes"],
+				"Write":   values["wbytes"],
+				"Discard": values["dbytes"],
+				"Total":   values["rbytes"] + values["wbytes"] + values["dbytes"],
+			},
+		})
+		serviced = append(serviced, info.PerDiskStats{
+			Major: major,
+			Minor: minor,
+			Stats: map[string]uint64{
+				"Read":    values["rios"],
+				"Write":   values["wios"],
+				"Discard": values["dios"],
+				"Total":   values["rios"] + values["wios"] + values["dios"],
+			},
+		})
+	}
+	return serviceBytes, serviced
+}
+
+// Fills in the memory events from memory.events. Only cgroup v2 has the file,
+// the events are left empty on cgroup v1.
+func setMemoryEvents(memoryCgroupPath string, ret *info.MemoryStats) {
+	if memoryCgroupPath == "" {
+		return
+	}
+	out, err := ioutil.ReadFile(path.Join(memoryCgroupPath, "memory.events"))
+	if err != nil {
+		return
+	}
+	ret.Events = parseMemoryEvents(string(out))
+}
+
+// Fills in the time IO was throttled by io.latency from io.stat. Only cgroup v2
+// has the file and only devices with an io.latency target report a delay, the
+// stats are left empty otherwise.
+func setIoLatencyStats(blkioCgroupPath string, ret *info.DiskIoStats) {
+	if blkioCgroupPath == "" {
+		return
+	}
+	out, err := ioutil.ReadFile(path.Join(blkioCgroupPath, "io.stat"))
+	if err != nil {
+		return
+	}
+	ret.IoLatencyThrottled = parseIoStatDelay(string(out))
+}
+
+// Parses the "delay_nsec" keys of the "<major>:<minor> <key>=<value>..." lines
+// of io.stat. Malformed lines are ignored.
+func parseIoStatDelay(content string) []info.PerDiskStats {
+	var ret []info.PerDiskStats
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		var major, minor uint64
+		if _, err := fmt.Sscanf(fields[0], "%d:%d", &major, &minor); err != nil {
+			continue
+		}
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "delay_nsec=") {
+				continue
+			}
+			v, err := strconv.ParseUint(strings.TrimPrefix(field, "delay_nsec="), 10, 64)
+			if err != nil {
+				break
+			}
+			ret = append(ret, info.PerDiskStats{
+				Major: major,
+				Minor: minor,
+				Stats: map[string]uint64{"Total": v},
+			})
+			break
+		}
+	}
+	return ret
+}
+
+// Fills in the IO pressure stall information from io.pressure. Only cgroup v2
+// has the file, and only when the kernel has PSI enabled.
+func setIoPressure(blkioCgroupPath string, ret *info.DiskIoStats) {
+	if blkioCgroupPath == "" {
+		return
+	}
+	out, err := ioutil.ReadFile(path.Join(blkioCgroupPath, "io.pressure"))
+	if err != nil {
+		return
+	}
+	ret.HasPSI = true
+	ret.PSI = parsePSI(string(out))
+}
+
+// Parses the "some" and "full" lines of a PSI file, e.g.
+// "some avg10=0.00 avg60=1.50 avg300=0.20 total=12345". Unknown and malformed
+// lines and fields are ignored.
+func parsePSI(content string) info.PSIStats {
+	var ret info.PSIStats
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		var data *info.PSIData
+		switch fields[0] {
+		case "some":
+			data = &ret.Some
+		case "full":
+			data = &ret.Full
+		default:
+			continue
+		}
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			if kv[0] == "total" {
+				if v, err := strconv.ParseUint(kv[1], 10, 64); err == nil {
+					data.Total = v
+				}
+				continue
+			}
+			v, err := strconv.ParseFloat(kv[1], 64)
+			if err != nil {
+				continue
+			}
+			switch kv[0] {
+			case "avg10":
+				data.Avg10 = v
+			case "avg60":
+				data.Avg60 = v
+			case "avg300":
+				data.Avg300 = v
+			}
+		}
+	}
+	return ret
+}
+
+// Fills in the total, user and system CPU usage from the cpu.stat file of
+// cgroup v2, which has no cpuacct controller, along with the CFS burst stats of
+// kernels supporting CPU bursting. The usage is left untouched on cgroup v1
+// whose cpu.stat only holds throttling stats.
+func setCpuStatsV2(cpuCgroupPath string, ret *info.CpuStats) {
+	if cpuCgroupPath == "" {
+		return
+	}
+	out, err := ioutil.ReadFile(path.Join(cpuCgroupPath, "cpu.stat"))
+	if err != nil {
+		return
+	}
+	stats := parseFlatKeyed(string(out))
+	usage, ok := stats["usage_usec"]
+	if !ok {
+		return
+	}
+	ret.Usage.Total = usage * uint64(time.Microsecond)
+	ret.Usage.User = stats["user_usec"] * uint64(time.Microsecond)
+	ret.Usage.System = stats["system_usec"] * uint64(time.Microsecond)
+	ret.CFS.BurstCount = stats["nr_bursts"]
+	ret.CFS.BurstTime = stats["burst_usec"] * uint64(time.Microsecond)
+}
+
+// Returns the CPU time of all the cores of the machine, replaced in tests.
+var getMachineCpuTimes = procfs.GetAllCpuTimes
+
+// Fills in the CPU usage of the root cgroup of cgroup v2. Kernels before 5.8
+// have no cpu.stat in the root cgroup and others may not account any usage in
+// it, in which case the usage of the whole machine is read from /proc/stat
+// instead, along with the usage of each core. Idle, I/O wait and steal time are
+// not counted as usage.
+func SetRootCpuStatsV2(cpuCgroupPath string, ret *info.CpuStats) {
+	if cpuCgroupPath == "" {
+		return
+	}
+	setCpuStatsV2(cpuCgroupPath, ret)
+	if ret.Usage.Total != 0 {
+		return
+	}
+	times, perCpu, err := getMachineCpuTimes()
+	if err != nil {
+		return
+	}
+	ret.Usage.User = uint64(times.User + times.Nice)
+	ret.Usage.System = uint64(times.System + times.Irq + times.Softirq)
+	ret.Usage.Total = ret.Usage.User + ret.Usage.System
+	ret.Usage.PerCpu = make([]uint64, len(perCpu))
+	for i, cpu := range perCpu {
+		ret.Usage.PerCpu[i] = uint64(cpu.User + cpu.Nice + cpu.System + cpu.Irq + cpu.Softirq)
+	}
+}
+
+// Returns the CPU burst of a cgroup v2 cgroup from cpu.max.burst in
+// microseconds, 0 on cgroup v1 and on kernels without CPU bursting.
+func GetCpuBurst(cpuCgroupPath string) uint64 {
+	if cpuCgroupPath == "" {
+		return 0
+	}
+	burst, err := readCgroupUint64(cpuCgroupPath, "cpu.max.burst")
+	if err != nil {
+		return 0
+	}
+	return burst
+}
+
+// Returns whether a cgroup v2 cgroup is scheduled as idle from cpu.idle, false
+// on cgroup v1 and on kernels without idle cgroups.
+func GetCpuIdle(cpuCgroupPath string) bool {
+	if cpuCgroupPath == "" {
+		return false
+	}
+	idle, err := readCgroupUint64(cpuCgroupPath, "cpu.idle")
+	if err != nil {
+		return false
+	}
+	return idle == 1
+}
+
+// Returns the CPUs and memory nodes the processes of a cgroup may run on from
+// its cpuset cgroup. cgroup v2 cgroups inherit them when unset so the
+// effective ones are read. Nil when unavailable.
+func GetCpuset(cpusetCgroupPath string, unified bool) (cpus []int, mems []int) {
+	if cpusetCgroupPath == "" {
+		return nil, nil
+	}
+	cpusFile, memsFile := "cpuset.cpus", "cpuset.mems"
+	if unified {
+		cpusFile, memsFile = "cpuset.cpus.effective", "cpuset.mems.effective"
+	}
+	if out, err := ioutil.ReadFile(path.Join(cpusetCgroupPath, cpusFile)); err == nil {
+		cpus, _ = utils.ParseCpuList(string(out))
+	}
+	if out, err := ioutil.ReadFile(path.Join(cpusetCgroupPath, memsFile)); err == nil {
+		mems, _ = utils.ParseCpuList(string(out))
+	}
+	return cpus, mems
+}
+
+// Returns the IO limits of a cgroup v2 cgroup from its io.max. Nil on cgroup
+// v1 or without limits.
+func GetIoMax(blkioCgroupPath string) []info.IoMaxLimit {
+	if blkioCgroupPath == "" {
+		return nil
+	}
+	out, err := ioutil.ReadFile(path.Join(blkioCgroupPath, "io.max"))
+	if err != nil {
+		return nil
+	}
+	return parseIoMax(string(out))
+}
+
+// Parses the "<major>:<minor> rbps=<value> wbps=<value> riops=<value>
+// wiops=<value>" lines of io.max, in which unlimited values are "max".
+// Devices without any limit and malformed lines are ignored.
+func parseIoMax(content string) []info.IoMaxLimit {
+	var ret []info.IoMaxLimit
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		var limit info.IoMaxLimit
+		if _, err := fmt.Sscanf(fields[0], "%d:%d", &limit.Major, &limit.Minor); err != nil {
+			continue
+		}
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 || kv[1] == "max" {
+				continue
+			}
+			v, err := strconv.ParseUint(kv[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			switch kv[0] {
+			case "rbps":
+				limit.ReadBps = v
+			case "wbps":
+				limit.WriteBps = v
+			case "riops":
+				limit.ReadIops = v
+			case "wiops":
+				limit.WriteIops = v
+			}
+		}
+		if limit.ReadBps == 0 && limit.WriteBps == 0 && limit.ReadIops == 0 && limit.WriteIops == 0 {
+			continue
+		}
+		ret = append(ret, limit)
+	}
+	return ret
+}
+
+// Returns the class ID tagging the network packets of a cgroup v1 cgroup from
+// its net_cls.classid, 0 if unset or unavailable.
+func GetNetClsId(netClsCgroupPath string) uint32 {
+	if netClsCgroupPath == "" {
+		return 0
+	}
+	out, err := ioutil.ReadFile(path.Join(netClsCgroupPath, "net_cls.classid"))
+	if err != nil {
+		return 0
+	}
+	classId, err := strconv.ParseUint(strings.TrimSpace(string(out)), 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint32(classId)
+}
+
+// Returns the priorities of the network traffic of a cgroup v1 cgroup per
+// interface from its net_prio.ifpriomap. Nil if unavailable.
+func GetNetPrioMap(netPrioCgroupPath string) map[string]uint32 {
+	if netPrioCgroupPath == "" {
+		return nil
+	}
+	out, err := ioutil.ReadFile(path.Join(netPrioCgroupPath, "net_prio.ifpriomap"))
+	if err != nil {
+		return nil
+	}
+	return parseIfPrioMap(string(out))
+}
+
+// Parses the "<interface> <priority>" lines of net_prio.ifpriomap. Interfaces
+// with the default priority 0 and malformed lines are ignored.
+func parseIfPrioMap(content string) map[string]uint32 {
+	var ret map[string]uint32
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		prio, err := strconv.ParseUint(fields[1], 10, 32)
+		if err != nil || prio == 0 {
+			continue
+		}
+		if ret == nil {
+			ret = make(map[string]uint32)
+		}
+		ret[fields[0]] = uint32(prio)
+	}
+	return ret
+}
+
+// Returns the device access rules of a cgroup v1 cgroup from its devices.list.
+// cgroup v2 enforces them with an eBPF program that cannot be read back, nil
+// is then returned.
+func GetDeviceAccess(devicesCgroupPath string) []info.DeviceAccessRule {
+	if devicesCgroupPath == "" {
+		return nil
+	}
+	out, err := ioutil.ReadFile(path.Join(devicesCgroupPath, "devices.list"))
+	if err != nil {
+		return nil
+	}
+	return parseDevicesList(string(out))
+}
+
+// Parses the "<type> <major>:<minor> <permissions>" lines of devices.list, in
+// which the major and minor numbers may be "*". Malformed lines are ignored.
+func parseDevicesList(content string) []info.DeviceAccessRule {
+	var ret []info.DeviceAccessRule
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		numbers := strings.SplitN(fields[1], ":", 2)
+		if len(numbers) != 2 {
+			continue
+		}
+		major, err := parseDeviceNumber(numbers[0])
+		if err != nil {
+			continue
+		}
+		minor, err := parseDeviceNumber(numbers[1])
+		if err != nil {
+			continue
+		}
+		ret = append(ret, info.DeviceAccessRule{
+			Type:        fields[0],
+			Major:       major,
+			Minor:       minor,
+			Permissions: fields[2],
+		})
+	}
+	return ret
+}
+
+func parseDeviceNumber(number string) (int64, error) {
+	if number == "*" {
+		return info.DeviceWildcard, nil
+	}
+	return strconv.ParseInt(number, 10, 64)
+}
+
+// Parses the "<key> <value>" lines of a flat keyed cgroup file. Malformed
+// lines are ignored.
+func parseFlatKeyed(content string) map[string]uint64 {
+	ret := make(map[string]uint64)
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		ret[fields[0]] = v
+	}
+	return ret
+}
+
+// Parses the "<event> <count>" lines of memory.events. Unknown and malformed
+// lines are ignored.
+func parseMemoryEvents(content string) info.MemoryEvents {
+	events := parseFlatKeyed(content)
+	return info.MemoryEvents{
+		Low:     events["low"],
+		High:    events["high"],
+		Max:     events["max"],
+		Oom:     events["oom"],
+		OomKill: events["oom_kill"],
+	}
+}
+
+func parseSwapEvents(content string) *info.SwapEvents {
+	events := parseFlatKeyed(content)
+	return &info.SwapEvents{
+		High: events["high"],
+		Max:  events["max"],
+	}
+}
+
 // Convert libcontainer stats to info.ContainerStats.
 func toContainerStats(libcontainerStats *libcontainer.Stats) *info.ContainerStats {
 	s := libcontainerStats.CgroupStats
@@ -158,6 +1125,7 @@ func toContainerStats(libcontainerStats *libcontainer.Stats) *info.ContainerStat
 			ret.Cpu.Usage.PerCpu[i] = s.CpuStats.CpuUsage.PercpuUsage[i]
 			ret.Cpu.Usage.Total += s.CpuStats.CpuUsage.PercpuUsage[i]
 		}
+		ret.Cpu.Schedstat.NrThrottled = s.CpuStats.ThrottlingData.ThrottledPeriods
 
 		ret.DiskIo.IoServiceBytes = DiskStatsCopy(s.BlkioStats.IoServiceBytesRecursive)
 		ret.DiskIo.IoServiced = DiskStatsCopy(s.BlkioStats.IoServicedRecursive)
@@ -169,20 +1137,9 @@ func toContainerStats(libcontainerStats *libcontainer.Stats) *info.ContainerStat
 		ret.DiskIo.IoTime = DiskStatsCopy(s.BlkioStats.IoTimeRecursive)
 
 		ret.Memory.Usage = s.MemoryStats.Usage
-		if v, ok := s.MemoryStats.Stats["pgfault"]; ok {
-			ret.Memory.ContainerData.Pgfault = v
-			ret.Memory.HierarchicalData.Pgfault = v
-		}
-		if v, ok := s.MemoryStats.Stats["pgmajfault"]; ok {
-			ret.Memory.ContainerData.Pgmajfault = v
-			ret.Memory.HierarchicalData.Pgmajfault = v
-		}
-		if v, ok := s.MemoryStats.Stats["total_inactive_anon"]; ok {
-			ret.Memory.WorkingSet = ret.Memory.Usage - v
-			if v, ok := s.MemoryStats.Stats["total_active_file"]; ok {
-				ret.Memory.WorkingSet -= v
-			}
-		}
+		setPageFaults(s.MemoryStats.Stats, &ret.Memory)
+		setPagingStats(s.MemoryStats.Stats, &ret.Memory)
+		setMemoryBreakdown(s.MemoryStats.Stats, &ret.Memory)
 	}
 	if len(libcontainerStats.Interfaces) > 0 {
 		// TODO(vmarmol): Handle multiple interfaces.
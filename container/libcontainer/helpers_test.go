@@ -0,0 +1,902 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcontainer
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/docker/libcontainer/cgroups"
+	cgroup_fs "github.com/docker/libcontainer/cgroups/fs"
+	"github.com/docker/libcontainer/configs"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/utils/procfs"
+)
+
+func TestSetMemoryBreakdown(t *testing.T) {
+	testCases := []struct {
+		stats    map[string]uint64
+		expected info.MemoryStats
+	}{
+		{
+			// cgroup v1 memory.stat.
+			stats: map[string]uint64{
+				"cache":               1,
+				"total_cache":         1000,
+				"total_rss":           2000,
+				"total_mapped_file":   300,
+				"total_swap":          400,
+				"total_inactive_file": 600,
+				"total_active_file":   400,
+			},
+			expected: info.MemoryStats{
+				Usage:      3000,
+				Cache:      1000,
+				RSS:        2000,
+				MappedFile: 300,
+				Swap:       400,
+				WorkingSet: 2400,
+			},
+		},
+		{
+			// cgroup v2 memory.stat.
+			stats: map[string]uint64{
+				"file":          1000,
+				"anon":          2000,
+				"file_mapped":   300,
+				"inactive_file": 600,
+				"slab":          200,
+				"kernel_stack":  50,
+				"sock":          10,
+			},
+			expected: info.MemoryStats{
+				Usage:          3000,
+				Cache:          1000,
+				RSS:            2000,
+				MappedFile:     300,
+				WorkingSet:     2400,
+				KernelUsage:    260,
+				KernelTCPUsage: 10,
+			},
+		},
+		{
+			// Working set never goes below zero.
+			stats: map[string]uint64{
+				"total_inactive_file": 5000,
+			},
+			expected: info.MemoryStats{
+				Usage: 3000,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		ret := info.MemoryStats{Usage: 3000}
+		setMemoryBreakdown(tc.stats, &ret)
+		if !reflect.DeepEqual(ret, tc.expected) {
+			t.Errorf("expected %+v from %v, got %+v", tc.expected, tc.stats, ret)
+		}
+	}
+}
+
+func TestSetPageFaults(t *testing.T) {
+	// cgroup v1 reports the faults of descendants in the total_ stats.
+	ret := info.MemoryStats{}
+	setPageFaults(map[string]uint64{
+		"pgfault":          10,
+		"pgmajfault":       1,
+		"total_pgfault":    30,
+		"total_pgmajfault": 3,
+	}, &ret)
+	expected := info.MemoryStats{
+		ContainerData:    info.MemoryStatsMemoryData{Pgfault: 10, Pgmajfault: 1},
+		HierarchicalData: info.MemoryStatsMemoryData{Pgfault: 30, Pgmajfault: 3},
+	}
+	if !reflect.DeepEqual(ret, expected) {
+		t.Errorf("expected %+v, got %+v", expected, ret)
+	}
+
+	// cgroup v2 stats are already hierarchical.
+	ret = info.MemoryStats{}
+	setPageFaults(map[string]uint64{"pgfault": 10, "pgmajfault": 1}, &ret)
+	expected.HierarchicalData = expected.ContainerData
+	if !reflect.DeepEqual(ret, expected) {
+		t.Errorf("expected %+v, got %+v", expected, ret)
+	}
+}
+
+func TestSetPagingStats(t *testing.T) {
+	// cgroup v1 reports the pages of descendants in the total_ stats.
+	ret := info.MemoryStats{}
+	setPagingStats(map[string]uint64{
+		"pgpgin":        10,
+		"pgpgout":       5,
+		"total_pgpgin":  30,
+		"total_pgpgout": 15,
+	}, &ret)
+	expected := info.MemoryStats{Pgpgin: 30, Pgpgout: 15}
+	if !reflect.DeepEqual(ret, expected) {
+		t.Errorf("expected %+v, got %+v", expected, ret)
+	}
+
+	// cgroup v2 stats are already hierarchical.
+	ret = info.MemoryStats{}
+	setPagingStats(map[string]uint64{"pswpin": 7, "pswpout": 9}, &ret)
+	expected = info.MemoryStats{Pswpin: 7, Pswpout: 9}
+	if !reflect.DeepEqual(ret, expected) {
+		t.Errorf("expected %+v, got %+v", expected, ret)
+	}
+}
+
+func TestSetWorkingSetEvents(t *testing.T) {
+	ret := info.MemoryStats{}
+	setWorkingSetEvents(map[string]uint64{
+		"workingset_refault":  10,
+		"workingset_activate": 5,
+		"workingset_restore":  2,
+	}, &ret)
+	expected := info.WorkingSetEvents{Refault: 10, Activate: 5, Restore: 2}
+	if ret.WorkingSetEvents != expected {
+		t.Errorf("expected %+v, got %+v", expected, ret.WorkingSetEvents)
+	}
+
+	// Newer kernels report anonymous and file pages separately.
+	ret = info.MemoryStats{}
+	setWorkingSetEvents(map[string]uint64{
+		"workingset_refault_anon":  3,
+		"workingset_refault_file":  7,
+		"workingset_activate_anon": 1,
+		"workingset_activate_file": 4,
+		"workingset_restore_anon":  0,
+		"workingset_restore_file":  2,
+	}, &ret)
+	if ret.WorkingSetEvents != expected {
+		t.Errorf("expected %+v, got %+v", expected, ret.WorkingSetEvents)
+	}
+}
+
+func TestSetKernelMemoryStats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kmem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Without kernel memory accounting the stats from memory.stat are kept.
+	ret := info.MemoryStats{KernelUsage: 7}
+	setKernelMemoryStats(dir, &ret)
+	if !reflect.DeepEqual(ret, info.MemoryStats{KernelUsage: 7}) {
+		t.Errorf("expected kernel memory stats to be untouched, got %+v", ret)
+	}
+
+	files := map[string]string{
+		"memory.kmem.usage_in_bytes":     "4096\n",
+		"memory.kmem.limit_in_bytes":     "9223372036854771712\n",
+		"memory.kmem.tcp.usage_in_bytes": "1024\n",
+		"memory.kmem.tcp.limit_in_bytes": "2048\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setKernelMemoryStats(dir, &ret)
+	expected := info.MemoryStats{
+		KernelUsage:    4096,
+		KernelLimit:    9223372036854771712,
+		KernelTCPUsage: 1024,
+		KernelTCPLimit: 2048,
+	}
+	if !reflect.DeepEqual(ret, expected) {
+		t.Errorf("expected %+v, got %+v", expected, ret)
+	}
+}
+
+func TestParseMemoryEvents(t *testing.T) {
+	events := parseMemoryEvents("low 1\nhigh 22\nmax 3\noom 4\noom_kill 5\noom_group_kill 6\nbogus\n")
+	expected := info.MemoryEvents{
+		Low:     1,
+		High:    22,
+		Max:     3,
+		Oom:     4,
+		OomKill: 5,
+	}
+	if events != expected {
+		t.Errorf("expected %+v, got %+v", expected, events)
+	}
+}
+
+func TestParseSwapEvents(t *testing.T) {
+	events := parseSwapEvents("high 0\nmax 7\nfail 2\n")
+	expected := info.SwapEvents{Max: 7}
+	if events == nil || *events != expected {
+		t.Errorf("expected %+v, got %+v", expected, events)
+	}
+}
+
+func TestParseIoStatDelay(t *testing.T) {
+	ioStat := "8:0 rbytes=1024 wbytes=2048 rios=1 wios=2 dbytes=0 dios=0 use_delay=1 delay_nsec=3000000000\n" +
+		"8:16 rbytes=1024 wbytes=2048 rios=1 wios=2 dbytes=0 dios=0\n" +
+		"bogus delay_nsec=1\n"
+	expected := []info.PerDiskStats{
+		{
+			Major: 8,
+			Minor: 0,
+			Stats: map[string]uint64{"Total": 3000000000},
+		},
+	}
+	if stats := parseIoStatDelay(ioStat); !reflect.DeepEqual(stats, expected) {
+		t.Errorf("expected %+v, got %+v", expected, stats)
+	}
+
+	// Hosts not using io.latency report no delay.
+	if stats := parseIoStatDelay("8:0 rbytes=1024 wbytes=2048\n"); stats != nil {
+		t.Errorf("expected no stats without io.latency, got %+v", stats)
+	}
+}
+
+func TestParseMiscStats(t *testing.T) {
+	stats := parseMiscStats("sev 3\nsev_es 0\ntdx 0\nbogus\n", "sev max\nsev_es 10\ntdx 0\n")
+	expected := map[string]info.MiscResourceStats{
+		"sev":    {Current: 3, Max: math.MaxUint64},
+		"sev_es": {Current: 0, Max: 10},
+		// A limit of 0 denies the resource.
+		"tdx": {Current: 0, Max: 0},
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("expected %+v, got %+v", expected, stats)
+	}
+
+	// The root cgroup has no misc.max.
+	stats = parseMiscStats("sev 3\n", "")
+	if stats["sev"].Max != math.MaxUint64 {
+		t.Errorf("expected sev to be unlimited without misc.max, got %+v", stats)
+	}
+
+	// Hosts without misc resources report none.
+	if stats := parseMiscStats("", ""); stats != nil {
+		t.Errorf("expected no stats without resources, got %+v", stats)
+	}
+}
+
+func TestParsePSI(t *testing.T) {
+	psi := parsePSI("some avg10=1.50 avg60=42.25 avg300=10.00 total=123456\n" +
+		"full avg10=0.50 avg60=20.00 avg300=bogus total=654\n" +
+		"bogus avg10=99.00\n")
+	expected := info.PSIStats{
+		Some: info.PSIData{Avg10: 1.5, Avg60: 42.25, Avg300: 10, Total: 123456},
+		Full: info.PSIData{Avg10: 0.5, Avg60: 20, Total: 654},
+	}
+	if psi != expected {
+		t.Errorf("expected %+v, got %+v", expected, psi)
+	}
+}
+
+func TestGetCpuBurst(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// cgroup v1 and kernels without CPU bursting have no cpu.max.burst.
+	if burst := GetCpuBurst(dir); burst != 0 {
+		t.Errorf("expected no burst without cpu.max.burst, got %d", burst)
+	}
+	if burst := GetCpuBurst(""); burst != 0 {
+		t.Errorf("expected no burst without a cpu cgroup, got %d", burst)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "cpu.max.burst"), []byte("20000\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if burst := GetCpuBurst(dir); burst != 20000 {
+		t.Errorf("expected a burst of 20000, got %d", burst)
+	}
+}
+
+func TestGetCpuIdle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// cgroup v1 and older kernels have no cpu.idle.
+	if GetCpuIdle(dir) || GetCpuIdle("") {
+		t.Errorf("expected not to be idle without cpu.idle")
+	}
+	for content, expected := range map[string]bool{"0\n": false, "1\n": true} {
+		if err := ioutil.WriteFile(filepath.Join(dir, "cpu.idle"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if idle := GetCpuIdle(dir); idle != expected {
+			t.Errorf("expected idle %v with cpu.idle %q, got %v", expected, content, idle)
+		}
+	}
+}
+
+func TestParseIoMax(t *testing.T) {
+	content := `8:0 rbps=1048576 wbps=max riops=max wiops=120
+8:16 rbps=max wbps=max riops=max wiops=max
+253:0 rbps=max wbps=2097152 riops=500 wiops=max
+`
+	expected := []info.IoMaxLimit{
+		{Major: 8, Minor: 0, ReadBps: 1048576, WriteIops: 120},
+		{Major: 253, Minor: 0, WriteBps: 2097152, ReadIops: 500},
+	}
+	if limits := parseIoMax(content); !reflect.DeepEqual(limits, expected) {
+		t.Errorf("expected %+v, got %+v", expected, limits)
+	}
+	if limits := parseIoMax(""); limits != nil {
+		t.Errorf("expected no limits for an empty io.max, got %+v", limits)
+	}
+}
+
+func TestParseDevicesList(t *testing.T) {
+	content := `c 1:3 rwm
+c 136:* rwm
+b *:* m
+a *:* rwm
+malformed
+`
+	expected := []info.DeviceAccessRule{
+		{Type: "c", Major: 1, Minor: 3, Permissions: "rwm"},
+		{Type: "c", Major: 136, Minor: info.DeviceWildcard, Permissions: "rwm"},
+		{Type: "b", Major: info.DeviceWildcard, Minor: info.DeviceWildcard, Permissions: "m"},
+		{Type: "a", Major: info.DeviceWildcard, Minor: info.DeviceWildcard, Permissions: "rwm"},
+	}
+	if rules := parseDevicesList(content); !reflect.DeepEqual(rules, expected) {
+		t.Errorf("expected %+v, got %+v", expected, rules)
+	}
+}
+
+func TestGetCpuset(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cpuset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"cpuset.cpus":           "0-3\n",
+		"cpuset.mems":           "0\n",
+		"cpuset.cpus.effective": "0-1,6\n",
+		"cpuset.mems.effective": "0-1\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cpus, mems := GetCpuset(dir, false)
+	if !reflect.DeepEqual(cpus, []int{0, 1, 2, 3}) || !reflect.DeepEqual(mems, []int{0}) {
+		t.Errorf("expected CPUs [0 1 2 3] and memory nodes [0] on cgroup v1, got %v and %v", cpus, mems)
+	}
+	cpus, mems = GetCpuset(dir, true)
+	if !reflect.DeepEqual(cpus, []int{0, 1, 6}) || !reflect.DeepEqual(mems, []int{0, 1}) {
+		t.Errorf("expected the effective CPUs [0 1 6] and memory nodes [0 1] on cgroup v2, got %v and %v", cpus, mems)
+	}
+	if cpus, mems := GetCpuset("", false); cpus != nil || mems != nil {
+		t.Errorf("expected no cpuset without cgroup, got %v and %v", cpus, mems)
+	}
+}
+
+func TestSetCpuStatsV2(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "cpu.stat")
+
+	// cgroup v1 cpu.stat only has throttling stats.
+	if err := ioutil.WriteFile(file, []byte("nr_periods 10\nnr_throttled 2\nthrottled_time 300\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ret := info.CpuStats{Usage: info.CpuUsage{Total: 7, User: 4, System: 3}}
+	setCpuStatsV2(dir, &ret)
+	if !reflect.DeepEqual(ret, info.CpuStats{Usage: info.CpuUsage{Total: 7, User: 4, System: 3}}) {
+		t.Errorf("expected CPU usage to be untouched on cgroup v1, got %+v", ret)
+	}
+
+	content := "usage_usec 3000\nuser_usec 2000\nsystem_usec 900\nnr_periods 0\nnr_throttled 0\nthrottled_usec 0\n"
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	setCpuStatsV2(dir, &ret)
+	expected := info.CpuStats{Usage: info.CpuUsage{Total: 3000000, User: 2000000, System: 900000}}
+	if !reflect.DeepEqual(ret, expected) {
+		t.Errorf("expected %+v, got %+v", expected, ret)
+	}
+
+	// Kernels supporting CPU bursting also report the use of the burst budget.
+	content += "nr_bursts 5\nburst_usec 1200\n"
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	setCpuStatsV2(dir, &ret)
+	expected.CFS = info.CpuCFS{BurstCount: 5, BurstTime: 1200000}
+	if !reflect.DeepEqual(ret, expected) {
+		t.Errorf("expected %+v, got %+v", expected, ret)
+	}
+}
+
+func TestSetRootCpuStatsV2(t *testing.T) {
+	defer func() { getMachineCpuTimes = procfs.GetAllCpuTimes }()
+	getMachineCpuTimes = func() (procfs.CpuTimes, []procfs.CpuTimes, error) {
+		return procfs.CpuTimes{
+			User:    40 * time.Second,
+			Nice:    2 * time.Second,
+			System:  10 * time.Second,
+			Idle:    500 * time.Second,
+			Iowait:  5 * time.Second,
+			Irq:     1 * time.Second,
+			Softirq: 3 * time.Second,
+			Steal:   4 * time.Second,
+		}, []procfs.CpuTimes{
+			{User: 30 * time.Second, Nice: 2 * time.Second, System: 6 * time.Second, Idle: 250 * time.Second, Irq: time.Second, Steal: 3 * time.Second},
+			{User: 10 * time.Second, System: 4 * time.Second, Idle: 250 * time.Second, Iowait: 5 * time.Second, Softirq: 3 * time.Second, Steal: time.Second},
+		}, nil
+	}
+	machineUsage := info.CpuUsage{
+		Total:  uint64(56 * time.Second),
+		PerCpu: []uint64{uint64(39 * time.Second), uint64(17 * time.Second)},
+		User:   uint64(42 * time.Second),
+		System: uint64(14 * time.Second),
+	}
+
+	dir, err := ioutil.TempDir("", "cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "cpu.stat")
+
+	// Older kernels have no cpu.stat in the root cgroup.
+	ret := info.CpuStats{}
+	SetRootCpuStatsV2(dir, &ret)
+	if !reflect.DeepEqual(ret.Usage, machineUsage) {
+		t.Errorf("expected %+v without cpu.stat, got %+v", machineUsage, ret.Usage)
+	}
+
+	// A root cgroup not accounting its usage.
+	if err := ioutil.WriteFile(file, []byte("usage_usec 0\nuser_usec 0\nsystem_usec 0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ret = info.CpuStats{}
+	SetRootCpuStatsV2(dir, &ret)
+	if !reflect.DeepEqual(ret.Usage, machineUsage) {
+		t.Errorf("expected %+v with an empty cpu.stat, got %+v", machineUsage, ret.Usage)
+	}
+
+	// The usage of the root cgroup is preferred when accounted.
+	if err := ioutil.WriteFile(file, []byte("usage_usec 60000000\nuser_usec 45000000\nsystem_usec 15000000\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ret = info.CpuStats{}
+	SetRootCpuStatsV2(dir, &ret)
+	expected := info.CpuUsage{
+		Total:  uint64(60 * time.Second),
+		User:   uint64(45 * time.Second),
+		System: uint64(15 * time.Second),
+	}
+	if !reflect.DeepEqual(ret.Usage, expected) {
+		t.Errorf("expected %+v, got %+v", expected, ret.Usage)
+	}
+
+	// Left untouched on cgroup v1.
+	ret = info.CpuStats{}
+	SetRootCpuStatsV2("", &ret)
+	if !reflect.DeepEqual(ret, info.CpuStats{}) {
+		t.Errorf("expected no CPU usage on cgroup v1, got %+v", ret)
+	}
+}
+
+func TestGetCgroupSubsystems(t *testing.T) {
+	v1Mounts := []cgroups.Mount{
+		{Mountpoint: "/sys/fs/cgroup/cpu,cpuacct", Subsystems: []string{"cpu", "cpuacct"}},
+		{Mountpoint: "/sys/fs/cgroup/cpuset", Subsystems: []string{"cpuset"}},
+		{Mountpoint: "/sys/fs/cgroup/systemd", Subsystems: []string{"name=systemd"}},
+	}
+
+	// cgroup v1 only. The pids, devices, net_cls and net_prio hierarchies are
+	// read from but not watched.
+	pidsMount := cgroups.Mount{Mountpoint: "/sys/fs/cgroup/pids", Subsystems: []string{"pids"}}
+	devicesMount := cgroups.Mount{Mountpoint: "/sys/fs/cgroup/devices", Subsystems: []string{"devices"}}
+	netMount := cgroups.Mount{Mountpoint: "/sys/fs/cgroup/net_cls,net_prio", Subsystems: []string{"net_cls", "net_prio"}}
+	subsystems := getCgroupSubsystems(append(v1Mounts, pidsMount, devicesMount, netMount), "", nil)
+	if len(subsystems.Unified) != 0 {
+		t.Errorf("expected no subsystem on cgroup v2, got %v", subsystems.Unified)
+	}
+	if subsystems.MountPoints["pids"] != "/sys/fs/cgroup/pids" || !subsystems.Auxiliary["pids"] {
+		t.Errorf("expected pids to be read from /sys/fs/cgroup/pids, got %v and auxiliary %v", subsystems.MountPoints, subsystems.Auxiliary)
+	}
+	if subsystems.MountPoints["devices"] != "/sys/fs/cgroup/devices" || !subsystems.Auxiliary["devices"] {
+		t.Errorf("expected devices to be read from /sys/fs/cgroup/devices, got %v and auxiliary %v", subsystems.MountPoints, subsystems.Auxiliary)
+	}
+	if subsystems.MountPoints["net_cls"] != netMount.Mountpoint || subsystems.MountPoints["net_prio"] != netMount.Mountpoint || !subsystems.Auxiliary["net_cls"] || !subsystems.Auxiliary["net_prio"] {
+		t.Errorf("expected net_cls and net_prio to be read from %s, got %v and auxiliary %v", netMount.Mountpoint, subsystems.MountPoints, subsystems.Auxiliary)
+	}
+	for _, mount := range subsystems.Mounts {
+		switch mount.Mountpoint {
+		case pidsMount.Mountpoint, devicesMount.Mountpoint, netMount.Mountpoint:
+			t.Errorf("expected the pids, devices, net_cls and net_prio hierarchies not to be watched, got %+v", subsystems.Mounts)
+		}
+	}
+	discoveryPaths := subsystems.DiscoveryPaths(map[string]string{
+		"cpu":     "/sys/fs/cgroup/cpu,cpuacct/test",
+		"pids":    "/sys/fs/cgroup/pids/test",
+		"devices": "/sys/fs/cgroup/devices/test",
+		"net_cls": "/sys/fs/cgroup/net_cls,net_prio/test",
+	})
+	if !reflect.DeepEqual(discoveryPaths, map[string]string{"cpu": "/sys/fs/cgroup/cpu,cpuacct/test"}) {
+		t.Errorf("expected only the cpu path to be looked for containers, got %v", discoveryPaths)
+	}
+
+	// Hybrid, with the memory, io and pids controllers on cgroup v2. The cpu
+	// controller listed by both is read from cgroup v1.
+	subsystems = getCgroupSubsystems(v1Mounts, "/sys/fs/cgroup/unified", []string{"cpu", "io", "memory", "pids"})
+	expectedMountPoints := map[string]string{
+		"cpu":     "/sys/fs/cgroup/cpu,cpuacct",
+		"cpuacct": "/sys/fs/cgroup/cpu,cpuacct",
+		"cpuset":  "/sys/fs/cgroup/cpuset",
+		"memory":  "/sys/fs/cgroup/unified",
+		"blkio":   "/sys/fs/cgroup/unified",
+		"pids":    "/sys/fs/cgroup/unified",
+	}
+	if !reflect.DeepEqual(subsystems.MountPoints, expectedMountPoints) {
+		t.Errorf("expected mount points %v, got %v", expectedMountPoints, subsystems.MountPoints)
+	}
+	expectedUnified := map[string]bool{"memory": true, "blkio": true, "pids": true}
+	if !reflect.DeepEqual(subsystems.Unified, expectedUnified) {
+		t.Errorf("expected %v on cgroup v2, got %v", expectedUnified, subsystems.Unified)
+	}
+	unifiedMount := subsystems.Mounts[len(subsystems.Mounts)-1]
+	sort.Strings(unifiedMount.Subsystems)
+	if unifiedMount.Mountpoint != "/sys/fs/cgroup/unified" || !reflect.DeepEqual(unifiedMount.Subsystems, []string{"blkio", "memory", "pids"}) {
+		t.Errorf("expected the unified hierarchy to be watched for blkio, memory and pids, got %+v", unifiedMount)
+	}
+
+	// cgroup v2 only.
+	subsystems = getCgroupSubsystems(nil, "/sys/fs/cgroup", []string{"cpuset", "cpu", "io", "memory"})
+	if len(subsystems.Unified) != 5 || subsystems.MountPoints["cpuacct"] != "/sys/fs/cgroup" {
+		t.Errorf("expected all subsystems on cgroup v2, got %v", subsystems.MountPoints)
+	}
+}
+
+func TestParseIoStat(t *testing.T) {
+	ioStat := "8:0 rbytes=1024 wbytes=2048 rios=3 wios=4 dbytes=0 dios=0\n" +
+		"8:16 delay_nsec=3000\n" +
+		"259:0 rbytes=4096 wbytes=8192 rios=5 wios=6 dbytes=16384 dios=7\n" +
+		"malformed\n"
+	serviceBytes, serviced := parseIoStat(ioStat)
+	expectedBytes := []info.PerDiskStats{
+		{Major: 8, Minor: 0, Stats: map[string]uint64{"Read": 1024, "Write": 2048, "Discard": 0, "Total": 3072}},
+		{Major: 259, Minor: 0, Stats: map[string]uint64{"Read": 4096, "Write": 8192, "Discard": 16384, "Total": 28672}},
+	}
+	if !reflect.DeepEqual(serviceBytes, expectedBytes) {
+		t.Errorf("expected %+v, got %+v", expectedBytes, serviceBytes)
+	}
+	expectedServiced := []info.PerDiskStats{
+		{Major: 8, Minor: 0, Stats: map[string]uint64{"Read": 3, "Write": 4, "Discard": 0, "Total": 7}},
+		{Major: 259, Minor: 0, Stats: map[string]uint64{"Read": 5, "Write": 6, "Discard": 7, "Total": 18}},
+	}
+	if !reflect.DeepEqual(serviced, expectedServiced) {
+		t.Errorf("expected %+v, got %+v", expectedServiced, serviced)
+	}
+}
+
+func TestGetStatsHybrid(t *testing.T) {
+	root, err := ioutil.TempDir("", "cgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	// The cpu controllers are on cgroup v1, memory, io, pids and misc on
+	// cgroup v2.
+	files := map[string]string{
+		"cpu,cpuacct/test/cpuacct.stat":         "user 10\nsystem 5\n",
+		"cpu,cpuacct/test/cpuacct.usage":        "300\n",
+		"cpu,cpuacct/test/cpuacct.usage_percpu": "100 200\n",
+		"cpu,cpuacct/test/cpu.stat":             "nr_periods 10\nnr_throttled 2\nthrottled_time 300\n",
+		"unified/test/memory.current":           "4096\n",
+		"unified/test/memory.swap.current":      "512\n",
+		"unified/test/memory.swap.events":       "high 0\nmax 3\nfail 1\n",
+		"unified/test/memory.stat":              "anon 1024\nfile 2048\ninactive_file 1000\npgfault 7\n",
+		"unified/test/io.stat":                  "8:0 rbytes=1024 wbytes=2048 rios=3 wios=4\n",
+		"unified/test/memory.events":            "low 0\nhigh 0\nmax 1\noom 0\noom_kill 0\n",
+		"unified/test/cgroup.threads":           "100\n101\n102\n",
+		"unified/test/misc.current":             "sev 2\n",
+		"unified/test/misc.max":                 "sev 8\n",
+	}
+	for name, content := range files {
+		file := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	subsystems := getCgroupSubsystems(
+		[]cgroups.Mount{{Mountpoint: filepath.Join(root, "cpu,cpuacct"), Subsystems: []string{"cpu", "cpuacct"}}},
+		filepath.Join(root, "unified"),
+		[]string{"io", "memory", "pids", "misc"},
+	)
+	cgroupPaths := make(map[string]string, len(subsystems.MountPoints))
+	for subsystem, mountPoint := range subsystems.MountPoints {
+		cgroupPaths[subsystem] = filepath.Join(mountPoint, "test")
+	}
+	v1Paths, unifiedPaths := subsystems.SplitPaths(cgroupPaths)
+	cgroupManager := &cgroup_fs.Manager{
+		Cgroups: &configs.Cgroup{Name: "/test"},
+		Paths:   v1Paths,
+	}
+
+	stats, err := GetStats(cgroupManager, unifiedPaths, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(stats.Cpu.Usage.PerCpu, []uint64{100, 200}) || stats.Cpu.Usage.Total != 300 {
+		t.Errorf("expected the CPU usage of cgroup v1, got %+v", stats.Cpu.Usage)
+	}
+	if stats.Cpu.Schedstat.NrThrottled != 2 {
+		t.Errorf("expected 2 throttled periods, got %d", stats.Cpu.Schedstat.NrThrottled)
+	}
+	if stats.Memory.Usage != 4096 || stats.Memory.WorkingSet != 3096 || stats.Memory.RSS != 1024 || stats.Memory.Cache != 2048 || stats.Memory.Swap != 512 {
+		t.Errorf("expected the memory usage of cgroup v2, got %+v", stats.Memory)
+	}
+	if stats.Memory.ContainerData.Pgfault != 7 || stats.Memory.Events.Max != 1 {
+		t.Errorf("expected the memory stats and events of cgroup v2, got %+v", stats.Memory)
+	}
+	if stats.Memory.SwapEvents == nil || stats.Memory.SwapEvents.Max != 3 {
+		t.Errorf("expected the swap events of cgroup v2, got %+v", stats.Memory.SwapEvents)
+	}
+	expectedIo := []info.PerDiskStats{
+		{Major: 8, Minor: 0, Stats: map[string]uint64{"Read": 1024, "Write": 2048, "Discard": 0, "Total": 3072}},
+	}
+	if !reflect.DeepEqual(stats.DiskIo.IoServiceBytes, expectedIo) {
+		t.Errorf("expected the IO stats of cgroup v2 %+v, got %+v", expectedIo, stats.DiskIo.IoServiceBytes)
+	}
+	if stats.Processes.ThreadCount != 3 {
+		t.Errorf("expected the 3 threads of the pids cgroup, got %d", stats.Processes.ThreadCount)
+	}
+	expectedMisc := map[string]info.MiscResourceStats{"sev": {Current: 2, Max: 8}}
+	if !reflect.DeepEqual(stats.MiscStats, expectedMisc) {
+		t.Errorf("expected the misc stats of cgroup v2 %+v, got %+v", expectedMisc, stats.MiscStats)
+	}
+}
+
+func TestNetnsStatsCacheShared(t *testing.T) {
+	reads := 0
+	cache := newNetnsStatsCache(time.Minute)
+	cache.getNetns = func(pid int) (uint64, error) {
+		// The pause container and the sidecar of a pod share a namespace.
+		netns := map[int]uint64{1: 4026532200, 2: 4026532200, 3: 4026532300}
+		return netns[pid], nil
+	}
+	cache.readStats = func(pid int) netnsStats {
+		reads++
+		return netnsStats{tcpMemUsage: uint64(pid) * 4096, conntrackCount: uint64(pid)}
+	}
+
+	pause := cache.get(1)
+	sidecar := cache.get(2)
+	if reads != 1 {
+		t.Errorf("expected a single read of the shared network namespace, got %d", reads)
+	}
+	if !reflect.DeepEqual(sidecar, pause) {
+		t.Errorf("expected the sidecar to share the stats %+v of the pause container, got %+v", pause, sidecar)
+	}
+
+	other := cache.get(3)
+	if reads != 2 {
+		t.Errorf("expected another network namespace to be read, got %d reads", reads)
+	}
+	if other.conntrackCount != 3 {
+		t.Errorf("expected the stats of process 3, got %+v", other)
+	}
+}
+
+func TestNetnsStatsCacheExpired(t *testing.T) {
+	reads := 0
+	cache := newNetnsStatsCache(time.Nanosecond)
+	cache.getNetns = func(pid int) (uint64, error) {
+		return 4026532200, nil
+	}
+	cache.readStats = func(pid int) netnsStats {
+		reads++
+		return netnsStats{}
+	}
+	cache.get(1)
+	time.Sleep(time.Millisecond)
+	cache.get(2)
+	if reads != 2 {
+		t.Errorf("expected the expired stats to be read again, got %d reads", reads)
+	}
+	if len(cache.entries) != 1 {
+		t.Errorf("expected the expired entry to be replaced, got %d entries", len(cache.entries))
+	}
+}
+
+func TestNetnsStatsCacheReadsWithoutLock(t *testing.T) {
+	cache := newNetnsStatsCache(time.Minute)
+	cache.getNetns = func(pid int) (uint64, error) {
+		return uint64(pid), nil
+	}
+	cache.readStats = func(pid int) netnsStats {
+		// Other namespaces can be looked up while one is read.
+		done := make(chan struct{})
+		go func() {
+			cache.lookup(2)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Errorf("lock held while reading the stats of network namespace %d", pid)
+		}
+		return netnsStats{conntrackCount: uint64(pid)}
+	}
+	if stats := cache.get(1); stats.conntrackCount != 1 {
+		t.Errorf("expected the stats of process 1, got %+v", stats)
+	}
+	if _, ok := cache.lookup(1); !ok {
+		t.Errorf("expected the stats read to be kept")
+	}
+}
+
+func TestGetNetCls(t *testing.T) {
+	dir, err := ioutil.TempDir("", "net_cls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"net_cls.classid":    "1048577\n",
+		"net_prio.ifpriomap": "lo 0\neth0 5\neth1 2\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if classId := GetNetClsId(dir); classId != 0x100001 {
+		t.Errorf("expected class ID 0x100001, got %#x", classId)
+	}
+	expected := map[string]uint32{"eth0": 5, "eth1": 2}
+	if prioMap := GetNetPrioMap(dir); !reflect.DeepEqual(prioMap, expected) {
+		t.Errorf("expected priorities %v, got %v", expected, prioMap)
+	}
+	if classId, prioMap := GetNetClsId(""), GetNetPrioMap(""); classId != 0 || prioMap != nil {
+		t.Errorf("expected no class ID nor priorities without cgroups, got %d and %v", classId, prioMap)
+	}
+}
+
+func TestGetAvailableControllers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	v1Cgroup := filepath.Join(dir, "cpu,cpuacct", "test")
+	unifiedCgroup := filepath.Join(dir, "unified", "test")
+	for _, cgroup := range []string{v1Cgroup, unifiedCgroup} {
+		if err := os.MkdirAll(cgroup, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The memory controller is enabled for the cgroup but not the pids one.
+	if err := ioutil.WriteFile(filepath.Join(unifiedCgroup, "cgroup.controllers"), []byte("cpuset memory io\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cgroupPaths := map[string]string{
+		"cpu":     v1Cgroup,
+		"cpuacct": v1Cgroup,
+		"devices": filepath.Join(dir, "devices", "test"),
+		"memory":  unifiedCgroup,
+		"blkio":   unifiedCgroup,
+		"pids":    unifiedCgroup,
+	}
+	unifiedPaths := map[string]string{
+		"memory": unifiedCgroup,
+		"blkio":  unifiedCgroup,
+		"pids":   unifiedCgroup,
+	}
+	expected := []string{"blkio", "cpu", "cpuacct", "memory"}
+	if controllers := GetAvailableControllers(cgroupPaths, unifiedPaths); !reflect.DeepEqual(controllers, expected) {
+		t.Errorf("expected controllers %v, got %v", expected, controllers)
+	}
+}
+
+func TestMemoryStatsHierarchical(t *testing.T) {
+	defer SetMemoryStatsHierarchical(true)
+	// cgroup v1 memory.stat of a container with a child cgroup.
+	stats := map[string]uint64{
+		"cache":               100,
+		"rss":                 200,
+		"mapped_file":         30,
+		"swap":                40,
+		"pgpgin":              10,
+		"pgpgout":             5,
+		"inactive_file":       60,
+		"total_cache":         1000,
+		"total_rss":           2000,
+		"total_mapped_file":   300,
+		"total_swap":          400,
+		"total_pgpgin":        30,
+		"total_pgpgout":       15,
+		"total_inactive_file": 600,
+	}
+	testCases := []struct {
+		hierarchical bool
+		expected     info.MemoryStats
+	}{
+		{
+			hierarchical: true,
+			expected: info.MemoryStats{
+				Usage:      3000,
+				Cache:      1000,
+				RSS:        2000,
+				MappedFile: 300,
+				Swap:       400,
+				Pgpgin:     30,
+				Pgpgout:    15,
+				WorkingSet: 2400,
+			},
+		},
+		{
+			hierarchical: false,
+			expected: info.MemoryStats{
+				Usage:      3000,
+				Cache:      100,
+				RSS:        200,
+				MappedFile: 30,
+				Swap:       40,
+				Pgpgin:     10,
+				Pgpgout:    5,
+				// Still the usage minus the hierarchical inactive file cache.
+				WorkingSet: 2400,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		SetMemoryStatsHierarchical(tc.hierarchical)
+		ret := info.MemoryStats{Usage: 3000}
+		setPagingStats(stats, &ret)
+		setMemoryBreakdown(stats, &ret)
+		if !reflect.DeepEqual(ret, tc.expected) {
+			t.Errorf("hierarchical=%v: expected %+v, got %+v", tc.hierarchical, tc.expected, ret)
+		}
+	}
+
+	// cgroup v2 stats are read the same way in both modes.
+	SetMemoryStatsHierarchical(false)
+	ret := info.MemoryStats{Usage: 3000}
+	setMemoryBreakdown(map[string]uint64{"file": 1000, "anon": 2000, "inactive_file": 600}, &ret)
+	expected := info.MemoryStats{Usage: 3000, Cache: 1000, RSS: 2000, WorkingSet: 2400}
+	if !reflect.DeepEqual(ret, expected) {
+		t.Errorf("expected %+v, got %+v", expected, ret)
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcontainer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/utils/procfs"
+)
+
+// Statistics of a network namespace rather than of one of its interfaces.
+type netnsStats struct {
+	tcpMemUsage    uint64
+	conntrackCount uint64
+	protocols      []info.ProtocolStats
+}
+
+// Whether to read the IP traffic of network namespaces per protocol.
+var protocolStatsEnabled bool
+
+// Enables the statistics of the IPv4 and IPv6 traffic of network namespaces.
+func EnableNetworkProtocolStats() {
+	protocolStatsEnabled = true
+}
+
+// Keeps the statistics recently read for each network namespace so that the
+// containers sharing a namespace, e.g. those of a Kubernetes pod, read them
+// once per housekeeping.
+type netnsStatsCache struct {
+	lock    sync.Mutex
+	maxAge  time.Duration
+	entries map[uint64]netnsStatsEntry
+
+	// Returns the inode identifying the network namespace of the process.
+	getNetns func(pid int) (uint64, error)
+	// Reads the statistics of the network namespace of the process.
+	readStats func(pid int) netnsStats
+}
+
+type netnsStatsEntry struct {
+	stats netnsStats
+	time  time.Time
+}
+
+var sharedNetnsStats = newNetnsStatsCache(0)
+
+func newNetnsStatsCache(maxAge time.Duration) *netnsStatsCache {
+	return &netnsStatsCache{
+		maxAge:    maxAge,
+		entries:   make(map[uint64]netnsStatsEntry),
+		getNetns:  procfs.GetNetnsInode,
+		readStats: readNetnsStats,
+	}
+}
+
+// Sets how long the statistics of a network namespace are shared between the
+// containers in it, typically the housekeeping interval. 0 disables sharing.
+func SetNetnsStatsMaxAge(maxAge time.Duration) {
+	sharedNetnsStats.lock.Lock()
+	defer sharedNetnsStats.lock.Unlock()
+	sharedNetnsStats.maxAge = maxAge
+}
+
+// Returns the statistics of the network namespace of the process, read at most
+// once per maxAge for all the processes in the namespace. The statistics are
+// read without holding the lock, so containers of the same namespace may each
+// read them when their housekeepings coincide.
+func (self *netnsStatsCache) get(pid int) netnsStats {
+	netns, err := self.getNetns(pid)
+	if err != nil {
+		glog.V(4).Infof("Unable to get the network namespace of process %d: %v", pid, err)
+		return self.readStats(pid)
+	}
+
+	stats, ok := self.lookup(netns)
+	if ok {
+		return stats
+	}
+	stats = self.readStats(pid)
+	self.publish(netns, stats)
+	return stats
+}
+
+// Returns the statistics of the network namespace read less than maxAge ago.
+func (self *netnsStatsCache) lookup(netns uint64) (netnsStats, bool) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.maxAge <= 0 {
+		return netnsStats{}, false
+	}
+	entry, ok := self.entries[netns]
+	if !ok || time.Since(entry.time) >= self.maxAge {
+		return netnsStats{}, false
+	}
+	return entry.stats, true
+}
+
+// Keeps the statistics just read for the network namespace.
+func (self *netnsStatsCache) publish(netns uint64, stats netnsStats) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.maxAge <= 0 {
+		return
+	}
+	now := time.Now()
+	// Forget the namespaces that were not read recently, they may be gone.
+	for key, entry := range self.entries {
+		if now.Sub(entry.time) >= self.maxAge {
+			delete(self.entries, key)
+		}
+	}
+	self.entries[netns] = netnsStatsEntry{
+		stats: stats,
+		time:  now,
+	}
+}
+
+// Left at zero when unavailable, e.g. when connection tracking is not loaded.
+func readNetnsStats(pid int) netnsStats {
+	var stats netnsStats
+	if v, err := procfs.GetTcpMemUsage(pid); err == nil {
+		stats.tcpMemUsage = v
+	}
+	if v, err := procfs.GetConntrackCount(pid); err == nil {
+		stats.conntrackCount = v
+	}
+	if protocolStatsEnabled {
+		stats.protocols = readProtocolStats(pid)
+	}
+	return stats
+}
+
+// Returns the IPv4 and IPv6 traffic of the network namespace of the process,
+// nil if either is unavailable, e.g. when IPv6 is disabled.
+func readProtocolStats(pid int) []info.ProtocolStats {
+	ipv6, err := procfs.GetIpv6Traffic(pid)
+	if err != nil {
+		return nil
+	}
+	ipv4, err := procfs.GetIpv4Traffic(pid)
+	if err != nil {
+		glog.V(4).Infof("Unable to get the IPv4 traffic of process %d: %v", pid, err)
+		return nil
+	}
+	return []info.ProtocolStats{
+		toProtocolStats("ipv4", ipv4),
+		toProtocolStats("ipv6", ipv6),
+	}
+}
+
+func toProtocolStats(protocol string, traffic procfs.IpTraffic) info.ProtocolStats {
+	return info.ProtocolStats{
+		Protocol:  protocol,
+		RxBytes:   traffic.InBytes,
+		RxPackets: traffic.InPackets,
+		TxBytes:   traffic.OutBytes,
+		TxPackets: traffic.OutPackets,
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcontainer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/utils/procfs"
+)
+
+// Reads the statistics of the SR-IOV virtual functions assigned to the network
+// namespaces of containers. Their counters are kept by the NIC and exposed in
+// the sysfs of the namespace rather than on a host veth.
+type sriovStatsReader struct {
+	enabled bool
+
+	// Roots of the sysfs of the host and of procfs, replaced in tests.
+	sysRoot  string
+	procRoot string
+
+	// Returns the inode identifying the network namespace of the process.
+	getNetns func(pid int) (uint64, error)
+}
+
+var sriovStats = &sriovStatsReader{
+	sysRoot:  "/sys",
+	procRoot: "/proc",
+	getNetns: procfs.GetNetnsInode,
+}
+
+// Enables the statistics of SR-IOV virtual functions if the host has a network
+// device with virtual functions enabled. Returns whether they were enabled.
+func EnableSriovStats() bool {
+	sriovStats.enabled = sriovStats.hostHasSriov()
+	return sriovStats.enabled
+}
+
+// Whether a network device of the host has SR-IOV virtual functions enabled.
+func (self *sriovStatsReader) hostHasSriov() bool {
+	files, err := filepath.Glob(path.Join(self.sysRoot, "class/net/*/device/sriov_numvfs"))
+	if err != nil {
+		return false
+	}
+	for _, file := range files {
+		out, err := ioutil.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		if numVfs, err := strconv.Atoi(strings.TrimSpace(string(out))); err == nil && numVfs > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Statistics files of a network interface in its statistics directory.
+var interfaceStatFiles = []struct {
+	name  string
+	value func(s *info.NetworkStats) *uint64
+}{
+	{"rx_bytes", func(s *info.NetworkStats) *uint64 { return &s.RxBytes }},
+	{"rx_packets", func(s *info.NetworkStats) *uint64 { return &s.RxPackets }},
+	{"rx_errors", func(s *info.NetworkStats) *uint64 { return &s.RxErrors }},
+	{"rx_dropped", func(s *info.NetworkStats) *uint64 { return &s.RxDropped }},
+	{"tx_bytes", func(s *info.NetworkStats) *uint64 { return &s.TxBytes }},
+	{"tx_packets", func(s *info.NetworkStats) *uint64 { return &s.TxPackets }},
+	{"tx_errors", func(s *info.NetworkStats) *uint64 { return &s.TxErrors }},
+	{"tx_dropped", func(s *info.NetworkStats) *uint64 { return &s.TxDropped }},
+}
+
+func readInterfaceStats(interfaceDir string) (info.NetworkStats, error) {
+	var stats info.NetworkStats
+	for _, file := range interfaceStatFiles {
+		out, err := ioutil.ReadFile(path.Join(interfaceDir, "statistics", file.name))
+		if err != nil {
+			return stats, err
+		}
+		v, err := strconv.ParseUint(strings.TrimSpace(string(out)), 10, 64)
+		if err != nil {
+			return stats, fmt.Errorf("invalid %s of interface %q: %v", file.name, path.Base(interfaceDir), err)
+		}
+		*file.value(&stats) = v
+	}
+	return stats, nil
+}
+
+// Returns the statistics of the virtual functions in the network namespace of
+// the process, read from the sysfs mounted in its root. Processes in the
+// network namespace of the host report none so that the virtual functions of
+// the host are not attributed to them.
+func (self *sriovStatsReader) get(pid int) []info.InterfaceStats {
+	if !self.enabled {
+		return nil
+	}
+	netns, err := self.getNetns(pid)
+	if err != nil {
+		glog.V(4).Infof("Unable to get the network namespace of process %d: %v", pid, err)
+		return nil
+	}
+	if hostNetns, err := self.getNetns(1); err != nil || netns == hostNetns {
+		return nil
+	}
+
+	netDir := path.Join(self.procRoot, strconv.Itoa(pid), "root/sys/class/net")
+	devices, err := ioutil.ReadDir(netDir)
+	if err != nil {
+		glog.V(4).Infof("Unable to list the network interfaces of process %d: %v", pid, err)
+		return nil
+	}
+	var ret []info.InterfaceStats
+	for _, device := range devices {
+		interfaceDir := path.Join(netDir, device.Name())
+		// Virtual functions link to their physical function.
+		if _, err := os.Lstat(path.Join(interfaceDir, "device/physfn")); err != nil {
+			continue
+		}
+		stats, err := readInterfaceStats(interfaceDir)
+		if err != nil {
+			glog.V(4).Infof("Unable to read the statistics of virtual function %q of process %d: %v", device.Name(), pid, err)
+			continue
+		}
+		ret = append(ret, info.InterfaceStats{
+			Name:         device.Name(),
+			NetworkStats: stats,
+		})
+	}
+	return ret
+}
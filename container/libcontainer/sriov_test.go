@@ -0,0 +1,119 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcontainer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+// Writes the files, relative to root, creating their directories.
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		file := path.Join(root, name)
+		if err := os.MkdirAll(path.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Writes the statistics of a network interface with values starting at base.
+func interfaceStatFilesFrom(interfaceDir string, base int) map[string]string {
+	files := make(map[string]string)
+	for i, file := range interfaceStatFiles {
+		files[path.Join(interfaceDir, "statistics", file.name)] = fmt.Sprintf("%d\n", base+i)
+	}
+	return files
+}
+
+func newTestSriovStatsReader(t *testing.T, numVfs string) (*sriovStatsReader, string) {
+	root, err := ioutil.TempDir("", "sriov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, root, map[string]string{"sys/class/net/eth0/device/sriov_numvfs": numVfs})
+
+	// Process 100 has a virtual function and a veth in its own network namespace.
+	vfDir := "proc/100/root/sys/class/net/eth1"
+	files := interfaceStatFilesFrom(vfDir, 1)
+	files[path.Join(vfDir, "device/physfn/vendor")] = "0x15b3\n"
+	for name, content := range interfaceStatFilesFrom("proc/100/root/sys/class/net/veth0", 100) {
+		files[name] = content
+	}
+	writeFiles(t, root, files)
+
+	reader := &sriovStatsReader{
+		sysRoot:  path.Join(root, "sys"),
+		procRoot: path.Join(root, "proc"),
+		getNetns: func(pid int) (uint64, error) {
+			if pid == 1 {
+				return 1, nil
+			}
+			return uint64(pid), nil
+		},
+	}
+	reader.enabled = reader.hostHasSriov()
+	return reader, root
+}
+
+func TestSriovStats(t *testing.T) {
+	reader, root := newTestSriovStatsReader(t, "4\n")
+	defer os.RemoveAll(root)
+	if !reader.enabled {
+		t.Fatal("expected SR-IOV to be detected")
+	}
+
+	expected := []info.InterfaceStats{{
+		Name: "eth1",
+		NetworkStats: info.NetworkStats{
+			RxBytes:   1,
+			RxPackets: 2,
+			RxErrors:  3,
+			RxDropped: 4,
+			TxBytes:   5,
+			TxPackets: 6,
+			TxErrors:  7,
+			TxDropped: 8,
+		},
+	}}
+	if stats := reader.get(100); !reflect.DeepEqual(stats, expected) {
+		t.Errorf("expected %+v, got %+v", expected, stats)
+	}
+
+	// Processes in the network namespace of the host report nothing.
+	if stats := reader.get(1); stats != nil {
+		t.Errorf("expected no stats in the network namespace of the host, got %+v", stats)
+	}
+}
+
+func TestSriovStatsWithoutSriov(t *testing.T) {
+	reader, root := newTestSriovStatsReader(t, "0\n")
+	defer os.RemoveAll(root)
+	if reader.enabled {
+		t.Fatal("expected SR-IOV not to be detected without virtual functions")
+	}
+	if stats := reader.get(100); stats != nil {
+		t.Errorf("expected no stats without SR-IOV, got %+v", stats)
+	}
+}
@@ -112,6 +112,6 @@ func (self *FactoryForMockContainerHandler) NewContainerHandler(name string) (Co
 	return handler, nil
 }
 
-func (self *FactoryForMockContainerHandler) CanHandle(name string) bool {
-	return true
+func (self *FactoryForMockContainerHandler) CanHandleAndAccept(name string) (bool, bool, error) {
+	return true, true, nil
 }
@@ -52,7 +52,9 @@ func (self *rawFactory) CanHandleAndAccept(name string) (bool, bool, error) {
 	return true, accept, nil
 }
 
-func Register(machineInfoFactory info.MachineInfoFactory, fsInfo fs.FsInfo) error {
+// Registers the raw factory, which handles every cgroup not claimed by a
+// factory of higher priority.
+func Register(machineInfoFactory info.MachineInfoFactory, fsInfo fs.FsInfo, priority int) error {
 	cgroupSubsystems, err := libcontainer.GetCgroupSubsystems()
 	if err != nil {
 		return fmt.Errorf("failed to get cgroup subsystems: %v", err)
@@ -67,6 +69,6 @@ func Register(machineInfoFactory info.MachineInfoFactory, fsInfo fs.FsInfo) erro
 		fsInfo:             fsInfo,
 		cgroupSubsystems:   &cgroupSubsystems,
 	}
-	container.RegisterContainerHandlerFactory(factory)
+	container.RegisterContainerHandlerFactory(factory, priority)
 	return nil
 }
@@ -18,6 +18,7 @@ package raw
 import (
 	"fmt"
 	"io/ioutil"
+	"math"
 	"os"
 	"path"
 	"strconv"
@@ -34,6 +35,7 @@ import (
 	"github.com/google/cadvisor/fs"
 	info "github.com/google/cadvisor/info/v1"
 	"github.com/google/cadvisor/utils"
+	"github.com/google/cadvisor/utils/procfs"
 )
 
 type rawContainerHandler struct {
@@ -43,7 +45,8 @@ type rawContainerHandler struct {
 	machineInfoFactory info.MachineInfoFactory
 
 	// Inotify event watcher.
-	watcher *inotify.Watcher
+	watcher    fsWatcher
+	newWatcher func() (fsWatcher, error)
 
 	// Signal for watcher thread to stop.
 	stopWatcher chan error
@@ -58,7 +61,11 @@ type rawContainerHandler struct {
 	// (e.g.: "cpu" -> "/sys/fs/cgroup/cpu/test")
 	cgroupPaths map[string]string
 
-	// Manager of this container's cgroups.
+	// Paths of the subsystems bound to the cgroup v2 unified hierarchy, which
+	// cgroupManager does not read.
+	unifiedPaths map[string]string
+
+	// Manager of this container's cgroup v1 cgroups.
 	cgroupManager cgroups.Manager
 
 	// Whether this container has network isolation enabled.
@@ -81,11 +88,12 @@ func newRawContainerHandler(name string, cgroupSubsystems *libcontainer.CgroupSu
 	}
 
 	// Generate the equivalent cgroup manager for this container.
+	v1Paths, unifiedPaths := cgroupSubsystems.SplitPaths(cgroupPaths)
 	cgroupManager := &cgroup_fs.Manager{
 		Cgroups: &configs.Cgroup{
 			Name: name,
 		},
-		Paths: cgroupPaths,
+		Paths: v1Paths,
 	}
 
 	hasNetwork := false
@@ -106,10 +114,12 @@ func newRawContainerHandler(name string, cgroupSubsystems *libcontainer.CgroupSu
 		name:               name,
 		cgroupSubsystems:   cgroupSubsystems,
 		machineInfoFactory: machineInfoFactory,
+		newWatcher:         newInotifyWatcher,
 		stopWatcher:        make(chan error),
 		watches:            make(map[string]struct{}),
 		cgroupWatches:      make(map[string]struct{}),
 		cgroupPaths:        cgroupPaths,
+		unifiedPaths:       unifiedPaths,
 		cgroupManager:      cgroupManager,
 		fsInfo:             fsInfo,
 		hasNetwork:         hasNetwork,
@@ -156,6 +166,24 @@ func readInt64(dirpath string, file string) uint64 {
 	return val
 }
 
+// Reads a cgroup v2 limit, which is "max" when unlimited.
+func readLimit(dirpath string, file string) uint64 {
+	if readString(dirpath, file) == "max" {
+		return math.MaxUint64
+	}
+	return readInt64(dirpath, file)
+}
+
+// Converts a cgroup v2 cpu.weight in [1, 10000] to cgroup v1 cpu.shares in
+// [2, 262144], the inverse of the conversion applied by container runtimes
+// setting shares on cgroup v2.
+func cpuWeightToShares(weight uint64) uint64 {
+	if weight == 0 {
+		return 0
+	}
+	return 2 + (weight-1)*262142/9999
+}
+
 func (self *rawContainerHandler) GetRootNetworkDevices() ([]info.NetInfo, error) {
 	nd := []info.NetInfo{}
 	if self.name == "/" {
@@ -176,7 +204,7 @@ func (self *rawContainerHandler) GetSpec() (info.ContainerSpec, error) {
 	// Get the lowest creation time from all hierarchies as the container creation time.
 	now := time.Now()
 	lowestTime := now
-	for _, cgroupPath := range self.cgroupPaths {
+	for _, cgroupPath := range self.cgroupSubsystems.DiscoveryPaths(self.cgroupPaths) {
 		// The modified time of the cgroup directory is when the container was created.
 		fi, err := os.Stat(cgroupPath)
 		if err == nil && fi.ModTime().Before(lowestTime) {
@@ -198,7 +226,13 @@ func (self *rawContainerHandler) GetSpec() (info.ContainerSpec, error) {
 	if ok {
 		if utils.FileExists(cpuRoot) {
 			spec.HasCpu = true
-			spec.Cpu.Limit = readInt64(cpuRoot, "cpu.shares")
+			if self.cgroupSubsystems.Unified["cpu"] {
+				spec.Cpu.Limit = cpuWeightToShares(readInt64(cpuRoot, "cpu.weight"))
+			} else {
+				spec.Cpu.Limit = readInt64(cpuRoot, "cpu.shares")
+			}
+			spec.Cpu.Burst = libcontainer.GetCpuBurst(cpuRoot)
+			spec.Cpu.Idle = libcontainer.GetCpuIdle(cpuRoot)
 		}
 	}
 
@@ -210,6 +244,7 @@ func (self *rawContainerHandler) GetSpec() (info.ContainerSpec, error) {
 			spec.HasCpu = true
 			mask := readString(cpusetRoot, "cpuset.cpus")
 			spec.Cpu.Mask = utils.FixCpuMask(mask, mi.NumCores)
+			spec.AllowedCpus, spec.AllowedMems = libcontainer.GetCpuset(cpusetRoot, self.cgroupSubsystems.Unified["cpuset"])
 		}
 	}
 
@@ -218,8 +253,14 @@ func (self *rawContainerHandler) GetSpec() (info.ContainerSpec, error) {
 	if ok {
 		if utils.FileExists(memoryRoot) {
 			spec.HasMemory = true
-			spec.Memory.Limit = readInt64(memoryRoot, "memory.limit_in_bytes")
-			spec.Memory.SwapLimit = readInt64(memoryRoot, "memory.memsw.limit_in_bytes")
+			if self.cgroupSubsystems.Unified["memory"] {
+				// cgroup v2 limits swap separately from memory, unlike the
+				// memory and swap limit of cgroup v1.
+				spec.Memory.Limit = readLimit(memoryRoot, "memory.max")
+			} else {
+				spec.Memory.Limit = readInt64(memoryRoot, "memory.limit_in_bytes")
+				spec.Memory.SwapLimit = readInt64(memoryRoot, "memory.memsw.limit_in_bytes")
+			}
 		}
 	}
 
@@ -234,8 +275,19 @@ func (self *rawContainerHandler) GetSpec() (info.ContainerSpec, error) {
 	// DiskIo.
 	if blkioRoot, ok := self.cgroupPaths["blkio"]; ok && utils.FileExists(blkioRoot) {
 		spec.HasDiskIo = true
+		spec.IoMax = libcontainer.GetIoMax(blkioRoot)
+	}
+
+	if devicesRoot, ok := self.cgroupPaths["devices"]; ok {
+		spec.DeviceAccess = libcontainer.GetDeviceAccess(devicesRoot)
 	}
 
+	spec.NetClsId = libcontainer.GetNetClsId(self.cgroupPaths["net_cls"])
+	spec.NetPrioMap = libcontainer.GetNetPrioMap(self.cgroupPaths["net_prio"])
+	spec.ControllersAvailable = libcontainer.GetAvailableControllers(self.cgroupPaths, self.unifiedPaths)
+
+	spec.OomScoreAdj = self.getOomScoreAdj()
+
 	// Check physical network devices for root container.
 	nd, err := self.GetRootNetworkDevices()
 	if err != nil {
@@ -247,6 +299,32 @@ func (self *rawContainerHandler) GetSpec() (info.ContainerSpec, error) {
 	return spec, nil
 }
 
+// Returns the OOM score adjustment of the process with the lowest PID in the
+// container, which is its init process when it has one. Nil if it could not be
+// read.
+func (self *rawContainerHandler) getOomScoreAdj() *int {
+	cpuRoot, ok := self.cgroupPaths["cpu"]
+	if !ok {
+		return nil
+	}
+	pids, err := procfs.GetCgroupPids(cpuRoot)
+	if err != nil || len(pids) == 0 {
+		return nil
+	}
+	initPid := pids[0]
+	for _, pid := range pids {
+		if pid < initPid {
+			initPid = pid
+		}
+	}
+	adj, err := procfs.GetOomScoreAdj(initPid)
+	if err != nil {
+		glog.V(4).Infof("Unable to read the OOM score adjustment of container %q: %v", self.name, err)
+		return nil
+	}
+	return &adj
+}
+
 func (self *rawContainerHandler) getFsStats(stats *info.ContainerStats) error {
 	// Get Filesystem information only for the root cgroup.
 	if self.name == "/" {
@@ -317,10 +395,13 @@ func (self *rawContainerHandler) GetStats() (*info.ContainerStats, error) {
 		// TODO(rjnagal): Handle multiple physical network devices.
 		networkInterfaces = []string{nd[0].Name}
 	}
-	stats, err := libcontainer.GetStats(self.cgroupManager, networkInterfaces)
+	stats, err := libcontainer.GetStats(self.cgroupManager, self.unifiedPaths, networkInterfaces)
 	if err != nil {
 		return stats, err
 	}
+	if self.name == "/" {
+		libcontainer.SetRootCpuStatsV2(self.unifiedPaths["cpu"], &stats.Cpu)
+	}
 
 	// Get filesystem stats.
 	err = self.getFsStats(stats)
@@ -370,7 +451,7 @@ func listDirectories(dirpath string, parent string, recursive bool, output map[s
 
 func (self *rawContainerHandler) ListContainers(listType container.ListType) ([]info.ContainerReference, error) {
 	containers := make(map[string]struct{})
-	for _, cgroupPath := range self.cgroupPaths {
+	for _, cgroupPath := range self.cgroupSubsystems.DiscoveryPaths(self.cgroupPaths) {
 		err := listDirectories(cgroupPath, self.name, listType == container.ListRecursive, containers)
 		if err != nil {
 			return nil, err
@@ -461,7 +542,23 @@ func (self *rawContainerHandler) processEvent(event *inotify.Event, events chan
 		// New container was created, watch it.
 		err := self.watchDirectory(event.Name, containerName)
 		if err != nil {
-			return err
+			if !isWatchLimitError(err) {
+				return err
+			}
+			// The container exists but its subcontainers will only be
+			// detected by polling.
+			glog.Warningf("Unable to watch container %q, the inotify watches are exhausted: %v", containerName, err)
+			if !alreadyWatched {
+				events <- container.SubcontainerEvent{
+					EventType: container.SubcontainerAdd,
+					Name:      containerName,
+				}
+			}
+			events <- container.SubcontainerEvent{
+				EventType: container.SubcontainerWatchFailure,
+				Name:      containerName,
+			}
+			return nil
 		}
 
 		// Only report container creation once.
@@ -499,7 +596,7 @@ func (self *rawContainerHandler) processEvent(event *inotify.Event, events chan
 func (self *rawContainerHandler) WatchSubcontainers(events chan container.SubcontainerEvent) error {
 	// Lazily initialize the watcher so we don't use it when not asked to.
 	if self.watcher == nil {
-		w, err := inotify.NewWatcher()
+		w, err := self.newWatcher()
 		if err != nil {
 			return err
 		}
@@ -507,9 +604,13 @@ func (self *rawContainerHandler) WatchSubcontainers(events chan container.Subcon
 	}
 
 	// Watch this container (all its cgroups) and all subdirectories.
-	for _, cgroupPath := range self.cgroupPaths {
+	for _, cgroupPath := range self.cgroupSubsystems.DiscoveryPaths(self.cgroupPaths) {
 		err := self.watchDirectory(cgroupPath, self.name)
 		if err != nil {
+			self.watcher.Close()
+			self.watcher = nil
+			self.watches = make(map[string]struct{})
+			self.cgroupWatches = make(map[string]struct{})
 			return err
 		}
 	}
@@ -518,12 +619,12 @@ func (self *rawContainerHandler) WatchSubcontainers(events chan container.Subcon
 	go func() {
 		for {
 			select {
-			case event := <-self.watcher.Event:
+			case event := <-self.watcher.Events():
 				err := self.processEvent(event, events)
 				if err != nil {
 					glog.Warningf("Error while processing event (%+v): %v", event, err)
 				}
-			case err := <-self.watcher.Error:
+			case err := <-self.watcher.Errors():
 				glog.Warningf("Error while watching %q:", self.name, err)
 			case <-self.stopWatcher:
 				err := self.watcher.Close()
@@ -551,7 +652,7 @@ func (self *rawContainerHandler) StopWatchingSubcontainers() error {
 
 func (self *rawContainerHandler) Exists() bool {
 	// If any cgroup exists, the container is still alive.
-	for _, cgroupPath := range self.cgroupPaths {
+	for _, cgroupPath := range self.cgroupSubsystems.DiscoveryPaths(self.cgroupPaths) {
 		if utils.FileExists(cgroupPath) {
 			return true
 		}
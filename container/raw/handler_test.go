@@ -0,0 +1,196 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package raw
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"code.google.com/p/go.exp/inotify"
+	"github.com/docker/libcontainer/cgroups"
+	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/container/libcontainer"
+)
+
+// Watches nothing, events are simulated by the tests.
+type fakeWatcher struct {
+	lock    sync.Mutex
+	watches map[string]bool
+	closed  bool
+	// Returned when adding watches if set, e.g. to simulate exhausted watches.
+	addErr error
+
+	events chan *inotify.Event
+	errors chan error
+}
+
+func newFakeWatcher() *fakeWatcher {
+	return &fakeWatcher{
+		watches: make(map[string]bool),
+		events:  make(chan *inotify.Event),
+		errors:  make(chan error),
+	}
+}
+
+func (self *fakeWatcher) AddWatch(path string, flags uint32) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.addErr != nil {
+		return self.addErr
+	}
+	self.watches[path] = true
+	return nil
+}
+
+func (self *fakeWatcher) RemoveWatch(path string) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	delete(self.watches, path)
+	return nil
+}
+
+func (self *fakeWatcher) Events() <-chan *inotify.Event {
+	return self.events
+}
+
+func (self *fakeWatcher) Errors() <-chan error {
+	return self.errors
+}
+
+func (self *fakeWatcher) Close() error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.closed = true
+	return nil
+}
+
+func (self *fakeWatcher) isWatched(path string) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.watches[path]
+}
+
+func (self *fakeWatcher) setAddErr(err error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.addErr = err
+}
+
+// Returns a handler of the root container of a cgroup hierarchy mounted at dir
+// and watched by the fake.
+func newWatchedHandler(dir string, watcher *fakeWatcher) *rawContainerHandler {
+	return &rawContainerHandler{
+		name: "/",
+		cgroupSubsystems: &libcontainer.CgroupSubsystems{
+			Mounts: []cgroups.Mount{{Mountpoint: dir, Subsystems: []string{"cpu"}}},
+		},
+		newWatcher:    func() (fsWatcher, error) { return watcher, nil },
+		stopWatcher:   make(chan error),
+		watches:       make(map[string]struct{}),
+		cgroupWatches: make(map[string]struct{}),
+		cgroupPaths:   map[string]string{"cpu": dir},
+	}
+}
+
+func expectSubcontainerEvent(t *testing.T, events chan container.SubcontainerEvent, expected container.SubcontainerEvent) {
+	select {
+	case event := <-events:
+		if event != expected {
+			t.Errorf("expected event %+v, got %+v", expected, event)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for event %+v", expected)
+	}
+}
+
+func TestWatchSubcontainers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(filepath.Join(dir, "a"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	watcher := newFakeWatcher()
+	handler := newWatchedHandler(dir, watcher)
+	events := make(chan container.SubcontainerEvent, 2)
+	if err := handler.WatchSubcontainers(events); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{dir, filepath.Join(dir, "a")} {
+		if !watcher.isWatched(path) {
+			t.Errorf("expected %q to be watched", path)
+		}
+	}
+
+	// A new cgroup is reported and watched.
+	if err := os.Mkdir(filepath.Join(dir, "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	watcher.events <- &inotify.Event{Name: filepath.Join(dir, "b"), Mask: inotify.IN_CREATE}
+	expectSubcontainerEvent(t, events, container.SubcontainerEvent{EventType: container.SubcontainerAdd, Name: "/b"})
+	if !watcher.isWatched(filepath.Join(dir, "b")) {
+		t.Errorf("expected the new cgroup to be watched")
+	}
+
+	// A removed cgroup is reported and no longer watched.
+	watcher.events <- &inotify.Event{Name: filepath.Join(dir, "a"), Mask: inotify.IN_DELETE}
+	expectSubcontainerEvent(t, events, container.SubcontainerEvent{EventType: container.SubcontainerDelete, Name: "/a"})
+	if watcher.isWatched(filepath.Join(dir, "a")) {
+		t.Errorf("expected the removed cgroup to no longer be watched")
+	}
+
+	// Once the watches are exhausted new cgroups are still reported, followed
+	// by a watch failure so that they are detected by polling.
+	watcher.setAddErr(&os.PathError{Op: "inotify_add_watch", Path: filepath.Join(dir, "c"), Err: syscall.ENOSPC})
+	if err := os.Mkdir(filepath.Join(dir, "c"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	watcher.events <- &inotify.Event{Name: filepath.Join(dir, "c"), Mask: inotify.IN_CREATE}
+	expectSubcontainerEvent(t, events, container.SubcontainerEvent{EventType: container.SubcontainerAdd, Name: "/c"})
+	expectSubcontainerEvent(t, events, container.SubcontainerEvent{EventType: container.SubcontainerWatchFailure, Name: "/c"})
+
+	if err := handler.StopWatchingSubcontainers(); err != nil {
+		t.Fatal(err)
+	}
+	if !watcher.closed {
+		t.Errorf("expected the watcher to be closed")
+	}
+}
+
+func TestWatchSubcontainersExhausted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	watcher := newFakeWatcher()
+	watcher.setAddErr(&os.PathError{Op: "inotify_add_watch", Path: dir, Err: syscall.ENOSPC})
+	handler := newWatchedHandler(dir, watcher)
+	if err := handler.WatchSubcontainers(make(chan container.SubcontainerEvent)); err == nil {
+		t.Fatalf("expected an error watching with exhausted watches")
+	}
+	if !watcher.closed || handler.watcher != nil {
+		t.Errorf("expected the watcher to be closed on failure")
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package raw
+
+import (
+	"os"
+	"syscall"
+
+	"code.google.com/p/go.exp/inotify"
+)
+
+// Watches cgroup directories for the creation and deletion of subdirectories.
+// Implemented with inotify, replaced by fakes in tests.
+type fsWatcher interface {
+	// Watches the directory for the events of flags.
+	AddWatch(path string, flags uint32) error
+
+	// Stops watching the directory.
+	RemoveWatch(path string) error
+
+	// Events on the watched directories.
+	Events() <-chan *inotify.Event
+
+	// Errors while watching.
+	Errors() <-chan error
+
+	// Stops watching all directories.
+	Close() error
+}
+
+type inotifyWatcher struct {
+	*inotify.Watcher
+}
+
+func newInotifyWatcher() (fsWatcher, error) {
+	w, err := inotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	return &inotifyWatcher{w}, nil
+}
+
+func (self *inotifyWatcher) Events() <-chan *inotify.Event {
+	return self.Event
+}
+
+func (self *inotifyWatcher) Errors() <-chan error {
+	return self.Error
+}
+
+// Whether the error is due to the limit on the number of inotify watches of
+// the user, fs.inotify.max_user_watches, being reached.
+func isWatchLimitError(err error) bool {
+	if pathErr, ok := err.(*os.PathError); ok {
+		err = pathErr.Err
+	}
+	return err == syscall.ENOSPC
+}
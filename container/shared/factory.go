@@ -0,0 +1,45 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"github.com/golang/glog"
+	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/storage/shared"
+)
+
+type sharedFactory struct {
+	store *shared.Store
+}
+
+func (self *sharedFactory) String() string {
+	return "shared"
+}
+
+func (self *sharedFactory) NewContainerHandler(name string) (container.ContainerHandler, error) {
+	return newSharedContainerHandler(name, self.store), nil
+}
+
+// The shared factory handles every container and accepts those in the store.
+func (self *sharedFactory) CanHandleAndAccept(name string) (bool, bool, error) {
+	return true, self.store.Exists(name), nil
+}
+
+// Registers a factory serving the containers of the store. It handles every
+// container so it must be the only factory registered.
+func Register(store *shared.Store) {
+	glog.Infof("Registering shared storage factory")
+	container.RegisterContainerHandlerFactory(&sharedFactory{store: store}, 0)
+}
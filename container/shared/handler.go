@@ -0,0 +1,187 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package shared provides container handlers serving the containers stored in
+// a shared storage by another cAdvisor instance, without collecting anything.
+package shared
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/cadvisor/container"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/storage/shared"
+)
+
+// Interval at which the shared storage is polled for new and removed containers.
+var pollInterval = time.Second
+
+type sharedContainerHandler struct {
+	name  string
+	store *shared.Store
+
+	// Timestamp of the last stats returned, to only return new stats.
+	lastStats time.Time
+
+	// Closed to stop watching for subcontainers.
+	stopWatcher chan struct{}
+	watcherLock sync.Mutex
+}
+
+func newSharedContainerHandler(name string, store *shared.Store) *sharedContainerHandler {
+	return &sharedContainerHandler{
+		name:  name,
+		store: store,
+	}
+}
+
+func (self *sharedContainerHandler) ContainerReference() (info.ContainerReference, error) {
+	return self.store.ContainerReference(self.name)
+}
+
+func (self *sharedContainerHandler) GetSpec() (info.ContainerSpec, error) {
+	return self.store.GetSpec(self.name)
+}
+
+// Returns the most recent stats stored for the container, or nil if they were
+// already returned.
+func (self *sharedContainerHandler) GetStats() (*info.ContainerStats, error) {
+	stats, err := self.store.RecentStats(self.name, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(stats) == 0 || !stats[0].Timestamp.After(self.lastStats) {
+		return nil, nil
+	}
+	self.lastStats = stats[0].Timestamp
+	return stats[0], nil
+}
+
+// Returns whether child is a subcontainer of parent, or a direct subcontainer
+// if recursive is false.
+func isSubcontainer(parent, child string, recursive bool) bool {
+	if child == parent {
+		return false
+	}
+	prefix := parent
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	if !strings.HasPrefix(child, prefix) {
+		return false
+	}
+	return recursive || path.Dir(child) == parent
+}
+
+func (self *sharedContainerHandler) ListContainers(listType container.ListType) ([]info.ContainerReference, error) {
+	names, err := self.store.ListContainers()
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]info.ContainerReference, 0, len(names))
+	for _, name := range names {
+		if isSubcontainer(self.name, name, listType == container.ListRecursive) {
+			ret = append(ret, info.ContainerReference{Name: name})
+		}
+	}
+	return ret, nil
+}
+
+func (self *sharedContainerHandler) ListThreads(listType container.ListType) ([]int, error) {
+	return nil, nil
+}
+
+func (self *sharedContainerHandler) ListProcesses(listType container.ListType) ([]int, error) {
+	return nil, nil
+}
+
+// Polls the shared storage for subcontainers added and removed.
+func (self *sharedContainerHandler) WatchSubcontainers(events chan container.SubcontainerEvent) error {
+	self.watcherLock.Lock()
+	defer self.watcherLock.Unlock()
+	if self.stopWatcher != nil {
+		return fmt.Errorf("already watching the subcontainers of %q", self.name)
+	}
+	known, err := self.subcontainerNames()
+	if err != nil {
+		return err
+	}
+	stop := make(chan struct{})
+	self.stopWatcher = stop
+
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			current, err := self.subcontainerNames()
+			if err != nil {
+				glog.Warningf("Failed to list the subcontainers of %q in the shared storage: %v", self.name, err)
+				continue
+			}
+			for name := range current {
+				if !known[name] {
+					events <- container.SubcontainerEvent{EventType: container.SubcontainerAdd, Name: name}
+				}
+			}
+			for name := range known {
+				if !current[name] {
+					events <- container.SubcontainerEvent{EventType: container.SubcontainerDelete, Name: name}
+				}
+			}
+			known = current
+		}
+	}()
+	return nil
+}
+
+func (self *sharedContainerHandler) subcontainerNames() (map[string]bool, error) {
+	refs, err := self.ListContainers(container.ListRecursive)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		names[ref.Name] = true
+	}
+	return names, nil
+}
+
+func (self *sharedContainerHandler) StopWatchingSubcontainers() error {
+	self.watcherLock.Lock()
+	defer self.watcherLock.Unlock()
+	if self.stopWatcher == nil {
+		return fmt.Errorf("can't stop watch that has not started for container %q", self.name)
+	}
+	close(self.stopWatcher)
+	self.stopWatcher = nil
+	return nil
+}
+
+func (self *sharedContainerHandler) GetCgroupPath(resource string) (string, error) {
+	return "", fmt.Errorf("containers served from a shared storage have no cgroup")
+}
+
+func (self *sharedContainerHandler) Exists() bool {
+	return self.store.Exists(self.name)
+}
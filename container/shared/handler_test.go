@@ -0,0 +1,93 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/cadvisor/container"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/storage/shared"
+)
+
+func TestSharedContainerHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cadvisor-shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := shared.New(dir, shared.DefaultMaxStats, shared.DefaultMaxAge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	for _, name := range []string{"/", "/docker", "/docker/abcd", "/system"} {
+		if err := store.AddStats(info.ContainerReference{Name: name}, &info.ContainerStats{Timestamp: start}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	handler := newSharedContainerHandler("/", store)
+	listNames := func(listType container.ListType) []string {
+		refs, err := handler.ListContainers(listType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, ref := range refs {
+			names = append(names, ref.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+	if names, expected := listNames(container.ListSelf), []string{"/docker", "/system"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected subcontainers %v, got %v", expected, names)
+	}
+	if names, expected := listNames(container.ListRecursive), []string{"/docker", "/docker/abcd", "/system"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected recursive subcontainers %v, got %v", expected, names)
+	}
+
+	// Stats are only returned once.
+	stats, err := handler.GetStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats == nil || !stats.Timestamp.Equal(start) {
+		t.Errorf("expected stats at %v, got %+v", start, stats)
+	}
+	stats, err = handler.GetStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != nil {
+		t.Errorf("expected no new stats, got %+v", stats)
+	}
+	next := start.Add(time.Second)
+	if err := store.AddStats(info.ContainerReference{Name: "/"}, &info.ContainerStats{Timestamp: next}); err != nil {
+		t.Fatal(err)
+	}
+	stats, err = handler.GetStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats == nil || !stats.Timestamp.Equal(next) {
+		t.Errorf("expected stats at %v, got %+v", next, stats)
+	}
+}
@@ -16,6 +16,7 @@ package events
 
 import (
 	"errors"
+	"fmt"
 	"sort"
 	"strings"
 	"sync"
@@ -71,6 +72,9 @@ type Request struct {
 	// if IncludeSubcontainers is false, only events occurring in the specific
 	// container, and not the subcontainers, will be returned
 	IncludeSubcontainers bool
+	// if non-empty, only events whose container had all of these labels
+	// with the same values will be returned
+	Labels map[string]string
 }
 
 // EventManager is implemented by Events. It provides two ways to monitor
@@ -86,6 +90,67 @@ type EventManager interface {
 	AddEvent(e *info.Event) error
 	// Cancels a previously requested watch event.
 	StopWatch(watch_id int)
+	// Cancels all watches, closing their channels.
+	StopAllWatches()
+	// Returns the limits on the events kept.
+	GetStoragePolicy() StoragePolicy
+	// Replaces the limits on the events kept. Events past the new limits are
+	// removed immediately.
+	SetStoragePolicy(policy StoragePolicy) error
+}
+
+// Limits on how long and how many events of each type are kept.
+type StoragePolicy struct {
+	// Limits of the event types without their own limits. Ages are in
+	// nanoseconds, a number of events of -1 means no limit.
+	DefaultMaxAge       time.Duration `json:"default_max_age"`
+	DefaultMaxNumEvents int           `json:"default_max_num_events"`
+
+	// Limits of specific event types, overriding the defaults.
+	PerTypeMaxAge       map[info.EventType]time.Duration `json:"per_type_max_age,omitempty"`
+	PerTypeMaxNumEvents map[info.EventType]int           `json:"per_type_max_num_events,omitempty"`
+}
+
+// Keeps events for a day with no limit on their number.
+func DefaultStoragePolicy() StoragePolicy {
+	return StoragePolicy{
+		DefaultMaxAge:       24 * time.Hour,
+		DefaultMaxNumEvents: -1,
+	}
+}
+
+// Returns an error if the policy has invalid limits.
+func (self StoragePolicy) validate() error {
+	if self.DefaultMaxAge <= 0 {
+		return fmt.Errorf("default max age must be positive, got %v", self.DefaultMaxAge)
+	}
+	if self.DefaultMaxNumEvents < -1 {
+		return fmt.Errorf("default max number of events must be -1 or more, got %d", self.DefaultMaxNumEvents)
+	}
+	for eventType, maxAge := range self.PerTypeMaxAge {
+		if maxAge <= 0 {
+			return fmt.Errorf("max age of %q events must be positive, got %v", eventType, maxAge)
+		}
+	}
+	for eventType, maxNumEvents := range self.PerTypeMaxNumEvents {
+		if maxNumEvents < -1 {
+			return fmt.Errorf("max number of %q events must be -1 or more, got %d", eventType, maxNumEvents)
+		}
+	}
+	return nil
+}
+
+// Returns the max age and max number of events of the specified type.
+func (self StoragePolicy) limits(eventType info.EventType) (time.Duration, int) {
+	maxAge := self.DefaultMaxAge
+	if v, ok := self.PerTypeMaxAge[eventType]; ok {
+		maxAge = v
+	}
+	maxNumEvents := self.DefaultMaxNumEvents
+	if v, ok := self.PerTypeMaxNumEvents[eventType]; ok {
+		maxNumEvents = v
+	}
+	return maxAge, maxNumEvents
 }
 
 // events provides an implementation for the EventManager interface.
@@ -94,14 +159,26 @@ type events struct {
 	eventStore map[info.EventType]*utils.TimedStore
 	// map of registered watchers keyed by watch id.
 	watchers map[int]*watch
-	// lock guarding the eventStore.
+	// lock guarding the eventStore and storagePolicy.
 	eventsLock sync.RWMutex
 	// lock guarding watchers.
 	watcherLock sync.RWMutex
 	// last allocated watch id.
 	lastId int
-	// Max duration for which to keep events.
-	maxAge time.Duration
+	// Limits on the events kept in eventStore.
+	storagePolicy StoragePolicy
+	// Window within which events of the same type in the same container are
+	// collapsed into the first one, 0 if disabled.
+	dedupWindow time.Duration
+	// Timestamp of the last event kept per container and event type. Guarded
+	// by eventsLock.
+	lastEvents map[dedupKey]time.Time
+}
+
+// Identifies the events collapsed together.
+type dedupKey struct {
+	containerName string
+	eventType     info.EventType
 }
 
 // initialized by a call to WatchEvents(), a watch struct will then be added
@@ -128,12 +205,17 @@ func NewEventChannel(watchId int) *EventChannel {
 }
 
 // returns a pointer to an initialized Events object.
-// eventMaxAge is the max duration for which to keep events.
-func NewEventManager(eventMaxAge time.Duration) *events {
+// storagePolicy limits how long and how many events are kept. Events of the
+// same type in the same container within dedupWindow of the last one kept are
+// dropped, e.g. the creation events of a container in a restart loop. 0
+// disables deduplication.
+func NewEventManager(storagePolicy StoragePolicy, dedupWindow time.Duration) *events {
 	return &events{
-		eventStore: make(map[info.EventType]*utils.TimedStore, 0),
-		watchers:   make(map[int]*watch),
-		maxAge:     eventMaxAge,
+		eventStore:    make(map[info.EventType]*utils.TimedStore, 0),
+		watchers:      make(map[int]*watch),
+		storagePolicy: storagePolicy,
+		dedupWindow:   dedupWindow,
+		lastEvents:    make(map[dedupKey]time.Time),
 	}
 }
 
@@ -183,6 +265,16 @@ func checkIfIsSubcontainer(request *Request, event *info.Event) bool {
 	return event.ContainerName == request.ContainerName
 }
 
+// returns true if the event carries every label of the request with the same value
+func checkIfLabelsMatch(request *Request, event *info.Event) bool {
+	for k, v := range request.Labels {
+		if val, ok := event.Labels[k]; !ok || val != v {
+			return false
+		}
+	}
+	return true
+}
+
 // determines if an event occurs within the time set in the request object and is the right type
 func checkIfEventSatisfiesRequest(request *Request, event *info.Event) bool {
 	startTime := request.StartTime
@@ -201,6 +293,9 @@ func checkIfEventSatisfiesRequest(request *Request, event *info.Event) bool {
 	if !request.EventType[event.EventType] {
 		return false
 	}
+	if !checkIfLabelsMatch(request, event) {
+		return false
+	}
 	if request.ContainerName != "" {
 		return checkIfIsSubcontainer(request, event)
 	}
@@ -257,16 +352,71 @@ func (self *events) WatchEvents(request *Request) (*EventChannel, error) {
 	return returnEventChannel, nil
 }
 
+// Returns whether the event duplicates one kept within the dedup window, and
+// otherwise records it as the last one kept.
+func (self *events) isDuplicate(e *info.Event) bool {
+	if self.dedupWindow <= 0 {
+		return false
+	}
+	self.eventsLock.Lock()
+	defer self.eventsLock.Unlock()
+	key := dedupKey{e.ContainerName, e.EventType}
+	if last, ok := self.lastEvents[key]; ok {
+		diff := e.Timestamp.Sub(last)
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff < self.dedupWindow {
+			return true
+		}
+	}
+	// Forget the events past the window so that the containers that went
+	// away are not tracked forever.
+	for k, last := range self.lastEvents {
+		if e.Timestamp.Sub(last) >= self.dedupWindow {
+			delete(self.lastEvents, k)
+		}
+	}
+	self.lastEvents[key] = e.Timestamp
+	return false
+}
+
 // helper function to update the event manager's eventStore
 func (self *events) updateEventStore(e *info.Event) {
 	self.eventsLock.Lock()
 	defer self.eventsLock.Unlock()
 	if _, ok := self.eventStore[e.EventType]; !ok {
-		self.eventStore[e.EventType] = utils.NewTimedStore(self.maxAge)
+		self.eventStore[e.EventType] = utils.NewTimedStore(self.storagePolicy.limits(e.EventType))
 	}
 	self.eventStore[e.EventType].Add(e.Timestamp, e)
 }
 
+func (self *events) GetStoragePolicy() StoragePolicy {
+	self.eventsLock.RLock()
+	defer self.eventsLock.RUnlock()
+	return self.storagePolicy
+}
+
+// Replaces the policy and re-adds the stored events under the new limits, which
+// drops those past them.
+func (self *events) SetStoragePolicy(policy StoragePolicy) error {
+	if err := policy.validate(); err != nil {
+		return err
+	}
+	self.eventsLock.Lock()
+	defer self.eventsLock.Unlock()
+	self.storagePolicy = policy
+	var empty time.Time
+	for eventType, store := range self.eventStore {
+		newStore := utils.NewTimedStore(policy.limits(eventType))
+		for _, e := range store.InTimeRange(empty, empty, -1) {
+			newStore.Add(e.(*info.Event).Timestamp, e)
+		}
+		self.eventStore[eventType] = newStore
+	}
+	return nil
+}
+
 func (self *events) findValidWatchers(e *info.Event) []*watch {
 	watchesToSend := make([]*watch, 0)
 	for _, watcher := range self.watchers {
@@ -282,6 +432,10 @@ func (self *events) findValidWatchers(e *info.Event) []*watch {
 // eventStore. It also feeds the event to a set of watch channels
 // held by the manager if it satisfies the request keys of the channels
 func (self *events) AddEvent(e *info.Event) error {
+	if self.isDuplicate(e) {
+		glog.V(4).Infof("Dropped duplicate event %v", e)
+		return nil
+	}
 	self.updateEventStore(e)
 	self.watcherLock.RLock()
 	defer self.watcherLock.RUnlock()
@@ -300,7 +454,18 @@ func (self *events) StopWatch(watchId int) {
 	_, ok := self.watchers[watchId]
 	if !ok {
 		glog.Errorf("Could not find watcher instance %v", watchId)
+		return
 	}
 	close(self.watchers[watchId].eventChannel.GetChannel())
 	delete(self.watchers, watchId)
 }
+
+// Removes all watch instances from the EventManager's watchers map
+func (self *events) StopAllWatches() {
+	self.watcherLock.Lock()
+	defer self.watcherLock.Unlock()
+	for watchId, watcher := range self.watchers {
+		close(watcher.eventChannel.GetChannel())
+		delete(self.watchers, watchId)
+	}
+}
@@ -47,7 +47,7 @@ func initializeScenario(t *testing.T) (*events, *Request, *info.Event, *info.Eve
 	fakeEvent := makeEvent(createOldTime(t), "/")
 	fakeEvent2 := makeEvent(time.Now(), "/")
 
-	return NewEventManager(time.Hour), NewRequest(), fakeEvent, fakeEvent2
+	return NewEventManager(DefaultStoragePolicy(), 0), NewRequest(), fakeEvent, fakeEvent2
 }
 
 func checkNumberOfEvents(t *testing.T, numEventsExpected int, numEventsReceived int) {
@@ -194,3 +194,148 @@ func TestGetEventsForNoTypeRequested(t *testing.T) {
 	assert.Nil(t, err)
 	checkNumberOfEvents(t, 0, len(receivedEvents))
 }
+
+func TestGetEventsForLabels(t *testing.T) {
+	myEventHolder, myRequest, _, _ := initializeScenario(t)
+	myRequest.EventType[info.EventContainerCreation] = true
+
+	frontend := &info.Event{
+		ContainerName: "/frontend",
+		Timestamp:     time.Now(),
+		EventType:     info.EventContainerCreation,
+		Labels:        map[string]string{"app": "web", "tier": "frontend"},
+	}
+	backend := &info.Event{
+		ContainerName: "/backend",
+		Timestamp:     time.Now(),
+		EventType:     info.EventContainerCreation,
+		Labels:        map[string]string{"app": "web", "tier": "backend"},
+	}
+	unlabeled := &info.Event{
+		ContainerName: "/unlabeled",
+		Timestamp:     time.Now(),
+		EventType:     info.EventContainerCreation,
+	}
+	myEventHolder.AddEvent(frontend)
+	myEventHolder.AddEvent(backend)
+	myEventHolder.AddEvent(unlabeled)
+
+	receivedEvents, err := myEventHolder.GetEvents(myRequest)
+	assert.Nil(t, err)
+	checkNumberOfEvents(t, 3, len(receivedEvents))
+
+	myRequest.Labels = map[string]string{"app": "web"}
+	receivedEvents, err = myEventHolder.GetEvents(myRequest)
+	assert.Nil(t, err)
+	checkNumberOfEvents(t, 2, len(receivedEvents))
+
+	myRequest.Labels = map[string]string{"app": "web", "tier": "backend"}
+	receivedEvents, err = myEventHolder.GetEvents(myRequest)
+	assert.Nil(t, err)
+	checkNumberOfEvents(t, 1, len(receivedEvents))
+	ensureProperEventReturned(t, backend, receivedEvents[0])
+
+	myRequest.Labels = map[string]string{"tier": "database"}
+	receivedEvents, err = myEventHolder.GetEvents(myRequest)
+	assert.Nil(t, err)
+	checkNumberOfEvents(t, 0, len(receivedEvents))
+}
+
+func TestWatchEventsForLabels(t *testing.T) {
+	myEventHolder, myRequest, _, _ := initializeScenario(t)
+	myRequest.EventType[info.EventOom] = true
+	myRequest.Labels = map[string]string{"app": "web"}
+	returnEventChannel, err := myEventHolder.WatchEvents(myRequest)
+	assert.Nil(t, err)
+
+	other := makeEvent(time.Now(), "/other")
+	other.Labels = map[string]string{"app": "db"}
+	web := makeEvent(time.Now(), "/web")
+	web.Labels = map[string]string{"app": "web"}
+	myEventHolder.AddEvent(other)
+	myEventHolder.AddEvent(web)
+
+	select {
+	case event := <-returnEventChannel.GetChannel():
+		ensureProperEventReturned(t, web, event)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the labeled event")
+	}
+	select {
+	case event := <-returnEventChannel.GetChannel():
+		t.Errorf("received unexpected event %v", event)
+	default:
+	}
+}
+
+func TestSetStoragePolicy(t *testing.T) {
+	myEventHolder, myRequest, _, _ := initializeScenario(t)
+	myRequest.MaxEventsReturned = -1
+	myRequest.EventType[info.EventOom] = true
+	myRequest.EventType[info.EventOomKill] = true
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		oom := makeEvent(start.Add(time.Duration(i)*time.Second), "/")
+		oomKill := makeEvent(start.Add(time.Duration(i)*time.Second), "/")
+		oomKill.EventType = info.EventOomKill
+		myEventHolder.AddEvent(oom)
+		myEventHolder.AddEvent(oomKill)
+	}
+
+	// Keep fewer OOM events, and OOM kill events for less time.
+	policy := DefaultStoragePolicy()
+	policy.PerTypeMaxNumEvents = map[info.EventType]int{info.EventOom: 2}
+	policy.PerTypeMaxAge = map[info.EventType]time.Duration{info.EventOomKill: 2500 * time.Millisecond}
+	assert.Nil(t, myEventHolder.SetStoragePolicy(policy))
+	assert.Equal(t, policy, myEventHolder.GetStoragePolicy())
+
+	receivedEvents, err := myEventHolder.GetEvents(myRequest)
+	assert.Nil(t, err)
+	counts := make(map[info.EventType]int)
+	for _, e := range receivedEvents {
+		counts[e.EventType]++
+	}
+	assert.Equal(t, map[info.EventType]int{info.EventOom: 2, info.EventOomKill: 3}, counts)
+
+	// New events are kept under the new policy.
+	myEventHolder.AddEvent(makeEvent(start.Add(5*time.Second), "/"))
+	assert.Equal(t, 2, myEventHolder.eventStore[info.EventOom].Size())
+
+	policy.DefaultMaxNumEvents = -2
+	assert.NotNil(t, myEventHolder.SetStoragePolicy(policy))
+}
+
+func TestDedupWindow(t *testing.T) {
+	myEventHolder := NewEventManager(DefaultStoragePolicy(), time.Minute)
+	myRequest := NewRequest()
+	myRequest.EventType[info.EventContainerCreation] = true
+	myRequest.IncludeSubcontainers = true
+	myRequest.MaxEventsReturned = -1
+
+	// A container in a restart loop is created again and again.
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		e := makeEvent(start.Add(time.Duration(i)*time.Second), "/flapping")
+		e.EventType = info.EventContainerCreation
+		assert.Nil(t, myEventHolder.AddEvent(e))
+	}
+	// Events of other containers are kept.
+	other := makeEvent(start.Add(2*time.Second), "/other")
+	other.EventType = info.EventContainerCreation
+	assert.Nil(t, myEventHolder.AddEvent(other))
+
+	receivedEvents, err := myEventHolder.GetEvents(myRequest)
+	assert.Nil(t, err)
+	checkNumberOfEvents(t, 2, len(receivedEvents))
+	ensureProperEventReturned(t, other, receivedEvents[1])
+	assert.Equal(t, start, receivedEvents[0].Timestamp)
+
+	// Events past the window are kept again.
+	later := makeEvent(start.Add(time.Minute), "/flapping")
+	later.EventType = info.EventContainerCreation
+	assert.Nil(t, myEventHolder.AddEvent(later))
+	receivedEvents, err = myEventHolder.GetEvents(myRequest)
+	assert.Nil(t, err)
+	checkNumberOfEvents(t, 3, len(receivedEvents))
+}
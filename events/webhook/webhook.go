@@ -0,0 +1,147 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package webhook posts events to an external webhook.
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/cadvisor/events"
+	info "github.com/google/cadvisor/info/v1"
+)
+
+// Registers for receiving the events satisfying a request, implemented by the manager.
+type eventWatcher interface {
+	WatchForEvents(request *events.Request) (*events.EventChannel, error)
+}
+
+// Sink posts events as JSON to a webhook. Events are queued so that a slow or
+// failing webhook never blocks the events manager. Events are dropped when the
+// queue is full.
+type Sink struct {
+	url    string
+	client *http.Client
+	queue  chan *info.Event
+
+	// Number of times an event is posted before giving up on it.
+	maxAttempts int
+	// Delay before the first retry, doubled on each subsequent retry.
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+// Returns a sink posting to the specified URL that queues up to queueSize events.
+func New(url string, queueSize int) *Sink {
+	return &Sink{
+		url:            url,
+		client:         &http.Client{Timeout: 10 * time.Second},
+		queue:          make(chan *info.Event, queueSize),
+		maxAttempts:    5,
+		initialBackoff: time.Second,
+		maxBackoff:     time.Minute,
+	}
+}
+
+// Watches the events satisfying the request and posts them to the webhook
+// until the watch is closed.
+func (self *Sink) Start(watcher eventWatcher, request *events.Request) error {
+	eventChannel, err := watcher.WatchForEvents(request)
+	if err != nil {
+		return err
+	}
+	go self.enqueue(eventChannel.GetChannel())
+	go self.send()
+	return nil
+}
+
+// Moves events from the watch to the queue without ever blocking the watch.
+func (self *Sink) enqueue(in <-chan *info.Event) {
+	for event := range in {
+		select {
+		case self.queue <- event:
+		default:
+			glog.Warningf("Event webhook queue is full, dropping %v event of container %q", event.EventType, event.ContainerName)
+		}
+	}
+	close(self.queue)
+}
+
+func (self *Sink) send() {
+	for event := range self.queue {
+		backoff := self.initialBackoff
+		for attempt := 1; ; attempt++ {
+			err := self.post(event)
+			if err == nil {
+				break
+			}
+			if attempt >= self.maxAttempts {
+				glog.Errorf("Failed to post %v event of container %q to the webhook, giving up after %d attempts: %v", event.EventType, event.ContainerName, attempt, err)
+				break
+			}
+			glog.Warningf("Failed to post %v event of container %q to the webhook, retrying in %v: %v", event.EventType, event.ContainerName, backoff, err)
+			time.Sleep(backoff)
+			backoff *= 2
+			if backoff > self.maxBackoff {
+				backoff = self.maxBackoff
+			}
+		}
+	}
+}
+
+func (self *Sink) post(event *info.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	resp, err := self.client.Post(self.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// Parses a comma-separated list of event types, e.g. "oom,oomKill".
+func ParseEventTypes(types string) (map[info.EventType]bool, error) {
+	known := map[info.EventType]bool{
+		info.EventOom:               true,
+		info.EventOomKill:           true,
+		info.EventContainerCreation: true,
+		info.EventContainerDeletion: true,
+		info.EventPidMigration:      true,
+		info.EventMemoryPressure:    true,
+	}
+	ret := make(map[info.EventType]bool)
+	for _, t := range strings.Split(types, ",") {
+		eventType := info.EventType(strings.TrimSpace(t))
+		if eventType == "" {
+			continue
+		}
+		if !known[eventType] {
+			return nil, fmt.Errorf("unknown event type %q", eventType)
+		}
+		ret[eventType] = true
+	}
+	return ret, nil
+}
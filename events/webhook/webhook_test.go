@@ -0,0 +1,106 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/cadvisor/events"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeWatcher struct {
+	eventChannel *events.EventChannel
+}
+
+func (self *fakeWatcher) WatchForEvents(request *events.Request) (*events.EventChannel, error) {
+	return self.eventChannel, nil
+}
+
+// Starts a sink posting to a server that fails the first failures posts and
+// returns the events received by the server.
+func startSink(t *testing.T, failures int) (chan *info.Event, chan<- *info.Event, func()) {
+	received := make(chan *info.Event, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		event := new(info.Event)
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(event))
+		received <- event
+	}))
+
+	sink := New(server.URL, 10)
+	sink.initialBackoff = time.Millisecond
+	sink.maxBackoff = time.Millisecond
+	watcher := &fakeWatcher{events.NewEventChannel(0)}
+	require.NoError(t, sink.Start(watcher, events.NewRequest()))
+	return received, watcher.eventChannel.GetChannel(), server.Close
+}
+
+func waitForEvent(t *testing.T, received chan *info.Event) *info.Event {
+	select {
+	case event := <-received:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the webhook to receive an event")
+	}
+	return nil
+}
+
+func TestPostsEvents(t *testing.T) {
+	received, in, stop := startSink(t, 0)
+	defer stop()
+
+	event := &info.Event{
+		ContainerName: "/docker/abc",
+		Timestamp:     time.Unix(1400000000, 0).UTC(),
+		EventType:     info.EventOom,
+		Labels:        map[string]string{"app": "web"},
+	}
+	in <- event
+
+	assert.Equal(t, event, waitForEvent(t, received))
+}
+
+func TestRetriesFailedPosts(t *testing.T) {
+	received, in, stop := startSink(t, 2)
+	defer stop()
+
+	in <- &info.Event{ContainerName: "/a", EventType: info.EventContainerCreation}
+	in <- &info.Event{ContainerName: "/b", EventType: info.EventContainerDeletion}
+
+	assert.Equal(t, "/a", waitForEvent(t, received).ContainerName)
+	assert.Equal(t, "/b", waitForEvent(t, received).ContainerName)
+}
+
+func TestParseEventTypes(t *testing.T) {
+	types, err := ParseEventTypes("oom, containerCreation")
+	require.NoError(t, err)
+	assert.Equal(t, map[info.EventType]bool{info.EventOom: true, info.EventContainerCreation: true}, types)
+
+	_, err = ParseEventTypes("oom,unknown")
+	assert.Error(t, err)
+}
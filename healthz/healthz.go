@@ -16,17 +16,34 @@ package healthz
 
 import (
 	"net/http"
+	"strings"
 
 	httpMux "github.com/google/cadvisor/http/mux"
 )
 
-func handleHealthz(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("ok"))
+// Reports the containers whose stats repeatedly failed to be collected.
+type degradedContainersProvider interface {
+	DegradedContainers() []string
 }
 
-// Register simple HTTP /healthz handler to return "ok".
-func RegisterHandler(mux httpMux.Mux) error {
-	mux.HandleFunc("/healthz", handleHealthz)
+// Returns "ok" when all containers are healthy. Otherwise returns "degraded"
+// followed by the names of the degraded containers, one per line. cAdvisor
+// itself is still serving so the status is OK in both cases.
+func healthzHandler(provider degradedContainersProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		degraded := provider.DegradedContainers()
+		if len(degraded) == 0 {
+			w.Write([]byte("ok"))
+			return
+		}
+		w.Write([]byte("degraded\n" + strings.Join(degraded, "\n")))
+	}
+}
+
+// Register simple HTTP /healthz handler to return "ok", or "degraded" along with
+// the degraded containers.
+func RegisterHandler(mux httpMux.Mux, provider degradedContainersProvider) error {
+	mux.HandleFunc("/healthz", healthzHandler(provider))
 	return nil
 }
@@ -25,6 +25,7 @@ import (
 	httpMux "github.com/google/cadvisor/http/mux"
 	"github.com/google/cadvisor/manager"
 	"github.com/google/cadvisor/metrics"
+	"github.com/google/cadvisor/nagios"
 	"github.com/google/cadvisor/pages"
 	"github.com/google/cadvisor/pages/static"
 	"github.com/google/cadvisor/validate"
@@ -33,10 +34,15 @@ import (
 
 func RegisterHandlers(mux httpMux.Mux, containerManager manager.Manager, httpAuthFile, httpAuthRealm, httpDigestFile, httpDigestRealm, prometheusEndpoint string) error {
 	// Basic health handler.
-	if err := healthz.RegisterHandler(mux); err != nil {
+	if err := healthz.RegisterHandler(mux, containerManager); err != nil {
 		return fmt.Errorf("failed to register healthz handler: %s", err)
 	}
 
+	// Nagios check handler.
+	if err := nagios.RegisterHandler(mux, containerManager); err != nil {
+		return fmt.Errorf("failed to register nagios handler: %s", err)
+	}
+
 	// Validation/Debug handler.
 	mux.HandleFunc(validate.ValidatePage, func(w http.ResponseWriter, r *http.Request) {
 		err := validate.HandleRequest(w, containerManager)
@@ -87,6 +93,7 @@ func RegisterHandlers(mux httpMux.Mux, containerManager manager.Manager, httpAut
 
 	collector := metrics.NewPrometheusCollector(containerManager)
 	prometheus.MustRegister(collector)
+	prometheus.MustRegister(manager.FirstSampleDelay)
 	http.Handle(prometheusEndpoint, prometheus.Handler())
 
 	return nil
@@ -0,0 +1,96 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	auth "github.com/abbot/go-http-auth"
+)
+
+// Options securing every endpoint of the HTTP server, the API included.
+type ServerOptions struct {
+	// htpasswd file holding the credentials of the HTTP basic auth required by
+	// every request. Auth is disabled if empty.
+	AuthFile  string
+	AuthRealm string
+	// Paths served without auth along with the paths below them, e.g. to let
+	// Prometheus scrape /metrics and probes reach /healthz.
+	AuthExemptPaths []string
+
+	// Certificate and key the server uses for TLS. Requests not made over TLS
+	// are rejected when set.
+	TLSCertFile string
+	TLSKeyFile  string
+}
+
+func (self *ServerOptions) tls() bool {
+	return self.TLSCertFile != "" && self.TLSKeyFile != ""
+}
+
+// A Server serves HTTP according to its ServerOptions.
+type Server struct {
+	http.Server
+	options ServerOptions
+}
+
+// Returns a server listening on addr that serves handler once the request is
+// authenticated and made over TLS, as required by the options.
+func NewServer(addr string, handler http.Handler, options ServerOptions) *Server {
+	return &Server{
+		Server: http.Server{
+			Addr:    addr,
+			Handler: secureHandler(handler, options),
+		},
+		options: options,
+	}
+}
+
+// Listens and serves HTTPS if TLS is configured, HTTP otherwise.
+func (self *Server) ListenAndServe() error {
+	if self.options.tls() {
+		return self.Server.ListenAndServeTLS(self.options.TLSCertFile, self.options.TLSKeyFile)
+	}
+	return self.Server.ListenAndServe()
+}
+
+func secureHandler(handler http.Handler, options ServerOptions) http.Handler {
+	var authenticator *auth.BasicAuth
+	if options.AuthFile != "" {
+		authenticator = auth.NewBasicAuthenticator(options.AuthRealm, auth.HtpasswdFileProvider(options.AuthFile))
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if options.tls() && r.TLS == nil {
+			http.Error(w, "TLS required", http.StatusForbidden)
+			return
+		}
+		if authenticator != nil && !authExempt(r.URL.Path, options.AuthExemptPaths) && authenticator.CheckAuth(r) == "" {
+			authenticator.RequireAuth(w, r)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// Whether the path is one of the exempt paths or below one of them.
+func authExempt(path string, exemptPaths []string) bool {
+	for _, exempt := range exemptPaths {
+		if path == exempt || strings.HasPrefix(path, strings.TrimSuffix(exempt, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serve(handler http.Handler, path, user, password string, overTLS bool) int {
+	r, _ := http.NewRequest("GET", path, nil)
+	if user != "" {
+		r.SetBasicAuth(user, password)
+	}
+	if overTLS {
+		r.TLS = &tls.ConnectionState{}
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w.Code
+}
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+func TestSecureHandlerAuth(t *testing.T) {
+	handler := secureHandler(okHandler, ServerOptions{
+		AuthFile:        "../test.htpasswd",
+		AuthRealm:       "localhost",
+		AuthExemptPaths: []string{"/metrics", "/healthz"},
+	})
+	testCases := []struct {
+		path     string
+		user     string
+		password string
+		expected int
+	}{
+		{"/api/v1.3/containers", "admin", "password1", http.StatusOK},
+		{"/api/v1.3/containers", "", "", http.StatusUnauthorized},
+		{"/api/v1.3/containers", "admin", "wrong", http.StatusUnauthorized},
+		{"/api/v1.3/containers", "nobody", "password1", http.StatusUnauthorized},
+		{"/metrics", "", "", http.StatusOK},
+		{"/healthz", "", "", http.StatusOK},
+		{"/healthzzz", "", "", http.StatusUnauthorized},
+	}
+	for _, tc := range testCases {
+		if code := serve(handler, tc.path, tc.user, tc.password, false); code != tc.expected {
+			t.Errorf("expected %d for %s as %q, got %d", tc.expected, tc.path, tc.user, code)
+		}
+	}
+}
+
+func TestSecureHandlerTLS(t *testing.T) {
+	handler := secureHandler(okHandler, ServerOptions{
+		TLSCertFile: "cert.pem",
+		TLSKeyFile:  "key.pem",
+	})
+	if code := serve(handler, "/containers/", "", "", false); code != http.StatusForbidden {
+		t.Errorf("expected %d without TLS, got %d", http.StatusForbidden, code)
+	}
+	if code := serve(handler, "/containers/", "", "", true); code != http.StatusOK {
+		t.Errorf("expected %d over TLS, got %d", http.StatusOK, code)
+	}
+}
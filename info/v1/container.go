@@ -16,6 +16,7 @@ package v1
 
 import (
 	"reflect"
+	"strings"
 	"time"
 )
 
@@ -23,6 +24,14 @@ type CpuSpec struct {
 	Limit    uint64 `json:"limit"`
 	MaxLimit uint64 `json:"max_limit"`
 	Mask     string `json:"mask,omitempty"`
+	// Time the container may run past its quota in a period by using the
+	// quota it left unused in earlier periods. 0 if CPU bursting is not
+	// supported, only cgroup v2 reports it.
+	// Units: microseconds.
+	Burst uint64 `json:"burst,omitempty"`
+	// Whether the processes of the container are scheduled with the SCHED_IDLE
+	// policy, i.e. only run when nothing else would. Only cgroup v2 reports it.
+	Idle bool `json:"idle,omitempty"`
 }
 
 type MemorySpec struct {
@@ -55,6 +64,144 @@ type ContainerSpec struct {
 
 	// HasDiskIo when true, indicates that DiskIo stats will be available.
 	HasDiskIo bool `json:"has_diskio"`
+
+	// Exit code of the last run of the container. Zero if the container never exited.
+	LastExitCode int `json:"last_exit_code,omitempty"`
+
+	// Whether the last run of the container was killed by the OOM killer.
+	LastOOMKilled bool `json:"last_oom_killed,omitempty"`
+
+	// ID of the image the container was started from, the sha256 digest of
+	// the image configuration, e.g. "sha256:<hex>". This is not the repository
+	// digest of the image in a registry. Empty if the container has no image.
+	ImageID string `json:"image_id,omitempty"`
+
+	// Time at which the image of the container was created.
+	ImageCreationTime time.Time `json:"image_creation_time,omitempty"`
+
+	// Registry host and repository of the image reference the container was
+	// created from, e.g. "docker.io" and "library/nginx" for "nginx:1.9".
+	ImageRegistry   string `json:"image_registry,omitempty"`
+	ImageRepository string `json:"image_repository,omitempty"`
+
+	// Repository tags of the image of the container.
+	ImageRepoTags []string `json:"image_repo_tags,omitempty"`
+
+	// Repository digest of the image reference the container was created
+	// from, e.g. "sha256:<hex>" for "redis@sha256:<hex>". Empty if it was
+	// created from a tag or an image ID.
+	ImageRepoDigest string `json:"image_repo_digest,omitempty"`
+
+	// Volumes and bind mounts of the container.
+	Mounts []Mount `json:"mounts,omitempty"`
+
+	// Key-value labels attached to the container by its runtime.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Resource limits of the main process of the container, as applied by the
+	// kernel. Empty if they could not be determined.
+	Ulimits []Ulimit `json:"ulimits,omitempty"`
+
+	// IDs of the CPUs and of the memory nodes the processes of the container may
+	// run on, from its cpuset cgroup. Empty if unknown.
+	AllowedCpus []int `json:"allowed_cpus,omitempty"`
+	AllowedMems []int `json:"allowed_mems,omitempty"`
+
+	// IO limits of the container on cgroup v2, from io.max. Devices without
+	// limits are not listed.
+	IoMax []IoMaxLimit `json:"io_max,omitempty"`
+
+	// Devices the processes of the container may access. Empty if unknown.
+	DeviceAccess []DeviceAccessRule `json:"device_access,omitempty"`
+
+	// Class ID tagging the network packets of the container from its net_cls
+	// cgroup, 0 if unset or unknown.
+	NetClsId uint32 `json:"net_cls_id,omitempty"`
+
+	// Priorities of the network traffic of the container per interface from
+	// its net_prio cgroup. Interfaces with the default priority are not listed.
+	NetPrioMap map[string]uint32 `json:"net_prio_map,omitempty"`
+
+	// Cgroup subsystems present for the container, sorted, e.g. "cpu" and
+	// "memory". Stats of the missing ones are not collected.
+	ControllersAvailable []string `json:"controllers_available,omitempty"`
+
+	// Adjustment of the OOM killer score of the init process of the container,
+	// from -1000 to 1000. Nil if it could not be read.
+	OomScoreAdj *int `json:"oom_score_adj,omitempty"`
+
+	// Metadata of the Kubernetes pod the container belongs to. Empty if the
+	// container is not managed by Kubernetes.
+	Kubernetes KubernetesMetadata `json:"kubernetes,omitempty"`
+}
+
+type KubernetesMetadata struct {
+	// Name of the pod.
+	PodName string `json:"pod_name,omitempty"`
+
+	// Namespace of the pod.
+	Namespace string `json:"namespace,omitempty"`
+
+	// UID of the pod.
+	PodUID string `json:"pod_uid,omitempty"`
+
+	// Name of the container within the pod.
+	ContainerName string `json:"container_name,omitempty"`
+}
+
+type Ulimit struct {
+	// Name of the limited resource as used by ulimit, e.g. "nofile".
+	Name string `json:"name"`
+
+	// Soft and hard limits of the resource, -1 if unlimited.
+	SoftLimit int64 `json:"soft_limit"`
+	HardLimit int64 `json:"hard_limit"`
+}
+
+// IO limits of the container on a block device. Zero if unlimited.
+type IoMaxLimit struct {
+	Major uint64 `json:"major"`
+	Minor uint64 `json:"minor"`
+
+	// Units: bytes per second.
+	ReadBps  uint64 `json:"read_bps,omitempty"`
+	WriteBps uint64 `json:"write_bps,omitempty"`
+
+	// Units: operations per second.
+	ReadIops  uint64 `json:"read_iops,omitempty"`
+	WriteIops uint64 `json:"write_iops,omitempty"`
+}
+
+// Access to devices granted by the devices cgroup of the container.
+type DeviceAccessRule struct {
+	// Type of the devices: "a" for all, "b" for block or "c" for character
+	// devices.
+	Type string `json:"type"`
+
+	// Major and minor numbers of the devices, DeviceWildcard for any.
+	Major int64 `json:"major"`
+	Minor int64 `json:"minor"`
+
+	// Allowed accesses out of "r" for read, "w" for write and "m" for mknod.
+	Permissions string `json:"permissions"`
+}
+
+// Major or minor number of DeviceAccessRule matching any device.
+const DeviceWildcard = -1
+
+type Mount struct {
+	// Path of the mounted directory on the host.
+	Source string `json:"source"`
+
+	// Path at which the directory is mounted in the container.
+	Destination string `json:"destination"`
+
+	// Access mode of the mount, either "ro" or "rw".
+	Mode string `json:"mode"`
+
+	// Type of the mount, either "volume" for volumes managed by the container
+	// runtime or "bind" for host directories.
+	Type string `json:"type"`
 }
 
 // Container reference contains enough information to uniquely identify a container
@@ -63,12 +210,50 @@ type ContainerReference struct {
 	Name string `json:"name"`
 
 	// Other names by which the container is known within a certain namespace.
-	// This is unique within that namespace.
+	// This is unique within that namespace. The original name of a container
+	// whose name was normalized is kept as an alias.
 	Aliases []string `json:"aliases,omitempty"`
 
 	// Namespace under which the aliases of a container are unique.
 	// An example of a namespace is "docker" for Docker containers.
 	Namespace string `json:"namespace,omitempty"`
+
+	// Identifier of the container that persists across its restarts while
+	// its name and aliases change, e.g. the pod UID and container name of a
+	// Kubernetes container or the ID of other Docker containers. Empty if the
+	// container has none.
+	StableID string `json:"stable_id,omitempty"`
+
+	// Absolute name of the parent of the container in the hierarchy of
+	// containers, empty for the root container.
+	ParentName string `json:"parent_name,omitempty"`
+
+	// Depth of the container in the hierarchy of containers, 0 for the root
+	// container and 1 for its children.
+	Depth int `json:"depth,omitempty"`
+}
+
+// Returns the identity of the series of samples of the container in storage
+// drivers identifying containers by their stable ID: its stable ID so that the
+// series continues across restarts of the container, or its canonical name if
+// it has none.
+func (self *ContainerReference) SeriesID() string {
+	if self.StableID != "" {
+		return self.StableID
+	}
+	return self.CanonicalName()
+}
+
+// Returns the name identifying the container in storage drivers and
+// Prometheus: its first alias, or its name if it has none. Aliases that are
+// absolute names, i.e. original names of containers, are skipped.
+func (self *ContainerReference) CanonicalName() string {
+	for _, alias := range self.Aliases {
+		if !strings.HasPrefix(alias, "/") {
+			return alias
+		}
+	}
+	return self.Name
 }
 
 // Sorts by container name.
@@ -121,6 +306,48 @@ type ContainerInfo struct {
 	Stats []*ContainerStats `json:"stats,omitempty"`
 }
 
+// Spec and most recent stats of a container, a compact snapshot of its state.
+type ContainerStatus struct {
+	ContainerReference
+
+	Spec ContainerSpec `json:"spec"`
+
+	// Most recent stats of the container. Nil if none were collected yet.
+	Stats *ContainerStats `json:"stats,omitempty"`
+}
+
+// Span of the stats of a container retained in memory, which is shorter than
+// the storage duration when collection had gaps.
+type ContainerRetention struct {
+	ContainerReference
+
+	// Timestamps of the oldest and newest retained samples. Zero if there are
+	// none.
+	Oldest time.Time `json:"oldest"`
+	Newest time.Time `json:"newest"`
+
+	// Number of retained samples.
+	NumSamples int `json:"num_samples"`
+}
+
+// Stats summed across the containers running an image.
+type ImageStats struct {
+	// Image the containers run, as requested.
+	Image string `json:"image"`
+
+	// Names of the containers whose stats are summed, sorted.
+	Containers []string `json:"containers"`
+
+	// Number of containers whose stats are summed.
+	NumContainers int `json:"num_containers"`
+
+	// Sums of the CPU, memory, network and filesystem stats of the containers,
+	// oldest first. The i-th most recent samples of the containers are summed
+	// together and timestamped with the newest of them, so there are as many
+	// samples as the container with the fewest has.
+	Stats []*ContainerStats `json:"stats,omitempty"`
+}
+
 // TODO(vmarmol): Refactor to not need this equality comparison.
 // ContainerInfo may be (un)marshaled by json or other en/decoder. In that
 // case, the Timestamp field in each stats/sample may not be precisely
@@ -187,6 +414,60 @@ func (self *ContainerSpec) Eq(b *ContainerSpec) bool {
 	if self.HasDiskIo != b.HasDiskIo {
 		return false
 	}
+	if self.LastExitCode != b.LastExitCode {
+		return false
+	}
+	if self.LastOOMKilled != b.LastOOMKilled {
+		return false
+	}
+	if self.ImageID != b.ImageID {
+		return false
+	}
+	if !self.ImageCreationTime.Equal(b.ImageCreationTime) {
+		return false
+	}
+	if self.ImageRegistry != b.ImageRegistry || self.ImageRepository != b.ImageRepository {
+		return false
+	}
+	if !reflect.DeepEqual(self.ImageRepoTags, b.ImageRepoTags) {
+		return false
+	}
+	if self.ImageRepoDigest != b.ImageRepoDigest {
+		return false
+	}
+	if !reflect.DeepEqual(self.Mounts, b.Mounts) {
+		return false
+	}
+	if !reflect.DeepEqual(self.Labels, b.Labels) {
+		return false
+	}
+	if !reflect.DeepEqual(self.Ulimits, b.Ulimits) {
+		return false
+	}
+	if !reflect.DeepEqual(self.AllowedCpus, b.AllowedCpus) || !reflect.DeepEqual(self.AllowedMems, b.AllowedMems) {
+		return false
+	}
+	if !reflect.DeepEqual(self.IoMax, b.IoMax) {
+		return false
+	}
+	if !reflect.DeepEqual(self.DeviceAccess, b.DeviceAccess) {
+		return false
+	}
+	if self.NetClsId != b.NetClsId {
+		return false
+	}
+	if !reflect.DeepEqual(self.NetPrioMap, b.NetPrioMap) {
+		return false
+	}
+	if !reflect.DeepEqual(self.ControllersAvailable, b.ControllersAvailable) {
+		return false
+	}
+	if !reflect.DeepEqual(self.OomScoreAdj, b.OomScoreAdj) {
+		return false
+	}
+	if self.Kubernetes != b.Kubernetes {
+		return false
+	}
 	return true
 }
 
@@ -270,6 +551,81 @@ type CpuStats struct {
 	// Load is smoothed over the last 10 seconds. Instantaneous value can be read
 	// from LoadStats.NrRunning.
 	LoadAverage int32 `json:"load_average"`
+
+	// Scheduler statistics of the tasks of the container. The run and
+	// runqueue times are only collected with --enable_schedstat.
+	Schedstat CpuSchedstat `json:"schedstat"`
+
+	// Context switches of the tasks in the container. Only collected when
+	// enabled and left as zero otherwise.
+	ContextSwitches CpuContextSwitches `json:"context_switches"`
+
+	// CFS bandwidth control stats of the container.
+	CFS CpuCFS `json:"cfs"`
+
+	// Rate of the CPU usage over the interval since the previous sample of the
+	// container, zero for its first sample.
+	UsageRate CpuUsageRate `json:"usage_rate"`
+}
+
+// CPU usage of a container per second, i.e. the number of cores it used.
+type CpuUsageRate struct {
+	// Total CPU usage.
+	// Units: millicores
+	Total uint64 `json:"total"`
+
+	// Usage in user space.
+	// Units: millicores
+	User uint64 `json:"user"`
+
+	// Usage in kernel space.
+	// Units: millicores
+	System uint64 `json:"system"`
+}
+
+// CFS bandwidth control stats of a container, from cpu.stat. Only reported on
+// cgroup v2 hosts whose kernel supports CPU bursting and left as zero
+// otherwise.
+type CpuCFS struct {
+	// Number of periods in which the container used its burst budget.
+	BurstCount uint64 `json:"burst_count"`
+
+	// Cumulative time the container ran above its quota using its burst budget.
+	// Units: nanoseconds.
+	BurstTime uint64 `json:"burst_time"`
+}
+
+// Cumulative context switches of the tasks in a container.
+type CpuContextSwitches struct {
+	// Switches made because a task blocked, e.g. waiting on IO.
+	Voluntary uint64 `json:"voluntary"`
+
+	// Switches forced by the scheduler, e.g. at the end of a timeslice.
+	Involuntary uint64 `json:"involuntary"`
+}
+
+// Scheduler statistics of the tasks in a container. All the values are
+// cumulative counters. The per-task values are summed from
+// /proc/<tid>/schedstat and keep accumulating as tasks exit. They are only
+// collected when enabled and left as zero otherwise.
+type CpuSchedstat struct {
+	// Number of CFS bandwidth periods in which the container was throttled,
+	// from cpu.stat. Always collected.
+	// Units: periods.
+	NrThrottled uint64 `json:"nr_throttled"`
+
+	// Cumulative time the tasks of the container spent running on a CPU.
+	// Units: nanoseconds.
+	RunTime uint64 `json:"run_time"`
+
+	// Cumulative time the tasks of the container spent runnable but waiting
+	// on a runqueue for a CPU.
+	// Units: nanoseconds.
+	RunqueueTime uint64 `json:"runqueue_time"`
+
+	// Cumulative number of timeslices run by the tasks of the container.
+	// Units: timeslices.
+	RunPeriods uint64 `json:"run_periods"`
 }
 
 type PerDiskStats struct {
@@ -279,14 +635,49 @@ type PerDiskStats struct {
 }
 
 type DiskIoStats struct {
+	// Cumulative bytes transferred and IOs completed per device, keyed by
+	// operation: Read, Write, Discard and Total on cgroup v2, which cgroup v1
+	// also splits into Sync and Async. Kernels before 4.19 report no discards
+	// on cgroup v1.
 	IoServiceBytes []PerDiskStats `json:"io_service_bytes,omitempty"`
 	IoServiced     []PerDiskStats `json:"io_serviced,omitempty"`
 	IoQueued       []PerDiskStats `json:"io_queued,omitempty"`
 	Sectors        []PerDiskStats `json:"sectors,omitempty"`
-	IoServiceTime  []PerDiskStats `json:"io_service_time,omitempty"`
-	IoWaitTime     []PerDiskStats `json:"io_wait_time,omitempty"`
-	IoMerged       []PerDiskStats `json:"io_merged,omitempty"`
-	IoTime         []PerDiskStats `json:"io_time,omitempty"`
+	// Cumulative time between dispatch and completion of IOs, and time IOs
+	// waited in the scheduler queues, in nanoseconds. Only reported by the CFQ
+	// scheduler of cgroup v1.
+	IoServiceTime []PerDiskStats `json:"io_service_time,omitempty"`
+	IoWaitTime    []PerDiskStats `json:"io_wait_time,omitempty"`
+	IoMerged      []PerDiskStats `json:"io_merged,omitempty"`
+	IoTime        []PerDiskStats `json:"io_time,omitempty"`
+
+	// Cumulative time IO was delayed by io.latency throttling, in nanoseconds.
+	// Only reported on cgroup v2 hosts for devices with an io.latency target.
+	IoLatencyThrottled []PerDiskStats `json:"io_latency_throttled,omitempty"`
+
+	// Pressure stall information of IO, from io.pressure. Only reported on
+	// cgroup v2 hosts with PSI enabled, as told by HasPSI.
+	HasPSI bool     `json:"has_psi"`
+	PSI    PSIStats `json:"psi"`
+}
+
+// Pressure stall information (PSI) of a resource: how long tasks were stalled
+// waiting for it.
+type PSIStats struct {
+	// Stalls of at least one task.
+	Some PSIData `json:"some"`
+	// Stalls of all non-idle tasks at the same time.
+	Full PSIData `json:"full"`
+}
+
+type PSIData struct {
+	// Percentage of the last 10, 60 and 300 seconds tasks were stalled.
+	Avg10  float64 `json:"avg10"`
+	Avg60  float64 `json:"avg60"`
+	Avg300 float64 `json:"avg300"`
+
+	// Cumulative time tasks were stalled, in microseconds.
+	Total uint64 `json:"total"`
 }
 
 type MemoryStats struct {
@@ -295,21 +686,133 @@ type MemoryStats struct {
 	// Units: Bytes.
 	Usage uint64 `json:"usage"`
 
+	// Page cache memory, including memory mapped files. On cgroup v1 this and
+	// the RSS, mapped file, swap and paging stats below are hierarchical by
+	// default, read from the total_ entries of memory.stat and including the
+	// memory of descendant cgroups. With --memory_stats_hierarchical=false they
+	// are local, read from the unprefixed entries and only counting the
+	// container's own cgroup. cgroup v2 stats are always hierarchical.
+	// Units: Bytes.
+	Cache uint64 `json:"cache"`
+
+	// Anonymous and swap cache memory, this includes transparent hugepages.
+	// Hierarchical (total_rss) or local (rss) on cgroup v1.
+	// Units: Bytes.
+	RSS uint64 `json:"rss"`
+
+	// Memory mapped files, part of the page cache. Hierarchical
+	// (total_mapped_file) or local (mapped_file) on cgroup v1.
+	// Units: Bytes.
+	MappedFile uint64 `json:"mapped_file"`
+
+	// Swap usage. Only reported when swap accounting is enabled. Hierarchical
+	// (total_swap) or local (swap) on cgroup v1.
+	// Units: Bytes.
+	Swap uint64 `json:"swap"`
+
+	// Cumulative pages charged to and uncharged from the container. Only
+	// reported on cgroup v1, hierarchical (total_pgpgin) or local (pgpgin).
+	// Units: Pages.
+	Pgpgin  uint64 `json:"pgpgin"`
+	Pgpgout uint64 `json:"pgpgout"`
+
+	// Cumulative pages swapped in and out by the container. Only reported by
+	// kernels with per-cgroup swap events.
+	// Units: Pages.
+	Pswpin  uint64 `json:"pswpin"`
+	Pswpout uint64 `json:"pswpout"`
+
+	// Kernel memory (slab, stacks, sockets) charged to the container. Zero if
+	// kernel memory accounting is not enabled.
+	// Units: Bytes.
+	KernelUsage uint64 `json:"kernel_usage"`
+
+	// Kernel memory limit. Zero if kernel memory accounting is not enabled.
+	// Units: Bytes.
+	KernelLimit uint64 `json:"kernel_limit"`
+
+	// Socket buffer memory charged to the container, part of the kernel memory.
+	// Units: Bytes.
+	KernelTCPUsage uint64 `json:"kernel_tcp_usage"`
+
+	// Socket buffer memory limit. Zero if not available.
+	// Units: Bytes.
+	KernelTCPLimit uint64 `json:"kernel_tcp_limit"`
+
 	// The amount of working set memory, this includes recently accessed memory,
-	// dirty memory, and kernel memory. Working set is <= "usage".
+	// dirty memory, and kernel memory. It is the usage minus the inactive file
+	// cache, both hierarchical whatever --memory_stats_hierarchical is set to.
+	// Working set is <= "usage".
 	// Units: Bytes.
 	WorkingSet uint64 `json:"working_set"`
 
 	ContainerData    MemoryStatsMemoryData `json:"container_data,omitempty"`
 	HierarchicalData MemoryStatsMemoryData `json:"hierarchical_data,omitempty"`
+
+	// Memory events of the cgroup. Only reported on cgroup v2 hosts.
+	Events MemoryEvents `json:"events,omitempty"`
+
+	// Reclaim activity of the working set of the cgroup. Only reported on
+	// cgroup v2 hosts.
+	WorkingSetEvents WorkingSetEvents `json:"workingset_events,omitempty"`
+
+	// Swap events of the cgroup. Only reported on cgroup v2 hosts with swap
+	// accounting enabled, nil otherwise.
+	SwapEvents *SwapEvents `json:"swap_events,omitempty"`
+}
+
+// Cumulative counts of the memory events of a cgroup, as reported by the
+// memory.events file of cgroup v2.
+type MemoryEvents struct {
+	// Number of times the cgroup was reclaimed below its low boundary.
+	Low uint64 `json:"low"`
+	// Number of times the cgroup was throttled and reclaimed over its high boundary.
+	High uint64 `json:"high"`
+	// Number of times the cgroup usage was about to go over its max boundary.
+	Max uint64 `json:"max"`
+	// Number of times the cgroup usage reached the limit and allocation failed.
+	Oom uint64 `json:"oom"`
+	// Number of processes of the cgroup killed by the OOM killer.
+	OomKill uint64 `json:"oom_kill"`
+}
+
+// Cumulative counts of the swap events of a cgroup, as reported by the
+// memory.swap.events file of cgroup v2.
+type SwapEvents struct {
+	// Number of times the swap usage of the cgroup went over its high boundary.
+	High uint64 `json:"high"`
+	// Number of times the swap usage of the cgroup was about to go over its max
+	// boundary and swapping out failed.
+	Max uint64 `json:"max"`
+}
+
+// Cumulative counts of the reclaim activity of the working set of a cgroup, as
+// reported by the workingset_* entries of the memory.stat file of cgroup v2.
+type WorkingSetEvents struct {
+	// Number of refaults of previously evicted pages.
+	Refault uint64 `json:"refault"`
+	// Number of refaulted pages that were immediately activated.
+	Activate uint64 `json:"activate"`
+	// Number of restored pages which had been detected as an active workingset
+	// before they were reclaimed.
+	Restore uint64 `json:"restore"`
 }
 
+// Cumulative page faults. Pgfault counts all faults, Pgmajfault the major
+// faults that required reading from disk, so minor faults are the difference.
 type MemoryStatsMemoryData struct {
 	Pgfault    uint64 `json:"pgfault"`
 	Pgmajfault uint64 `json:"pgmajfault"`
 }
 
 type NetworkStats struct {
+	// Name of the network interface the stats are for.
+	Interface string `json:"interface,omitempty"`
+	// Link speed of the interface in bytes per second. Zero if the interface
+	// does not report it, e.g. virtual interfaces.
+	Speed uint64 `json:"speed,omitempty"`
+	// Maximum transmission unit of the interface in bytes.
+	Mtu uint64 `json:"mtu,omitempty"`
 	// Cumulative count of bytes received.
 	RxBytes uint64 `json:"rx_bytes"`
 	// Cumulative count of packets received.
@@ -326,12 +829,45 @@ type NetworkStats struct {
 	TxErrors uint64 `json:"tx_errors"`
 	// Cumulative count of packets dropped while transmitting.
 	TxDropped uint64 `json:"tx_dropped"`
+	// Memory used by TCP socket buffers in the network namespace, in bytes.
+	TcpMemUsage uint64 `json:"tcp_mem_usage"`
+	// Memory TCP sockets may use before the kernel drops packets, in bytes.
+	TcpMemLimit uint64 `json:"tcp_mem_limit"`
+	// Number of connections tracked by netfilter in the network namespace.
+	ConntrackCount uint64 `json:"conntrack_count"`
+	// Number of connections netfilter tracks before it drops new ones.
+	ConntrackLimit uint64 `json:"conntrack_limit"`
+	// Statistics of the SR-IOV virtual functions assigned to the network
+	// namespace of the container, whose traffic bypasses the interface above.
+	Interfaces []InterfaceStats `json:"interfaces,omitempty"`
+	// IP traffic of the network namespace of the container per protocol. Only
+	// reported when enabled and on hosts with IPv6.
+	Protocols []ProtocolStats `json:"protocols,omitempty"`
+}
+
+// Cumulative IP traffic of a network namespace for one IP version, counting
+// the datagrams received and those sent by the namespace itself.
+type ProtocolStats struct {
+	// IP version, "ipv4" or "ipv6".
+	Protocol string `json:"protocol"`
+	// Cumulative count of bytes received.
+	RxBytes uint64 `json:"rx_bytes"`
+	// Cumulative count of datagrams received.
+	RxPackets uint64 `json:"rx_packets"`
+	// Cumulative count of bytes transmitted.
+	TxBytes uint64 `json:"tx_bytes"`
+	// Cumulative count of datagrams transmitted.
+	TxPackets uint64 `json:"tx_packets"`
 }
 
 type FsStats struct {
 	// The block device name associated with the filesystem.
 	Device string `json:"device,omitempty"`
 
+	// Path in the container at which the volume these stats are for is
+	// mounted. Empty for the filesystems of the container itself.
+	Volume string `json:"volume,omitempty"`
+
 	// Number of bytes that can be consumed by the container on this filesystem.
 	Limit uint64 `json:"capacity"`
 
@@ -393,6 +929,52 @@ type FsStats struct {
 	WeightedIoTime uint64 `json:"weighted_io_time"`
 }
 
+type ProcessStats struct {
+	// Number of file descriptors open across all processes in the container.
+	OpenFds uint64 `json:"open_fds"`
+
+	// Soft limit on open file descriptors of the init process of the
+	// container, taken as the one with the lowest PID. 0 if unlimited.
+	OpenFdsLimit uint64 `json:"open_fds_limit"`
+
+	// Number of processes in the container. Only set when PID tracking is enabled.
+	PidCount uint64 `json:"pid_count,omitempty"`
+
+	// Cumulative number of processes that appeared in and disappeared from the
+	// container, whether started, exited or migrated between cgroups. Only set
+	// when PID tracking is enabled.
+	PidsAdded   uint64 `json:"pids_added,omitempty"`
+	PidsRemoved uint64 `json:"pids_removed,omitempty"`
+
+	// Number of threads in the container, which count against the limit of
+	// its pids cgroup just like processes.
+	ThreadCount uint64 `json:"thread_count,omitempty"`
+}
+
+// DNS queries sent by the processes of a container, as counted by an eBPF
+// probe on its DNS traffic.
+type DnsStats struct {
+	// Cumulative number of DNS queries sent.
+	Queries uint64 `json:"queries"`
+
+	// Cumulative number of DNS queries answered with an error or left
+	// unanswered.
+	Failures uint64 `json:"failures"`
+
+	// Cumulative time spent waiting for the answers to the DNS queries, in
+	// nanoseconds.
+	LatencyTotal uint64 `json:"latency_total"`
+}
+
+type MiscResourceStats struct {
+	// Number of units of the resource in use by the container.
+	Current uint64 `json:"current"`
+
+	// Maximum number of units of the resource the container may use,
+	// math.MaxUint64 if unlimited. A limit of 0 denies the resource.
+	Max uint64 `json:"max"`
+}
+
 type ContainerStats struct {
 	// The time of this stat point.
 	Timestamp time.Time    `json:"timestamp"`
@@ -406,6 +988,32 @@ type ContainerStats struct {
 
 	// Task load stats
 	TaskStats LoadStats `json:"task_stats,omitempty"`
+
+	// Cumulative number of OOM kills seen in this container.
+	OomEvents uint64 `json:"oom_events,omitempty"`
+
+	// Process statistics. Only sampled when enabled.
+	Processes ProcessStats `json:"processes,omitempty"`
+
+	// Usage and limits of the resources of the misc controller, e.g. the "sev"
+	// ASIDs, keyed by resource name. Only reported on cgroup v2 hosts with the
+	// misc controller.
+	MiscStats map[string]MiscResourceStats `json:"misc_stats,omitempty"`
+
+	// DNS query statistics. Only collected when enabled, the eBPF probe is
+	// available and the container has a cgroup v2 cgroup.
+	Dns *DnsStats `json:"dns,omitempty"`
+
+	// Metrics derived from the stats by the enabled stats transforms, keyed by name.
+	DerivedMetrics map[string]float64 `json:"derived_metrics,omitempty"`
+
+	// Time the stats were actually collected. Only set when Timestamp is
+	// aligned to the housekeeping interval.
+	CollectionTime *time.Time `json:"collection_time,omitempty"`
+
+	// Number of the sample, increasing by one with each sample collected from
+	// the container starting at 1. Gaps mean samples were dropped.
+	SequenceNumber uint64 `json:"sequence_number,omitempty"`
 }
 
 func timeEq(t1, t2 time.Time, tolerance time.Duration) bool {
@@ -498,6 +1106,10 @@ type Event struct {
 	// the original event object and all of its extraneous data, ex. an
 	// OomInstance
 	EventData EventData `json:"event_data,omitempty"`
+
+	// the labels of the container for which the event occurred, captured
+	// when the event was created
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 // EventType is an enumerated type which lists the categories under which
@@ -509,6 +1121,8 @@ const (
 	EventOomKill                     = "oomKill"
 	EventContainerCreation           = "containerCreation"
 	EventContainerDeletion           = "containerDeletion"
+	EventPidMigration                = "pidMigration"
+	EventMemoryPressure              = "memoryPressure"
 )
 
 // Extra information about an event. Only one type will be set.
@@ -518,6 +1132,12 @@ type EventData struct {
 
 	// Information about an OOM kill event.
 	OomKill *OomKillEventData `json:"oom,omitempty"`
+
+	// Information about processes moving in or out of a container.
+	PidMigration *PidMigrationEventData `json:"pid_migration,omitempty"`
+
+	// Information about the memory usage of a container nearing its limit.
+	MemoryPressure *MemoryPressureEventData `json:"memory_pressure,omitempty"`
 }
 
 // Information related to a container creation event.
@@ -534,3 +1154,23 @@ type OomKillEventData struct {
 	// The name of the killed process
 	ProcessName string `json:"process_name"`
 }
+
+// Information related to processes moving in or out of a container between
+// two housekeepings.
+type PidMigrationEventData struct {
+	// Number of processes that appeared in the container.
+	PidsAdded int `json:"pids_added"`
+
+	// Number of processes that disappeared from the container.
+	PidsRemoved int `json:"pids_removed"`
+}
+
+// Information related to the memory usage of a container crossing a fraction
+// of its limit.
+type MemoryPressureEventData struct {
+	// Working set of the container in bytes.
+	WorkingSet uint64 `json:"working_set"`
+
+	// Memory limit of the container in bytes.
+	Limit uint64 `json:"limit"`
+}
@@ -15,6 +15,7 @@
 package v1
 
 import (
+	"reflect"
 	"testing"
 	"time"
 )
@@ -77,3 +78,84 @@ func createStats(cpuUsage, memUsage uint64, timestamp time.Time) *ContainerStats
 	stats.Timestamp = timestamp
 	return stats
 }
+
+func TestSeriesID(t *testing.T) {
+	// The series of a container continues across a restart changing its name
+	// and aliases as long as its stable ID is kept.
+	before := ContainerReference{
+		Name:     "/docker/abcd",
+		Aliases:  []string{"k8s_nginx.4c2a4f8a_frontend-1", "abcd"},
+		StableID: "0f8e1d62-5b2a-11e5-9f9c-42010af00002/nginx",
+	}
+	after := ContainerReference{
+		Name:     "/docker/ef01",
+		Aliases:  []string{"k8s_nginx.9d1e03b7_frontend-1", "ef01"},
+		StableID: "0f8e1d62-5b2a-11e5-9f9c-42010af00002/nginx",
+	}
+	if before.SeriesID() != after.SeriesID() {
+		t.Errorf("expected the series to continue across the restart, got %q and %q", before.SeriesID(), after.SeriesID())
+	}
+	if id := before.SeriesID(); id != before.StableID {
+		t.Errorf("expected the stable ID to identify the series, got %q", id)
+	}
+
+	noStableID := ContainerReference{Name: "/docker/abcd", Aliases: []string{"web", "abcd"}}
+	if id := noStableID.SeriesID(); id != "web" {
+		t.Errorf("expected the canonical name to identify the series, got %q", id)
+	}
+}
+
+// Sets v, or the first field of v if it is a struct, to a non-zero value.
+func setNonZero(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(1)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(1)
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+	case reflect.Map:
+		v.Set(reflect.MakeMap(v.Type()))
+		v.SetMapIndex(reflect.Zero(v.Type().Key()), reflect.Zero(v.Type().Elem()))
+	case reflect.Ptr:
+		v.Set(reflect.New(v.Type().Elem()))
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(time.Time{}) {
+			v.Set(reflect.ValueOf(time.Unix(1000, 0)))
+		} else {
+			setNonZero(v.Field(0))
+		}
+	}
+}
+
+func TestContainerSpecEq(t *testing.T) {
+	var empty ContainerSpec
+	if !empty.Eq(&ContainerSpec{}) {
+		t.Errorf("expected empty specs to be equal")
+	}
+	// Every field of the spec is compared.
+	specType := reflect.TypeOf(empty)
+	for i := 0; i < specType.NumField(); i++ {
+		var spec ContainerSpec
+		setNonZero(reflect.ValueOf(&spec).Elem().Field(i))
+		if spec.Eq(&empty) || empty.Eq(&spec) {
+			t.Errorf("expected specs differing in %s not to be equal", specType.Field(i).Name)
+		}
+		if !spec.Eq(&spec) {
+			t.Errorf("expected spec with %s set to equal itself", specType.Field(i).Name)
+		}
+	}
+
+	// Creation times within a second of each other are equal.
+	a := ContainerSpec{CreationTime: time.Unix(1000, 0)}
+	b := ContainerSpec{CreationTime: time.Unix(1000, 500000000)}
+	if !a.Eq(&b) {
+		t.Errorf("expected specs created within a second to be equal")
+	}
+}
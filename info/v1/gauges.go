@@ -0,0 +1,129 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import "sort"
+
+// Returns a copy of the stats whose gauges can be changed without changing
+// those of the stats, i.e. with its own filesystem stats and maps.
+func (self *ContainerStats) GaugeCopy() *ContainerStats {
+	ret := *self
+	ret.Filesystem = make([]FsStats, len(self.Filesystem))
+	copy(ret.Filesystem, self.Filesystem)
+	if self.MiscStats != nil {
+		ret.MiscStats = make(map[string]MiscResourceStats, len(self.MiscStats))
+		for name, misc := range self.MiscStats {
+			ret.MiscStats[name] = misc
+		}
+	}
+	if self.DerivedMetrics != nil {
+		ret.DerivedMetrics = make(map[string]float64, len(self.DerivedMetrics))
+		for name, value := range self.DerivedMetrics {
+			ret.DerivedMetrics[name] = value
+		}
+	}
+	return &ret
+}
+
+// Calls fn with a pointer to each gauge of the stats, i.e. each value that may
+// go down as well as up like the memory usage, as opposed to the cumulative
+// counters like the CPU usage. limit is true for the gauges holding a limit
+// rather than a usage. The load average is passed as an unsigned integer and
+// stored back, and so are the values of maps, which are passed in the order of
+// their keys. Storage drivers use the gauges to tell which values they may
+// round or compare loosely, on a GaugeCopy() of the stats.
+func (self *ContainerStats) ForEachGauge(fn func(value *uint64, limit bool)) {
+	for _, usage := range []*uint64{
+		&self.Memory.Usage,
+		&self.Memory.Cache,
+		&self.Memory.RSS,
+		&self.Memory.MappedFile,
+		&self.Memory.Swap,
+		&self.Memory.WorkingSet,
+		&self.Memory.KernelUsage,
+		&self.Memory.KernelTCPUsage,
+		&self.Network.TcpMemUsage,
+		&self.Network.ConntrackCount,
+		&self.TaskStats.NrSleeping,
+		&self.TaskStats.NrRunning,
+		&self.TaskStats.NrStopped,
+		&self.TaskStats.NrUninterruptible,
+		&self.TaskStats.NrIoWait,
+		&self.Processes.OpenFds,
+		&self.Processes.PidCount,
+		&self.Processes.ThreadCount,
+		&self.Cpu.UsageRate.Total,
+		&self.Cpu.UsageRate.User,
+		&self.Cpu.UsageRate.System,
+	} {
+		fn(usage, false)
+	}
+	for _, limit := range []*uint64{
+		&self.Memory.KernelLimit,
+		&self.Memory.KernelTCPLimit,
+		&self.Network.TcpMemLimit,
+		&self.Network.ConntrackLimit,
+		&self.Processes.OpenFdsLimit,
+	} {
+		fn(limit, true)
+	}
+
+	loadAverage := uint64(self.Cpu.LoadAverage)
+	fn(&loadAverage, false)
+	self.Cpu.LoadAverage = int32(loadAverage)
+
+	for i := range self.Filesystem {
+		fn(&self.Filesystem[i].Usage, false)
+		fn(&self.Filesystem[i].IoInProgress, false)
+		fn(&self.Filesystem[i].Limit, true)
+	}
+
+	names := make([]string, 0, len(self.MiscStats))
+	for name := range self.MiscStats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		misc := self.MiscStats[name]
+		fn(&misc.Current, false)
+		fn(&misc.Max, true)
+		self.MiscStats[name] = misc
+	}
+}
+
+// Calls fn with a pointer to each gauge of the stats holding a float, e.g. the
+// pressure stall averages and the derived metrics, which are left out of
+// ForEachGauge.
+func (self *ContainerStats) ForEachFloatGauge(fn func(value *float64)) {
+	for _, psi := range []*PSIData{
+		&self.DiskIo.PSI.Some,
+		&self.DiskIo.PSI.Full,
+	} {
+		fn(&psi.Avg10)
+		fn(&psi.Avg60)
+		fn(&psi.Avg300)
+	}
+
+	names := make([]string, 0, len(self.DerivedMetrics))
+	for name := range self.DerivedMetrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := self.DerivedMetrics[name]
+		fn(&value)
+		self.DerivedMetrics[name] = value
+	}
+}
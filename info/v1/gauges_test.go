@@ -0,0 +1,219 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// Values of the numbers of the stats by their path, e.g. ".Memory.Usage".
+// Slices and maps are given a single element, whose path ends with "[]", and
+// recursive types are only walked once.
+type numbers map[string]float64
+
+// Walks the numbers of v, setting them to set if it is not nil, and records
+// their values.
+func (self numbers) walk(v reflect.Value, path string, set *float64, seen map[reflect.Type]bool) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			if set == nil {
+				return
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		self.walk(v.Elem(), path, set, seen)
+	case reflect.Struct:
+		if v.Type().PkgPath() == "time" || seen[v.Type()] {
+			return
+		}
+		seen[v.Type()] = true
+		defer delete(seen, v.Type())
+		for i := 0; i < v.NumField(); i++ {
+			self.walk(v.Field(i), path+"."+v.Type().Field(i).Name, set, seen)
+		}
+	case reflect.Slice:
+		if v.Len() == 0 && set != nil {
+			v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		}
+		for i := 0; i < v.Len(); i++ {
+			self.walk(v.Index(i), path+"[]", set, seen)
+		}
+	case reflect.Map:
+		if v.Len() == 0 && set != nil {
+			v.Set(reflect.MakeMap(v.Type()))
+			v.SetMapIndex(reflect.New(v.Type().Key()).Elem(), reflect.New(v.Type().Elem()).Elem())
+		}
+		for _, key := range v.MapKeys() {
+			// Map values are not addressable, so a copy is walked and stored back.
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			self.walk(elem, path+"[]", set, seen)
+			v.SetMapIndex(key, elem)
+		}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		if set != nil {
+			v.SetInt(int64(*set))
+		}
+		self[path] = float64(v.Int())
+	case reflect.Uint, reflect.Uint32, reflect.Uint64:
+		if set != nil {
+			v.SetUint(uint64(*set))
+		}
+		self[path] = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		if set != nil {
+			v.SetFloat(*set)
+		}
+		self[path] = v.Float()
+	}
+}
+
+func TestForEachGauge(t *testing.T) {
+	// The numbers of the stats that are not gauges: cumulative counters, and
+	// values compared exactly like the device numbers and the link speed.
+	notGauges := []string{
+		".Cpu.CFS.BurstCount",
+		".Cpu.CFS.BurstTime",
+		".Cpu.ContextSwitches.Involuntary",
+		".Cpu.ContextSwitches.Voluntary",
+		".Cpu.Schedstat.NrThrottled",
+		".Cpu.Schedstat.RunPeriods",
+		".Cpu.Schedstat.RunTime",
+		".Cpu.Schedstat.RunqueueTime",
+		".Cpu.Usage.PerCpu[]",
+		".Cpu.Usage.System",
+		".Cpu.Usage.Total",
+		".Cpu.Usage.User",
+		".DiskIo.PSI.Full.Total",
+		".DiskIo.PSI.Some.Total",
+		".Dns.Failures",
+		".Dns.LatencyTotal",
+		".Dns.Queries",
+		".Filesystem[].IoTime",
+		".Filesystem[].ReadTime",
+		".Filesystem[].ReadsCompleted",
+		".Filesystem[].ReadsMerged",
+		".Filesystem[].SectorsRead",
+		".Filesystem[].SectorsWritten",
+		".Filesystem[].WeightedIoTime",
+		".Filesystem[].WriteTime",
+		".Filesystem[].WritesCompleted",
+		".Filesystem[].WritesMerged",
+		".Memory.ContainerData.Pgfault",
+		".Memory.ContainerData.Pgmajfault",
+		".Memory.Events.High",
+		".Memory.Events.Low",
+		".Memory.Events.Max",
+		".Memory.Events.Oom",
+		".Memory.Events.OomKill",
+		".Memory.HierarchicalData.Pgfault",
+		".Memory.HierarchicalData.Pgmajfault",
+		".Memory.Pgpgin",
+		".Memory.Pgpgout",
+		".Memory.Pswpin",
+		".Memory.Pswpout",
+		".Memory.SwapEvents.High",
+		".Memory.SwapEvents.Max",
+		".Memory.WorkingSetEvents.Activate",
+		".Memory.WorkingSetEvents.Refault",
+		".Memory.WorkingSetEvents.Restore",
+		".Network.Mtu",
+		".Network.Protocols[].RxBytes",
+		".Network.Protocols[].RxPackets",
+		".Network.Protocols[].TxBytes",
+		".Network.Protocols[].TxPackets",
+		".Network.RxBytes",
+		".Network.RxDropped",
+		".Network.RxErrors",
+		".Network.RxPackets",
+		".Network.Speed",
+		".Network.TxBytes",
+		".Network.TxDropped",
+		".Network.TxErrors",
+		".Network.TxPackets",
+		".OomEvents",
+		".Processes.PidsAdded",
+		".Processes.PidsRemoved",
+		".SequenceNumber",
+	}
+	limits := []string{
+		".Filesystem[].Limit",
+		".Memory.KernelLimit",
+		".Memory.KernelTCPLimit",
+		".MiscStats[].Max",
+		".Network.ConntrackLimit",
+		".Network.TcpMemLimit",
+		".Processes.OpenFdsLimit",
+	}
+	// Numbers of per-device disk IO stats, which are all counters.
+	for _, field := range []string{"IoLatencyThrottled", "IoMerged", "IoQueued", "IoServiceBytes", "IoServiceTime", "IoServiced", "IoTime", "IoWaitTime", "Sectors"} {
+		for _, number := range []string{"Major", "Minor", "Stats[]"} {
+			notGauges = append(notGauges, ".DiskIo."+field+"[]."+number)
+		}
+	}
+
+	stats := &ContainerStats{}
+	const initial, usage, limit = 7, 1, 2
+	all := numbers{}
+	set := float64(initial)
+	all.walk(reflect.ValueOf(stats).Elem(), "", &set, map[reflect.Type]bool{})
+
+	copied := stats.GaugeCopy()
+	copied.ForEachGauge(func(value *uint64, isLimit bool) {
+		*value = usage
+		if isLimit {
+			*value = limit
+		}
+	})
+	copied.ForEachFloatGauge(func(value *float64) {
+		*value = usage
+	})
+
+	got := numbers{}
+	got.walk(reflect.ValueOf(copied).Elem(), "", nil, map[reflect.Type]bool{})
+	expected := numbers{}
+	for path := range all {
+		expected[path] = usage
+	}
+	for _, path := range notGauges {
+		expected[path] = initial
+	}
+	for _, path := range limits {
+		expected[path] = limit
+	}
+	// Every number of the stats is listed as a gauge, a limit or not a gauge.
+	var paths []string
+	for path := range expected {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if _, ok := all[path]; !ok {
+			t.Errorf("%s is not a number of the stats", path)
+		} else if got[path] != expected[path] {
+			t.Errorf("expected %s to be %v, got %v", path, expected[path], got[path])
+		}
+	}
+
+	// The gauges of the original stats are left as they are.
+	original := numbers{}
+	original.walk(reflect.ValueOf(stats).Elem(), "", nil, map[reflect.Type]bool{})
+	if !reflect.DeepEqual(original, all) {
+		t.Errorf("expected the stats to be left as they are, got %v", original)
+	}
+}
@@ -14,6 +14,8 @@
 
 package v1
 
+import "time"
+
 type FsInfo struct {
 	// Block device associated with the filesystem.
 	Device string `json:"device"`
@@ -96,6 +98,12 @@ type DiskInfo struct {
 
 	// I/O Scheduler - one of "none", "noop", "cfq", "deadline"
 	Scheduler string `json:"scheduler"`
+
+	// Whether the device is rotational, i.e. a spinning disk.
+	Rotational bool `json:"rotational"`
+
+	// Model of the device, empty for virtual devices.
+	Model string `json:"model,omitempty"`
 }
 
 type NetInfo struct {
@@ -112,6 +120,109 @@ type NetInfo struct {
 	Mtu int64 `json:"mtu"`
 }
 
+// Network statistics of a network interface of the machine.
+type InterfaceStats struct {
+	// Name of the interface.
+	Name string `json:"name"`
+
+	NetworkStats
+}
+
+// Host-wide usage of the machine at a point in time.
+type MachineStats struct {
+	// Time at which the usage was sampled.
+	Timestamp time.Time `json:"timestamp"`
+
+	Cpu    MachineCpuStats    `json:"cpu"`
+	Memory MachineMemoryStats `json:"memory"`
+
+	// Network statistics of the physical network interfaces of the machine.
+	Network []InterfaceStats `json:"network,omitempty"`
+
+	// Entropy available in the random number pool of the kernel, which
+	// blocking reads of /dev/random wait on. Zero if it could not be read.
+	// Units: bits.
+	AvailableEntropy uint64 `json:"available_entropy,omitempty"`
+
+	// Load averages and task counts of the machine. Zero if they could not be
+	// read.
+	Load MachineLoadStats `json:"load"`
+}
+
+type MachineLoadStats struct {
+	// Average number of runnable or uninterruptible tasks over the last 1, 5
+	// and 15 minutes.
+	LoadAverage1  float64 `json:"load_average_1m"`
+	LoadAverage5  float64 `json:"load_average_5m"`
+	LoadAverage15 float64 `json:"load_average_15m"`
+
+	// Number of runnable tasks of the machine.
+	RunningTasks uint64 `json:"running_tasks"`
+
+	// Number of tasks of the machine, threads included.
+	TotalTasks uint64 `json:"total_tasks"`
+}
+
+// Cumulative CPU time of all the cores of the machine.
+type MachineCpuStats struct {
+	// Time spent running tasks, in user or system mode.
+	// Units: nanoseconds.
+	Total uint64 `json:"total"`
+
+	// Time spent in user mode, niced tasks included.
+	// Units: nanoseconds.
+	User uint64 `json:"user"`
+
+	// Time spent in system mode, interrupts included.
+	// Units: nanoseconds.
+	System uint64 `json:"system"`
+
+	// Time stolen by the hypervisor to run other virtual machines, not
+	// counted in Total.
+	// Units: nanoseconds.
+	Steal uint64 `json:"steal"`
+
+	// Rate of the CPU usage over the interval since the previous sample of the
+	// machine, zero for its first sample.
+	UsageRate CpuUsageRate `json:"usage_rate"`
+}
+
+type MachineMemoryStats struct {
+	// Total usable memory of the machine.
+	// Units: bytes.
+	Total uint64 `json:"total"`
+
+	// Memory in use, i.e. not available to new applications without swapping.
+	// Units: bytes.
+	Usage uint64 `json:"usage"`
+
+	// Memory available to new applications without swapping, reclaimable caches
+	// included.
+	// Units: bytes.
+	Available uint64 `json:"available"`
+
+	// Memory not used at all.
+	// Units: bytes.
+	Free uint64 `json:"free"`
+
+	// Memory used by the buffers of block devices.
+	// Units: bytes.
+	Buffers uint64 `json:"buffers"`
+
+	// Memory used by the page cache, tmpfs and shared memory included.
+	// Units: bytes.
+	Cached uint64 `json:"cached"`
+
+	// Slab memory that can be reclaimed, e.g. the dentry and inode caches.
+	// Units: bytes.
+	SlabReclaimable uint64 `json:"slab_reclaimable"`
+
+	// Total and unused swap space of the machine.
+	// Units: bytes.
+	SwapTotal uint64 `json:"swap_total"`
+	SwapFree  uint64 `json:"swap_free"`
+}
+
 type MachineInfo struct {
 	// The number of cores in this machine.
 	NumCores int `json:"num_cores"`
@@ -125,12 +236,18 @@ type MachineInfo struct {
 	// The machine id
 	MachineID string `json:"machine_id"`
 
+	// The hostname of the machine
+	Hostname string `json:"hostname"`
+
 	// The system uuid
 	SystemUUID string `json:"system_uuid"`
 
 	// The boot id
 	BootID string `json:"boot_id"`
 
+	// The time the machine booted, the uptime is the time elapsed since.
+	BootTime time.Time `json:"boot_time"`
+
 	// Filesystems on this machine.
 	Filesystems []FsInfo `json:"filesystems"`
 
@@ -0,0 +1,454 @@
+// Code generated by protoc-gen-go.
+// source: container.proto
+// DO NOT EDIT!
+
+/*
+Package pb is a generated protocol buffer package.
+
+It is generated from these files:
+
+	container.proto
+
+It has these top-level messages:
+
+	ContainerReference
+	Label
+	CpuSpec
+	MemorySpec
+	ContainerSpec
+	CpuUsage
+	CpuStats
+	DiskStat
+	PerDiskStats
+	DiskIoStats
+	MemoryStats
+	InterfaceStats
+	NetworkStats
+	FsStats
+	LoadStats
+	ProcessStats
+	ContainerStats
+	ContainerInfo
+	ContainerInfoList
+*/
+package pb
+
+import proto "github.com/golang/protobuf/proto"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+
+type ContainerReference struct {
+	Name       string   `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Aliases    []string `protobuf:"bytes,2,rep,name=aliases" json:"aliases,omitempty"`
+	Namespace  string   `protobuf:"bytes,3,opt,name=namespace" json:"namespace,omitempty"`
+	StableId   string   `protobuf:"bytes,4,opt,name=stable_id" json:"stable_id,omitempty"`
+	ParentName string   `protobuf:"bytes,5,opt,name=parent_name" json:"parent_name,omitempty"`
+	Depth      int64    `protobuf:"varint,6,opt,name=depth" json:"depth,omitempty"`
+}
+
+func (m *ContainerReference) Reset()         { *m = ContainerReference{} }
+func (m *ContainerReference) String() string { return proto.CompactTextString(m) }
+func (*ContainerReference) ProtoMessage()    {}
+
+type Label struct {
+	Key   string `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+	Value string `protobuf:"bytes,2,opt,name=value" json:"value,omitempty"`
+}
+
+func (m *Label) Reset()         { *m = Label{} }
+func (m *Label) String() string { return proto.CompactTextString(m) }
+func (*Label) ProtoMessage()    {}
+
+type CpuSpec struct {
+	Limit    uint64 `protobuf:"varint,1,opt,name=limit" json:"limit,omitempty"`
+	MaxLimit uint64 `protobuf:"varint,2,opt,name=max_limit" json:"max_limit,omitempty"`
+	Mask     string `protobuf:"bytes,3,opt,name=mask" json:"mask,omitempty"`
+	Burst    uint64 `protobuf:"varint,4,opt,name=burst" json:"burst,omitempty"`
+	Idle     bool   `protobuf:"varint,5,opt,name=idle" json:"idle,omitempty"`
+}
+
+func (m *CpuSpec) Reset()         { *m = CpuSpec{} }
+func (m *CpuSpec) String() string { return proto.CompactTextString(m) }
+func (*CpuSpec) ProtoMessage()    {}
+
+type MemorySpec struct {
+	Limit       uint64 `protobuf:"varint,1,opt,name=limit" json:"limit,omitempty"`
+	Reservation uint64 `protobuf:"varint,2,opt,name=reservation" json:"reservation,omitempty"`
+	SwapLimit   uint64 `protobuf:"varint,3,opt,name=swap_limit" json:"swap_limit,omitempty"`
+}
+
+func (m *MemorySpec) Reset()         { *m = MemorySpec{} }
+func (m *MemorySpec) String() string { return proto.CompactTextString(m) }
+func (*MemorySpec) ProtoMessage()    {}
+
+type ContainerSpec struct {
+	CreationTime  int64       `protobuf:"varint,1,opt,name=creation_time" json:"creation_time,omitempty"`
+	Labels        []*Label    `protobuf:"bytes,2,rep,name=labels" json:"labels,omitempty"`
+	HasCpu        bool        `protobuf:"varint,3,opt,name=has_cpu" json:"has_cpu,omitempty"`
+	Cpu           *CpuSpec    `protobuf:"bytes,4,opt,name=cpu" json:"cpu,omitempty"`
+	HasMemory     bool        `protobuf:"varint,5,opt,name=has_memory" json:"has_memory,omitempty"`
+	Memory        *MemorySpec `protobuf:"bytes,6,opt,name=memory" json:"memory,omitempty"`
+	HasNetwork    bool        `protobuf:"varint,7,opt,name=has_network" json:"has_network,omitempty"`
+	HasFilesystem bool        `protobuf:"varint,8,opt,name=has_filesystem" json:"has_filesystem,omitempty"`
+	HasDiskio     bool        `protobuf:"varint,9,opt,name=has_diskio" json:"has_diskio,omitempty"`
+	ImageId       string      `protobuf:"bytes,10,opt,name=image_id" json:"image_id,omitempty"`
+}
+
+func (m *ContainerSpec) Reset()         { *m = ContainerSpec{} }
+func (m *ContainerSpec) String() string { return proto.CompactTextString(m) }
+func (*ContainerSpec) ProtoMessage()    {}
+
+func (m *ContainerSpec) GetLabels() []*Label {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+func (m *ContainerSpec) GetCpu() *CpuSpec {
+	if m != nil {
+		return m.Cpu
+	}
+	return nil
+}
+
+func (m *ContainerSpec) GetMemory() *MemorySpec {
+	if m != nil {
+		return m.Memory
+	}
+	return nil
+}
+
+type CpuUsage struct {
+	Total  uint64   `protobuf:"varint,1,opt,name=total" json:"total,omitempty"`
+	PerCpu []uint64 `protobuf:"varint,2,rep,packed,name=per_cpu" json:"per_cpu,omitempty"`
+	User   uint64   `protobuf:"varint,3,opt,name=user" json:"user,omitempty"`
+	System uint64   `protobuf:"varint,4,opt,name=system" json:"system,omitempty"`
+}
+
+func (m *CpuUsage) Reset()         { *m = CpuUsage{} }
+func (m *CpuUsage) String() string { return proto.CompactTextString(m) }
+func (*CpuUsage) ProtoMessage()    {}
+
+type CpuStats struct {
+	Usage       *CpuUsage `protobuf:"bytes,1,opt,name=usage" json:"usage,omitempty"`
+	LoadAverage int32     `protobuf:"varint,2,opt,name=load_average" json:"load_average,omitempty"`
+}
+
+func (m *CpuStats) Reset()         { *m = CpuStats{} }
+func (m *CpuStats) String() string { return proto.CompactTextString(m) }
+func (*CpuStats) ProtoMessage()    {}
+
+func (m *CpuStats) GetUsage() *CpuUsage {
+	if m != nil {
+		return m.Usage
+	}
+	return nil
+}
+
+type DiskStat struct {
+	Key   string `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+	Value uint64 `protobuf:"varint,2,opt,name=value" json:"value,omitempty"`
+}
+
+func (m *DiskStat) Reset()         { *m = DiskStat{} }
+func (m *DiskStat) String() string { return proto.CompactTextString(m) }
+func (*DiskStat) ProtoMessage()    {}
+
+type PerDiskStats struct {
+	Major uint64      `protobuf:"varint,1,opt,name=major" json:"major,omitempty"`
+	Minor uint64      `protobuf:"varint,2,opt,name=minor" json:"minor,omitempty"`
+	Stats []*DiskStat `protobuf:"bytes,3,rep,name=stats" json:"stats,omitempty"`
+}
+
+func (m *PerDiskStats) Reset()         { *m = PerDiskStats{} }
+func (m *PerDiskStats) String() string { return proto.CompactTextString(m) }
+func (*PerDiskStats) ProtoMessage()    {}
+
+func (m *PerDiskStats) GetStats() []*DiskStat {
+	if m != nil {
+		return m.Stats
+	}
+	return nil
+}
+
+type DiskIoStats struct {
+	IoServiceBytes []*PerDiskStats `protobuf:"bytes,1,rep,name=io_service_bytes" json:"io_service_bytes,omitempty"`
+	IoServiced     []*PerDiskStats `protobuf:"bytes,2,rep,name=io_serviced" json:"io_serviced,omitempty"`
+	IoQueued       []*PerDiskStats `protobuf:"bytes,3,rep,name=io_queued" json:"io_queued,omitempty"`
+	Sectors        []*PerDiskStats `protobuf:"bytes,4,rep,name=sectors" json:"sectors,omitempty"`
+	IoServiceTime  []*PerDiskStats `protobuf:"bytes,5,rep,name=io_service_time" json:"io_service_time,omitempty"`
+	IoWaitTime     []*PerDiskStats `protobuf:"bytes,6,rep,name=io_wait_time" json:"io_wait_time,omitempty"`
+	IoMerged       []*PerDiskStats `protobuf:"bytes,7,rep,name=io_merged" json:"io_merged,omitempty"`
+	IoTime         []*PerDiskStats `protobuf:"bytes,8,rep,name=io_time" json:"io_time,omitempty"`
+}
+
+func (m *DiskIoStats) Reset()         { *m = DiskIoStats{} }
+func (m *DiskIoStats) String() string { return proto.CompactTextString(m) }
+func (*DiskIoStats) ProtoMessage()    {}
+
+func (m *DiskIoStats) GetIoServiceBytes() []*PerDiskStats {
+	if m != nil {
+		return m.IoServiceBytes
+	}
+	return nil
+}
+
+func (m *DiskIoStats) GetIoServiced() []*PerDiskStats {
+	if m != nil {
+		return m.IoServiced
+	}
+	return nil
+}
+
+func (m *DiskIoStats) GetIoQueued() []*PerDiskStats {
+	if m != nil {
+		return m.IoQueued
+	}
+	return nil
+}
+
+func (m *DiskIoStats) GetSectors() []*PerDiskStats {
+	if m != nil {
+		return m.Sectors
+	}
+	return nil
+}
+
+func (m *DiskIoStats) GetIoServiceTime() []*PerDiskStats {
+	if m != nil {
+		return m.IoServiceTime
+	}
+	return nil
+}
+
+func (m *DiskIoStats) GetIoWaitTime() []*PerDiskStats {
+	if m != nil {
+		return m.IoWaitTime
+	}
+	return nil
+}
+
+func (m *DiskIoStats) GetIoMerged() []*PerDiskStats {
+	if m != nil {
+		return m.IoMerged
+	}
+	return nil
+}
+
+func (m *DiskIoStats) GetIoTime() []*PerDiskStats {
+	if m != nil {
+		return m.IoTime
+	}
+	return nil
+}
+
+type MemoryStats struct {
+	Usage       uint64 `protobuf:"varint,1,opt,name=usage" json:"usage,omitempty"`
+	Cache       uint64 `protobuf:"varint,2,opt,name=cache" json:"cache,omitempty"`
+	Rss         uint64 `protobuf:"varint,3,opt,name=rss" json:"rss,omitempty"`
+	MappedFile  uint64 `protobuf:"varint,4,opt,name=mapped_file" json:"mapped_file,omitempty"`
+	Swap        uint64 `protobuf:"varint,5,opt,name=swap" json:"swap,omitempty"`
+	WorkingSet  uint64 `protobuf:"varint,6,opt,name=working_set" json:"working_set,omitempty"`
+	KernelUsage uint64 `protobuf:"varint,7,opt,name=kernel_usage" json:"kernel_usage,omitempty"`
+}
+
+func (m *MemoryStats) Reset()         { *m = MemoryStats{} }
+func (m *MemoryStats) String() string { return proto.CompactTextString(m) }
+func (*MemoryStats) ProtoMessage()    {}
+
+type InterfaceStats struct {
+	Name      string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	RxBytes   uint64 `protobuf:"varint,2,opt,name=rx_bytes" json:"rx_bytes,omitempty"`
+	RxPackets uint64 `protobuf:"varint,3,opt,name=rx_packets" json:"rx_packets,omitempty"`
+	RxErrors  uint64 `protobuf:"varint,4,opt,name=rx_errors" json:"rx_errors,omitempty"`
+	RxDropped uint64 `protobuf:"varint,5,opt,name=rx_dropped" json:"rx_dropped,omitempty"`
+	TxBytes   uint64 `protobuf:"varint,6,opt,name=tx_bytes" json:"tx_bytes,omitempty"`
+	TxPackets uint64 `protobuf:"varint,7,opt,name=tx_packets" json:"tx_packets,omitempty"`
+	TxErrors  uint64 `protobuf:"varint,8,opt,name=tx_errors" json:"tx_errors,omitempty"`
+	TxDropped uint64 `protobuf:"varint,9,opt,name=tx_dropped" json:"tx_dropped,omitempty"`
+}
+
+func (m *InterfaceStats) Reset()         { *m = InterfaceStats{} }
+func (m *InterfaceStats) String() string { return proto.CompactTextString(m) }
+func (*InterfaceStats) ProtoMessage()    {}
+
+type NetworkStats struct {
+	Total      *InterfaceStats   `protobuf:"bytes,1,opt,name=total" json:"total,omitempty"`
+	Interfaces []*InterfaceStats `protobuf:"bytes,2,rep,name=interfaces" json:"interfaces,omitempty"`
+}
+
+func (m *NetworkStats) Reset()         { *m = NetworkStats{} }
+func (m *NetworkStats) String() string { return proto.CompactTextString(m) }
+func (*NetworkStats) ProtoMessage()    {}
+
+func (m *NetworkStats) GetTotal() *InterfaceStats {
+	if m != nil {
+		return m.Total
+	}
+	return nil
+}
+
+func (m *NetworkStats) GetInterfaces() []*InterfaceStats {
+	if m != nil {
+		return m.Interfaces
+	}
+	return nil
+}
+
+type FsStats struct {
+	Device          string `protobuf:"bytes,1,opt,name=device" json:"device,omitempty"`
+	Limit           uint64 `protobuf:"varint,2,opt,name=limit" json:"limit,omitempty"`
+	Usage           uint64 `protobuf:"varint,3,opt,name=usage" json:"usage,omitempty"`
+	ReadsCompleted  uint64 `protobuf:"varint,4,opt,name=reads_completed" json:"reads_completed,omitempty"`
+	WritesCompleted uint64 `protobuf:"varint,5,opt,name=writes_completed" json:"writes_completed,omitempty"`
+	IoTime          uint64 `protobuf:"varint,6,opt,name=io_time" json:"io_time,omitempty"`
+}
+
+func (m *FsStats) Reset()         { *m = FsStats{} }
+func (m *FsStats) String() string { return proto.CompactTextString(m) }
+func (*FsStats) ProtoMessage()    {}
+
+type LoadStats struct {
+	NrSleeping        uint64 `protobuf:"varint,1,opt,name=nr_sleeping" json:"nr_sleeping,omitempty"`
+	NrRunning         uint64 `protobuf:"varint,2,opt,name=nr_running" json:"nr_running,omitempty"`
+	NrStopped         uint64 `protobuf:"varint,3,opt,name=nr_stopped" json:"nr_stopped,omitempty"`
+	NrUninterruptible uint64 `protobuf:"varint,4,opt,name=nr_uninterruptible" json:"nr_uninterruptible,omitempty"`
+	NrIoWait          uint64 `protobuf:"varint,5,opt,name=nr_io_wait" json:"nr_io_wait,omitempty"`
+}
+
+func (m *LoadStats) Reset()         { *m = LoadStats{} }
+func (m *LoadStats) String() string { return proto.CompactTextString(m) }
+func (*LoadStats) ProtoMessage()    {}
+
+type ProcessStats struct {
+	OpenFds      uint64 `protobuf:"varint,1,opt,name=open_fds" json:"open_fds,omitempty"`
+	OpenFdsLimit uint64 `protobuf:"varint,2,opt,name=open_fds_limit" json:"open_fds_limit,omitempty"`
+	PidCount     uint64 `protobuf:"varint,3,opt,name=pid_count" json:"pid_count,omitempty"`
+	ThreadCount  uint64 `protobuf:"varint,4,opt,name=thread_count" json:"thread_count,omitempty"`
+}
+
+func (m *ProcessStats) Reset()         { *m = ProcessStats{} }
+func (m *ProcessStats) String() string { return proto.CompactTextString(m) }
+func (*ProcessStats) ProtoMessage()    {}
+
+type ContainerStats struct {
+	Timestamp      int64         `protobuf:"varint,1,opt,name=timestamp" json:"timestamp,omitempty"`
+	Cpu            *CpuStats     `protobuf:"bytes,2,opt,name=cpu" json:"cpu,omitempty"`
+	Diskio         *DiskIoStats  `protobuf:"bytes,3,opt,name=diskio" json:"diskio,omitempty"`
+	Memory         *MemoryStats  `protobuf:"bytes,4,opt,name=memory" json:"memory,omitempty"`
+	Network        *NetworkStats `protobuf:"bytes,5,opt,name=network" json:"network,omitempty"`
+	Filesystem     []*FsStats    `protobuf:"bytes,6,rep,name=filesystem" json:"filesystem,omitempty"`
+	TaskStats      *LoadStats    `protobuf:"bytes,7,opt,name=task_stats" json:"task_stats,omitempty"`
+	Processes      *ProcessStats `protobuf:"bytes,8,opt,name=processes" json:"processes,omitempty"`
+	OomEvents      uint64        `protobuf:"varint,9,opt,name=oom_events" json:"oom_events,omitempty"`
+	SequenceNumber uint64        `protobuf:"varint,10,opt,name=sequence_number" json:"sequence_number,omitempty"`
+}
+
+func (m *ContainerStats) Reset()         { *m = ContainerStats{} }
+func (m *ContainerStats) String() string { return proto.CompactTextString(m) }
+func (*ContainerStats) ProtoMessage()    {}
+
+func (m *ContainerStats) GetCpu() *CpuStats {
+	if m != nil {
+		return m.Cpu
+	}
+	return nil
+}
+
+func (m *ContainerStats) GetDiskio() *DiskIoStats {
+	if m != nil {
+		return m.Diskio
+	}
+	return nil
+}
+
+func (m *ContainerStats) GetMemory() *MemoryStats {
+	if m != nil {
+		return m.Memory
+	}
+	return nil
+}
+
+func (m *ContainerStats) GetNetwork() *NetworkStats {
+	if m != nil {
+		return m.Network
+	}
+	return nil
+}
+
+func (m *ContainerStats) GetFilesystem() []*FsStats {
+	if m != nil {
+		return m.Filesystem
+	}
+	return nil
+}
+
+func (m *ContainerStats) GetTaskStats() *LoadStats {
+	if m != nil {
+		return m.TaskStats
+	}
+	return nil
+}
+
+func (m *ContainerStats) GetProcesses() *ProcessStats {
+	if m != nil {
+		return m.Processes
+	}
+	return nil
+}
+
+type ContainerInfo struct {
+	Reference     *ContainerReference   `protobuf:"bytes,1,opt,name=reference" json:"reference,omitempty"`
+	Subcontainers []*ContainerReference `protobuf:"bytes,2,rep,name=subcontainers" json:"subcontainers,omitempty"`
+	Spec          *ContainerSpec        `protobuf:"bytes,3,opt,name=spec" json:"spec,omitempty"`
+	Stats         []*ContainerStats     `protobuf:"bytes,4,rep,name=stats" json:"stats,omitempty"`
+}
+
+func (m *ContainerInfo) Reset()         { *m = ContainerInfo{} }
+func (m *ContainerInfo) String() string { return proto.CompactTextString(m) }
+func (*ContainerInfo) ProtoMessage()    {}
+
+func (m *ContainerInfo) GetReference() *ContainerReference {
+	if m != nil {
+		return m.Reference
+	}
+	return nil
+}
+
+func (m *ContainerInfo) GetSubcontainers() []*ContainerReference {
+	if m != nil {
+		return m.Subcontainers
+	}
+	return nil
+}
+
+func (m *ContainerInfo) GetSpec() *ContainerSpec {
+	if m != nil {
+		return m.Spec
+	}
+	return nil
+}
+
+func (m *ContainerInfo) GetStats() []*ContainerStats {
+	if m != nil {
+		return m.Stats
+	}
+	return nil
+}
+
+type ContainerInfoList struct {
+	Containers []*ContainerInfo `protobuf:"bytes,1,rep,name=containers" json:"containers,omitempty"`
+}
+
+func (m *ContainerInfoList) Reset()         { *m = ContainerInfoList{} }
+func (m *ContainerInfoList) String() string { return proto.CompactTextString(m) }
+func (*ContainerInfoList) ProtoMessage()    {}
+
+func (m *ContainerInfoList) GetContainers() []*ContainerInfo {
+	if m != nil {
+		return m.Containers
+	}
+	return nil
+}
@@ -0,0 +1,374 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pb
+
+import (
+	"sort"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+// FromContainerInfo converts a ContainerInfo into its protocol buffer form.
+func FromContainerInfo(cinfo *info.ContainerInfo) *ContainerInfo {
+	ret := &ContainerInfo{
+		Reference: fromReference(cinfo.ContainerReference),
+		Spec:      fromSpec(&cinfo.Spec),
+	}
+	for _, ref := range cinfo.Subcontainers {
+		ret.Subcontainers = append(ret.Subcontainers, fromReference(ref))
+	}
+	for _, stats := range cinfo.Stats {
+		ret.Stats = append(ret.Stats, fromStats(stats))
+	}
+	return ret
+}
+
+// ToContainerInfo converts a protocol buffer ContainerInfo back into its
+// info/v1 form. Fields that are not part of the protocol buffer
+// representation are left unset.
+func ToContainerInfo(cinfo *ContainerInfo) *info.ContainerInfo {
+	ret := &info.ContainerInfo{
+		ContainerReference: toReference(cinfo.GetReference()),
+		Spec:               toSpec(cinfo.GetSpec()),
+	}
+	for _, ref := range cinfo.GetSubcontainers() {
+		ret.Subcontainers = append(ret.Subcontainers, toReference(ref))
+	}
+	for _, stats := range cinfo.GetStats() {
+		ret.Stats = append(ret.Stats, toStats(stats))
+	}
+	return ret
+}
+
+// Timestamps are encoded as nanoseconds since the Unix epoch, with 0 for the
+// zero time.
+func fromTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func toTime(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+func fromReference(ref info.ContainerReference) *ContainerReference {
+	return &ContainerReference{
+		Name:       ref.Name,
+		Aliases:    ref.Aliases,
+		Namespace:  ref.Namespace,
+		StableId:   ref.StableID,
+		ParentName: ref.ParentName,
+		Depth:      int64(ref.Depth),
+	}
+}
+
+func toReference(ref *ContainerReference) info.ContainerReference {
+	if ref == nil {
+		return info.ContainerReference{}
+	}
+	return info.ContainerReference{
+		Name:       ref.Name,
+		Aliases:    ref.Aliases,
+		Namespace:  ref.Namespace,
+		StableID:   ref.StableId,
+		ParentName: ref.ParentName,
+		Depth:      int(ref.Depth),
+	}
+}
+
+func fromSpec(spec *info.ContainerSpec) *ContainerSpec {
+	ret := &ContainerSpec{
+		CreationTime: fromTime(spec.CreationTime),
+		HasCpu:       spec.HasCpu,
+		Cpu: &CpuSpec{
+			Limit:    spec.Cpu.Limit,
+			MaxLimit: spec.Cpu.MaxLimit,
+			Mask:     spec.Cpu.Mask,
+			Burst:    spec.Cpu.Burst,
+			Idle:     spec.Cpu.Idle,
+		},
+		HasMemory: spec.HasMemory,
+		Memory: &MemorySpec{
+			Limit:       spec.Memory.Limit,
+			Reservation: spec.Memory.Reservation,
+			SwapLimit:   spec.Memory.SwapLimit,
+		},
+		HasNetwork:    spec.HasNetwork,
+		HasFilesystem: spec.HasFilesystem,
+		HasDiskio:     spec.HasDiskIo,
+		ImageId:       spec.ImageID,
+	}
+	// Sort the labels so that the encoding is deterministic.
+	keys := make([]string, 0, len(spec.Labels))
+	for k := range spec.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		ret.Labels = append(ret.Labels, &Label{Key: k, Value: spec.Labels[k]})
+	}
+	return ret
+}
+
+func toSpec(spec *ContainerSpec) info.ContainerSpec {
+	if spec == nil {
+		return info.ContainerSpec{}
+	}
+	ret := info.ContainerSpec{
+		CreationTime:  toTime(spec.CreationTime),
+		HasCpu:        spec.HasCpu,
+		HasMemory:     spec.HasMemory,
+		HasNetwork:    spec.HasNetwork,
+		HasFilesystem: spec.HasFilesystem,
+		HasDiskIo:     spec.HasDiskio,
+		ImageID:       spec.ImageId,
+	}
+	if cpu := spec.GetCpu(); cpu != nil {
+		ret.Cpu = info.CpuSpec{
+			Limit:    cpu.Limit,
+			MaxLimit: cpu.MaxLimit,
+			Mask:     cpu.Mask,
+			Burst:    cpu.Burst,
+			Idle:     cpu.Idle,
+		}
+	}
+	if memory := spec.GetMemory(); memory != nil {
+		ret.Memory = info.MemorySpec{
+			Limit:       memory.Limit,
+			Reservation: memory.Reservation,
+			SwapLimit:   memory.SwapLimit,
+		}
+	}
+	if len(spec.Labels) > 0 {
+		ret.Labels = make(map[string]string, len(spec.Labels))
+		for _, label := range spec.Labels {
+			ret.Labels[label.Key] = label.Value
+		}
+	}
+	return ret
+}
+
+func fromStats(stats *info.ContainerStats) *ContainerStats {
+	ret := &ContainerStats{
+		Timestamp: fromTime(stats.Timestamp),
+		Cpu: &CpuStats{
+			Usage: &CpuUsage{
+				Total:  stats.Cpu.Usage.Total,
+				PerCpu: stats.Cpu.Usage.PerCpu,
+				User:   stats.Cpu.Usage.User,
+				System: stats.Cpu.Usage.System,
+			},
+			LoadAverage: stats.Cpu.LoadAverage,
+		},
+		Diskio: &DiskIoStats{
+			IoServiceBytes: fromPerDiskStats(stats.DiskIo.IoServiceBytes),
+			IoServiced:     fromPerDiskStats(stats.DiskIo.IoServiced),
+			IoQueued:       fromPerDiskStats(stats.DiskIo.IoQueued),
+			Sectors:        fromPerDiskStats(stats.DiskIo.Sectors),
+			IoServiceTime:  fromPerDiskStats(stats.DiskIo.IoServiceTime),
+			IoWaitTime:     fromPerDiskStats(stats.DiskIo.IoWaitTime),
+			IoMerged:       fromPerDiskStats(stats.DiskIo.IoMerged),
+			IoTime:         fromPerDiskStats(stats.DiskIo.IoTime),
+		},
+		Memory: &MemoryStats{
+			Usage:       stats.Memory.Usage,
+			Cache:       stats.Memory.Cache,
+			Rss:         stats.Memory.RSS,
+			MappedFile:  stats.Memory.MappedFile,
+			Swap:        stats.Memory.Swap,
+			WorkingSet:  stats.Memory.WorkingSet,
+			KernelUsage: stats.Memory.KernelUsage,
+		},
+		Network: &NetworkStats{
+			Total: fromInterfaceStats(stats.Network.Interface, &stats.Network),
+		},
+		TaskStats: &LoadStats{
+			NrSleeping:        stats.TaskStats.NrSleeping,
+			NrRunning:         stats.TaskStats.NrRunning,
+			NrStopped:         stats.TaskStats.NrStopped,
+			NrUninterruptible: stats.TaskStats.NrUninterruptible,
+			NrIoWait:          stats.TaskStats.NrIoWait,
+		},
+		Processes: &ProcessStats{
+			OpenFds:      stats.Processes.OpenFds,
+			OpenFdsLimit: stats.Processes.OpenFdsLimit,
+			PidCount:     stats.Processes.PidCount,
+			ThreadCount:  stats.Processes.ThreadCount,
+		},
+		OomEvents:      stats.OomEvents,
+		SequenceNumber: stats.SequenceNumber,
+	}
+	for i := range stats.Network.Interfaces {
+		iface := &stats.Network.Interfaces[i]
+		ret.Network.Interfaces = append(ret.Network.Interfaces, fromInterfaceStats(iface.Name, &iface.NetworkStats))
+	}
+	for _, fs := range stats.Filesystem {
+		ret.Filesystem = append(ret.Filesystem, &FsStats{
+			Device:          fs.Device,
+			Limit:           fs.Limit,
+			Usage:           fs.Usage,
+			ReadsCompleted:  fs.ReadsCompleted,
+			WritesCompleted: fs.WritesCompleted,
+			IoTime:          fs.IoTime,
+		})
+	}
+	return ret
+}
+
+func toStats(stats *ContainerStats) *info.ContainerStats {
+	ret := &info.ContainerStats{
+		Timestamp:      toTime(stats.Timestamp),
+		OomEvents:      stats.OomEvents,
+		SequenceNumber: stats.SequenceNumber,
+	}
+	if cpu := stats.GetCpu(); cpu != nil {
+		if usage := cpu.GetUsage(); usage != nil {
+			ret.Cpu.Usage = info.CpuUsage{
+				Total:  usage.Total,
+				PerCpu: usage.PerCpu,
+				User:   usage.User,
+				System: usage.System,
+			}
+		}
+		ret.Cpu.LoadAverage = cpu.LoadAverage
+	}
+	if diskio := stats.GetDiskio(); diskio != nil {
+		ret.DiskIo = info.DiskIoStats{
+			IoServiceBytes: toPerDiskStats(diskio.IoServiceBytes),
+			IoServiced:     toPerDiskStats(diskio.IoServiced),
+			IoQueued:       toPerDiskStats(diskio.IoQueued),
+			Sectors:        toPerDiskStats(diskio.Sectors),
+			IoServiceTime:  toPerDiskStats(diskio.IoServiceTime),
+			IoWaitTime:     toPerDiskStats(diskio.IoWaitTime),
+			IoMerged:       toPerDiskStats(diskio.IoMerged),
+			IoTime:         toPerDiskStats(diskio.IoTime),
+		}
+	}
+	if memory := stats.GetMemory(); memory != nil {
+		ret.Memory.Usage = memory.Usage
+		ret.Memory.Cache = memory.Cache
+		ret.Memory.RSS = memory.Rss
+		ret.Memory.MappedFile = memory.MappedFile
+		ret.Memory.Swap = memory.Swap
+		ret.Memory.WorkingSet = memory.WorkingSet
+		ret.Memory.KernelUsage = memory.KernelUsage
+	}
+	if network := stats.GetNetwork(); network != nil {
+		if total := network.GetTotal(); total != nil {
+			ret.Network.Interface = total.Name
+			toInterfaceStats(total, &ret.Network)
+		}
+		for _, iface := range network.GetInterfaces() {
+			ifaceStats := info.InterfaceStats{Name: iface.Name}
+			toInterfaceStats(iface, &ifaceStats.NetworkStats)
+			ret.Network.Interfaces = append(ret.Network.Interfaces, ifaceStats)
+		}
+	}
+	for _, fs := range stats.GetFilesystem() {
+		ret.Filesystem = append(ret.Filesystem, info.FsStats{
+			Device:          fs.Device,
+			Limit:           fs.Limit,
+			Usage:           fs.Usage,
+			ReadsCompleted:  fs.ReadsCompleted,
+			WritesCompleted: fs.WritesCompleted,
+			IoTime:          fs.IoTime,
+		})
+	}
+	if load := stats.GetTaskStats(); load != nil {
+		ret.TaskStats = info.LoadStats{
+			NrSleeping:        load.NrSleeping,
+			NrRunning:         load.NrRunning,
+			NrStopped:         load.NrStopped,
+			NrUninterruptible: load.NrUninterruptible,
+			NrIoWait:          load.NrIoWait,
+		}
+	}
+	if processes := stats.GetProcesses(); processes != nil {
+		ret.Processes = info.ProcessStats{
+			OpenFds:      processes.OpenFds,
+			OpenFdsLimit: processes.OpenFdsLimit,
+			PidCount:     processes.PidCount,
+			ThreadCount:  processes.ThreadCount,
+		}
+	}
+	return ret
+}
+
+func fromPerDiskStats(disks []info.PerDiskStats) []*PerDiskStats {
+	var ret []*PerDiskStats
+	for _, disk := range disks {
+		stats := &PerDiskStats{
+			Major: disk.Major,
+			Minor: disk.Minor,
+		}
+		keys := make([]string, 0, len(disk.Stats))
+		for k := range disk.Stats {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			stats.Stats = append(stats.Stats, &DiskStat{Key: k, Value: disk.Stats[k]})
+		}
+		ret = append(ret, stats)
+	}
+	return ret
+}
+
+func toPerDiskStats(disks []*PerDiskStats) []info.PerDiskStats {
+	var ret []info.PerDiskStats
+	for _, disk := range disks {
+		stats := info.PerDiskStats{
+			Major: disk.Major,
+			Minor: disk.Minor,
+			Stats: make(map[string]uint64, len(disk.Stats)),
+		}
+		for _, stat := range disk.Stats {
+			stats.Stats[stat.Key] = stat.Value
+		}
+		ret = append(ret, stats)
+	}
+	return ret
+}
+
+func fromInterfaceStats(name string, stats *info.NetworkStats) *InterfaceStats {
+	return &InterfaceStats{
+		Name:      name,
+		RxBytes:   stats.RxBytes,
+		RxPackets: stats.RxPackets,
+		RxErrors:  stats.RxErrors,
+		RxDropped: stats.RxDropped,
+		TxBytes:   stats.TxBytes,
+		TxPackets: stats.TxPackets,
+		TxErrors:  stats.TxErrors,
+		TxDropped: stats.TxDropped,
+	}
+}
+
+func toInterfaceStats(iface *InterfaceStats, stats *info.NetworkStats) {
+	stats.RxBytes = iface.RxBytes
+	stats.RxPackets = iface.RxPackets
+	stats.RxErrors = iface.RxErrors
+	stats.RxDropped = iface.RxDropped
+	stats.TxBytes = iface.TxBytes
+	stats.TxPackets = iface.TxPackets
+	stats.TxErrors = iface.TxErrors
+	stats.TxDropped = iface.TxDropped
+}
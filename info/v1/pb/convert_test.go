@@ -0,0 +1,128 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pb
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	info "github.com/google/cadvisor/info/v1"
+)
+
+func TestContainerInfoRoundTrip(t *testing.T) {
+	now := time.Unix(1425000000, 123456789)
+	cinfo := &info.ContainerInfo{
+		ContainerReference: info.ContainerReference{
+			Name:       "/docker/abc",
+			Aliases:    []string{"web", "abc"},
+			Namespace:  "docker",
+			StableID:   "0f8e1d62-5b2a-11e5-9f9c-42010af00002/web",
+			ParentName: "/docker",
+			Depth:      2,
+		},
+		Subcontainers: []info.ContainerReference{
+			{Name: "/docker/abc/child"},
+		},
+		Spec: info.ContainerSpec{
+			CreationTime: now.Add(-time.Hour),
+			Labels:       map[string]string{"app": "web", "tier": "frontend"},
+			HasCpu:       true,
+			Cpu:          info.CpuSpec{Limit: 1024, MaxLimit: 2000, Mask: "0-3", Burst: 500, Idle: true},
+			HasMemory:    true,
+			Memory:       info.MemorySpec{Limit: 1 << 30, Reservation: 1 << 29, SwapLimit: 1 << 31},
+			HasNetwork:   true,
+			HasDiskIo:    true,
+			ImageID:      "sha256:0123",
+		},
+		Stats: []*info.ContainerStats{
+			{
+				Timestamp: now,
+				Cpu: info.CpuStats{
+					Usage:       info.CpuUsage{Total: 100, PerCpu: []uint64{60, 40}, User: 70, System: 30},
+					LoadAverage: 2,
+				},
+				DiskIo: info.DiskIoStats{
+					IoServiceBytes: []info.PerDiskStats{
+						{Major: 8, Minor: 0, Stats: map[string]uint64{"Read": 10, "Write": 20}},
+					},
+				},
+				Memory: info.MemoryStats{Usage: 1000, Cache: 200, RSS: 800, MappedFile: 5, Swap: 3, WorkingSet: 900, KernelUsage: 7},
+				Network: info.NetworkStats{
+					Interface: "eth0",
+					RxBytes:   11,
+					TxBytes:   12,
+					Interfaces: []info.InterfaceStats{
+						{Name: "eth0", NetworkStats: info.NetworkStats{RxBytes: 11, RxPackets: 1, TxBytes: 12, TxDropped: 2}},
+					},
+				},
+				Filesystem: []info.FsStats{
+					{Device: "/dev/sda1", Limit: 5000, Usage: 2500, ReadsCompleted: 4, WritesCompleted: 6, IoTime: 8},
+				},
+				TaskStats:      info.LoadStats{NrSleeping: 3, NrRunning: 1},
+				Processes:      info.ProcessStats{OpenFds: 9, OpenFdsLimit: 1024, PidCount: 2, ThreadCount: 5},
+				OomEvents:      1,
+				SequenceNumber: 42,
+			},
+		},
+	}
+
+	out, err := proto.Marshal(FromContainerInfo(cinfo))
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	var decoded ContainerInfo
+	if err := proto.Unmarshal(out, &decoded); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	actual := ToContainerInfo(&decoded)
+
+	if !actual.Spec.CreationTime.Equal(cinfo.Spec.CreationTime) {
+		t.Errorf("expected creation time %v, got %v", cinfo.Spec.CreationTime, actual.Spec.CreationTime)
+	}
+	if !actual.Stats[0].Timestamp.Equal(cinfo.Stats[0].Timestamp) {
+		t.Errorf("expected timestamp %v, got %v", cinfo.Stats[0].Timestamp, actual.Stats[0].Timestamp)
+	}
+	// Times are compared above as the location is not preserved.
+	actual.Spec.CreationTime = cinfo.Spec.CreationTime
+	actual.Stats[0].Timestamp = cinfo.Stats[0].Timestamp
+	if !reflect.DeepEqual(cinfo, actual) {
+		t.Errorf("expected %+v, got %+v", cinfo, actual)
+	}
+}
+
+func TestContainerInfoRoundTripZeroTime(t *testing.T) {
+	cinfo := &info.ContainerInfo{
+		ContainerReference: info.ContainerReference{Name: "/"},
+		Stats:              []*info.ContainerStats{{}},
+	}
+
+	out, err := proto.Marshal(FromContainerInfo(cinfo))
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	var decoded ContainerInfo
+	if err := proto.Unmarshal(out, &decoded); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	actual := ToContainerInfo(&decoded)
+	if !actual.Spec.CreationTime.IsZero() {
+		t.Errorf("expected zero creation time, got %v", actual.Spec.CreationTime)
+	}
+	if len(actual.Stats) != 1 || !actual.Stats[0].Timestamp.IsZero() {
+		t.Errorf("expected one sample with a zero timestamp, got %+v", actual.Stats)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Whether timestamps are marshaled to JSON in the time zone they were taken
+// in, usually the local time zone of the host, as cAdvisor used to. They are
+// otherwise marshaled in UTC, e.g. "2015-01-02T03:04:05Z", whatever the time
+// zone of the host.
+var LocalTimestamps = false
+
+// Returns the timestamp to marshal to JSON.
+func jsonTime(t time.Time) time.Time {
+	if LocalTimestamps {
+		return t
+	}
+	return t.UTC()
+}
+
+func (self ContainerSpec) MarshalJSON() ([]byte, error) {
+	type containerSpec ContainerSpec
+	spec := containerSpec(self)
+	spec.CreationTime = jsonTime(spec.CreationTime)
+	spec.ImageCreationTime = jsonTime(spec.ImageCreationTime)
+	return json.Marshal(spec)
+}
+
+func (self ContainerRetention) MarshalJSON() ([]byte, error) {
+	type containerRetention ContainerRetention
+	retention := containerRetention(self)
+	retention.Oldest = jsonTime(retention.Oldest)
+	retention.Newest = jsonTime(retention.Newest)
+	return json.Marshal(retention)
+}
+
+func (self ContainerStats) MarshalJSON() ([]byte, error) {
+	type containerStats ContainerStats
+	stats := containerStats(self)
+	stats.Timestamp = jsonTime(stats.Timestamp)
+	if stats.CollectionTime != nil {
+		collectionTime := jsonTime(*stats.CollectionTime)
+		stats.CollectionTime = &collectionTime
+	}
+	return json.Marshal(stats)
+}
+
+func (self Event) MarshalJSON() ([]byte, error) {
+	type event Event
+	e := event(self)
+	e.Timestamp = jsonTime(e.Timestamp)
+	return json.Marshal(e)
+}
+
+func (self MachineStats) MarshalJSON() ([]byte, error) {
+	type machineStats MachineStats
+	stats := machineStats(self)
+	stats.Timestamp = jsonTime(stats.Timestamp)
+	return json.Marshal(stats)
+}
+
+func (self MachineInfo) MarshalJSON() ([]byte, error) {
+	type machineInfo MachineInfo
+	info := machineInfo(self)
+	info.BootTime = jsonTime(info.BootTime)
+	return json.Marshal(info)
+}
@@ -0,0 +1,87 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Sets the local time zone of the test to a zone other than UTC.
+func setNonUTCLocal() func() {
+	local := time.Local
+	time.Local = time.FixedZone("UTC+2", 2*60*60)
+	return func() {
+		time.Local = local
+	}
+}
+
+func TestTimestampsMarshaledInUTC(t *testing.T) {
+	defer setNonUTCLocal()()
+
+	taken := time.Date(2015, 3, 4, 12, 30, 0, 0, time.Local)
+	collected := taken.Add(time.Second)
+	values := []interface{}{
+		&ContainerStats{Timestamp: taken, CollectionTime: &collected},
+		ContainerSpec{CreationTime: taken, ImageCreationTime: taken},
+		&Event{ContainerName: "/", Timestamp: taken, EventType: EventOom},
+		&MachineStats{Timestamp: taken},
+		&MachineInfo{BootTime: taken},
+		ContainerRetention{Oldest: taken, Newest: taken},
+		// Nested values are marshaled by their own methods.
+		&ContainerInfo{Stats: []*ContainerStats{{Timestamp: taken}}},
+	}
+	for _, value := range values {
+		out, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(out), `"2015-03-04T10:30:00Z"`) || strings.Contains(string(out), "+02:00") {
+			t.Errorf("expected timestamps in UTC, got %s", out)
+		}
+	}
+
+	// The values themselves are left as is.
+	if taken.Location() != time.Local || collected.Location() != time.Local {
+		t.Errorf("expected the marshaled timestamps to be unchanged")
+	}
+}
+
+func TestLocalTimestamps(t *testing.T) {
+	defer setNonUTCLocal()()
+	LocalTimestamps = true
+	defer func() {
+		LocalTimestamps = false
+	}()
+
+	out, err := json.Marshal(&ContainerStats{Timestamp: time.Date(2015, 3, 4, 12, 30, 0, 0, time.Local)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `"timestamp":"2015-03-04T12:30:00+02:00"`) {
+		t.Errorf("expected the timestamp in the local time zone, got %s", out)
+	}
+
+	// Stats marshaled the legacy way are read back unchanged.
+	var stats ContainerStats
+	if err := json.Unmarshal(out, &stats); err != nil {
+		t.Fatal(err)
+	}
+	if !stats.Timestamp.Equal(time.Date(2015, 3, 4, 10, 30, 0, 0, time.UTC)) {
+		t.Errorf("unexpected timestamp read back: %v", stats.Timestamp)
+	}
+}
@@ -25,6 +25,9 @@ import (
 const (
 	TypeName   = "name"
 	TypeDocker = "docker"
+	// Identifier of a container persisting across its restarts, see
+	// v1.ContainerReference.StableID.
+	TypeStable = "stable"
 )
 
 type CpuSpec struct {
@@ -36,6 +39,10 @@ type CpuSpec struct {
 	// Cpu affinity mask.
 	// TODO(rjnagal): Add a library to convert mask string to set of cpu bitmask.
 	Mask string `json:"mask,omitempty"`
+	// Time the container may run past its quota in a period. 0 if CPU
+	// bursting is not supported.
+	// Units: microseconds.
+	Burst uint64 `json:"burst,omitempty"`
 }
 
 type MemorySpec struct {
@@ -71,6 +78,33 @@ type ContainerSpec struct {
 	Memory    MemorySpec `json:"memory,omitempty"`
 }
 
+// Node of the container hierarchy.
+type ContainerTree struct {
+	// The absolute name of the container.
+	Name string `json:"name"`
+
+	Spec ContainerSpec `json:"spec"`
+
+	// Direct subcontainers of the container, sorted by name. Empty when the
+	// tree was truncated at this depth.
+	Children []ContainerTree `json:"children,omitempty"`
+}
+
+// Process of a container and the processes it started.
+type ProcessTree struct {
+	// PID of the process, as seen from the host.
+	Pid int `json:"pid"`
+
+	// PID of the parent of the process, as seen from the host.
+	Ppid int `json:"ppid"`
+
+	// Name of the executable of the process, truncated to 15 characters.
+	Command string `json:"command"`
+
+	// Children of the process in the container, sorted by PID.
+	Children []ProcessTree `json:"children,omitempty"`
+}
+
 type ContainerStats struct {
 	// The time of this stat point.
 	Timestamp time.Time `json:"timestamp"`
@@ -92,6 +126,14 @@ type ContainerStats struct {
 	// Task load statistics
 	HasLoad bool         `json:"has_load"`
 	Load    v1.LoadStats `json:"load_stats,omitempty"`
+	// Metrics derived from the stats by the enabled stats transforms.
+	DerivedMetrics map[string]float64 `json:"derived_metrics,omitempty"`
+	// Time the stats were actually collected when Timestamp is aligned to the
+	// housekeeping interval.
+	CollectionTime *time.Time `json:"collection_time,omitempty"`
+	// Number of the sample, increasing by one with each sample collected from
+	// the container.
+	SequenceNumber uint64 `json:"sequence_number,omitempty"`
 }
 
 type Percentiles struct {
@@ -0,0 +1,56 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/cadvisor/info/v1"
+)
+
+// Returns the timestamp to marshal to JSON, in UTC unless v1.LocalTimestamps
+// is set.
+func jsonTime(t time.Time) time.Time {
+	if v1.LocalTimestamps {
+		return t
+	}
+	return t.UTC()
+}
+
+func (self ContainerSpec) MarshalJSON() ([]byte, error) {
+	type containerSpec ContainerSpec
+	spec := containerSpec(self)
+	spec.CreationTime = jsonTime(spec.CreationTime)
+	return json.Marshal(spec)
+}
+
+func (self ContainerStats) MarshalJSON() ([]byte, error) {
+	type containerStats ContainerStats
+	stats := containerStats(self)
+	stats.Timestamp = jsonTime(stats.Timestamp)
+	if stats.CollectionTime != nil {
+		collectionTime := jsonTime(*stats.CollectionTime)
+		stats.CollectionTime = &collectionTime
+	}
+	return json.Marshal(stats)
+}
+
+func (self DerivedStats) MarshalJSON() ([]byte, error) {
+	type derivedStats DerivedStats
+	stats := derivedStats(self)
+	stats.Timestamp = jsonTime(stats.Timestamp)
+	return json.Marshal(stats)
+}
@@ -0,0 +1,90 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+var aliasPreferenceFlag = flag.String("alias_preference", "name,id,short_id", "Comma-separated order in which the kinds of aliases of a container are preferred. The first alias is the identity of the container in storage drivers and Prometheus. Options are: name, id, short_id")
+
+// Kinds of the aliases of a container.
+const (
+	aliasName    = "name"
+	aliasId      = "id"
+	aliasShortId = "short_id"
+	// Absolute names, i.e. original names of containers whose name was
+	// normalized. Always preferred last.
+	aliasOriginalName = "original_name"
+)
+
+// Parses a comma-separated list of kinds of aliases.
+func parseAliasPreference(preference string) ([]string, error) {
+	var ret []string
+	if preference == "" {
+		return ret, nil
+	}
+	for _, kind := range strings.Split(preference, ",") {
+		switch kind {
+		case aliasName, aliasId, aliasShortId:
+			ret = append(ret, kind)
+		default:
+			return nil, fmt.Errorf("unknown alias kind %q, expected one of %s, %s or %s", kind, aliasName, aliasId, aliasShortId)
+		}
+	}
+	return ret, nil
+}
+
+// Returns the kind of the alias: full and short IDs are 64 and 12 hex digits
+// long, absolute names are original names and anything else is a name.
+func aliasKind(alias string) string {
+	if strings.HasPrefix(alias, "/") {
+		return aliasOriginalName
+	}
+	if strings.Trim(alias, "0123456789abcdef") != "" {
+		return aliasName
+	}
+	switch len(alias) {
+	case 64:
+		return aliasId
+	case 12:
+		return aliasShortId
+	}
+	return aliasName
+}
+
+// Returns a copy of the aliases ordered by the preference of their kind.
+// Aliases of the same kind keep their order and those of a kind missing from
+// the preference go last.
+func orderAliases(aliases []string, preference []string) []string {
+	ret := make([]string, 0, len(aliases))
+	ordered := make([]bool, len(aliases))
+	for _, kind := range preference {
+		for i, alias := range aliases {
+			if !ordered[i] && aliasKind(alias) == kind {
+				ret = append(ret, alias)
+				ordered[i] = true
+			}
+		}
+	}
+	for i, alias := range aliases {
+		if !ordered[i] {
+			ret = append(ret, alias)
+		}
+	}
+	return ret
+}
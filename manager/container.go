@@ -18,25 +18,43 @@ import (
 	"flag"
 	"fmt"
 	"math"
+	"math/rand"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/docker/docker/pkg/units"
 	"github.com/golang/glog"
 	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/events"
 	info "github.com/google/cadvisor/info/v1"
 	"github.com/google/cadvisor/info/v2"
 	"github.com/google/cadvisor/storage/memory"
 	"github.com/google/cadvisor/summary"
 	"github.com/google/cadvisor/utils/cpuload"
+	"github.com/google/cadvisor/utils/dnsprobe"
+	"github.com/google/cadvisor/utils/procfs"
 )
 
 // Housekeeping interval.
 var HousekeepingInterval = flag.Duration("housekeeping_interval", 1*time.Second, "Interval between container housekeepings")
 var maxHousekeepingInterval = flag.Duration("max_housekeeping_interval", 60*time.Second, "Largest interval to allow between container housekeepings")
 var allowDynamicHousekeeping = flag.Bool("allow_dynamic_housekeeping", true, "Whether to allow the housekeeping interval to be dynamic")
+var enableFdSampling = flag.Bool("enable_fd_sampling", false, "Whether to sample the open file descriptors of the processes in each container. Expensive for containers with many processes")
+var fdSamplingInterval = flag.Duration("fd_sampling_interval", 30*time.Second, "Interval between samples of open file descriptors")
+var statsFailureThreshold = flag.Int("stats_failure_threshold", 0, "Number of consecutive failures to get the stats of a container after which its collection is backed off and it is reported as degraded. 0 disables the back off")
+var statsProbeInterval = flag.Duration("stats_probe_interval", time.Minute, "Interval at which the collection of stats of a degraded container is retried")
+var enableSchedstat = flag.Bool("enable_schedstat", false, "Whether to aggregate /proc/<tid>/schedstat of the tasks in each container. Expensive for containers with many threads")
+var enableContextSwitches = flag.Bool("enable_context_switches", false, "Whether to aggregate the context switches of the tasks in each container from /proc/<tid>/status. Expensive for containers with many threads")
+var enablePidTracking = flag.Bool("enable_pid_tracking", false, "Whether to track the processes of each container between housekeepings to report their count and churn. Expensive for containers with many processes")
+var pidMigrationThreshold = flag.Int("pid_migration_threshold", 0, "Number of processes appearing in or disappearing from a container between two housekeepings above which a pidMigration event is emitted. 0 disables the events")
+var ioConstrainedThreshold = flag.Float64("io_constrained_threshold", 0, "Percentage of the last minute some tasks of a container were stalled on IO, per the some avg60 of its io.pressure, above which the container is flagged as IO-constrained in the io_constrained derived metric. Requires cgroup v2 with PSI. 0 disables the flag")
+var backpressureHousekeepingFactor = flag.Float64("backpressure_housekeeping_factor", 2, "Factor by which the housekeeping interval of every container is lengthened while the storage backends fall behind, per storage_driver_queue_size. 1 disables the back off")
+var memoryPressureThreshold = flag.Float64("memory_pressure_threshold", 0, "Fraction of its memory limit, e.g. 0.9, at and above which the working set of a container emits a memoryPressure event. 0 disables the events")
+var memoryPressureHysteresis = flag.Float64("memory_pressure_hysteresis", 0.05, "Fraction of its memory limit by which the working set of a container must fall below memory_pressure_threshold before another memoryPressure event can be emitted")
+var alignSampleTimestamps = flag.Bool("align_sample_timestamps", false, "Whether to snap the timestamp of each sample to the nearest housekeeping interval boundary. The actual collection time is reported as collection_time")
 
 // Decay value used for load average smoothing. Interval length of 10 seconds is used.
 var loadDecay = math.Exp(float64(-1 * (*HousekeepingInterval).Seconds() / 10))
@@ -65,8 +83,96 @@ type containerData struct {
 	// Whether to log the usage of this container when it is updated.
 	logUsage bool
 
+	// Name of the container given by its handler if info.Name was normalized.
+	originalName string
+
+	// Largest fraction of the housekeeping interval by which each housekeeping
+	// is randomly moved earlier or later, 0 if disabled.
+	maxHousekeepingJitter float64
+
+	// Pauses the housekeeping of this container along with the others sharing
+	// it. Housekeeping is never paused if nil.
+	pause *housekeepingPause
+
+	// Number of consecutive failures to get stats after which the container is
+	// degraded, 0 if never. Degraded containers only get stats once per
+	// statsProbeInterval until it succeeds again.
+	statsFailureThreshold int
+	statsProbeInterval    time.Duration
+	// Guarded by lock.
+	statsFailures  int
+	degraded       bool
+	nextStatsProbe time.Time
+
+	// Number of OOM kills seen in this container. Accessed atomically.
+	oomEvents uint64
+
+	// Interval between samples of open file descriptors, 0 if disabled.
+	fdSamplingInterval time.Duration
+	lastFdSample       time.Time
+	processStats       info.ProcessStats
+
+	// Whether to aggregate the scheduler statistics of the tasks in the container.
+	collectSchedstat bool
+	// Last schedstat seen for each task, used to accumulate across task exits.
+	taskSchedstat map[int]procfs.Schedstat
+	schedstat     info.CpuSchedstat
+
+	// Whether to aggregate the context switches of the tasks in the container.
+	collectContextSwitches bool
+	// Last context switches seen for each task, used to accumulate across task exits.
+	taskContextSwitches map[int]procfs.ContextSwitches
+	contextSwitches     info.CpuContextSwitches
+
+	// CPU usage and time of the previous stats, zero until the first ones.
+	prevCpuUsage info.CpuUsage
+	prevCpuTime  time.Time
+
+	// Whether to track the processes in the container between housekeepings.
+	trackPids bool
+	// Processes seen at the last housekeeping, nil until the first one.
+	pids      map[int]struct{}
+	pidCounts info.ProcessStats
+	// Churn between two housekeepings above which a pidMigration event is
+	// added to eventHandler, 0 if never.
+	pidMigrationThreshold int
+	eventHandler          events.EventManager
+
+	// Transforms deriving metrics from the collected stats.
+	statsTransforms []StatsTransform
+	// IO pressure above which the container is flagged as IO-constrained, 0 if
+	// never.
+	ioConstrainedThreshold float64
+
+	// Reads the DNS stats of the container, nil if they are not reported.
+	dnsReader *dnsprobe.Reader
+
+	// Fraction of the memory limit at and above which the working set emits
+	// a memoryPressure event to eventHandler, 0 if never, and fraction below
+	// the threshold it must fall to before another event is emitted.
+	memoryPressureThreshold  float64
+	memoryPressureHysteresis float64
+	// Whether the working set crossed the threshold and did not fall back yet.
+	memoryPressure bool
+
+	// Whether to snap the timestamp of each sample to the nearest housekeeping
+	// interval boundary.
+	alignTimestamps bool
+	// Timestamp of the last sample, used to keep aligned timestamps increasing.
+	lastSampleTimestamp time.Time
+
+	// Sequence number of the last sample collected from the container.
+	sequenceNumber uint64
+
+	// Time of the creation event of the container, zero if it generated none,
+	// and time of its first successful sample, zero until then.
+	creationEventTime time.Time
+	firstSampleTime   time.Time
+
 	// Tells the container to stop.
 	stop chan bool
+	// Closed once housekeeping has stopped.
+	stopped chan struct{}
 }
 
 func (c *containerData) Start() error {
@@ -79,6 +185,45 @@ func (c *containerData) Stop() error {
 	return nil
 }
 
+// Pauses and resumes the housekeeping of all the containers sharing it.
+type housekeepingPause struct {
+	lock sync.Mutex
+	// Closed when housekeeping is resumed, nil while housekeeping is not paused.
+	resumeChannel chan struct{}
+}
+
+func (self *housekeepingPause) Pause() {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.resumeChannel == nil {
+		self.resumeChannel = make(chan struct{})
+	}
+}
+
+func (self *housekeepingPause) Resume() {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.resumeChannel != nil {
+		close(self.resumeChannel)
+		self.resumeChannel = nil
+	}
+}
+
+func (self *housekeepingPause) Paused() bool {
+	return self.resumed() != nil
+}
+
+// Returns a channel closed when housekeeping is resumed, nil if housekeeping is
+// not paused.
+func (self *housekeepingPause) resumed() <-chan struct{} {
+	if self == nil {
+		return nil
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.resumeChannel
+}
+
 func (c *containerData) allowErrorLogging() bool {
 	if time.Since(c.lastErrorTime) > time.Minute {
 		c.lastErrorTime = time.Now()
@@ -113,7 +258,15 @@ func (c *containerData) DerivedStats() (v2.DerivedStats, error) {
 	return c.summaryReader.DerivedStats()
 }
 
-func newContainerData(containerName string, memoryStorage *memory.InMemoryStorage, handler container.ContainerHandler, loadReader cpuload.CpuLoadReader, logUsage bool) (*containerData, error) {
+// Returns the name of the container given by its handler, before normalization.
+func (c *containerData) handlerName() string {
+	if c.originalName != "" {
+		return c.originalName
+	}
+	return c.info.Name
+}
+
+func newContainerData(containerName string, memoryStorage *memory.InMemoryStorage, handler container.ContainerHandler, loadReader cpuload.CpuLoadReader, logUsage bool, maxHousekeepingJitter float64) (*containerData, error) {
 	if memoryStorage == nil {
 		return nil, fmt.Errorf("nil memory storage")
 	}
@@ -133,8 +286,26 @@ func newContainerData(containerName string, memoryStorage *memory.InMemoryStorag
 		logUsage:             logUsage,
 		loadAvg:              -1.0, // negative value indicates uninitialized.
 		stop:                 make(chan bool, 1),
+		stopped:              make(chan struct{}),
+
+		maxHousekeepingJitter: maxHousekeepingJitter,
 	}
 	cont.info.ContainerReference = ref
+	cont.statsFailureThreshold = *statsFailureThreshold
+	cont.statsProbeInterval = *statsProbeInterval
+	cont.alignTimestamps = *alignSampleTimestamps
+	if *enableFdSampling {
+		cont.fdSamplingInterval = *fdSamplingInterval
+	}
+	if *enableSchedstat {
+		cont.collectSchedstat = true
+		cont.taskSchedstat = make(map[int]procfs.Schedstat)
+	}
+	if *enableContextSwitches {
+		cont.collectContextSwitches = true
+		cont.taskContextSwitches = make(map[int]procfs.ContextSwitches)
+	}
+	cont.trackPids = *enablePidTracking
 
 	err = cont.updateSpec()
 	if err != nil {
@@ -173,10 +344,28 @@ func (self *containerData) nextHousekeeping(lastHousekeeping time.Time) time.Tim
 		}
 	}
 
-	return lastHousekeeping.Add(self.housekeepingInterval)
+	interval := self.housekeepingInterval
+	if *backpressureHousekeepingFactor > 1 && self.memoryStorage.Backpressure() {
+		// Give the storage backends time to catch up.
+		interval = time.Duration(float64(interval) * *backpressureHousekeepingFactor)
+	}
+	return lastHousekeeping.Add(jitter(interval, self.maxHousekeepingJitter))
+}
+
+// Returns the duration moved by a uniformly random amount of at most maxFactor
+// times the duration in either direction, so that on average it is unchanged.
+// This keeps containers from all collecting on the same tick.
+func jitter(duration time.Duration, maxFactor float64) time.Duration {
+	if maxFactor <= 0 {
+		return duration
+	}
+	offset := (rand.Float64()*2 - 1) * maxFactor * float64(duration)
+	return duration + time.Duration(offset)
 }
 
 func (c *containerData) housekeeping() {
+	defer close(c.stopped)
+
 	// Long housekeeping is either 100ms or half of the housekeeping interval.
 	longHousekeeping := 100 * time.Millisecond
 	if *HousekeepingInterval/2 < longHousekeeping {
@@ -187,6 +376,20 @@ func (c *containerData) housekeeping() {
 	glog.V(3).Infof("Start housekeeping for container %q\n", c.info.Name)
 	lastHousekeeping := time.Now()
 	for {
+		// Wait while housekeeping is paused. The schedule restarts from the
+		// time housekeeping is resumed so that no samples are taken in a burst
+		// to catch up.
+		if resumed := c.pause.resumed(); resumed != nil {
+			glog.V(3).Infof("Housekeeping of container %q paused", c.info.Name)
+			select {
+			case <-c.stop:
+				return
+			case <-resumed:
+			}
+			glog.V(3).Infof("Housekeeping of container %q resumed", c.info.Name)
+			lastHousekeeping = time.Now()
+		}
+
 		select {
 		case <-c.stop:
 			// Stop housekeeping when signaled.
@@ -240,7 +443,11 @@ func (c *containerData) housekeeping() {
 }
 
 func (c *containerData) housekeepingTick() {
+	if c.statsBackedOff() {
+		return
+	}
 	err := c.updateStats()
+	c.recordStatsResult(err)
 	if err != nil {
 		if c.allowErrorLogging() {
 			glog.Infof("Failed to update stats for container \"%s\": %s", c.info.Name, err)
@@ -248,6 +455,44 @@ func (c *containerData) housekeepingTick() {
 	}
 }
 
+// Whether the container is degraded and not due to be probed yet.
+func (c *containerData) statsBackedOff() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.degraded && time.Now().Before(c.nextStatsProbe)
+}
+
+// Degrades the container after too many consecutive failures to get its stats,
+// recovers it on success.
+func (c *containerData) recordStatsResult(err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if err == nil {
+		if c.degraded {
+			glog.Infof("Recovered getting stats for container %q", c.info.Name)
+		}
+		c.statsFailures = 0
+		c.degraded = false
+		return
+	}
+	c.statsFailures++
+	if c.statsFailureThreshold <= 0 || c.statsFailures < c.statsFailureThreshold {
+		return
+	}
+	if !c.degraded {
+		glog.Warningf("Failed to get stats for container %q %d times in a row, retrying every %v", c.info.Name, c.statsFailures, c.statsProbeInterval)
+	}
+	c.degraded = true
+	c.nextStatsProbe = time.Now().Add(c.statsProbeInterval)
+}
+
+// Whether getting the stats of the container keeps failing.
+func (c *containerData) Degraded() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.degraded
+}
+
 func (c *containerData) updateSpec() error {
 	spec, err := c.handler.GetSpec()
 	if err != nil {
@@ -257,6 +502,9 @@ func (c *containerData) updateSpec() error {
 		}
 		return err
 	}
+	if err := c.memoryStorage.AddSpec(c.info.ContainerReference, spec); err != nil {
+		glog.V(3).Infof("failed to store the spec of %q: %v", c.info.Name, err)
+	}
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	c.info.Spec = spec
@@ -332,6 +580,8 @@ func (c *containerData) updateStats() error {
 	if stats == nil {
 		return statsErr
 	}
+	c.sequenceNumber++
+	stats.SequenceNumber = c.sequenceNumber
 	if c.loadReader != nil {
 		preloadavg := c.loadAvg
 		// calls GetCpuLoad or gets most recent stats
@@ -354,6 +604,45 @@ func (c *containerData) updateStats() error {
 		glog.V(2).Infof("container: %+v; loadavg pre: %v, mid: %+v, post: %v\n", c.info.Name, preloadavg, midloadavg, postloadavg)
 
 	}
+	stats.OomEvents = atomic.LoadUint64(&c.oomEvents)
+	if c.fdSamplingInterval > 0 {
+		c.updateProcessStats()
+		stats.Processes.OpenFds = c.processStats.OpenFds
+		stats.Processes.OpenFdsLimit = c.processStats.OpenFdsLimit
+	}
+	if c.trackPids {
+		c.updatePids()
+		stats.Processes.PidCount = c.pidCounts.PidCount
+		stats.Processes.PidsAdded = c.pidCounts.PidsAdded
+		stats.Processes.PidsRemoved = c.pidCounts.PidsRemoved
+	}
+	if c.collectSchedstat {
+		c.updateSchedstat()
+		stats.Cpu.Schedstat.RunTime = c.schedstat.RunTime
+		stats.Cpu.Schedstat.RunqueueTime = c.schedstat.RunqueueTime
+		stats.Cpu.Schedstat.RunPeriods = c.schedstat.RunPeriods
+	}
+	if c.collectContextSwitches {
+		c.updateContextSwitches()
+		stats.Cpu.ContextSwitches = c.contextSwitches
+	}
+	if c.dnsReader != nil {
+		c.updateDnsStats(stats)
+	}
+	if c.memoryPressureThreshold > 0 {
+		c.updateMemoryPressure(stats)
+	}
+	// Measured over the actual interval, before the timestamps are aligned.
+	c.updateCpuUsageRate(stats)
+	if c.alignTimestamps {
+		c.alignTimestamp(stats)
+	}
+	if c.ioConstrainedThreshold > 0 {
+		setIoConstrained(stats, c.ioConstrainedThreshold)
+	}
+	if len(c.statsTransforms) > 0 {
+		c.applyStatsTransforms(stats)
+	}
 	if c.summaryReader != nil {
 		err := c.summaryReader.AddSample(*stats)
 		if err != nil {
@@ -361,21 +650,329 @@ func (c *containerData) updateStats() error {
 			glog.V(2).Infof("failed to add summary stats for %q: %v", c.info.Name, err)
 		}
 	}
-	ref, err := c.handler.ContainerReference()
+	// Stored under the normalized name and preferred order of aliases rather
+	// than the reference of the handler.
+	err := c.memoryStorage.AddStats(c.info.ContainerReference, stats)
 	if err != nil {
-		// Ignore errors if the container is dead.
-		if !c.handler.Exists() {
-			return nil
-		}
 		return err
 	}
-	err = c.memoryStorage.AddStats(ref, stats)
-	if err != nil {
-		return err
+	if statsErr == nil {
+		c.recordFirstSample(time.Now())
 	}
 	return statsErr
 }
 
+// Snaps the timestamp of the stats to the nearest boundary of the configured
+// housekeeping interval, which unlike the dynamic interval of the container is
+// the same for all containers, and keeps the actual time in CollectionTime.
+// Samples collected close together
+// may round to the same boundary, in which case the later one is moved to the
+// next boundary so timestamps keep increasing.
+func (c *containerData) alignTimestamp(stats *info.ContainerStats) {
+	collectionTime := stats.Timestamp
+	aligned := collectionTime.Round(*HousekeepingInterval)
+	if !aligned.After(c.lastSampleTimestamp) {
+		aligned = c.lastSampleTimestamp.Add(*HousekeepingInterval)
+	}
+	c.lastSampleTimestamp = aligned
+	stats.Timestamp = aligned
+	stats.CollectionTime = &collectionTime
+}
+
+// Samples the open file descriptors of the processes in the container. Walking
+// every process is expensive so samples are taken at most once per fdSamplingInterval.
+func (c *containerData) updateProcessStats() {
+	if time.Since(c.lastFdSample) < c.fdSamplingInterval {
+		return
+	}
+	c.lastFdSample = time.Now()
+
+	cgroupPath, err := c.handler.GetCgroupPath("cpu")
+	if err != nil {
+		glog.V(3).Infof("failed to get cgroup path of %q: %v", c.info.Name, err)
+		return
+	}
+	pids, err := procfs.GetCgroupPids(cgroupPath)
+	if err != nil {
+		glog.V(3).Infof("failed to list processes of %q: %v", c.info.Name, err)
+		return
+	}
+	var processStats info.ProcessStats
+	initPid := 0
+	for _, pid := range pids {
+		// Processes may exit while we walk them, ignore those.
+		fds, err := procfs.GetOpenFds(pid)
+		if err != nil {
+			continue
+		}
+		processStats.OpenFds += fds
+		if initPid == 0 || pid < initPid {
+			initPid = pid
+		}
+	}
+	// The limit of the container is that of its init process, which the other
+	// processes inherit unless they change it.
+	if initPid != 0 {
+		if limit, err := procfs.GetOpenFdsLimit(initPid); err == nil {
+			processStats.OpenFdsLimit = limit
+		}
+	}
+	c.processStats = processStats
+}
+
+// Reads the DNS stats of the container counted by the eBPF probe. The probe
+// identifies containers by their cgroup v2 cgroup, nothing is reported for
+// containers without one.
+func (c *containerData) updateDnsStats(stats *info.ContainerStats) {
+	cgroupPath := c.unifiedCgroupPath()
+	if cgroupPath == "" {
+		return
+	}
+	dnsStats, err := c.dnsReader.Stats(cgroupPath)
+	if err != nil {
+		glog.V(3).Infof("failed to get DNS stats of %q: %v", c.info.Name, err)
+		return
+	}
+	stats.Dns = &dnsStats
+}
+
+// Returns the cgroup of the container on the cgroup v2 unified hierarchy, or
+// an empty path if none of the controllers it is in are bound to it.
+func (c *containerData) unifiedCgroupPath() string {
+	for _, resource := range []string{"memory", "cpu", "blkio", "pids", "cpuset"} {
+		cgroupPath, err := c.handler.GetCgroupPath(resource)
+		if err == nil && dnsprobe.IsUnifiedCgroup(cgroupPath) {
+			return cgroupPath
+		}
+	}
+	return ""
+}
+
+// Compares the processes in the container with those seen at the last
+// housekeeping and accumulates the processes added and removed. Processes
+// migrating between cgroups shift the attribution of their usage, so a large
+// churn is logged and reported as an event if enabled.
+func (c *containerData) updatePids() {
+	cgroupPath, err := c.handler.GetCgroupPath("cpu")
+	if err != nil {
+		glog.V(3).Infof("failed to get cgroup path of %q: %v", c.info.Name, err)
+		return
+	}
+	pids, err := procfs.GetCgroupPids(cgroupPath)
+	if err != nil {
+		glog.V(3).Infof("failed to list processes of %q: %v", c.info.Name, err)
+		return
+	}
+	seen := make(map[int]struct{}, len(pids))
+	added := 0
+	for _, pid := range pids {
+		seen[pid] = struct{}{}
+		if _, ok := c.pids[pid]; !ok {
+			added++
+		}
+	}
+	removed := 0
+	for pid := range c.pids {
+		if _, ok := seen[pid]; !ok {
+			removed++
+		}
+	}
+	first := c.pids == nil
+	c.pids = seen
+	c.pidCounts.PidCount = uint64(len(seen))
+	// The processes found at the first housekeeping are not churn.
+	if first {
+		return
+	}
+	c.pidCounts.PidsAdded += uint64(added)
+	c.pidCounts.PidsRemoved += uint64(removed)
+
+	if c.pidMigrationThreshold <= 0 || added+removed <= c.pidMigrationThreshold {
+		return
+	}
+	glog.V(4).Infof("%d processes added to and %d removed from %q since the last housekeeping", added, removed, c.info.Name)
+	if c.eventHandler == nil {
+		return
+	}
+	event := &info.Event{
+		ContainerName: c.info.Name,
+		Timestamp:     time.Now(),
+		EventType:     info.EventPidMigration,
+		EventData: info.EventData{
+			PidMigration: &info.PidMigrationEventData{
+				PidsAdded:   added,
+				PidsRemoved: removed,
+			},
+		},
+		Labels: c.labels(),
+	}
+	if err := c.eventHandler.AddEvent(event); err != nil {
+		glog.Errorf("failed to add pid migration event for %q: %v", c.info.Name, err)
+	}
+}
+
+// Emits a memoryPressure event when the working set of the container crosses
+// the threshold fraction of its memory limit. No other event is emitted until
+// the working set falls below the threshold by the hysteresis, so that usage
+// hovering around the threshold does not flap.
+func (c *containerData) updateMemoryPressure(stats *info.ContainerStats) {
+	c.lock.RLock()
+	spec := c.info.Spec
+	c.lock.RUnlock()
+	if !spec.HasMemory || spec.Memory.Limit == 0 {
+		return
+	}
+	fraction := float64(stats.Memory.WorkingSet) / float64(spec.Memory.Limit)
+	if c.memoryPressure {
+		if fraction < c.memoryPressureThreshold-c.memoryPressureHysteresis {
+			c.memoryPressure = false
+		}
+		return
+	}
+	if fraction < c.memoryPressureThreshold {
+		return
+	}
+	c.memoryPressure = true
+	glog.V(4).Infof("Working set of %q is %.0f%% of its memory limit", c.info.Name, fraction*100)
+	if c.eventHandler == nil {
+		return
+	}
+	event := &info.Event{
+		ContainerName: c.info.Name,
+		Timestamp:     time.Now(),
+		EventType:     info.EventMemoryPressure,
+		EventData: info.EventData{
+			MemoryPressure: &info.MemoryPressureEventData{
+				WorkingSet: stats.Memory.WorkingSet,
+				Limit:      spec.Memory.Limit,
+			},
+		},
+		Labels: spec.Labels,
+	}
+	if err := c.eventHandler.AddEvent(event); err != nil {
+		glog.Errorf("failed to add memory pressure event for %q: %v", c.info.Name, err)
+	}
+}
+
+// Accumulates the scheduler statistics of the tasks in the container. Only the
+// growth of each task since its last sample is added so the totals stay
+// monotonic as tasks exit.
+func (c *containerData) updateSchedstat() {
+	cgroupPath, err := c.handler.GetCgroupPath("cpu")
+	if err != nil {
+		glog.V(3).Infof("failed to get cgroup path of %q: %v", c.info.Name, err)
+		return
+	}
+	tids, err := procfs.GetCgroupTasks(cgroupPath)
+	if err != nil {
+		glog.V(3).Infof("failed to list tasks of %q: %v", c.info.Name, err)
+		return
+	}
+	seen := make(map[int]procfs.Schedstat, len(tids))
+	for _, tid := range tids {
+		// Tasks may exit while we walk them, ignore those.
+		cur, err := procfs.GetSchedstat(tid)
+		if err != nil {
+			continue
+		}
+		prev := c.taskSchedstat[tid]
+		if cur.RunTime >= prev.RunTime && cur.RunqueueTime >= prev.RunqueueTime && cur.RunPeriods >= prev.RunPeriods {
+			c.schedstat.RunTime += cur.RunTime - prev.RunTime
+			c.schedstat.RunqueueTime += cur.RunqueueTime - prev.RunqueueTime
+			c.schedstat.RunPeriods += cur.RunPeriods - prev.RunPeriods
+		}
+		seen[tid] = cur
+	}
+	c.taskSchedstat = seen
+}
+
+// Accumulates the context switches of the tasks in the container the same way
+// as updateSchedstat.
+func (c *containerData) updateContextSwitches() {
+	cgroupPath, err := c.handler.GetCgroupPath("cpu")
+	if err != nil {
+		glog.V(3).Infof("failed to get cgroup path of %q: %v", c.info.Name, err)
+		return
+	}
+	tids, err := procfs.GetCgroupTasks(cgroupPath)
+	if err != nil {
+		glog.V(3).Infof("failed to list tasks of %q: %v", c.info.Name, err)
+		return
+	}
+	seen := make(map[int]procfs.ContextSwitches, len(tids))
+	for _, tid := range tids {
+		// Tasks may exit while we walk them, ignore those.
+		cur, err := procfs.GetContextSwitches(tid)
+		if err != nil {
+			continue
+		}
+		prev := c.taskContextSwitches[tid]
+		if cur.Voluntary >= prev.Voluntary && cur.Involuntary >= prev.Involuntary {
+			c.contextSwitches.Voluntary += cur.Voluntary - prev.Voluntary
+			c.contextSwitches.Involuntary += cur.Involuntary - prev.Involuntary
+		}
+		seen[tid] = cur
+	}
+	c.taskContextSwitches = seen
+}
+
+// Sets the CPU usage rate of the stats from the usage of the previous stats.
+func (c *containerData) updateCpuUsageRate(stats *info.ContainerStats) {
+	stats.Cpu.UsageRate = cpuUsageRate(c.prevCpuUsage, c.prevCpuTime, stats.Cpu.Usage, stats.Timestamp)
+	c.prevCpuUsage = stats.Cpu.Usage
+	c.prevCpuTime = stats.Timestamp
+}
+
+// Returns the CPU usage per second between the previous and the current usage.
+// Zero without a previous usage and when the usage went backwards, e.g. when
+// the cgroup was recreated.
+func cpuUsageRate(prev info.CpuUsage, prevTime time.Time, cur info.CpuUsage, curTime time.Time) info.CpuUsageRate {
+	if prevTime.IsZero() || !curTime.After(prevTime) {
+		return info.CpuUsageRate{}
+	}
+	if cur.Total < prev.Total || cur.User < prev.User || cur.System < prev.System {
+		return info.CpuUsageRate{}
+	}
+	elapsed := float64(curTime.Sub(prevTime))
+	millicores := func(prev, cur uint64) uint64 {
+		return uint64(float64(cur-prev) / elapsed * 1000)
+	}
+	return info.CpuUsageRate{
+		Total:  millicores(prev.Total, cur.Total),
+		User:   millicores(prev.User, cur.User),
+		System: millicores(prev.System, cur.System),
+	}
+}
+
+// Applies the stats transforms to the stats, with the previous stats of the
+// container if there are any.
+func (c *containerData) applyStatsTransforms(stats *info.ContainerStats) {
+	var prev *info.ContainerStats
+	var empty time.Time
+	recent, err := c.memoryStorage.RecentStats(c.info.Name, empty, empty, 1)
+	if err == nil && len(recent) == 1 {
+		prev = recent[0]
+	}
+	c.lock.RLock()
+	spec := c.info.Spec
+	c.lock.RUnlock()
+	for _, transform := range c.statsTransforms {
+		transform(&spec, prev, stats)
+	}
+}
+
+// Records an OOM kill in this container.
+func (c *containerData) addOomEvent() {
+	atomic.AddUint64(&c.oomEvents, 1)
+}
+
+// Returns the labels of the container as of its last spec update.
+func (c *containerData) labels() map[string]string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.info.Spec.Labels
+}
+
 func (c *containerData) updateSubcontainers() error {
 	var subcontainers info.ContainerReferenceSlice
 	subcontainers, err := c.handler.ListContainers(container.ListSelf)
@@ -18,15 +18,23 @@ package manager
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/events"
 	info "github.com/google/cadvisor/info/v1"
 	itest "github.com/google/cadvisor/info/v1/test"
 	"github.com/google/cadvisor/storage/memory"
+	stest "github.com/google/cadvisor/storage/test"
+	"github.com/google/cadvisor/utils/dnsprobe"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -39,8 +47,8 @@ func setupContainerData(t *testing.T, spec info.ContainerSpec) (*containerData,
 		spec,
 		nil,
 	)
-	memoryStorage := memory.New(60, nil)
-	ret, err := newContainerData(containerName, memoryStorage, mockHandler, nil, false)
+	memoryStorage := memory.New(60, nil, nil)
+	ret, err := newContainerData(containerName, memoryStorage, mockHandler, nil, false, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -141,6 +149,114 @@ func TestUpdateStats(t *testing.T) {
 	mockHandler.AssertExpectations(t)
 }
 
+func TestUpdateStatsWithOomEvents(t *testing.T) {
+	statsList := itest.GenerateRandomStats(1, 4, 1*time.Second)
+	stats := statsList[0]
+
+	cd, mockHandler, _ := newTestContainerData(t)
+	mockHandler.On("GetStats").Return(
+		stats,
+		nil,
+	)
+
+	cd.addOomEvent()
+	cd.addOomEvent()
+	err := cd.updateStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.OomEvents != 2 {
+		t.Errorf("expected 2 OOM events, got %d", stats.OomEvents)
+	}
+	mockHandler.AssertExpectations(t)
+}
+
+func TestUpdateStatsSequenceNumbers(t *testing.T) {
+	cd, mockHandler, memoryStorage := newTestContainerData(t)
+	for _, stats := range itest.GenerateRandomStats(3, 4, 1*time.Second) {
+		mockHandler.On("GetStats").Return(stats, nil).Once()
+		if err := cd.updateStats(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var empty time.Time
+	stats, err := memoryStorage.RecentStats(containerName, empty, empty, -1)
+	require.Nil(t, err)
+	if len(stats) != 3 {
+		t.Fatalf("expected 3 samples, got %d", len(stats))
+	}
+	for i, s := range stats {
+		if s.SequenceNumber != uint64(i+1) {
+			t.Errorf("expected sample %d to have sequence number %d, got %d", i, i+1, s.SequenceNumber)
+		}
+	}
+}
+
+// Returns the number of delays and the sum of the delays in seconds observed
+// by the first sample delay histogram.
+func firstSampleDelays(t *testing.T) (uint64, float64) {
+	var metric dto.Metric
+	if err := FirstSampleDelay.Write(&metric); err != nil {
+		t.Fatal(err)
+	}
+	return metric.GetHistogram().GetSampleCount(), metric.GetHistogram().GetSampleSum()
+}
+
+func TestUpdateStatsRecordsFirstSampleDelay(t *testing.T) {
+	cd, mockHandler, _ := newTestContainerData(t)
+	cd.creationEventTime = time.Now().Add(-3 * time.Second)
+	statsList := itest.GenerateRandomStats(2, 4, 1*time.Second)
+	mockHandler.On("GetStats").Return(statsList[0], fmt.Errorf("partial stats")).Once()
+	mockHandler.On("Exists").Return(true)
+	mockHandler.On("GetStats").Return(statsList[1], nil).Once()
+
+	count, sum := firstSampleDelays(t)
+	// Failed samples do not count as the first sample.
+	if err := cd.updateStats(); err == nil {
+		t.Fatal("expected the error getting the stats")
+	}
+	if !cd.firstSampleTime.IsZero() {
+		t.Errorf("expected no first sample, got one at %v", cd.firstSampleTime)
+	}
+	if err := cd.updateStats(); err != nil {
+		t.Fatal(err)
+	}
+	newCount, newSum := firstSampleDelays(t)
+	if newCount != count+1 {
+		t.Fatalf("expected one delay to be observed, got %d", newCount-count)
+	}
+	if delay := newSum - sum; delay < 3 || delay > 60 {
+		t.Errorf("expected a delay of about 3s, got %vs", delay)
+	}
+	if cd.firstSampleTime.Before(cd.creationEventTime.Add(3 * time.Second)) {
+		t.Errorf("expected the first sample to be taken after the creation event, got %v", cd.firstSampleTime)
+	}
+
+	// Only the first sample is observed.
+	mockHandler.On("GetStats").Return(statsList[1], nil)
+	if err := cd.updateStats(); err != nil {
+		t.Fatal(err)
+	}
+	if newCount, _ := firstSampleDelays(t); newCount != count+1 {
+		t.Errorf("expected only the first sample to be observed, got %d delays", newCount-count)
+	}
+}
+
+func TestUpdateStatsSkipsFirstSampleDelayOfPreexistingContainers(t *testing.T) {
+	cd, mockHandler, _ := newTestContainerData(t)
+	mockHandler.On("GetStats").Return(itest.GenerateRandomStats(1, 4, 1*time.Second)[0], nil)
+
+	count, _ := firstSampleDelays(t)
+	if err := cd.updateStats(); err != nil {
+		t.Fatal(err)
+	}
+	if newCount, _ := firstSampleDelays(t); newCount != count {
+		t.Errorf("expected no delay to be observed without a creation event, got %d", newCount-count)
+	}
+}
+
 func TestUpdateSpec(t *testing.T) {
 	spec := itest.GenerateRandomContainerSpec(4)
 	cd, mockHandler, _ := newTestContainerData(t)
@@ -202,3 +318,447 @@ func TestGetInfo(t *testing.T) {
 		t.Errorf("received wrong container name: received %v; should be %v", info.Name, mockHandler.Name)
 	}
 }
+
+func TestJitter(t *testing.T) {
+	const interval = time.Second
+	const maxFactor = 0.2
+	assert.Equal(t, interval, jitter(interval, 0))
+
+	var total time.Duration
+	const samples = 10000
+	for i := 0; i < samples; i++ {
+		d := jitter(interval, maxFactor)
+		if d < 800*time.Millisecond || d > 1200*time.Millisecond {
+			t.Fatalf("jittered interval %v is outside of [800ms, 1.2s]", d)
+		}
+		total += d
+	}
+	// The average interval stays the configured one.
+	average := total / samples
+	if average < 990*time.Millisecond || average > 1010*time.Millisecond {
+		t.Errorf("expected an average interval of about %v, got %v", interval, average)
+	}
+}
+
+func TestHousekeepingPause(t *testing.T) {
+	statsList := itest.GenerateRandomStats(1, 4, 1*time.Second)
+	cd, mockHandler, memoryStorage := newTestContainerData(t)
+	mockHandler.On("GetStats").Return(statsList[0], nil)
+	mockHandler.On("ListContainers", container.ListSelf).Return([]info.ContainerReference(nil), nil)
+	cd.housekeepingInterval = 10 * time.Millisecond
+	cd.pause = &housekeepingPause{}
+	numStats := func() int {
+		// No stats were ever added if the container is unknown to the storage.
+		stats, _ := memoryStorage.RecentStats(containerName, time.Time{}, time.Time{}, -1)
+		return len(stats)
+	}
+
+	// Housekeeping does not start while paused.
+	cd.pause.Pause()
+	require.Nil(t, cd.Start())
+	defer cd.Stop()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 0, numStats())
+
+	// Stats are collected once resumed.
+	cd.pause.Resume()
+	deadline := time.Now().Add(5 * time.Second)
+	for numStats() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("no stats collected after resuming housekeeping")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// No new stats are collected once paused again. Allow for a housekeeping
+	// in progress when pausing to finish.
+	cd.pause.Pause()
+	time.Sleep(50 * time.Millisecond)
+	paused := numStats()
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, paused, numStats())
+	assert.True(t, cd.pause.Paused())
+}
+
+// Storage driver signalling backpressure while the flag is set.
+type backpressureStorageDriver struct {
+	stest.MockStorageDriver
+	backpressure bool
+}
+
+func (self *backpressureStorageDriver) Backpressure() bool {
+	return self.backpressure
+}
+
+func TestBackpressureHousekeeping(t *testing.T) {
+	defer func(dynamic bool) { *allowDynamicHousekeeping = dynamic }(*allowDynamicHousekeeping)
+	*allowDynamicHousekeeping = false
+	backend := &backpressureStorageDriver{}
+	mockHandler := container.NewMockContainerHandler(containerName)
+	mockHandler.On("GetSpec").Return(itest.GenerateRandomContainerSpec(4), nil)
+	cd, err := newContainerData(containerName, memory.New(60, backend, nil), mockHandler, nil, false, 0)
+	require.Nil(t, err)
+	cd.housekeepingInterval = time.Second
+	now := time.Now()
+
+	assert.Equal(t, now.Add(time.Second), cd.nextHousekeeping(now))
+
+	// The interval is lengthened by the default factor while the backends fall
+	// behind.
+	backend.backpressure = true
+	assert.Equal(t, now.Add(2*time.Second), cd.nextHousekeeping(now))
+
+	backend.backpressure = false
+	assert.Equal(t, now.Add(time.Second), cd.nextHousekeeping(now))
+}
+
+func TestStatsFailureBackOff(t *testing.T) {
+	statsList := itest.GenerateRandomStats(1, 4, 1*time.Second)
+	cd, mockHandler, memoryStorage := newTestContainerData(t)
+	cd.statsFailureThreshold = 2
+	cd.statsProbeInterval = time.Hour
+	mockHandler.On("GetStats").Return((*info.ContainerStats)(nil), fmt.Errorf("cgroup disappeared")).Times(3)
+	mockHandler.On("GetStats").Return(statsList[0], nil)
+	mockHandler.On("Exists").Return(true)
+
+	// The container is degraded once the threshold is reached.
+	cd.housekeepingTick()
+	assert.False(t, cd.Degraded())
+	cd.housekeepingTick()
+	assert.True(t, cd.Degraded())
+
+	// Stats are not collected until the next probe.
+	cd.housekeepingTick()
+	cd.housekeepingTick()
+	mockHandler.AssertNumberOfCalls(t, "GetStats", 2)
+
+	// A failed probe keeps the container degraded.
+	cd.nextStatsProbe = time.Now()
+	cd.housekeepingTick()
+	mockHandler.AssertNumberOfCalls(t, "GetStats", 3)
+	assert.True(t, cd.Degraded())
+
+	// A successful probe recovers it.
+	cd.nextStatsProbe = time.Now()
+	cd.housekeepingTick()
+	mockHandler.AssertNumberOfCalls(t, "GetStats", 4)
+	assert.False(t, cd.Degraded())
+	checkNumStats(t, memoryStorage, 1)
+
+	cd.housekeepingTick()
+	mockHandler.AssertNumberOfCalls(t, "GetStats", 5)
+}
+
+func TestStatsTransforms(t *testing.T) {
+	defer func() {
+		statsTransformsLock.Lock()
+		defer statsTransformsLock.Unlock()
+		delete(statsTransforms, "test")
+	}()
+	RegisterStatsTransform("test", func(spec *info.ContainerSpec, prev, cur *info.ContainerStats) {
+		addDerivedMetric(cur, "has_prev", 0)
+		if prev != nil {
+			addDerivedMetric(cur, "has_prev", 1)
+		}
+	})
+	transforms, err := getStatsTransforms("test, utilization")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := getStatsTransforms("unknown"); err == nil {
+		t.Errorf("expected error for an unknown stats transform")
+	}
+
+	spec := info.ContainerSpec{
+		HasCpu:    true,
+		HasMemory: true,
+		Memory:    info.MemorySpec{Limit: 1000},
+	}
+	cd, mockHandler, _ := setupContainerData(t, spec)
+	cd.statsTransforms = transforms
+
+	start := time.Now()
+	first := &info.ContainerStats{Timestamp: start}
+	first.Memory.WorkingSet = 250
+	second := &info.ContainerStats{Timestamp: start.Add(time.Second)}
+	second.Cpu.Usage.Total = uint64(500 * time.Millisecond)
+	second.Memory.WorkingSet = 500
+	mockHandler.On("GetStats").Return(first, nil).Once()
+	mockHandler.On("GetStats").Return(second, nil).Once()
+
+	if err := cd.updateStats(); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]float64{"has_prev": 0, "memory_utilization": 0.25}
+	if !reflect.DeepEqual(first.DerivedMetrics, expected) {
+		t.Errorf("expected derived metrics %v, got %v", expected, first.DerivedMetrics)
+	}
+
+	if err := cd.updateStats(); err != nil {
+		t.Fatal(err)
+	}
+	expected = map[string]float64{"has_prev": 1, "memory_utilization": 0.5, "cpu_usage_percent": 50}
+	if !reflect.DeepEqual(second.DerivedMetrics, expected) {
+		t.Errorf("expected derived metrics %v, got %v", expected, second.DerivedMetrics)
+	}
+}
+
+func TestIoConstrained(t *testing.T) {
+	cd, mockHandler, _ := setupContainerData(t, info.ContainerSpec{HasDiskIo: true})
+	cd.ioConstrainedThreshold = 40
+
+	high := &info.ContainerStats{Timestamp: time.Now()}
+	high.DiskIo.HasPSI = true
+	high.DiskIo.PSI.Some = info.PSIData{Avg10: 5, Avg60: 55.5, Avg300: 20}
+	low := &info.ContainerStats{Timestamp: time.Now()}
+	low.DiskIo.HasPSI = true
+	low.DiskIo.PSI.Some = info.PSIData{Avg10: 90, Avg60: 12.25, Avg300: 3}
+	// Without PSI, e.g. on cgroup v1.
+	none := &info.ContainerStats{Timestamp: time.Now()}
+	mockHandler.On("GetStats").Return(high, nil).Once()
+	mockHandler.On("GetStats").Return(low, nil).Once()
+	mockHandler.On("GetStats").Return(none, nil).Once()
+
+	if err := cd.updateStats(); err != nil {
+		t.Fatal(err)
+	}
+	if constrained := high.DerivedMetrics["io_constrained"]; constrained != 1 {
+		t.Errorf("expected container to be IO-constrained at high pressure, got %v", constrained)
+	}
+
+	if err := cd.updateStats(); err != nil {
+		t.Fatal(err)
+	}
+	if constrained, ok := low.DerivedMetrics["io_constrained"]; !ok || constrained != 0 {
+		t.Errorf("expected container not to be IO-constrained at low pressure, got %v", low.DerivedMetrics)
+	}
+
+	if err := cd.updateStats(); err != nil {
+		t.Fatal(err)
+	}
+	if constrained, ok := none.DerivedMetrics["io_constrained"]; ok {
+		t.Errorf("expected no IO-constrained flag without PSI, got %v", constrained)
+	}
+}
+
+func TestAlignTimestamps(t *testing.T) {
+	cd, mockHandler, _ := setupContainerData(t, info.ContainerSpec{})
+	cd.alignTimestamps = true
+	defer func(interval time.Duration) { *HousekeepingInterval = interval }(*HousekeepingInterval)
+	*HousekeepingInterval = time.Second
+	// Timestamps are aligned to the configured interval rather than to the
+	// interval lengthened by dynamic housekeeping.
+	cd.housekeepingInterval = 4 * time.Second
+
+	start := time.Date(2015, time.March, 1, 10, 0, 0, 0, time.UTC)
+	collected := []time.Time{
+		start.Add(1400 * time.Millisecond),
+		start.Add(2600 * time.Millisecond),
+		// Rounds to the same boundary as the previous sample.
+		start.Add(3400 * time.Millisecond),
+	}
+	expected := []time.Time{
+		start.Add(1 * time.Second),
+		start.Add(3 * time.Second),
+		start.Add(4 * time.Second),
+	}
+	for i, collectionTime := range collected {
+		stats := &info.ContainerStats{Timestamp: collectionTime}
+		mockHandler.On("GetStats").Return(stats, nil).Once()
+		if err := cd.updateStats(); err != nil {
+			t.Fatal(err)
+		}
+		if !stats.Timestamp.Equal(expected[i]) {
+			t.Errorf("expected timestamp %v, got %v", expected[i], stats.Timestamp)
+		}
+		if stats.CollectionTime == nil || !stats.CollectionTime.Equal(collectionTime) {
+			t.Errorf("expected collection time %v, got %v", collectionTime, stats.CollectionTime)
+		}
+	}
+}
+
+func TestCpuUsageRate(t *testing.T) {
+	cd, mockHandler, _ := setupContainerData(t, info.ContainerSpec{})
+
+	start := time.Date(2015, time.March, 1, 10, 0, 0, 0, time.UTC)
+	samples := []*info.ContainerStats{
+		{
+			Timestamp: start,
+			Cpu:       info.CpuStats{Usage: info.CpuUsage{Total: 4000000000, User: 3000000000, System: 1000000000}},
+		}, {
+			Timestamp: start.Add(2 * time.Second),
+			Cpu:       info.CpuStats{Usage: info.CpuUsage{Total: 7000000000, User: 5500000000, System: 1500000000}},
+		}, {
+			// The usage went backwards, e.g. the cgroup was recreated.
+			Timestamp: start.Add(4 * time.Second),
+			Cpu:       info.CpuStats{Usage: info.CpuUsage{Total: 1000000000, User: 800000000, System: 200000000}},
+		},
+	}
+	for _, stats := range samples {
+		mockHandler.On("GetStats").Return(stats, nil).Once()
+		if err := cd.updateStats(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The first sample has no previous usage to derive a rate from.
+	if samples[0].Cpu.UsageRate != (info.CpuUsageRate{}) {
+		t.Errorf("expected no rate for the first sample, got %+v", samples[0].Cpu.UsageRate)
+	}
+	derivative := func(prev, cur uint64) uint64 {
+		elapsed := samples[1].Timestamp.Sub(samples[0].Timestamp).Seconds()
+		return uint64(float64(cur-prev) / 1e9 / elapsed * 1000)
+	}
+	prev, cur := samples[0].Cpu.Usage, samples[1].Cpu.Usage
+	expected := info.CpuUsageRate{
+		Total:  derivative(prev.Total, cur.Total),
+		User:   derivative(prev.User, cur.User),
+		System: derivative(prev.System, cur.System),
+	}
+	if expected.Total != 1500 {
+		t.Fatalf("expected a derivative of 1500 millicores, got %d", expected.Total)
+	}
+	if samples[1].Cpu.UsageRate != expected {
+		t.Errorf("expected rate %+v, got %+v", expected, samples[1].Cpu.UsageRate)
+	}
+	if samples[2].Cpu.UsageRate != (info.CpuUsageRate{}) {
+		t.Errorf("expected no rate when the usage went backwards, got %+v", samples[2].Cpu.UsageRate)
+	}
+}
+
+func TestPidTracking(t *testing.T) {
+	cgroupPath, err := ioutil.TempDir("", "cadvisor-pids")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cgroupPath)
+	setPids := func(pids string) {
+		if err := ioutil.WriteFile(filepath.Join(cgroupPath, "cgroup.procs"), []byte(pids), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cd, mockHandler, _ := setupContainerData(t, info.ContainerSpec{})
+	cd.trackPids = true
+	cd.pidMigrationThreshold = 3
+	eventHandler := events.NewEventManager(events.DefaultStoragePolicy(), 0)
+	cd.eventHandler = eventHandler
+	mockHandler.On("GetCgroupPath", "cpu").Return(cgroupPath, nil)
+
+	steps := []struct {
+		pids     string
+		expected info.ProcessStats
+	}{
+		{"1\n2\n3\n", info.ProcessStats{PidCount: 3}},
+		{"1\n2\n4\n", info.ProcessStats{PidCount: 3, PidsAdded: 1, PidsRemoved: 1}},
+		{"1\n5\n6\n7\n", info.ProcessStats{PidCount: 4, PidsAdded: 4, PidsRemoved: 3}},
+	}
+	for _, step := range steps {
+		setPids(step.pids)
+		stats := &info.ContainerStats{}
+		mockHandler.On("GetStats").Return(stats, nil).Once()
+		if err := cd.updateStats(); err != nil {
+			t.Fatal(err)
+		}
+		if stats.Processes != step.expected {
+			t.Errorf("expected process stats %+v, got %+v", step.expected, stats.Processes)
+		}
+	}
+
+	request := events.NewRequest()
+	request.EventType[info.EventPidMigration] = true
+	migrations, err := eventHandler.GetEvents(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the last housekeeping churned enough processes.
+	if len(migrations) != 1 {
+		t.Fatalf("expected 1 pid migration event, got %d", len(migrations))
+	}
+	expected := info.PidMigrationEventData{PidsAdded: 3, PidsRemoved: 2}
+	if *migrations[0].EventData.PidMigration != expected {
+		t.Errorf("expected pid migration %+v, got %+v", expected, *migrations[0].EventData.PidMigration)
+	}
+}
+
+func TestDnsStatsWithoutUnifiedCgroup(t *testing.T) {
+	cgroupPath, err := ioutil.TempDir("", "cadvisor-dns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cgroupPath)
+
+	// The probe identifies containers by their cgroup v2 cgroup, a cgroup v1
+	// one must not be looked up.
+	cd, mockHandler, _ := setupContainerData(t, info.ContainerSpec{})
+	cd.dnsReader = &dnsprobe.Reader{}
+	mockHandler.On("GetCgroupPath", mock.Anything).Return(cgroupPath, nil)
+	stats := &info.ContainerStats{}
+	mockHandler.On("GetStats").Return(stats, nil).Once()
+	if err := cd.updateStats(); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Dns != nil {
+		t.Errorf("expected no DNS stats, got %+v", *stats.Dns)
+	}
+}
+
+func TestMemoryPressureEvents(t *testing.T) {
+	spec := info.ContainerSpec{
+		HasMemory: true,
+		Memory:    info.MemorySpec{Limit: 1000},
+	}
+	cd, mockHandler, _ := setupContainerData(t, spec)
+	cd.memoryPressureThreshold = 0.9
+	cd.memoryPressureHysteresis = 0.05
+	eventHandler := events.NewEventManager(events.DefaultStoragePolicy(), 0)
+	cd.eventHandler = eventHandler
+
+	// The working set crosses the threshold, hovers around it, falls below
+	// the hysteresis and crosses the threshold again.
+	workingSets := []uint64{500, 900, 950, 880, 910, 840, 920}
+	for _, workingSet := range workingSets {
+		stats := &info.ContainerStats{Memory: info.MemoryStats{WorkingSet: workingSet}}
+		mockHandler.On("GetStats").Return(stats, nil).Once()
+		if err := cd.updateStats(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	request := events.NewRequest()
+	request.EventType[info.EventMemoryPressure] = true
+	pressures, err := eventHandler.GetEvents(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pressures) != 2 {
+		t.Fatalf("expected 2 memory pressure events, got %d", len(pressures))
+	}
+	for i, workingSet := range []uint64{900, 920} {
+		expected := info.MemoryPressureEventData{WorkingSet: workingSet, Limit: 1000}
+		if *pressures[i].EventData.MemoryPressure != expected {
+			t.Errorf("expected memory pressure %+v, got %+v", expected, *pressures[i].EventData.MemoryPressure)
+		}
+	}
+}
+
+func TestNoMemoryPressureWithoutLimit(t *testing.T) {
+	cd, mockHandler, _ := setupContainerData(t, info.ContainerSpec{})
+	cd.memoryPressureThreshold = 0.9
+	eventHandler := events.NewEventManager(events.DefaultStoragePolicy(), 0)
+	cd.eventHandler = eventHandler
+
+	mockHandler.On("GetStats").Return(&info.ContainerStats{Memory: info.MemoryStats{WorkingSet: 1 << 30}}, nil)
+	if err := cd.updateStats(); err != nil {
+		t.Fatal(err)
+	}
+	request := events.NewRequest()
+	request.EventType[info.EventMemoryPressure] = true
+	pressures, err := eventHandler.GetEvents(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pressures) != 0 {
+		t.Errorf("expected no memory pressure event without a memory limit, got %d", len(pressures))
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Delay between the creation event of a container and its first successful
+// sample, i.e. how quickly cAdvisor begins reporting new containers. Exported
+// for the HTTP handlers to register next to the Prometheus collector.
+var FirstSampleDelay = prometheus.NewHistogram(prometheus.HistogramOpts{
+	Name:    "cadvisor_container_first_sample_delay_seconds",
+	Help:    "Delay between the creation event of a container and its first successful stats sample in seconds.",
+	Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+})
+
+// Records the first successful sample of the container, taken at sampleTime.
+// Only containers that generated a creation event are recorded, pre-existing
+// containers have no meaningful delay.
+func (c *containerData) recordFirstSample(sampleTime time.Time) {
+	if !c.firstSampleTime.IsZero() || c.creationEventTime.IsZero() {
+		return
+	}
+	c.firstSampleTime = sampleTime
+	delay := sampleTime.Sub(c.creationEventTime)
+	if delay < 0 {
+		// The creation time may be read from a clock ahead of ours.
+		delay = 0
+	}
+	FirstSampleDelay.Observe(delay.Seconds())
+}
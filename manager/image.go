@@ -0,0 +1,219 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/cadvisor/container/docker"
+	info "github.com/google/cadvisor/info/v1"
+)
+
+// Shortest prefix of an image ID matched as an ID, as short IDs are displayed
+// by Docker.
+const minImageIdLength = 12
+
+// Returns whether a container runs the image. The image is matched by its ID
+// or a prefix of at least 12 characters of it, e.g. sha256:<hex>, by repository
+// and tag, e.g. redis:3.0, or by repository alone, e.g. redis, in which case
+// every tag matches. A reference such as redis@sha256:<hex> matches the
+// containers created from that same digest reference.
+func imageMatches(spec *info.ContainerSpec, image string) bool {
+	if spec.ImageID != "" {
+		id := strings.TrimPrefix(image, "sha256:")
+		if len(id) >= minImageIdLength && strings.HasPrefix(strings.TrimPrefix(spec.ImageID, "sha256:"), id) {
+			return true
+		}
+	}
+	if i := strings.Index(image, "@"); i >= 0 {
+		return spec.ImageRepoDigest == image[i+1:] && repositoryMatches(spec, image[:i])
+	}
+	if !repositoryMatches(spec, image) {
+		return false
+	}
+	tag, ok := imageTag(image)
+	if !ok {
+		return true
+	}
+	for _, repoTag := range spec.ImageRepoTags {
+		if t, ok := imageTag(repoTag); ok && t == tag && repositoryMatches(spec, repoTag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns whether an image reference names the repository of the image of the
+// container.
+func repositoryMatches(spec *info.ContainerSpec, ref string) bool {
+	if spec.ImageRepository == "" {
+		return false
+	}
+	registry, repository := docker.ParseImageReference(ref)
+	return registry == spec.ImageRegistry && repository == spec.ImageRepository
+}
+
+// Returns the tag of an image reference, whose ":" follows the last "/".
+func imageTag(ref string) (string, bool) {
+	i := strings.LastIndex(ref, ":")
+	if i < 0 || strings.Contains(ref[i:], "/") {
+		return "", false
+	}
+	return ref[i+1:], true
+}
+
+func (self *manager) GetStatsByImage(image string, query *info.ContainerInfoRequest) (*info.ImageStats, error) {
+	containers := self.getSubcontainers("/")
+	names := make([]string, 0, len(containers))
+	for name := range containers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ret := &info.ImageStats{
+		Image:      image,
+		Containers: []string{},
+	}
+	var samples [][]*info.ContainerStats
+	for _, name := range names {
+		cinfo, err := containers[name].GetInfo()
+		if err != nil {
+			return nil, err
+		}
+		// Containers without samples yet are left out.
+		if !imageMatches(&cinfo.Spec, image) || !self.memoryStorage.HasStats(cinfo.Name) {
+			continue
+		}
+		stats, err := self.memoryStorage.RecentStats(cinfo.Name, query.Start, query.End, query.NumStats)
+		if err != nil {
+			return nil, err
+		}
+		if len(stats) == 0 {
+			continue
+		}
+		ret.Containers = append(ret.Containers, cinfo.Name)
+		samples = append(samples, stats)
+	}
+	ret.NumContainers = len(ret.Containers)
+	ret.Stats = sumStats(samples, *HousekeepingInterval)
+	return ret, nil
+}
+
+// Sums the stats of containers, oldest first. The timestamps of the samples are
+// rounded to the housekeeping interval, and each sum is timestamped with one
+// of the rounded times and adds up the most recent sample of each container
+// at or before it. Containers sampled at different intervals are thus summed
+// at the same instants. Sums before every container has a sample are left out.
+func sumStats(samples [][]*info.ContainerStats, interval time.Duration) []*info.ContainerStats {
+	if len(samples) == 0 {
+		return nil
+	}
+	var times []time.Time
+	seen := make(map[time.Time]bool)
+	for _, stats := range samples {
+		for _, s := range stats {
+			t := s.Timestamp.Round(interval)
+			if !seen[t] {
+				seen[t] = true
+				times = append(times, t)
+			}
+		}
+	}
+	sort.Sort(timeSlice(times))
+
+	var ret []*info.ContainerStats
+	// Index in each container's samples of the first one after the current time.
+	next := make([]int, len(samples))
+	for _, t := range times {
+		sum := &info.ContainerStats{}
+		complete := true
+		for i, stats := range samples {
+			for next[i] < len(stats) && !stats[next[i]].Timestamp.Round(interval).After(t) {
+				next[i]++
+			}
+			if next[i] == 0 {
+				complete = false
+				break
+			}
+			addStats(sum, stats[next[i]-1])
+		}
+		if complete {
+			sum.Timestamp = t
+			ret = append(ret, sum)
+		}
+	}
+	return ret
+}
+
+type timeSlice []time.Time
+
+func (t timeSlice) Len() int           { return len(t) }
+func (t timeSlice) Less(i, j int) bool { return t[i].Before(t[j]) }
+func (t timeSlice) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+
+// Adds the CPU, memory, network and filesystem stats of a sample to a sum,
+// whose timestamp is left to the caller.
+// The filesystems of the containers are summed per device, their volumes are
+// left out.
+func addStats(sum *info.ContainerStats, stats *info.ContainerStats) {
+	sum.Cpu.Usage.Total += stats.Cpu.Usage.Total
+	sum.Cpu.Usage.User += stats.Cpu.Usage.User
+	sum.Cpu.Usage.System += stats.Cpu.Usage.System
+	for i, usage := range stats.Cpu.Usage.PerCpu {
+		if i == len(sum.Cpu.Usage.PerCpu) {
+			sum.Cpu.Usage.PerCpu = append(sum.Cpu.Usage.PerCpu, 0)
+		}
+		sum.Cpu.Usage.PerCpu[i] += usage
+	}
+
+	sum.Memory.Usage += stats.Memory.Usage
+	sum.Memory.WorkingSet += stats.Memory.WorkingSet
+	sum.Memory.Cache += stats.Memory.Cache
+	sum.Memory.RSS += stats.Memory.RSS
+	sum.Memory.MappedFile += stats.Memory.MappedFile
+	sum.Memory.Swap += stats.Memory.Swap
+
+	sum.Network.RxBytes += stats.Network.RxBytes
+	sum.Network.RxPackets += stats.Network.RxPackets
+	sum.Network.RxErrors += stats.Network.RxErrors
+	sum.Network.RxDropped += stats.Network.RxDropped
+	sum.Network.TxBytes += stats.Network.TxBytes
+	sum.Network.TxPackets += stats.Network.TxPackets
+	sum.Network.TxErrors += stats.Network.TxErrors
+	sum.Network.TxDropped += stats.Network.TxDropped
+
+	for _, fs := range stats.Filesystem {
+		if fs.Volume != "" {
+			continue
+		}
+		found := false
+		for i := range sum.Filesystem {
+			if sum.Filesystem[i].Device == fs.Device {
+				sum.Filesystem[i].Usage += fs.Usage
+				found = true
+				break
+			}
+		}
+		if !found {
+			sum.Filesystem = append(sum.Filesystem, info.FsStats{
+				Device: fs.Device,
+				Limit:  fs.Limit,
+				Usage:  fs.Usage,
+			})
+		}
+	}
+}
@@ -19,10 +19,13 @@ import (
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	dclient "github.com/fsouza/go-dockerclient"
 	"github.com/golang/glog"
@@ -40,9 +43,34 @@ var coreRegExp = regexp.MustCompile("core id\\t*: +([0-9]+)")
 var nodeRegExp = regexp.MustCompile("physical id\\t*: +([0-9]+)")
 var CpuClockSpeedMHz = regexp.MustCompile("cpu MHz\\t*: +([0-9]+.[0-9]+)")
 var memoryCapacityRegexp = regexp.MustCompile("MemTotal: *([0-9]+) kB")
+var bootTimeRegexp = regexp.MustCompile("(?m)^btime +([0-9]+)$")
 
 var machineIdFilePath = flag.String("machine_id_file", "/etc/machine-id,/var/lib/dbus/machine-id", "Comma-separated list of files to check for machine-id. Use the first one that exists.")
 var bootIdFilePath = flag.String("boot_id_file", "/proc/sys/kernel/random/boot_id", "Comma-separated list of files to check for boot-id. Use the first one that exists.")
+var machineIdOverride = flag.String("machine_id", "", "Machine ID to report instead of the one read from --machine_id_file.")
+var hostnameOverride = flag.String("hostname_override", "", "Hostname to report and tag stored stats with instead of the kernel hostname.")
+var machineInfoRefreshInterval = flag.Duration("machine_info_refresh_interval", 0, "Interval at which the machine info, e.g. its number of cores and memory capacity, is read again to pick up hot-added CPUs or memory. 0 only refreshes it on SIGHUP")
+
+// Files the CPUs and the memory of the machine are read from.
+var (
+	cpuInfoFile = "/proc/cpuinfo"
+	memInfoFile = "/proc/meminfo"
+)
+
+// Hostname returns the name this machine is known by, honoring --hostname_override.
+func Hostname() (string, error) {
+	if *hostnameOverride != "" {
+		return *hostnameOverride, nil
+	}
+	return os.Hostname()
+}
+
+func getMachineID() string {
+	if *machineIdOverride != "" {
+		return *machineIdOverride
+	}
+	return getInfoFromFiles(*machineIdFilePath)
+}
 
 func getClockSpeed(procInfo []byte) (uint64, error) {
 	// First look through sys to find a max supported cpu frequency.
@@ -86,6 +114,19 @@ func getMemoryCapacity(b []byte) (int64, error) {
 	return m * 1024, err
 }
 
+// Returns the boot time of the machine from the content of /proc/stat.
+func getBootTime(b []byte) (time.Time, error) {
+	matches := bootTimeRegexp.FindSubmatch(b)
+	if len(matches) != 2 {
+		return time.Time{}, fmt.Errorf("failed to find boot time in output: %q", string(b))
+	}
+	btime, err := strconv.ParseInt(string(matches[1]), 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(btime, 0).UTC(), nil
+}
+
 func extractValue(s string, r *regexp.Regexp) (bool, int, error) {
 	matches := r.FindSubmatch([]byte(s))
 	if len(matches) == 2 {
@@ -225,14 +266,14 @@ func getInfoFromFiles(filePaths string) string {
 }
 
 func getMachineInfo(sysFs sysfs.SysFs, fsInfo fs.FsInfo) (*info.MachineInfo, error) {
-	cpuinfo, err := ioutil.ReadFile("/proc/cpuinfo")
+	cpuinfo, err := ioutil.ReadFile(cpuInfoFile)
 	clockSpeed, err := getClockSpeed(cpuinfo)
 	if err != nil {
 		return nil, err
 	}
 
 	// Get the amount of usable memory from /proc/meminfo.
-	out, err := ioutil.ReadFile("/proc/meminfo")
+	out, err := ioutil.ReadFile(memInfoFile)
 	if err != nil {
 		return nil, err
 	}
@@ -267,6 +308,20 @@ func getMachineInfo(sysFs sysfs.SysFs, fsInfo fs.FsInfo) (*info.MachineInfo, err
 		glog.Errorf("Failed to get system UUID: %v", err)
 	}
 
+	hostname, err := Hostname()
+	if err != nil {
+		glog.Errorf("Failed to get hostname: %v", err)
+	}
+
+	var bootTime time.Time
+	procStat, err := ioutil.ReadFile("/proc/stat")
+	if err == nil {
+		bootTime, err = getBootTime(procStat)
+	}
+	if err != nil {
+		glog.Errorf("Failed to get boot time: %v", err)
+	}
+
 	machineInfo := &info.MachineInfo{
 		NumCores:       numCores,
 		CpuFrequency:   clockSpeed,
@@ -274,9 +329,11 @@ func getMachineInfo(sysFs sysfs.SysFs, fsInfo fs.FsInfo) (*info.MachineInfo, err
 		DiskMap:        diskMap,
 		NetworkDevices: netDevices,
 		Topology:       topology,
-		MachineID:      getInfoFromFiles(*machineIdFilePath),
+		MachineID:      getMachineID(),
+		Hostname:       hostname,
 		SystemUUID:     systemUUID,
 		BootID:         getInfoFromFiles(*bootIdFilePath),
+		BootTime:       bootTime,
 	}
 
 	for _, fs := range filesystems {
@@ -286,6 +343,52 @@ func getMachineInfo(sysFs sysfs.SysFs, fsInfo fs.FsInfo) (*info.MachineInfo, err
 	return machineInfo, nil
 }
 
+// Reads the machine info again and replaces the cached one, logging the
+// changes of the CPUs and memory of the machine. The cached machine info is
+// kept if no cores are found.
+func (m *manager) RefreshMachineInfo() error {
+	machineInfo, err := getMachineInfo(m.sysFs, m.fsInfo)
+	if err != nil {
+		return err
+	}
+	if machineInfo.NumCores < 1 {
+		return fmt.Errorf("no cores found")
+	}
+	m.machineInfoLock.Lock()
+	old := m.machineInfo
+	m.machineInfo = *machineInfo
+	m.machineInfoLock.Unlock()
+
+	if old.NumCores != machineInfo.NumCores {
+		glog.Infof("Number of cores of the machine changed from %d to %d", old.NumCores, machineInfo.NumCores)
+	}
+	if old.MemoryCapacity != machineInfo.MemoryCapacity {
+		glog.Infof("Memory capacity of the machine changed from %d to %d bytes", old.MemoryCapacity, machineInfo.MemoryCapacity)
+	}
+	if !reflect.DeepEqual(old.Topology, machineInfo.Topology) {
+		glog.Infof("Topology of the machine changed to %+v", machineInfo.Topology)
+	}
+	return nil
+}
+
+func (m *manager) machineInfoHousekeeping(interval time.Duration, quit chan error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := m.RefreshMachineInfo(); err != nil {
+				glog.Warningf("Failed to refresh machine info: %v", err)
+			}
+		case <-quit:
+			// Quit if asked to do so.
+			quit <- nil
+			glog.Infof("Exiting machine info housekeeping thread")
+			return
+		}
+	}
+}
+
 func getVersionInfo() (*info.VersionInfo, error) {
 
 	kernel_version := getKernelVersion()
@@ -0,0 +1,214 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"flag"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/utils"
+	"github.com/google/cadvisor/utils/procfs"
+	"github.com/google/cadvisor/utils/sysfs"
+	"github.com/google/cadvisor/utils/sysinfo"
+)
+
+var machineStatsInterval = flag.Duration("machine_stats_interval", 10*time.Second, "Interval between samples of the host-wide CPU, memory and network usage. 0 disables their collection")
+
+// Number of samples buffered for each watcher of the machine stats. Samples
+// are dropped for watchers falling further behind.
+const machineStatsWatchBuffer = 10
+
+// Periodically samples the host-wide usage of the machine, keeps the recent
+// samples and sends new ones to watchers.
+type machineStatsCollector struct {
+	sysFs sysfs.SysFs
+
+	// Read /proc/stat, /proc/meminfo, /proc/sys/kernel/random/entropy_avail
+	// and /proc/loadavg, replaced by fakes in tests.
+	getCpuTimes     func() (procfs.CpuTimes, error)
+	getMemInfo      func() (procfs.MemInfo, error)
+	getEntropyAvail func() (uint64, error)
+	getLoadAvg      func() (procfs.LoadAvg, error)
+
+	// CPU usage and time of the previous sample, from which the CPU usage
+	// rate is measured. Only used by collect().
+	prevCpu     info.CpuUsage
+	prevCpuTime time.Time
+
+	lock        sync.RWMutex
+	stats       *utils.TimedStore
+	watchers    map[int]chan *info.MachineStats
+	nextWatchId int
+}
+
+// Samples are kept for maxAge.
+func newMachineStatsCollector(sysFs sysfs.SysFs, maxAge time.Duration) *machineStatsCollector {
+	return &machineStatsCollector{
+		sysFs:           sysFs,
+		getCpuTimes:     procfs.GetCpuTimes,
+		getMemInfo:      procfs.GetMemInfo,
+		getEntropyAvail: procfs.GetEntropyAvail,
+		getLoadAvg:      procfs.GetLoadAvg,
+		stats:           utils.NewTimedStore(maxAge, -1),
+		watchers:        make(map[int]chan *info.MachineStats),
+	}
+}
+
+func (self *machineStatsCollector) sample() (*info.MachineStats, error) {
+	cpu, err := self.getCpuTimes()
+	if err != nil {
+		return nil, err
+	}
+	mem, err := self.getMemInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &info.MachineStats{
+		Timestamp: time.Now(),
+	}
+	stats.Cpu.User = uint64(cpu.User + cpu.Nice)
+	stats.Cpu.System = uint64(cpu.System + cpu.Irq + cpu.Softirq)
+	stats.Cpu.Total = stats.Cpu.User + stats.Cpu.System
+	stats.Cpu.Steal = uint64(cpu.Steal)
+
+	stats.Memory.Total = mem.Total
+	stats.Memory.Free = mem.Free
+	stats.Memory.Buffers = mem.Buffers
+	stats.Memory.Cached = mem.Cached
+	stats.Memory.SlabReclaimable = mem.SReclaimable
+	stats.Memory.SwapTotal = mem.SwapTotal
+	stats.Memory.SwapFree = mem.SwapFree
+	// Kernels not reporting the available memory count the page cache and
+	// buffers as available.
+	stats.Memory.Available = mem.Available
+	if !mem.HasAvailable {
+		stats.Memory.Available = mem.Free + mem.Buffers + mem.Cached
+	}
+	if stats.Memory.Available < stats.Memory.Total {
+		stats.Memory.Usage = stats.Memory.Total - stats.Memory.Available
+	}
+
+	// The network is not required for the CPU and memory usage to be useful.
+	stats.Network, err = sysinfo.GetMachineNetworkStats(self.sysFs)
+	if err != nil {
+		glog.V(4).Infof("Failed to get machine network stats: %v", err)
+	}
+	stats.AvailableEntropy, err = self.getEntropyAvail()
+	if err != nil {
+		glog.V(4).Infof("Failed to get available entropy: %v", err)
+	}
+	loadAvg, err := self.getLoadAvg()
+	if err != nil {
+		glog.V(4).Infof("Failed to get load average: %v", err)
+	} else {
+		stats.Load = info.MachineLoadStats{
+			LoadAverage1:  loadAvg.Load1,
+			LoadAverage5:  loadAvg.Load5,
+			LoadAverage15: loadAvg.Load15,
+			RunningTasks:  loadAvg.RunningTasks,
+			TotalTasks:    loadAvg.TotalTasks,
+		}
+	}
+	return stats, nil
+}
+
+// Takes a sample and sends it to the watchers.
+func (self *machineStatsCollector) collect() {
+	stats, err := self.sample()
+	if err != nil {
+		glog.Errorf("Failed to sample machine stats: %v", err)
+		return
+	}
+	cpu := info.CpuUsage{Total: stats.Cpu.Total, User: stats.Cpu.User, System: stats.Cpu.System}
+	stats.Cpu.UsageRate = cpuUsageRate(self.prevCpu, self.prevCpuTime, cpu, stats.Timestamp)
+	self.prevCpu, self.prevCpuTime = cpu, stats.Timestamp
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.stats.Add(stats.Timestamp, stats)
+	for id, watcher := range self.watchers {
+		select {
+		case watcher <- stats:
+		default:
+			glog.V(4).Infof("Dropped machine stats for slow watcher %d", id)
+		}
+	}
+}
+
+func (self *machineStatsCollector) housekeeping(interval time.Duration, quit chan error) {
+	self.collect()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			self.collect()
+		case <-quit:
+			// Quit if asked to do so.
+			quit <- nil
+			glog.Infof("Exiting machine stats housekeeping thread")
+			return
+		}
+	}
+}
+
+// Returns the numStats most recent samples, oldest first. Returns all the
+// samples kept if numStats is negative.
+func (self *machineStatsCollector) recentStats(numStats int) []*info.MachineStats {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	elements := self.stats.InTimeRange(time.Time{}, time.Time{}, numStats)
+	ret := make([]*info.MachineStats, 0, len(elements))
+	for _, element := range elements {
+		ret = append(ret, element.(*info.MachineStats))
+	}
+	return ret
+}
+
+// Returns a channel receiving the samples taken from now on, along with the
+// id of the watch.
+func (self *machineStatsCollector) watch() (int, <-chan *info.MachineStats) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	id := self.nextWatchId
+	self.nextWatchId++
+	watcher := make(chan *info.MachineStats, machineStatsWatchBuffer)
+	self.watchers[id] = watcher
+	return id, watcher
+}
+
+// Closes the channel of the specified watch.
+func (self *machineStatsCollector) stopWatch(id int) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if watcher, ok := self.watchers[id]; ok {
+		close(watcher)
+		delete(self.watchers, id)
+	}
+}
+
+// Closes the channels of all the watches.
+func (self *machineStatsCollector) stopAllWatches() {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	for id, watcher := range self.watchers {
+		close(watcher)
+		delete(self.watchers, id)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/utils/procfs"
+	"github.com/google/cadvisor/utils/sysfs/fakesysfs"
+)
+
+func newFakeMachineStatsCollector(cpu procfs.CpuTimes, mem procfs.MemInfo) *machineStatsCollector {
+	collector := newMachineStatsCollector(&fakesysfs.FakeSysFs{}, time.Minute)
+	collector.getCpuTimes = func() (procfs.CpuTimes, error) { return cpu, nil }
+	collector.getMemInfo = func() (procfs.MemInfo, error) { return mem, nil }
+	collector.getEntropyAvail = func() (uint64, error) { return 3754, nil }
+	collector.getLoadAvg = func() (procfs.LoadAvg, error) {
+		return procfs.LoadAvg{Load1: 0.5, Load5: 1.5, Load15: 2.5, RunningTasks: 3, TotalTasks: 816}, nil
+	}
+	return collector
+}
+
+func TestMachineStatsSample(t *testing.T) {
+	collector := newFakeMachineStatsCollector(
+		procfs.CpuTimes{User: 10, Nice: 1, System: 20, Idle: 1000, Iowait: 50, Irq: 2, Softirq: 3, Steal: 4},
+		procfs.MemInfo{Total: 1000, Free: 100, Available: 600, Buffers: 50, Cached: 300, SwapTotal: 200, SwapFree: 150, SReclaimable: 20, HasAvailable: true},
+	)
+	stats, err := collector.sample()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Cpu.User != 11 || stats.Cpu.System != 25 || stats.Cpu.Total != 36 || stats.Cpu.Steal != 4 {
+		t.Errorf("expected user 11, system 25, total 36 and steal 4, got %+v", stats.Cpu)
+	}
+	if stats.Memory.Total != 1000 || stats.Memory.Available != 600 || stats.Memory.Usage != 400 || stats.Memory.Free != 100 {
+		t.Errorf("unexpected memory stats %+v", stats.Memory)
+	}
+	if stats.Memory.Buffers != 50 || stats.Memory.Cached != 300 || stats.Memory.SlabReclaimable != 20 || stats.Memory.SwapTotal != 200 || stats.Memory.SwapFree != 150 {
+		t.Errorf("unexpected memory breakdown %+v", stats.Memory)
+	}
+	if len(stats.Network) != 1 {
+		t.Errorf("expected the network stats of the fake interface, got %+v", stats.Network)
+	}
+	if stats.AvailableEntropy != 3754 {
+		t.Errorf("expected 3754 bits of entropy available, got %d", stats.AvailableEntropy)
+	}
+	expectedLoad := info.MachineLoadStats{LoadAverage1: 0.5, LoadAverage5: 1.5, LoadAverage15: 2.5, RunningTasks: 3, TotalTasks: 816}
+	if stats.Load != expectedLoad {
+		t.Errorf("expected load %+v, got %+v", expectedLoad, stats.Load)
+	}
+
+	// Without MemAvailable the page cache and buffers count as available.
+	collector = newFakeMachineStatsCollector(
+		procfs.CpuTimes{},
+		procfs.MemInfo{Total: 1000, Free: 100, Buffers: 50, Cached: 300},
+	)
+	stats, err = collector.sample()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Memory.Available != 450 || stats.Memory.Usage != 550 {
+		t.Errorf("expected 450 bytes available and 550 used, got %+v", stats.Memory)
+	}
+}
+
+func TestMachineStatsCpuUsageRate(t *testing.T) {
+	collector := newFakeMachineStatsCollector(procfs.CpuTimes{}, procfs.MemInfo{Total: 1000})
+	var user time.Duration
+	collector.getCpuTimes = func() (procfs.CpuTimes, error) {
+		user += time.Second
+		return procfs.CpuTimes{User: user}, nil
+	}
+	collector.collect()
+	collector.collect()
+	stats := collector.recentStats(-1)
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 samples, got %d", len(stats))
+	}
+	if stats[0].Cpu.UsageRate != (info.CpuUsageRate{}) {
+		t.Errorf("expected no rate for the first sample, got %+v", stats[0].Cpu.UsageRate)
+	}
+	if rate := stats[1].Cpu.UsageRate; rate.Total == 0 || rate.User != rate.Total {
+		t.Errorf("expected a user CPU usage rate, got %+v", rate)
+	}
+}
+
+func TestMachineStatsWatch(t *testing.T) {
+	collector := newFakeMachineStatsCollector(procfs.CpuTimes{User: 10}, procfs.MemInfo{Total: 1000})
+	collector.collect()
+
+	id, watcher := collector.watch()
+	collector.collect()
+	collector.collect()
+	if stats := collector.recentStats(-1); len(stats) != 3 {
+		t.Errorf("expected 3 samples kept, got %d", len(stats))
+	}
+	if stats := collector.recentStats(1); len(stats) != 1 {
+		t.Errorf("expected the most recent sample, got %d", len(stats))
+	}
+
+	// Only the samples taken once watching are received.
+	for i := 0; i < 2; i++ {
+		if stats := <-watcher; stats == nil || stats.Cpu.User != 10 {
+			t.Errorf("expected a sample, got %+v", stats)
+		}
+	}
+	select {
+	case stats := <-watcher:
+		t.Errorf("expected no more samples, got %+v", stats)
+	default:
+	}
+
+	// Samples are dropped rather than blocking on slow watchers.
+	for i := 0; i < machineStatsWatchBuffer+5; i++ {
+		collector.collect()
+	}
+	if len(watcher) != machineStatsWatchBuffer {
+		t.Errorf("expected %d buffered samples, got %d", machineStatsWatchBuffer, len(watcher))
+	}
+
+	collector.stopWatch(id)
+	for range watcher {
+	}
+	// Stopping twice is harmless.
+	collector.stopWatch(id)
+}
@@ -0,0 +1,163 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/cadvisor/fs"
+	"github.com/google/cadvisor/utils/sysfs"
+	"github.com/google/cadvisor/utils/sysfs/fakesysfs"
+)
+
+func TestHostnameOverride(t *testing.T) {
+	defer func(old string) { *hostnameOverride = old }(*hostnameOverride)
+
+	*hostnameOverride = ""
+	expected, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostname, err := Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hostname != expected {
+		t.Errorf("expected hostname %q, got %q", expected, hostname)
+	}
+
+	// Storage backends are tagged with the result of Hostname().
+	*hostnameOverride = "node-from-metadata"
+	hostname, err = Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hostname != "node-from-metadata" {
+		t.Errorf("expected overridden hostname %q, got %q", "node-from-metadata", hostname)
+	}
+}
+
+func TestMachineIdOverride(t *testing.T) {
+	defer func(old string) { *machineIdOverride = old }(*machineIdOverride)
+	defer func(old string) { *machineIdFilePath = old }(*machineIdFilePath)
+
+	*machineIdFilePath = "testdata/machine-id"
+	*machineIdOverride = ""
+	if id := getMachineID(); id != "0123456789abcdef0123456789abcdef" {
+		t.Errorf("expected machine ID from file, got %q", id)
+	}
+
+	*machineIdOverride = "cloud-instance-id"
+	if id := getMachineID(); id != "cloud-instance-id" {
+		t.Errorf("expected overridden machine ID %q, got %q", "cloud-instance-id", id)
+	}
+}
+
+func TestGetBootTime(t *testing.T) {
+	procStat := []byte("cpu  1 2 3 4\nintr 12345\nctxt 67890\nbtime 1420070400\nprocesses 42\n")
+	bootTime, err := getBootTime(procStat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := time.Date(2015, time.January, 1, 0, 0, 0, 0, time.UTC); !bootTime.Equal(expected) {
+		t.Errorf("expected boot time %v, got %v", expected, bootTime)
+	}
+
+	if _, err := getBootTime([]byte("cpu  1 2 3 4\n")); err == nil {
+		t.Errorf("expected error when the boot time is missing")
+	}
+}
+
+// Serves the global filesystems of the machine, which are not of interest.
+type fakeFsInfo struct {
+	fs.FsInfo
+}
+
+func (self fakeFsInfo) GetGlobalFsInfo() ([]fs.Fs, error) {
+	return nil, nil
+}
+
+func TestRefreshMachineInfo(t *testing.T) {
+	defer func(cpuInfo, memInfo string) {
+		cpuInfoFile, memInfoFile = cpuInfo, memInfo
+	}(cpuInfoFile, memInfoFile)
+	dir, err := ioutil.TempDir("", "machine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cpuInfoFile = filepath.Join(dir, "cpuinfo")
+	memInfoFile = filepath.Join(dir, "meminfo")
+	writeMachine := func(numCores int, memoryKb int) {
+		var cpuinfo string
+		for i := 0; i < numCores; i++ {
+			cpuinfo += fmt.Sprintf("processor\t: %d\ncpu MHz\t\t: 2400.000\nphysical id\t: 0\ncore id\t\t: %d\n\n", i, i)
+		}
+		if err := ioutil.WriteFile(cpuInfoFile, []byte(cpuinfo), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(memInfoFile, []byte(fmt.Sprintf("MemTotal:       %d kB\n", memoryKb)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sysFs := &fakesysfs.FakeSysFs{}
+	sysFs.SetCacheInfo(sysfs.CacheInfo{Size: 32 * 1024, Type: "Data", Level: 1, Cpus: 1})
+	m := &manager{sysFs: sysFs, fsInfo: fakeFsInfo{}}
+
+	writeMachine(2, 1024)
+	if err := m.RefreshMachineInfo(); err != nil {
+		t.Fatal(err)
+	}
+	machineInfo, err := m.GetMachineInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if machineInfo.NumCores != 2 || machineInfo.MemoryCapacity != 1024*1024 || len(machineInfo.Topology[0].Cores) != 2 {
+		t.Errorf("expected 2 cores and 1MiB of memory, got %+v", machineInfo)
+	}
+
+	// CPUs and memory are hot-added.
+	writeMachine(4, 2048)
+	if err := m.RefreshMachineInfo(); err != nil {
+		t.Fatal(err)
+	}
+	if machineInfo.NumCores != 2 {
+		t.Errorf("expected the machine info returned earlier to be left untouched, got %d cores", machineInfo.NumCores)
+	}
+	machineInfo, err = m.GetMachineInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if machineInfo.NumCores != 4 || machineInfo.MemoryCapacity != 2048*1024 || len(machineInfo.Topology[0].Cores) != 4 {
+		t.Errorf("expected 4 cores and 2MiB of memory, got %+v", machineInfo)
+	}
+
+	// The cached machine info is kept when the cores cannot be read.
+	if err := ioutil.WriteFile(cpuInfoFile, []byte("cpu MHz\t\t: 2400.000\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RefreshMachineInfo(); err == nil {
+		t.Errorf("expected an error without cores")
+	}
+	if machineInfo, _ := m.GetMachineInfo(); machineInfo.NumCores != 4 {
+		t.Errorf("expected 4 cores to be kept, got %d", machineInfo.NumCores)
+	}
+}
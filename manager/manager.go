@@ -16,10 +16,13 @@
 package manager
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"path"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -28,20 +31,34 @@ import (
 	"github.com/golang/glog"
 	"github.com/google/cadvisor/container"
 	"github.com/google/cadvisor/container/docker"
+	containerLibcontainer "github.com/google/cadvisor/container/libcontainer"
 	"github.com/google/cadvisor/container/raw"
+	sharedcontainer "github.com/google/cadvisor/container/shared"
 	"github.com/google/cadvisor/events"
 	"github.com/google/cadvisor/fs"
 	info "github.com/google/cadvisor/info/v1"
 	"github.com/google/cadvisor/info/v2"
 	"github.com/google/cadvisor/storage/memory"
+	"github.com/google/cadvisor/storage/shared"
 	"github.com/google/cadvisor/utils/cpuload"
+	"github.com/google/cadvisor/utils/dnsprobe"
 	"github.com/google/cadvisor/utils/oomparser"
 	"github.com/google/cadvisor/utils/sysfs"
+	"github.com/google/cadvisor/utils/sysinfo"
 )
 
 var globalHousekeepingInterval = flag.Duration("global_housekeeping_interval", 1*time.Minute, "Interval between global housekeepings")
+var containerPollInterval = flag.Duration("container_poll_interval", 5*time.Second, "Interval between detections of new containers by listing their cgroups when the cgroups cannot be watched, e.g. because the inotify watches are exhausted")
 var logCadvisorUsage = flag.Bool("log_cadvisor_usage", false, "Whether to log the usage of the cAdvisor container")
 var enableLoadReader = flag.Bool("enable_load_reader", false, "Whether to enable cpu load reader")
+var eventDedupWindow = flag.Duration("event_dedup_window", 0, "Window within which events of the same type in the same container are collapsed into the first one, e.g. the creation events of a container in a restart loop. 0 disables deduplication")
+var memoryStatsHierarchical = flag.Bool("memory_stats_hierarchical", true, "Whether the memory cache, RSS, mapped file, swap and paging stats of cgroup v1 containers include those of their descendant cgroups. cgroup v2 stats are always hierarchical")
+var enableNetworkProtocolStats = flag.Bool("enable_network_protocol_stats", false, "Whether to report the IPv4 and IPv6 traffic of the network namespace of containers separately. Ignored on hosts without IPv6")
+var enableSriovStats = flag.Bool("enable_sriov_stats", false, "Whether to report the network stats of the SR-IOV virtual functions assigned to containers. Ignored on hosts without SR-IOV virtual functions")
+var enableDnsStats = flag.Bool("enable_dns_stats", false, "Whether to report the DNS queries of containers counted by the eBPF probe whose map is pinned at dns_stats_map. Ignored when eBPF or the probe is not available")
+var dnsStatsMap = flag.String("dns_stats_map", "/sys/fs/bpf/cadvisor/dns_stats", "Path of the map pinned by the eBPF probe counting the DNS queries of containers")
+var factoryPriorities = flag.String("container_factory_priorities", "", "Comma-separated list of <factory>=<priority> overriding the priority of container factories, e.g. raw=20. Factories with a higher priority are asked first whether they handle a container. The defaults are docker=10 and raw=0")
+var storageDurationOverrides = flag.String("container_storage_duration", "", "Semicolon-separated list of <regexp>=<duration> overriding --storage_duration for the containers whose name or alias matches the regexp, e.g. ^/docker/=10m;^/batch/job-[0-9]{1,3}$=1m. The first match is used")
 
 // The Manager interface defines operations for starting a manager and getting
 // container and machine information.
@@ -52,9 +69,42 @@ type Manager interface {
 	// Stops the manager.
 	Stop() error
 
+	// Stops the manager and the housekeeping of all containers, closes all
+	// event watches and closes the storage driver. Returns once done or when
+	// ctx expires, in which case the shutdown goes on in the background.
+	// Later calls only wait for the first shutdown.
+	Shutdown(ctx context.Context) error
+
+	// Pauses the collection of stats of all containers. Stats collected so far
+	// are still served.
+	Pause() error
+
+	// Resumes the collection of stats paused by Pause().
+	Resume() error
+
+	// Returns whether the collection of stats is paused.
+	Paused() bool
+
+	// Returns the names of the containers whose stats repeatedly failed to be
+	// collected, sorted.
+	DegradedContainers() []string
+
 	// Get information about a container.
 	GetContainerInfo(containerName string, query *info.ContainerInfoRequest) (*info.ContainerInfo, error)
 
+	// Get the spec and the most recent stats of the specified containers, or
+	// of all the containers if none is specified, sorted by name.
+	GetContainerStatus(containerNames []string) ([]info.ContainerStatus, error)
+
+	// Get the span of the stats retained in memory for the specified
+	// containers, or for all the containers if none is specified, sorted by
+	// name.
+	GetStatsRetention(containerNames []string) ([]info.ContainerRetention, error)
+
+	// Get the stats summed across the containers running the image, matched by
+	// ID, by repository and tag or by repository alone.
+	GetStatsByImage(image string, query *info.ContainerInfoRequest) (*info.ImageStats, error)
+
 	// Get information about all subcontainers of the specified container (includes self).
 	SubcontainersInfo(containerName string, query *info.ContainerInfoRequest) ([]*info.ContainerInfo, error)
 
@@ -73,12 +123,48 @@ type Manager interface {
 	// Get info for all requested containers based on the request options.
 	GetRequestedContainersInfo(containerName string, options v2.RequestOptions) (map[string]*info.ContainerInfo, error)
 
+	// Get the hierarchy of containers rooted at the specified container. Levels
+	// below maxDepth are left out, a negative maxDepth returns the whole tree.
+	GetContainerTree(containerName string, maxDepth int) (*v2.ContainerTree, error)
+
+	// Get the tree of the processes in the container, rooted at its init
+	// process.
+	GetProcessTree(containerName string) (*v2.ProcessTree, error)
+
+	// Get the containers, selected by the request options, whose most recent
+	// value of the usage metric exceeds the threshold. Returns a map from
+	// container name to the value of the metric.
+	GetContainersAboveThreshold(containerName string, metric string, threshold float64, options v2.RequestOptions) (map[string]float64, error)
+
 	// Returns true if the named container exists.
 	Exists(containerName string) bool
 
 	// Get information about the machine.
 	GetMachineInfo() (*info.MachineInfo, error)
 
+	// Read the information about the machine again, e.g. after CPUs or memory
+	// were hot-added.
+	RefreshMachineInfo() error
+
+	// Get the network statistics of the physical network interfaces of the machine.
+	GetMachineNetworkStats() ([]info.InterfaceStats, error)
+
+	// Get the most recent samples of the host-wide usage of the machine, oldest
+	// first. Returns all the samples kept if numStats is negative.
+	GetMachineStats(numStats int) ([]*info.MachineStats, error)
+
+	// Get the samples of the host-wide usage of the machine taken from now on
+	// through the returned channel, until the watch with the returned id is
+	// closed by CloseMachineStatsChannel.
+	WatchMachineStats() (int, <-chan *info.MachineStats, error)
+
+	// Close a watch of the host-wide usage of the machine, closing its channel.
+	CloseMachineStatsChannel(watchId int)
+
+	// Returns whether the storage backends fall behind, in which case the
+	// housekeeping of the containers may be slowed down.
+	CollectionBackpressure() bool
+
 	// Get version information about different components we depend on.
 	GetVersionInfo() (*info.VersionInfo, error)
 
@@ -93,13 +179,88 @@ type Manager interface {
 	GetPastEvents(request *events.Request) ([]*info.Event, error)
 
 	CloseEventChannel(watch_id int)
+
+	// Get the limits on the events kept.
+	GetEventStoragePolicy() events.StoragePolicy
+
+	// Replace the limits on the events kept, removing the events past them.
+	SetEventStoragePolicy(policy events.StoragePolicy) error
 }
 
-// New takes a memory storage and returns a new manager.
-func New(memoryStorage *memory.InMemoryStorage, sysfs sysfs.SysFs) (Manager, error) {
+// Options of the manager. The zero value disables all of them.
+type Options struct {
+	// Each container housekeeping is randomly moved by up to this factor of
+	// the housekeeping interval, in [0, 1). 0 disables the jitter.
+	MaxHousekeepingJitter float64
+
+	// Containers are only monitored once they are this old. 0 monitors them
+	// as soon as they are seen.
+	MinContainerAge time.Duration
+
+	// Containers found within this duration of the start of the manager are
+	// taken as pre-existing and generate no creation event.
+	EventWarmup time.Duration
+}
+
+// New takes a memory storage and returns a new manager configured by options.
+func New(memoryStorage *memory.InMemoryStorage, sysfs sysfs.SysFs, options Options) (Manager, error) {
+	priorities, err := parseFactoryPriorities(*factoryPriorities)
+	if err != nil {
+		return nil, err
+	}
+	newManager, err := newManager(memoryStorage, sysfs, options)
+	if err != nil {
+		return nil, err
+	}
+
+	// Register Docker container factory.
+	err = docker.Register(newManager, newManager.fsInfo, priorities[docker.DockerNamespace])
+	if err != nil {
+		glog.Errorf("Docker container factory registration failed: %v.", err)
+	}
+
+	// Register the raw driver.
+	err = raw.Register(newManager, newManager.fsInfo, priorities["raw"])
+	if err != nil {
+		glog.Errorf("Registration of the raw container factory failed: %v", err)
+	}
+
+	return newManager, nil
+}
+
+// NewReadOnly takes a memory storage and returns a new manager serving the
+// containers another cAdvisor instance writes to the shared store, without
+// collecting any stats itself. Machine information is read from the local
+// machine.
+func NewReadOnly(memoryStorage *memory.InMemoryStorage, sysfs sysfs.SysFs, store *shared.Store) (Manager, error) {
+	if store == nil {
+		return nil, fmt.Errorf("read-only manager requires a shared store")
+	}
+	newManager, err := newManager(memoryStorage, sysfs, Options{})
+	if err != nil {
+		return nil, err
+	}
+	newManager.readOnly = true
+	// The host-wide usage is only collected by the primary instance.
+	newManager.machineStats = nil
+	sharedcontainer.Register(store)
+	return newManager, nil
+}
+
+// Creates a manager without registering any container factory.
+func newManager(memoryStorage *memory.InMemoryStorage, sysfs sysfs.SysFs, options Options) (*manager, error) {
 	if memoryStorage == nil {
 		return nil, fmt.Errorf("manager requires memory storage")
 	}
+	if options.MaxHousekeepingJitter < 0 || options.MaxHousekeepingJitter >= 1 {
+		return nil, fmt.Errorf("housekeeping jitter must be in [0, 1), got %v", options.MaxHousekeepingJitter)
+	}
+	if options.MinContainerAge < 0 {
+		return nil, fmt.Errorf("minimum container age must not be negative, got %v", options.MinContainerAge)
+	}
+	if options.EventWarmup < 0 {
+		return nil, fmt.Errorf("event warmup must not be negative, got %v", options.EventWarmup)
+	}
 
 	// Detect the container we are running on.
 	selfContainer, err := cgroups.GetThisCgroupDir("cpu")
@@ -113,6 +274,34 @@ func New(memoryStorage *memory.InMemoryStorage, sysfs sysfs.SysFs) (Manager, err
 	if err != nil {
 		return nil, err
 	}
+	durationOverrides, err := parseStorageDurationOverrides(*storageDurationOverrides)
+	if err != nil {
+		return nil, err
+	}
+	transforms, err := getStatsTransforms(*statsTransformsFlag)
+	if err != nil {
+		return nil, err
+	}
+	aliasPreference, err := parseAliasPreference(*aliasPreferenceFlag)
+	if err != nil {
+		return nil, err
+	}
+	normalizer, err := newNameNormalizer(*nameStripPrefix, *nameLowercase, *nameReplace)
+	if err != nil {
+		return nil, err
+	}
+
+	// Containers sharing a network namespace read its statistics once per
+	// housekeeping.
+	containerLibcontainer.SetNetnsStatsMaxAge(*HousekeepingInterval)
+	containerLibcontainer.SetMemoryStatsHierarchical(*memoryStatsHierarchical)
+	if *enableNetworkProtocolStats {
+		containerLibcontainer.EnableNetworkProtocolStats()
+	}
+	if *enableSriovStats && !containerLibcontainer.EnableSriovStats() {
+		glog.Infof("No SR-IOV virtual functions found, not reporting their network stats")
+	}
+
 	newManager := &manager{
 		containers:        make(map[namespacedContainerName]*containerData),
 		quitChannels:      make([]chan error, 0, 2),
@@ -120,6 +309,27 @@ func New(memoryStorage *memory.InMemoryStorage, sysfs sysfs.SysFs) (Manager, err
 		fsInfo:            fsInfo,
 		cadvisorContainer: selfContainer,
 		startupTime:       time.Now(),
+		sysFs:             sysfs,
+
+		storageDurationOverrides: durationOverrides,
+		maxHousekeepingJitter:    options.MaxHousekeepingJitter,
+		housekeepingPause:        &housekeepingPause{},
+		minContainerAge:          options.MinContainerAge,
+		delayedContainers:        make(map[string]*time.Timer),
+		eventWarmup:              options.EventWarmup,
+		pollContainers:           make(chan struct{}, 1),
+		statsTransforms:          transforms,
+		aliasPreference:          aliasPreference,
+		nameNormalizer:           normalizer,
+	}
+	if *enableDnsStats {
+		newManager.dnsReader, err = dnsprobe.New(*dnsStatsMap)
+		if err != nil {
+			glog.Infof("Not reporting DNS stats: %v", err)
+		}
+	}
+	if *machineStatsInterval > 0 {
+		newManager.machineStats = newMachineStatsCollector(sysfs, memoryStorage.MaxAge())
 	}
 
 	machineInfo, err := getMachineInfo(sysfs, fsInfo)
@@ -136,20 +346,7 @@ func New(memoryStorage *memory.InMemoryStorage, sysfs sysfs.SysFs) (Manager, err
 	newManager.versionInfo = *versionInfo
 	glog.Infof("Version: %+v", newManager.versionInfo)
 
-	// TODO(vmarmol): Make configurable.
-	newManager.eventHandler = events.NewEventManager(24 * time.Hour)
-
-	// Register Docker container factory.
-	err = docker.Register(newManager, fsInfo)
-	if err != nil {
-		glog.Errorf("Docker container factory registration failed: %v.", err)
-	}
-
-	// Register the raw driver.
-	err = raw.Register(newManager, fsInfo)
-	if err != nil {
-		glog.Errorf("Registration of the raw container factory failed: %v", err)
-	}
+	newManager.eventHandler = events.NewEventManager(events.DefaultStoragePolicy(), *eventDedupWindow)
 
 	return newManager, nil
 }
@@ -169,6 +366,7 @@ type manager struct {
 	memoryStorage          *memory.InMemoryStorage
 	fsInfo                 fs.FsInfo
 	machineInfo            info.MachineInfo
+	machineInfoLock        sync.RWMutex
 	versionInfo            info.VersionInfo
 	quitChannels           []chan error
 	cadvisorContainer      string
@@ -176,12 +374,145 @@ type manager struct {
 	loadReader             cpuload.CpuLoadReader
 	eventHandler           events.EventManager
 	startupTime            time.Time
+	sysFs                  sysfs.SysFs
+
+	// Overrides of how long stats are kept in memory for matching containers.
+	storageDurationOverrides []storageDurationOverride
+
+	// Largest fraction of the housekeeping interval by which container
+	// housekeepings are randomly moved.
+	maxHousekeepingJitter float64
+
+	// Shared by all containers to pause their housekeeping.
+	housekeepingPause *housekeepingPause
+
+	// Age containers must reach before they are monitored.
+	minContainerAge time.Duration
+	// Timers creating the containers waiting to reach minContainerAge, keyed by
+	// name. Protected by containersLock.
+	delayedContainers map[string]*time.Timer
+
+	// Time after startup during which the containers found are taken as
+	// pre-existing and generate no creation event.
+	eventWarmup time.Duration
+
+	// Signals the global housekeeping to detect new containers every
+	// containerPollInterval since they can no longer all be watched.
+	pollContainers chan struct{}
+
+	// Transforms applied to the stats of every container.
+	statsTransforms []StatsTransform
+
+	// Order in which the kinds of aliases of containers are preferred.
+	aliasPreference []string
+
+	// Normalizes the names of containers, nil if they are kept as is.
+	nameNormalizer *nameNormalizer
+
+	// Reads the DNS stats of containers, nil if they are not reported.
+	dnsReader *dnsprobe.Reader
+
+	// Whether the containers are served from a shared store rather than
+	// monitored, in which case no load or OOMs are collected.
+	readOnly bool
+
+	// Samples the host-wide usage of the machine, nil if disabled.
+	machineStats *machineStatsCollector
+
+	shutdownOnce sync.Once
+	shutdownErr  error
+}
+
+// Overrides how long the stats of the containers whose name or alias matches
+// the pattern are kept in memory.
+type storageDurationOverride struct {
+	pattern  *regexp.Regexp
+	duration time.Duration
+}
+
+// Docker containers are also cgroups the raw factory could handle, so the
+// Docker factory is asked first by default.
+var defaultFactoryPriorities = map[string]int{
+	docker.DockerNamespace: 10,
+	"raw":                  0,
+}
+
+// Returns the priority of each container factory, the defaults overridden by
+// the comma-separated <factory>=<priority> pairs.
+func parseFactoryPriorities(overrides string) (map[string]int, error) {
+	ret := make(map[string]int, len(defaultFactoryPriorities))
+	for name, priority := range defaultFactoryPriorities {
+		ret[name] = priority
+	}
+	if overrides == "" {
+		return ret, nil
+	}
+	for _, override := range strings.Split(overrides, ",") {
+		i := strings.Index(override, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid factory priority %q, expected <factory>=<priority>", override)
+		}
+		name := override[:i]
+		if _, ok := defaultFactoryPriorities[name]; !ok {
+			return nil, fmt.Errorf("unknown container factory %q", name)
+		}
+		priority, err := strconv.Atoi(override[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid priority in factory priority %q: %v", override, err)
+		}
+		ret[name] = priority
+	}
+	return ret, nil
+}
+
+// Parses a semicolon-separated list of <regexp>=<duration>. Regexps may contain
+// commas, e.g. in {1,3}, and equal signs, the duration follows the last one.
+func parseStorageDurationOverrides(overrides string) ([]storageDurationOverride, error) {
+	var ret []storageDurationOverride
+	if overrides == "" {
+		return ret, nil
+	}
+	for _, override := range strings.Split(overrides, ";") {
+		i := strings.LastIndex(override, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid storage duration override %q, expected <regexp>=<duration>", override)
+		}
+		pattern, err := regexp.Compile(override[:i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern in storage duration override %q: %v", override, err)
+		}
+		duration, err := time.ParseDuration(override[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration in storage duration override %q: %v", override, err)
+		}
+		ret = append(ret, storageDurationOverride{
+			pattern:  pattern,
+			duration: duration,
+		})
+	}
+	return ret, nil
+}
+
+// Keeps the stats of the specified container in memory for the duration of the
+// first override it matches. Containers matching no override use the default.
+func (m *manager) applyStorageDuration(ref info.ContainerReference) {
+	for _, override := range m.storageDurationOverrides {
+		matches := override.pattern.MatchString(ref.Name)
+		for _, alias := range ref.Aliases {
+			matches = matches || override.pattern.MatchString(alias)
+		}
+		if matches {
+			glog.V(3).Infof("Keeping stats of container %q for %v", ref.Name, override.duration)
+			m.memoryStorage.SetMaxAge(ref, override.duration)
+			return
+		}
+	}
 }
 
 // Start the container manager.
 func (self *manager) Start() error {
 
-	if *enableLoadReader {
+	if *enableLoadReader && !self.readOnly {
 		// Create cpu load reader.
 		cpuLoadReader, err := cpuload.New()
 		if err != nil {
@@ -198,9 +529,25 @@ func (self *manager) Start() error {
 	}
 
 	// Watch for OOMs.
-	err := self.watchForNewOoms()
-	if err != nil {
-		glog.Errorf("Failed to start OOM watcher, will not get OOM events: %v", err)
+	var err error
+	if !self.readOnly {
+		err = self.watchForNewOoms()
+		if err != nil {
+			glog.Errorf("Failed to start OOM watcher, will not get OOM events: %v", err)
+		}
+	}
+
+	if *machineInfoRefreshInterval > 0 {
+		quitMachineInfo := make(chan error)
+		self.quitChannels = append(self.quitChannels, quitMachineInfo)
+		go self.machineInfoHousekeeping(*machineInfoRefreshInterval, quitMachineInfo)
+	}
+
+	// Sample the host-wide usage of the machine.
+	if self.machineStats != nil {
+		quitMachineStats := make(chan error)
+		self.quitChannels = append(self.quitChannels, quitMachineStats)
+		go self.machineStats.housekeeping(*machineStatsInterval, quitMachineStats)
 	}
 
 	// If there are no factories, don't start any housekeeping and serve the information we do have.
@@ -249,6 +596,11 @@ func (self *manager) Stop() error {
 		}
 	}
 	self.quitChannels = make([]chan error, 0, 2)
+	self.containersLock.Lock()
+	for name := range self.delayedContainers {
+		self.cancelDelayedContainer(name)
+	}
+	self.containersLock.Unlock()
 	if self.loadReader != nil {
 		self.loadReader.Stop()
 		self.loadReader = nil
@@ -256,6 +608,91 @@ func (self *manager) Stop() error {
 	return nil
 }
 
+func (self *manager) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		self.shutdownOnce.Do(func() {
+			self.shutdownErr = self.shutdown()
+		})
+		close(done)
+	}()
+	select {
+	case <-done:
+		return self.shutdownErr
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (self *manager) shutdown() error {
+	errs := []string{}
+	if err := self.Stop(); err != nil {
+		errs = append(errs, fmt.Sprintf("failed to stop manager: %v", err))
+	}
+
+	// Stop the housekeeping of all containers and wait for it to finish so
+	// that no stats are added once the storage driver is closed. Containers
+	// are listed once per alias.
+	self.containersLock.Lock()
+	conts := make(map[*containerData]struct{}, len(self.containers))
+	for _, cont := range self.containers {
+		conts[cont] = struct{}{}
+	}
+	self.containers = make(map[namespacedContainerName]*containerData)
+	self.containersLock.Unlock()
+	for cont := range conts {
+		cont.Stop()
+	}
+	for cont := range conts {
+		<-cont.stopped
+	}
+
+	// Ends the event and machine stats streams served by the API.
+	self.eventHandler.StopAllWatches()
+	if self.machineStats != nil {
+		self.machineStats.stopAllWatches()
+	}
+
+	if err := self.memoryStorage.Close(); err != nil {
+		errs = append(errs, fmt.Sprintf("failed to close storage: %v", err))
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	glog.Infof("Shut down the container manager")
+	return nil
+}
+
+func (self *manager) Pause() error {
+	self.housekeepingPause.Pause()
+	glog.Infof("Paused the housekeeping of all containers")
+	return nil
+}
+
+func (self *manager) Resume() error {
+	self.housekeepingPause.Resume()
+	glog.Infof("Resumed the housekeeping of all containers")
+	return nil
+}
+
+func (self *manager) Paused() bool {
+	return self.housekeepingPause.Paused()
+}
+
+func (self *manager) DegradedContainers() []string {
+	self.containersLock.RLock()
+	defer self.containersLock.RUnlock()
+	var names []string
+	for name, cont := range self.containers {
+		// Only report containers once, not for each of their aliases.
+		if name.Name == cont.info.Name && cont.Degraded() {
+			names = append(names, cont.info.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
 func (self *manager) globalHousekeeping(quit chan error) {
 	// Long housekeeping is either 100ms or half of the housekeeping interval.
 	longHousekeeping := 100 * time.Millisecond
@@ -263,10 +700,19 @@ func (self *manager) globalHousekeeping(quit chan error) {
 		longHousekeeping = *globalHousekeepingInterval / 2
 	}
 
-	ticker := time.Tick(*globalHousekeepingInterval)
+	ticker := time.NewTicker(*globalHousekeepingInterval)
+	defer func() {
+		ticker.Stop()
+	}()
 	for {
 		select {
-		case t := <-ticker:
+		case <-self.pollContainers:
+			if *containerPollInterval < *globalHousekeepingInterval {
+				glog.Warningf("Detecting new containers every %v since they cannot all be watched", *containerPollInterval)
+				ticker.Stop()
+				ticker = time.NewTicker(*containerPollInterval)
+			}
+		case t := <-ticker.C:
 			start := time.Now()
 
 			// Check for new containers.
@@ -340,6 +786,49 @@ func (self *manager) GetContainerSpec(containerName string, options v2.RequestOp
 	return specs, nil
 }
 
+func (self *manager) GetContainerTree(containerName string, maxDepth int) (*v2.ContainerTree, error) {
+	cont, err := self.getContainerData(containerName)
+	if err != nil {
+		return nil, err
+	}
+	return self.containerTree(cont, maxDepth, make(map[string]bool))
+}
+
+// Builds the tree of the specified container from the subcontainers it lists.
+// Containers already in the tree are skipped so a misbehaving handler cannot
+// make us recurse forever.
+func (self *manager) containerTree(cont *containerData, depth int, seen map[string]bool) (*v2.ContainerTree, error) {
+	cinfo, err := cont.GetInfo()
+	if err != nil {
+		return nil, err
+	}
+	seen[cinfo.Name] = true
+	tree := &v2.ContainerTree{
+		Name: cinfo.Name,
+		Spec: self.getV2Spec(cinfo),
+	}
+	if depth == 0 {
+		return tree, nil
+	}
+	for _, ref := range cinfo.Subcontainers {
+		if seen[ref.Name] {
+			glog.Warningf("Container %q lists %q as subcontainer which is already in the tree", cinfo.Name, ref.Name)
+			continue
+		}
+		child, err := self.getContainerData(ref.Name)
+		if err != nil {
+			// The subcontainer is not tracked (yet or anymore).
+			continue
+		}
+		childTree, err := self.containerTree(child, depth-1, seen)
+		if err != nil {
+			return nil, err
+		}
+		tree.Children = append(tree.Children, *childTree)
+	}
+	return tree, nil
+}
+
 // Get V2 container spec from v1 container info.
 func (self *manager) getV2Spec(cinfo *containerInfo) v2.ContainerSpec {
 	specV1 := self.getAdjustedSpec(cinfo)
@@ -352,6 +841,7 @@ func (self *manager) getV2Spec(cinfo *containerInfo) v2.ContainerSpec {
 		specV2.Cpu.Limit = specV1.Cpu.Limit
 		specV2.Cpu.MaxLimit = specV1.Cpu.MaxLimit
 		specV2.Cpu.Mask = specV1.Cpu.Mask
+		specV2.Cpu.Burst = specV1.Cpu.Burst
 	}
 	if specV1.HasMemory {
 		specV2.Memory.Limit = specV1.Memory.Limit
@@ -370,7 +860,9 @@ func (self *manager) getAdjustedSpec(cinfo *containerInfo) info.ContainerSpec {
 	if spec.HasMemory {
 		// Memory.Limit is 0 means there's no limit
 		if spec.Memory.Limit == 0 {
+			self.machineInfoLock.RLock()
 			spec.Memory.Limit = uint64(self.machineInfo.MemoryCapacity)
+			self.machineInfoLock.RUnlock()
 		}
 	}
 	return spec
@@ -385,6 +877,81 @@ func (self *manager) GetContainerInfo(containerName string, query *info.Containe
 	return self.containerDataToContainerInfo(cont, query)
 }
 
+// Returns the data of the specified containers, or of all the containers if
+// none is specified, and their names sorted.
+func (self *manager) getSelectedContainers(containerNames []string) (map[string]*containerData, []string, error) {
+	var containers map[string]*containerData
+	if len(containerNames) == 0 {
+		containers = self.getSubcontainers("/")
+	} else {
+		containers = make(map[string]*containerData, len(containerNames))
+		for _, name := range containerNames {
+			cont, err := self.getContainerData(name)
+			if err != nil {
+				return nil, nil, err
+			}
+			containers[cont.info.Name] = cont
+		}
+	}
+
+	names := make([]string, 0, len(containers))
+	for name := range containers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return containers, names, nil
+}
+
+func (self *manager) GetContainerStatus(containerNames []string) ([]info.ContainerStatus, error) {
+	containers, names, err := self.getSelectedContainers(containerNames)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]info.ContainerStatus, 0, len(names))
+	for _, name := range names {
+		cinfo, err := containers[name].GetInfo()
+		if err != nil {
+			return nil, err
+		}
+		status := info.ContainerStatus{
+			ContainerReference: cinfo.ContainerReference,
+			Spec:               self.getAdjustedSpec(cinfo),
+		}
+		// Containers without samples yet are reported without stats.
+		if !self.memoryStorage.HasStats(cinfo.Name) {
+			ret = append(ret, status)
+			continue
+		}
+		stats, err := self.memoryStorage.RecentStats(cinfo.Name, time.Time{}, time.Time{}, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(stats) > 0 {
+			status.Stats = stats[len(stats)-1]
+		}
+		ret = append(ret, status)
+	}
+	return ret, nil
+}
+
+func (self *manager) GetStatsRetention(containerNames []string) ([]info.ContainerRetention, error) {
+	containers, names, err := self.getSelectedContainers(containerNames)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]info.ContainerRetention, 0, len(names))
+	for _, name := range names {
+		cinfo, err := containers[name].GetInfo()
+		if err != nil {
+			return nil, err
+		}
+		retention := self.memoryStorage.Retention(cinfo.Name)
+		retention.ContainerReference = cinfo.ContainerReference
+		ret = append(ret, retention)
+	}
+	return ret, nil
+}
+
 func (self *manager) containerDataToContainerInfo(cont *containerData, query *info.ContainerInfoRequest) (*info.ContainerInfo, error) {
 	// Get the info from the container.
 	cinfo, err := cont.GetInfo()
@@ -486,6 +1053,28 @@ func (self *manager) getDockerContainer(containerName string) (*containerData, e
 	return cont, nil
 }
 
+// Returns the container with the stable ID. The previous instance of a
+// restarted container may not have been destroyed yet, the most recently
+// created one is returned.
+func (self *manager) getStableContainer(stableID string) (*containerData, error) {
+	self.containersLock.RLock()
+	defer self.containersLock.RUnlock()
+
+	var ret *containerData
+	for _, cont := range self.containers {
+		if cont.info.StableID != stableID || stableID == "" {
+			continue
+		}
+		if ret == nil || cont.info.Spec.CreationTime.After(ret.info.Spec.CreationTime) {
+			ret = cont
+		}
+	}
+	if ret == nil {
+		return nil, fmt.Errorf("unknown container with stable ID %q", stableID)
+	}
+	return ret, nil
+}
+
 func (self *manager) DockerContainer(containerName string, query *info.ContainerInfoRequest) (info.ContainerInfo, error) {
 	container, err := self.getDockerContainer(containerName)
 	if err != nil {
@@ -568,6 +1157,15 @@ func (self *manager) getRequestedContainers(containerName string, options v2.Req
 			}
 			containersMap = self.getAllDockerContainers()
 		}
+	case v2.TypeStable:
+		if options.Recursive {
+			return containersMap, fmt.Errorf("invalid request for container with stable ID %q with subcontainers", containerName)
+		}
+		cont, err := self.getStableContainer(strings.TrimPrefix(containerName, "/"))
+		if err != nil {
+			return containersMap, err
+		}
+		containersMap[cont.info.Name] = cont
 	default:
 		return containersMap, fmt.Errorf("invalid request type %q", options.IdType)
 	}
@@ -615,7 +1213,39 @@ func (self *manager) GetFsInfo(label string) ([]v2.FsInfo, error) {
 
 func (m *manager) GetMachineInfo() (*info.MachineInfo, error) {
 	// Copy and return the MachineInfo.
-	return &m.machineInfo, nil
+	m.machineInfoLock.RLock()
+	defer m.machineInfoLock.RUnlock()
+	machineInfo := m.machineInfo
+	return &machineInfo, nil
+}
+
+func (m *manager) GetMachineNetworkStats() ([]info.InterfaceStats, error) {
+	return sysinfo.GetMachineNetworkStats(m.sysFs)
+}
+
+func (m *manager) GetMachineStats(numStats int) ([]*info.MachineStats, error) {
+	if m.machineStats == nil {
+		return nil, fmt.Errorf("machine stats are not collected")
+	}
+	return m.machineStats.recentStats(numStats), nil
+}
+
+func (m *manager) CollectionBackpressure() bool {
+	return m.memoryStorage.Backpressure()
+}
+
+func (m *manager) WatchMachineStats() (int, <-chan *info.MachineStats, error) {
+	if m.machineStats == nil {
+		return 0, nil, fmt.Errorf("machine stats are not collected")
+	}
+	id, watcher := m.machineStats.watch()
+	return id, watcher, nil
+}
+
+func (m *manager) CloseMachineStatsChannel(watchId int) {
+	if m.machineStats != nil {
+		m.machineStats.stopWatch(watchId)
+	}
 }
 
 func (m *manager) GetVersionInfo() (*info.VersionInfo, error) {
@@ -648,14 +1278,50 @@ func (m *manager) createContainer(containerName string) error {
 		glog.V(4).Infof("ignoring container %q", containerName)
 		return nil
 	}
+	if m.minContainerAge > 0 {
+		spec, err := handler.GetSpec()
+		if err != nil {
+			return err
+		}
+		if age := time.Since(spec.CreationTime); age < m.minContainerAge {
+			m.delayContainer(containerName, m.minContainerAge-age)
+			return nil
+		}
+	}
 	logUsage := *logCadvisorUsage && containerName == m.cadvisorContainer
-	cont, err := newContainerData(containerName, m.memoryStorage, handler, m.loadReader, logUsage)
+	cont, err := newContainerData(containerName, m.memoryStorage, handler, m.loadReader, logUsage, m.maxHousekeepingJitter)
 	if err != nil {
 		return err
 	}
+	// Containers are known by their normalized name, the original name is
+	// kept as an alias.
+	name := m.nameNormalizer.normalize(containerName)
+	if name != containerName {
+		cont.info.Name = name
+		cont.info.Aliases = append(cont.info.Aliases, containerName)
+		cont.originalName = containerName
+	}
+	if m.aliasPreference != nil {
+		cont.info.Aliases = orderAliases(cont.info.Aliases, m.aliasPreference)
+	}
+	cont.info.ParentName, cont.info.Depth = parentAndDepth(name)
+	m.applyStorageDuration(cont.info.ContainerReference)
+	cont.pause = m.housekeepingPause
+	cont.statsTransforms = m.statsTransforms
+	cont.ioConstrainedThreshold = *ioConstrainedThreshold
+	cont.dnsReader = m.dnsReader
+	if cont.trackPids && *pidMigrationThreshold > 0 {
+		cont.pidMigrationThreshold = *pidMigrationThreshold
+		cont.eventHandler = m.eventHandler
+	}
+	if *memoryPressureThreshold > 0 {
+		cont.memoryPressureThreshold = *memoryPressureThreshold
+		cont.memoryPressureHysteresis = *memoryPressureHysteresis
+		cont.eventHandler = m.eventHandler
+	}
 
 	namespacedName := namespacedContainerName{
-		Name: containerName,
+		Name: name,
 	}
 
 	// Add to the containers map.
@@ -683,21 +1349,18 @@ func (m *manager) createContainer(containerName string) error {
 	if alreadyExists {
 		return nil
 	}
-	glog.V(2).Infof("Added container: %q (aliases: %v, namespace: %q)", containerName, cont.info.Aliases, cont.info.Namespace)
+	glog.V(2).Infof("Added container: %q (aliases: %v, namespace: %q)", name, cont.info.Aliases, cont.info.Namespace)
 
 	contSpec, err := cont.handler.GetSpec()
 	if err != nil {
 		return err
 	}
 
-	if contSpec.CreationTime.After(m.startupTime) {
-		contRef, err := cont.handler.ContainerReference()
-		if err != nil {
-			return err
-		}
-
+	// Containers found while warming up are pre-existing even if their creation
+	// time, e.g. the modification time of their cgroup, is after startup.
+	if contSpec.CreationTime.After(m.startupTime) && time.Since(m.startupTime) >= m.eventWarmup {
 		newEvent := &info.Event{
-			ContainerName: contRef.Name,
+			ContainerName: cont.info.Name,
 			Timestamp:     contSpec.CreationTime,
 			EventType:     info.EventContainerCreation,
 			EventData: info.EventData{
@@ -705,15 +1368,17 @@ func (m *manager) createContainer(containerName string) error {
 					Spec: contSpec,
 				},
 			},
+			Labels: contSpec.Labels,
 		}
 		err = m.eventHandler.AddEvent(newEvent)
 		if err != nil {
 			return err
 		}
+		cont.creationEventTime = contSpec.CreationTime
 	}
 
 	if *enableLoadReader {
-		subcontainers := m.getSubcontainerContainerData(containerName)
+		subcontainers := m.getSubcontainerContainerData(name)
 		cont.contSubcontainers = subcontainers
 		m.addAsSubcontainer(cont, namespacedName)
 
@@ -731,12 +1396,56 @@ func (m *manager) createContainer(containerName string) error {
 	return nil
 }
 
+// Creates the container after the delay unless it has disappeared by then.
+// Short-lived containers are thus never registered and generate no events.
+func (m *manager) delayContainer(containerName string, delay time.Duration) {
+	m.containersLock.Lock()
+	defer m.containersLock.Unlock()
+	if _, ok := m.delayedContainers[containerName]; ok {
+		return
+	}
+	glog.V(3).Infof("Delaying container %q for %v until it reaches the minimum age", containerName, delay)
+
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		m.containersLock.Lock()
+		// The timer may have fired while being stopped.
+		if m.delayedContainers[containerName] != timer {
+			m.containersLock.Unlock()
+			return
+		}
+		delete(m.delayedContainers, containerName)
+		m.containersLock.Unlock()
+
+		handler, accept, err := container.NewContainerHandler(containerName)
+		if err != nil || !accept || !handler.Exists() {
+			glog.V(3).Infof("Container %q disappeared before reaching the minimum age", containerName)
+			return
+		}
+		if err := m.createContainer(containerName); err != nil {
+			glog.Errorf("Failed to create delayed container %q: %v", containerName, err)
+		}
+	})
+	m.delayedContainers[containerName] = timer
+}
+
+// Stops creating the container if it is waiting to reach the minimum age. Must
+// be called with containersLock held.
+func (m *manager) cancelDelayedContainer(containerName string) {
+	if timer, ok := m.delayedContainers[containerName]; ok {
+		timer.Stop()
+		delete(m.delayedContainers, containerName)
+	}
+}
+
 func (m *manager) destroyContainer(containerName string) error {
 	m.containersLock.Lock()
 	defer m.containersLock.Unlock()
 
+	m.cancelDelayedContainer(containerName)
+
 	namespacedName := namespacedContainerName{
-		Name: containerName,
+		Name: m.nameNormalizer.normalize(containerName),
 	}
 	cont, ok := m.containers[namespacedName]
 	if !ok {
@@ -762,17 +1471,17 @@ func (m *manager) destroyContainer(containerName string) error {
 			Name:      alias,
 		})
 	}
-	glog.V(2).Infof("Destroyed container: %q (aliases: %v, namespace: %q)", containerName, cont.info.Aliases, cont.info.Namespace)
+	glog.V(2).Infof("Destroyed container: %q (aliases: %v, namespace: %q)", cont.info.Name, cont.info.Aliases, cont.info.Namespace)
 
-	contRef, err := cont.handler.ContainerReference()
-	if err != nil {
-		return err
+	if err := m.memoryStorage.RemoveContainer(cont.info.ContainerReference); err != nil {
+		glog.Warningf("Failed to remove container %q from the storage: %v", cont.info.Name, err)
 	}
 
 	newEvent := &info.Event{
-		ContainerName: contRef.Name,
+		ContainerName: cont.info.Name,
 		Timestamp:     time.Now(),
 		EventType:     info.EventContainerDeletion,
+		Labels:        cont.labels(),
 	}
 	err = m.eventHandler.AddEvent(newEvent)
 	if err != nil {
@@ -799,12 +1508,13 @@ func (m *manager) getContainersDiff(containerName string) (added []info.Containe
 	}
 	allContainers = append(allContainers, info.ContainerReference{Name: containerName})
 
-	// Determine which were added and which were removed.
+	// Determine which were added and which were removed. The listing has the
+	// names of the handlers, before normalization.
 	allContainersSet := make(map[string]*containerData)
 	for name, d := range m.containers {
 		// Only add the canonical name.
 		if d.info.Name == name.Name {
-			allContainersSet[name.Name] = d
+			allContainersSet[d.handlerName()] = d
 		}
 	}
 
@@ -812,7 +1522,7 @@ func (m *manager) getContainersDiff(containerName string) (added []info.Containe
 	for _, c := range allContainers {
 		delete(allContainersSet, c.Name)
 		_, ok := m.containers[namespacedContainerName{
-			Name: c.Name,
+			Name: m.nameNormalizer.normalize(c.Name),
 		}]
 		if !ok {
 			added = append(added, c)
@@ -821,7 +1531,9 @@ func (m *manager) getContainersDiff(containerName string) (added []info.Containe
 
 	// Removed ones are no longer in the container listing.
 	for _, d := range allContainersSet {
-		removed = append(removed, d.info.ContainerReference)
+		ref := d.info.ContainerReference
+		ref.Name = d.handlerName()
+		removed = append(removed, ref)
 	}
 
 	return
@@ -868,11 +1580,19 @@ func (self *manager) watchForNewContainers(quit chan error) error {
 		return fmt.Errorf("root container does not exist when watching for new containers")
 	}
 
-	// Register for new subcontainers.
+	// Register for new subcontainers, falling back to detecting them in the
+	// global housekeeping if they cannot be watched.
 	eventsChannel := make(chan container.SubcontainerEvent, 16)
 	err := root.handler.WatchSubcontainers(eventsChannel)
 	if err != nil {
-		return err
+		glog.Warningf("Failed to watch for new containers: %v", err)
+		self.fallBackToPolling()
+		go func() {
+			// Nothing to stop.
+			<-quit
+			quit <- nil
+		}()
+		return nil
 	}
 
 	// There is a race between starting the watch and new container creation so we do a detection before we read new containers.
@@ -891,6 +1611,9 @@ func (self *manager) watchForNewContainers(quit chan error) error {
 					err = self.createContainer(event.Name)
 				case event.EventType == container.SubcontainerDelete:
 					err = self.destroyContainer(event.Name)
+				case event.EventType == container.SubcontainerWatchFailure:
+					err = nil
+					self.fallBackToPolling()
 				}
 				if err != nil {
 					glog.Warningf("Failed to process watch event: %v", err)
@@ -909,6 +1632,15 @@ func (self *manager) watchForNewContainers(quit chan error) error {
 	return nil
 }
 
+// Makes the global housekeeping detect new containers every
+// containerPollInterval from now on.
+func (self *manager) fallBackToPolling() {
+	select {
+	case self.pollContainers <- struct{}{}:
+	default:
+	}
+}
+
 func (self *manager) watchForNewOoms() error {
 	glog.Infof("Started watching for new ooms in manager")
 	outStream := make(chan *oomparser.OomInstance, 10)
@@ -925,6 +1657,7 @@ func (self *manager) watchForNewOoms() error {
 				ContainerName: oomInstance.ContainerName,
 				Timestamp:     oomInstance.TimeOfDeath,
 				EventType:     info.EventOom,
+				Labels:        self.containerLabels(oomInstance.ContainerName),
 			}
 			err := self.eventHandler.AddEvent(newEvent)
 			if err != nil {
@@ -942,16 +1675,29 @@ func (self *manager) watchForNewOoms() error {
 						ProcessName: oomInstance.ProcessName,
 					},
 				},
+				Labels: self.containerLabels(oomInstance.VictimContainerName),
 			}
 			err = self.eventHandler.AddEvent(newEvent)
 			if err != nil {
 				glog.Errorf("failed to add OOM kill event for %q: %v", oomInstance.ContainerName, err)
 			}
+			if cont, err := self.getContainerData(oomInstance.VictimContainerName); err == nil {
+				cont.addOomEvent()
+			}
 		}
 	}()
 	return nil
 }
 
+// Returns the labels of the specified container, or nil if it is not known.
+func (self *manager) containerLabels(containerName string) map[string]string {
+	cont, err := self.getContainerData(containerName)
+	if err != nil {
+		return nil
+	}
+	return cont.labels()
+}
+
 // can be called by the api which will take events returned on the channel
 func (self *manager) WatchForEvents(request *events.Request) (*events.EventChannel, error) {
 	return self.eventHandler.WatchEvents(request)
@@ -966,3 +1712,11 @@ func (self *manager) GetPastEvents(request *events.Request) ([]*info.Event, erro
 func (self *manager) CloseEventChannel(watch_id int) {
 	self.eventHandler.StopWatch(watch_id)
 }
+
+func (self *manager) GetEventStoragePolicy() events.StoragePolicy {
+	return self.eventHandler.GetStoragePolicy()
+}
+
+func (self *manager) SetEventStoragePolicy(policy events.StoragePolicy) error {
+	return self.eventHandler.SetStoragePolicy(policy)
+}
@@ -15,6 +15,8 @@
 package manager
 
 import (
+	"context"
+
 	"github.com/google/cadvisor/events"
 	info "github.com/google/cadvisor/info/v1"
 	"github.com/google/cadvisor/info/v2"
@@ -35,11 +37,46 @@ func (c *ManagerMock) Stop() error {
 	return args.Error(0)
 }
 
+func (c *ManagerMock) Shutdown(ctx context.Context) error {
+	args := c.Called(ctx)
+	return args.Error(0)
+}
+
+func (c *ManagerMock) Pause() error {
+	args := c.Called()
+	return args.Error(0)
+}
+
+func (c *ManagerMock) Resume() error {
+	args := c.Called()
+	return args.Error(0)
+}
+
+func (c *ManagerMock) Paused() bool {
+	args := c.Called()
+	return args.Bool(0)
+}
+
+func (c *ManagerMock) DegradedContainers() []string {
+	args := c.Called()
+	return args.Get(0).([]string)
+}
+
 func (c *ManagerMock) GetContainerInfo(name string, query *info.ContainerInfoRequest) (*info.ContainerInfo, error) {
 	args := c.Called(name, query)
 	return args.Get(0).(*info.ContainerInfo), args.Error(1)
 }
 
+func (c *ManagerMock) GetContainerStatus(containerNames []string) ([]info.ContainerStatus, error) {
+	args := c.Called(containerNames)
+	return args.Get(0).([]info.ContainerStatus), args.Error(1)
+}
+
+func (c *ManagerMock) GetStatsRetention(containerNames []string) ([]info.ContainerRetention, error) {
+	args := c.Called(containerNames)
+	return args.Get(0).([]info.ContainerRetention), args.Error(1)
+}
+
 func (c *ManagerMock) SubcontainersInfo(containerName string, query *info.ContainerInfoRequest) ([]*info.ContainerInfo, error) {
 	args := c.Called(containerName, query)
 	return args.Get(0).([]*info.ContainerInfo), args.Error(1)
@@ -60,6 +97,21 @@ func (c *ManagerMock) GetContainerSpec(containerName string, options v2.RequestO
 	return args.Get(0).(map[string]v2.ContainerSpec), args.Error(1)
 }
 
+func (c *ManagerMock) GetProcessTree(containerName string) (*v2.ProcessTree, error) {
+	args := c.Called(containerName)
+	return args.Get(0).(*v2.ProcessTree), args.Error(1)
+}
+
+func (c *ManagerMock) GetContainersAboveThreshold(containerName string, metric string, threshold float64, options v2.RequestOptions) (map[string]float64, error) {
+	args := c.Called(containerName, metric, threshold, options)
+	return args.Get(0).(map[string]float64), args.Error(1)
+}
+
+func (c *ManagerMock) GetContainerTree(containerName string, maxDepth int) (*v2.ContainerTree, error) {
+	args := c.Called(containerName, maxDepth)
+	return args.Get(0).(*v2.ContainerTree), args.Error(1)
+}
+
 func (c *ManagerMock) GetDerivedStats(containerName string, options v2.RequestOptions) (map[string]v2.DerivedStats, error) {
 	args := c.Called(containerName, options)
 	return args.Get(0).(map[string]v2.DerivedStats), args.Error(1)
@@ -85,11 +137,55 @@ func (c *ManagerMock) GetPastEvents(queryuest *events.Request) ([]*info.Event, e
 	return args.Get(0).([]*info.Event), args.Error(1)
 }
 
+func (c *ManagerMock) GetEventStoragePolicy() events.StoragePolicy {
+	args := c.Called()
+	return args.Get(0).(events.StoragePolicy)
+}
+
+func (c *ManagerMock) SetEventStoragePolicy(policy events.StoragePolicy) error {
+	args := c.Called(policy)
+	return args.Error(0)
+}
+
 func (c *ManagerMock) GetMachineInfo() (*info.MachineInfo, error) {
 	args := c.Called()
 	return args.Get(0).(*info.MachineInfo), args.Error(1)
 }
 
+func (c *ManagerMock) RefreshMachineInfo() error {
+	args := c.Called()
+	return args.Error(0)
+}
+
+func (c *ManagerMock) GetMachineNetworkStats() ([]info.InterfaceStats, error) {
+	args := c.Called()
+	return args.Get(0).([]info.InterfaceStats), args.Error(1)
+}
+
+func (c *ManagerMock) GetMachineStats(numStats int) ([]*info.MachineStats, error) {
+	args := c.Called(numStats)
+	return args.Get(0).([]*info.MachineStats), args.Error(1)
+}
+
+func (c *ManagerMock) GetStatsByImage(image string, query *info.ContainerInfoRequest) (*info.ImageStats, error) {
+	args := c.Called(image, query)
+	return args.Get(0).(*info.ImageStats), args.Error(1)
+}
+
+func (c *ManagerMock) CollectionBackpressure() bool {
+	args := c.Called()
+	return args.Bool(0)
+}
+
+func (c *ManagerMock) WatchMachineStats() (int, <-chan *info.MachineStats, error) {
+	args := c.Called()
+	return args.Int(0), args.Get(1).(<-chan *info.MachineStats), args.Error(2)
+}
+
+func (c *ManagerMock) CloseMachineStatsChannel(watchId int) {
+	c.Called(watchId)
+}
+
 func (c *ManagerMock) GetVersionInfo() (*info.VersionInfo, error) {
 	args := c.Called()
 	return args.Get(0).(*info.VersionInfo), args.Error(1)
@@ -17,17 +17,24 @@
 package manager
 
 import (
+	"context"
+	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/cadvisor/container"
 	"github.com/google/cadvisor/container/docker"
+	"github.com/google/cadvisor/events"
 	info "github.com/google/cadvisor/info/v1"
 	itest "github.com/google/cadvisor/info/v1/test"
+	"github.com/google/cadvisor/info/v2"
 	"github.com/google/cadvisor/storage/memory"
+	stest "github.com/google/cadvisor/storage/test"
 	"github.com/google/cadvisor/utils/sysfs/fakesysfs"
+	"github.com/stretchr/testify/mock"
 )
 
 // TODO(vmarmol): Refactor these tests.
@@ -52,7 +59,7 @@ func createManagerAndAddContainers(
 			spec,
 			nil,
 		).Once()
-		cont, err := newContainerData(name, memoryStorage, mockHandler, nil, false)
+		cont, err := newContainerData(name, memoryStorage, mockHandler, nil, false, 0)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -81,7 +88,7 @@ func expectManagerWithContainers(containers []string, query *info.ContainerInfoR
 		infosMap[container] = itest.GenerateRandomContainerInfo(container, 4, query, 1*time.Second)
 	}
 
-	memoryStorage := memory.New(time.Duration(query.NumStats)*time.Second, nil)
+	memoryStorage := memory.New(time.Duration(query.NumStats)*time.Second, nil, nil)
 	sysfs := &fakesysfs.FakeSysFs{}
 	m := createManagerAndAddContainers(
 		memoryStorage,
@@ -150,6 +157,110 @@ func TestGetContainerInfo(t *testing.T) {
 
 }
 
+func TestGetContainerStatus(t *testing.T) {
+	containers := []string{
+		"/c2",
+		"/c1",
+	}
+	query := &info.ContainerInfoRequest{
+		NumStats: 16,
+	}
+	m, infosMap, _ := expectManagerWithContainers(containers, query, t)
+
+	for _, names := range [][]string{nil, containers} {
+		status, err := m.GetContainerStatus(names)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(status) != 2 || status[0].Name != "/c1" || status[1].Name != "/c2" {
+			t.Fatalf("expected the status of /c1 and /c2, got %+v", status)
+		}
+		for _, s := range status {
+			expected := infosMap[s.Name]
+			// Only the most recent sample is returned.
+			if latest := expected.Stats[len(expected.Stats)-1]; !reflect.DeepEqual(s.Stats, latest) {
+				t.Errorf("expected the most recent stats of %q %+v, got %+v", s.Name, latest, s.Stats)
+			}
+			if !reflect.DeepEqual(s.Spec, expected.Spec) {
+				t.Errorf("expected the spec of %q %+v, got %+v", s.Name, expected.Spec, s.Spec)
+			}
+		}
+	}
+
+	if _, err := m.GetContainerStatus([]string{"/unknown"}); err == nil {
+		t.Errorf("expected an error for an unknown container")
+	}
+}
+
+func TestGetContainerStatusWithoutStats(t *testing.T) {
+	query := &info.ContainerInfoRequest{
+		NumStats: 16,
+	}
+	m, infosMap, _ := expectManagerWithContainers([]string{"/c1"}, query, t)
+	// A container created since the last housekeeping has no stats yet.
+	handler := container.NewMockContainerHandler("/new")
+	spec := itest.GenerateRandomContainerSpec(4)
+	handler.On("GetSpec").Return(spec, nil)
+	handler.On("ListContainers", container.ListSelf).Return([]info.ContainerReference(nil), nil)
+	cont, err := newContainerData("/new", m.memoryStorage, handler, nil, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.containers[namespacedContainerName{Name: "/new"}] = cont
+
+	status, err := m.GetContainerStatus([]string{"/c1", "/new"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status) != 2 || status[0].Name != "/c1" || status[1].Name != "/new" {
+		t.Fatalf("expected the status of /c1 and /new, got %+v", status)
+	}
+	if expected := infosMap["/c1"].Stats; !reflect.DeepEqual(status[0].Stats, expected[len(expected)-1]) {
+		t.Errorf("expected the most recent stats of /c1 %+v, got %+v", expected[len(expected)-1], status[0].Stats)
+	}
+	if status[1].Stats != nil {
+		t.Errorf("expected no stats for /new, got %+v", status[1].Stats)
+	}
+	if !reflect.DeepEqual(status[1].Spec, spec) {
+		t.Errorf("expected the spec of /new %+v, got %+v", spec, status[1].Spec)
+	}
+}
+
+func TestGetStatsRetention(t *testing.T) {
+	containers := []string{
+		"/c2",
+		"/c1",
+	}
+	query := &info.ContainerInfoRequest{
+		NumStats: 16,
+	}
+	m, infosMap, _ := expectManagerWithContainers(containers, query, t)
+
+	retention, err := m.GetStatsRetention(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(retention) != 2 || retention[0].Name != "/c1" || retention[1].Name != "/c2" {
+		t.Fatalf("expected the retention of /c1 and /c2, got %+v", retention)
+	}
+	for _, r := range retention {
+		stats := infosMap[r.Name].Stats
+		if r.NumSamples != len(stats) {
+			t.Errorf("expected %d samples for %q, got %d", len(stats), r.Name, r.NumSamples)
+		}
+		if oldest := stats[0].Timestamp; !r.Oldest.Equal(oldest) {
+			t.Errorf("expected oldest sample of %q at %v, got %v", r.Name, oldest, r.Oldest)
+		}
+		if newest := stats[len(stats)-1].Timestamp; !r.Newest.Equal(newest) {
+			t.Errorf("expected newest sample of %q at %v, got %v", r.Name, newest, r.Newest)
+		}
+	}
+
+	if _, err := m.GetStatsRetention([]string{"/unknown"}); err == nil {
+		t.Errorf("expected an error for an unknown container")
+	}
+}
+
 func TestSubcontainersInfo(t *testing.T) {
 	containers := []string{
 		"/c1",
@@ -203,9 +314,785 @@ func TestDockerContainersInfo(t *testing.T) {
 	}
 }
 
+func TestGetRequestedContainersByStableID(t *testing.T) {
+	containers := []string{
+		"/docker/c1",
+		"/docker/c2",
+	}
+	m, _, _ := expectManagerWithContainers(containers, &info.ContainerInfoRequest{NumStats: 2}, t)
+
+	// c2 replaced c1 when the container restarted, but c1 was not destroyed
+	// yet.
+	now := time.Now()
+	for i, name := range containers {
+		cont := m.containers[namespacedContainerName{Name: name}]
+		cont.info.StableID = "0f8e1d62/nginx"
+		cont.info.Spec.CreationTime = now.Add(time.Duration(i) * time.Minute)
+	}
+
+	options := v2.RequestOptions{IdType: v2.TypeStable}
+	conts, err := m.getRequestedContainers("/0f8e1d62/nginx", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := conts["/docker/c2"]; !ok || len(conts) != 1 {
+		t.Errorf("expected the most recent container /docker/c2, got %v", conts)
+	}
+
+	if _, err := m.getRequestedContainers("/unknown", options); err == nil {
+		t.Errorf("expected an unknown stable ID to fail")
+	}
+	options.Recursive = true
+	if _, err := m.getRequestedContainers("/0f8e1d62/nginx", options); err == nil {
+		t.Errorf("expected a recursive request by stable ID to fail")
+	}
+}
+
 func TestNewNilManager(t *testing.T) {
-	_, err := New(nil, nil)
+	_, err := New(nil, nil, Options{})
 	if err == nil {
 		t.Fatalf("Expected nil manager to return error")
 	}
 }
+
+func TestStorageDurationOverride(t *testing.T) {
+	overrides, err := parseStorageDurationOverrides("^/important$=10s;^/ignored$=1s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &manager{
+		memoryStorage:            memory.New(3*time.Second, nil, nil),
+		storageDurationOverrides: overrides,
+	}
+
+	important := info.ContainerReference{Name: "/important"}
+	other := info.ContainerReference{Name: "/other"}
+	var zero time.Time
+	for _, ref := range []info.ContainerReference{important, other} {
+		m.applyStorageDuration(ref)
+		for i := 0; i < 10; i++ {
+			err := m.memoryStorage.AddStats(ref, &info.ContainerStats{
+				Timestamp: zero.Add(time.Duration(i) * time.Second),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	stats, err := m.memoryStorage.RecentStats(important.Name, zero, zero, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 10 {
+		t.Errorf("expected matching container to keep 10 stats, got %d", len(stats))
+	}
+	stats, err = m.memoryStorage.RecentStats(other.Name, zero, zero, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 3 {
+		t.Errorf("expected non-matching container to keep 3 stats, got %d", len(stats))
+	}
+}
+
+func TestParseStorageDurationOverridesWithCommas(t *testing.T) {
+	overrides, err := parseStorageDurationOverrides("^/[a-z]{1,3}$=10s;^/a=b$=1m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(overrides) != 2 {
+		t.Fatalf("expected 2 overrides, got %d", len(overrides))
+	}
+	if !overrides[0].pattern.MatchString("/abc") || overrides[0].pattern.MatchString("/abcd") || overrides[0].duration != 10*time.Second {
+		t.Errorf("expected ^/[a-z]{1,3}$ to override with 10s, got %v with %v", overrides[0].pattern, overrides[0].duration)
+	}
+	if overrides[1].pattern.String() != "^/a=b$" || overrides[1].duration != time.Minute {
+		t.Errorf("expected ^/a=b$ to override with 1m, got %v with %v", overrides[1].pattern, overrides[1].duration)
+	}
+}
+
+func TestParseStorageDurationOverridesInvalid(t *testing.T) {
+	for _, overrides := range []string{"/foo", "[=1s", "/foo=soon"} {
+		if _, err := parseStorageDurationOverrides(overrides); err == nil {
+			t.Errorf("expected error parsing %q", overrides)
+		}
+	}
+}
+
+func TestParseFactoryPriorities(t *testing.T) {
+	priorities, err := parseFactoryPriorities("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if priorities["docker"] <= priorities["raw"] {
+		t.Errorf("expected the docker factory to take precedence by default, got %v", priorities)
+	}
+
+	priorities, err = parseFactoryPriorities("raw=20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]int{"docker": 10, "raw": 20}
+	if !reflect.DeepEqual(priorities, expected) {
+		t.Errorf("expected %v, got %v", expected, priorities)
+	}
+
+	for _, overrides := range []string{"raw", "raw=high", "systemd=1"} {
+		if _, err := parseFactoryPriorities(overrides); err == nil {
+			t.Errorf("expected error parsing %q", overrides)
+		}
+	}
+}
+
+func TestOrderAliases(t *testing.T) {
+	id := "4e1a3ff3a1bb5e4a5d7d5a6ccd0b6a1b0c2b46f0bf1e6e4c8e3a2c3c5b7d9e1f"
+	aliases := []string{id, "web", id[:12]}
+	tests := []struct {
+		preference string
+		canonical  string
+		expected   []string
+	}{
+		{"name,id,short_id", "web", []string{"web", id, id[:12]}},
+		{"short_id,name", id[:12], []string{id[:12], "web", id}},
+		{"id", id, []string{id, "web", id[:12]}},
+		{"", id, aliases},
+	}
+	// Original names of containers are preferred last.
+	if ordered := orderAliases([]string{"/docker/" + id, id}, []string{aliasName, aliasId}); ordered[0] != id {
+		t.Errorf("expected the original name to be preferred last, got %v", ordered)
+	}
+	for _, test := range tests {
+		preference, err := parseAliasPreference(test.preference)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ordered := orderAliases(aliases, preference)
+		if ordered[0] != test.canonical {
+			t.Errorf("preference %q: expected canonical alias %q, got %q", test.preference, test.canonical, ordered[0])
+		}
+		if !reflect.DeepEqual(ordered, test.expected) {
+			t.Errorf("preference %q: expected aliases %v, got %v", test.preference, test.expected, ordered)
+		}
+	}
+	if aliases[0] != id {
+		t.Errorf("aliases were reordered in place: %v", aliases)
+	}
+}
+
+func TestParseAliasPreferenceInvalid(t *testing.T) {
+	if _, err := parseAliasPreference("name,uuid"); err == nil {
+		t.Errorf("expected error parsing unknown alias kind")
+	}
+}
+
+func TestNameNormalization(t *testing.T) {
+	container.ClearContainerHandlerFactories()
+	defer container.ClearContainerHandlerFactories()
+	container.RegisterContainerHandlerFactory(&container.FactoryForMockContainerHandler{
+		Name: "mock",
+		PrepareContainerHandlerFunc: func(name string, h *container.MockContainerHandler) {
+			h.Name = name
+			h.On("GetSpec").Return(info.ContainerSpec{}, nil)
+		},
+	}, 0)
+	normalizer, err := newNameNormalizer("/kubepods/", true, "-[0-9a-f]{8}$=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &manager{
+		containers:        make(map[namespacedContainerName]*containerData),
+		memoryStorage:     memory.New(60, nil, nil),
+		eventHandler:      events.NewEventManager(events.DefaultStoragePolicy(), 0),
+		housekeepingPause: &housekeepingPause{},
+		nameNormalizer:    normalizer,
+	}
+	// Keep the housekeeping of the mock containers from running.
+	m.housekeepingPause.Pause()
+
+	const original = "/kubepods/Burstable/Pod1-0123abcd"
+	if err := m.createContainer(original); err != nil {
+		t.Fatal(err)
+	}
+	cont, ok := m.containers[namespacedContainerName{Name: "/burstable/pod1"}]
+	if !ok {
+		t.Fatalf("expected the normalized name to be the key of the container, got %v", m.containers)
+	}
+	if cont.info.Name != "/burstable/pod1" || !reflect.DeepEqual(cont.info.Aliases, []string{original}) {
+		t.Errorf("expected the normalized name and the original as alias, got %+v", cont.info.ContainerReference)
+	}
+	if name := cont.info.CanonicalName(); name != "/burstable/pod1" {
+		t.Errorf("expected the normalized name to identify the container, got %q", name)
+	}
+
+	// Containers are destroyed by the name of their handler.
+	if err := m.destroyContainer(original); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.containers) != 0 {
+		t.Errorf("expected the container and its alias to be removed, got %v", m.containers)
+	}
+}
+
+func TestContainerHierarchy(t *testing.T) {
+	container.ClearContainerHandlerFactories()
+	defer container.ClearContainerHandlerFactories()
+	container.RegisterContainerHandlerFactory(&container.FactoryForMockContainerHandler{
+		Name: "mock",
+		PrepareContainerHandlerFunc: func(name string, h *container.MockContainerHandler) {
+			h.Name = name
+			h.On("GetSpec").Return(info.ContainerSpec{}, nil)
+		},
+	}, 0)
+	normalizer, err := newNameNormalizer("/kubepods", false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &manager{
+		containers:        make(map[namespacedContainerName]*containerData),
+		memoryStorage:     memory.New(60, nil, nil),
+		eventHandler:      events.NewEventManager(events.DefaultStoragePolicy(), 0),
+		housekeepingPause: &housekeepingPause{},
+		nameNormalizer:    normalizer,
+	}
+	// Keep the housekeeping of the mock containers from running.
+	m.housekeepingPause.Pause()
+
+	expected := []struct {
+		name   string
+		parent string
+		depth  int
+	}{
+		{"/", "", 0},
+		{"/system.slice", "/", 1},
+		{"/system.slice/docker.service", "/system.slice", 2},
+		// Containers are placed in the hierarchy by their normalized name.
+		{"/kubepods/burstable", "/", 1},
+		{"/kubepods/burstable/pod1", "/burstable", 2},
+		{"/kubepods/burstable/pod1/abcd", "/burstable/pod1", 3},
+	}
+	for _, c := range expected {
+		if err := m.createContainer(c.name); err != nil {
+			t.Fatal(err)
+		}
+		cont, ok := m.containers[namespacedContainerName{Name: normalizer.normalize(c.name)}]
+		if !ok {
+			t.Fatalf("container %q was not created", c.name)
+		}
+		if cont.info.ParentName != c.parent || cont.info.Depth != c.depth {
+			t.Errorf("expected %q to have parent %q at depth %d, got parent %q at depth %d", c.name, c.parent, c.depth, cont.info.ParentName, cont.info.Depth)
+		}
+		if c.parent != "" {
+			if _, ok := m.containers[namespacedContainerName{Name: c.parent}]; !ok {
+				t.Errorf("expected the parent %q of %q to be a known container", c.parent, c.name)
+			}
+		}
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	normalizer, err := newNameNormalizer("/kubepods", false, "^/system.slice/(.*)\\.service$=/services/$1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, expected := range map[string]string{
+		"/":                            "/",
+		"/kubepods":                    "/kubepods",
+		"/kubepods/pod1":               "/pod1",
+		"/kubepodsx/pod1":              "/kubepodsx/pod1",
+		"/system.slice/docker.service": "/services/docker",
+	} {
+		if normalized := normalizer.normalize(name); normalized != expected {
+			t.Errorf("expected %q to be normalized to %q, got %q", name, expected, normalized)
+		}
+	}
+
+	// No normalization is configured by default.
+	normalizer, err = newNameNormalizer("", false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if normalized := normalizer.normalize("/Foo"); normalized != "/Foo" {
+		t.Errorf("expected the name to be kept, got %q", normalized)
+	}
+	if _, err := newNameNormalizer("", false, "[="); err == nil {
+		t.Errorf("expected error parsing an invalid replacement")
+	}
+}
+
+func TestGetContainerTree(t *testing.T) {
+	children := map[string][]info.ContainerReference{
+		"/":    {{Name: "/a"}, {Name: "/c"}},
+		"/a":   {{Name: "/a/b"}},
+		"/a/b": {{Name: "/a"}}, // Cycle back to a parent.
+		"/c":   {{Name: "/c/gone"}},
+	}
+	containers := []string{"/", "/a", "/a/b", "/c"}
+	memoryStorage := memory.New(60, nil, nil)
+	m := createManagerAndAddContainers(
+		memoryStorage,
+		&fakesysfs.FakeSysFs{},
+		containers,
+		func(h *container.MockContainerHandler) {
+			h.On("ListContainers", container.ListSelf).Return(children[h.Name], nil)
+			h.On("GetSpec").Return(info.ContainerSpec{}, nil)
+		},
+		t,
+	)
+
+	tree, err := m.GetContainerTree("/", -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.Name != "/" || len(tree.Children) != 2 {
+		t.Fatalf("expected / with 2 children, got %+v", tree)
+	}
+	a, c := tree.Children[0], tree.Children[1]
+	if a.Name != "/a" || len(a.Children) != 1 || a.Children[0].Name != "/a/b" {
+		t.Errorf("expected /a with child /a/b, got %+v", a)
+	}
+	if len(a.Children) == 1 && len(a.Children[0].Children) != 0 {
+		t.Errorf("expected the cycle from /a/b to /a to be skipped, got %+v", a.Children[0])
+	}
+	if c.Name != "/c" || len(c.Children) != 0 {
+		t.Errorf("expected /c without untracked children, got %+v", c)
+	}
+
+	tree, err = m.GetContainerTree("/", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree.Children) != 2 || len(tree.Children[0].Children) != 0 {
+		t.Errorf("expected the tree to be truncated below depth 1, got %+v", tree)
+	}
+
+	_, err = m.GetContainerTree("/unknown", -1)
+	if err == nil {
+		t.Errorf("expected error for an unknown container")
+	}
+}
+
+func TestMinContainerAge(t *testing.T) {
+	now := time.Now()
+	creationTimes := map[string]time.Time{
+		"/old":         now.Add(-time.Hour),
+		"/young":       now,
+		"/short-lived": now,
+		// Destroyed before reaching the minimum age.
+		"/destroyed": now,
+		// Created after the others, still below the minimum age, and only
+		// reaching it once the manager is stopped.
+		"/stopped": now.Add(500 * time.Millisecond),
+	}
+	container.ClearContainerHandlerFactories()
+	defer container.ClearContainerHandlerFactories()
+	container.RegisterContainerHandlerFactory(&container.FactoryForMockContainerHandler{
+		Name: "mock",
+		PrepareContainerHandlerFunc: func(name string, h *container.MockContainerHandler) {
+			h.Name = name
+			h.On("GetSpec").Return(info.ContainerSpec{CreationTime: creationTimes[name]}, nil)
+			h.On("Exists").Return(name != "/short-lived")
+		},
+	}, 0)
+
+	m := &manager{
+		containers:        make(map[namespacedContainerName]*containerData),
+		memoryStorage:     memory.New(60, nil, nil),
+		eventHandler:      events.NewEventManager(events.DefaultStoragePolicy(), 0),
+		startupTime:       now.Add(-2 * time.Hour),
+		housekeepingPause: &housekeepingPause{},
+		minContainerAge:   100 * time.Millisecond,
+		delayedContainers: make(map[string]*time.Timer),
+	}
+	// Keep the housekeeping of the mock containers from running.
+	m.housekeepingPause.Pause()
+
+	for name := range creationTimes {
+		if name == "/stopped" {
+			continue
+		}
+		if err := m.createContainer(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.destroyContainer("/destroyed"); err != nil {
+		t.Fatal(err)
+	}
+	registered := func(name string) bool {
+		m.containersLock.RLock()
+		defer m.containersLock.RUnlock()
+		_, ok := m.containers[namespacedContainerName{Name: name}]
+		return ok
+	}
+	if !registered("/old") {
+		t.Errorf("expected /old to be registered right away")
+	}
+	if registered("/young") || registered("/short-lived") {
+		t.Errorf("expected containers below the minimum age not to be registered")
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if !registered("/young") {
+		t.Errorf("expected /young to be registered once it reached the minimum age")
+	}
+	if registered("/short-lived") || registered("/destroyed") {
+		t.Errorf("expected /short-lived and /destroyed to never be registered")
+	}
+
+	request := events.NewRequest()
+	request.EventType[info.EventContainerCreation] = true
+	evs, err := m.GetPastEvents(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := make(map[string]bool)
+	for _, ev := range evs {
+		created[ev.ContainerName] = true
+	}
+	if expected := map[string]bool{"/old": true, "/young": true}; !reflect.DeepEqual(created, expected) {
+		t.Errorf("expected creation events for %v, got %v", expected, created)
+	}
+
+	// Containers waiting to reach the minimum age are not created once the
+	// manager is stopped.
+	if err := m.createContainer("/stopped"); err != nil {
+		t.Fatal(err)
+	}
+	if registered("/stopped") {
+		t.Errorf("expected /stopped not to be registered below the minimum age")
+	}
+	if err := m.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.delayedContainers) != 0 {
+		t.Errorf("expected no delayed containers after the manager stopped, got %v", m.delayedContainers)
+	}
+	time.Sleep(500 * time.Millisecond)
+	if registered("/stopped") {
+		t.Errorf("expected /stopped not to be registered after the manager stopped")
+	}
+}
+
+func TestEventWarmup(t *testing.T) {
+	startup := time.Now()
+	container.ClearContainerHandlerFactories()
+	defer container.ClearContainerHandlerFactories()
+	container.RegisterContainerHandlerFactory(&container.FactoryForMockContainerHandler{
+		Name: "mock",
+		PrepareContainerHandlerFunc: func(name string, h *container.MockContainerHandler) {
+			h.Name = name
+			// The creation time of raw containers is that of their cgroup,
+			// which may be modified after startup.
+			h.On("GetSpec").Return(info.ContainerSpec{CreationTime: startup.Add(time.Millisecond)}, nil)
+		},
+	}, 0)
+
+	m := &manager{
+		containers:        make(map[namespacedContainerName]*containerData),
+		memoryStorage:     memory.New(60, nil, nil),
+		eventHandler:      events.NewEventManager(events.DefaultStoragePolicy(), 0),
+		startupTime:       startup,
+		housekeepingPause: &housekeepingPause{},
+		eventWarmup:       100 * time.Millisecond,
+	}
+	// Keep the housekeeping of the mock containers from running.
+	m.housekeepingPause.Pause()
+
+	if err := m.createContainer("/existing"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if err := m.createContainer("/new"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.destroyContainer("/existing"); err != nil {
+		t.Fatal(err)
+	}
+
+	request := events.NewRequest()
+	request.EventType[info.EventContainerCreation] = true
+	request.EventType[info.EventContainerDeletion] = true
+	evs, err := m.GetPastEvents(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, ev := range evs {
+		got = append(got, fmt.Sprintf("%s %s", ev.EventType, ev.ContainerName))
+	}
+	sort.Strings(got)
+	// Containers found during the warmup still generate deletion events.
+	expected := []string{"containerCreation /new", "containerDeletion /existing"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected events %v, got %v", expected, got)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	container.ClearContainerHandlerFactories()
+	container.RegisterContainerHandlerFactory(&container.FactoryForMockContainerHandler{
+		Name: "mock",
+		PrepareContainerHandlerFunc: func(name string, h *container.MockContainerHandler) {
+			h.Name = name
+			h.On("GetSpec").Return(info.ContainerSpec{}, nil)
+			h.On("Exists").Return(true)
+		},
+	}, 0)
+
+	backend := &stest.MockStorageDriver{MockCloseMethod: true}
+	backend.On("Close").Return(nil)
+	m := &manager{
+		containers:        make(map[namespacedContainerName]*containerData),
+		quitChannels:      make([]chan error, 0, 2),
+		memoryStorage:     memory.New(60, backend, nil),
+		eventHandler:      events.NewEventManager(events.DefaultStoragePolicy(), 0),
+		startupTime:       time.Now(),
+		housekeepingPause: &housekeepingPause{},
+		delayedContainers: make(map[string]*time.Timer),
+	}
+	// Keep the housekeeping of the mock containers from running.
+	m.housekeepingPause.Pause()
+	for _, name := range []string{"/", "/a"} {
+		if err := m.createContainer(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	watch, err := m.WatchForEvents(events.NewRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Shutting down again does nothing.
+	if err := m.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	backend.AssertNumberOfCalls(t, "Close", 1)
+	if _, ok := <-watch.GetChannel(); ok {
+		t.Errorf("expected the event watch to be closed")
+	}
+	if len(m.containers) != 0 {
+		t.Errorf("expected no containers after shutdown, got %d", len(m.containers))
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	m := &manager{
+		containers:    make(map[namespacedContainerName]*containerData),
+		memoryStorage: memory.New(60, nil, nil),
+		eventHandler:  events.NewEventManager(events.DefaultStoragePolicy(), 0),
+		// Never answers, as a global housekeeping stuck on a slow handler.
+		quitChannels: []chan error{make(chan error)},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := m.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestWatchForNewContainersFallback(t *testing.T) {
+	m := createManagerAndAddContainers(
+		memory.New(time.Minute, nil, nil),
+		&fakesysfs.FakeSysFs{},
+		[]string{"/"},
+		func(h *container.MockContainerHandler) {
+			h.On("WatchSubcontainers", mock.Anything).Return(fmt.Errorf("inotify watches exhausted"))
+		},
+		t,
+	)
+	m.pollContainers = make(chan struct{}, 1)
+
+	quit := make(chan error)
+	if err := m.watchForNewContainers(quit); err != nil {
+		t.Fatalf("expected to fall back to polling, got %v", err)
+	}
+	select {
+	case <-m.pollContainers:
+	default:
+		t.Errorf("expected the global housekeeping to be asked to poll for containers")
+	}
+	quit <- nil
+	if err := <-quit; err != nil {
+		t.Errorf("expected the fallback to stop cleanly, got %v", err)
+	}
+}
+
+func TestGetContainersAboveThreshold(t *testing.T) {
+	// CPU usage in cores and memory usage of each container.
+	usage := map[string]struct {
+		cores  float64
+		memory uint64
+	}{
+		"/":      {2, 4000},
+		"/idle":  {0.1, 100},
+		"/busy":  {1.5, 3000},
+		"/fresh": {0, 0},
+	}
+	memoryStorage := memory.New(time.Minute, nil, nil)
+	start := time.Now().Add(-10 * time.Second)
+	m := createManagerAndAddContainers(
+		memoryStorage,
+		&fakesysfs.FakeSysFs{},
+		[]string{"/", "/idle", "/busy", "/fresh"},
+		func(h *container.MockContainerHandler) {
+			ref, _ := h.ContainerReference()
+			u := usage[h.Name]
+			numStats := 2
+			if h.Name == "/fresh" {
+				// Too few stats to compute the CPU usage.
+				numStats = 1
+			}
+			for i := 0; i < numStats; i++ {
+				stats := &info.ContainerStats{Timestamp: start.Add(time.Duration(i) * time.Second)}
+				stats.Cpu.Usage.Total = uint64(float64(i) * u.cores * float64(time.Second))
+				if i > 0 {
+					stats.Cpu.UsageRate.Total = uint64(u.cores * 1000)
+				}
+				stats.Memory.Usage = u.memory
+				if err := memoryStorage.AddStats(ref, stats); err != nil {
+					t.Fatal(err)
+				}
+			}
+		},
+		t,
+	)
+	options := v2.RequestOptions{IdType: v2.TypeName, Count: 1, Recursive: true}
+
+	above, err := m.GetContainersAboveThreshold("/", "cpu_usage_percent", 100, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := map[string]float64{"/": 200, "/busy": 150}; !reflect.DeepEqual(above, expected) {
+		t.Errorf("expected %v above 100%% CPU, got %v", expected, above)
+	}
+
+	above, err = m.GetContainersAboveThreshold("/", "memory_usage_bytes", 1000, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := map[string]float64{"/": 4000, "/busy": 3000}; !reflect.DeepEqual(above, expected) {
+		t.Errorf("expected %v above 1000 bytes, got %v", expected, above)
+	}
+
+	options.Recursive = false
+	above, err = m.GetContainersAboveThreshold("/idle", "memory_usage_bytes", 10, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := map[string]float64{"/idle": 100}; !reflect.DeepEqual(above, expected) {
+		t.Errorf("expected %v, got %v", expected, above)
+	}
+
+	if _, err := m.GetContainersAboveThreshold("/", "unknown", 0, options); err == nil {
+		t.Errorf("expected error for an unknown metric")
+	}
+}
+
+func TestGetStatsByImage(t *testing.T) {
+	redisId := "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	redisDigest := "sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+	specs := map[string]info.ContainerSpec{
+		"/docker/a": {ImageID: redisId, ImageRegistry: "docker.io", ImageRepository: "library/redis", ImageRepoTags: []string{"redis:3.0", "redis:latest"}, ImageRepoDigest: redisDigest},
+		"/docker/b": {ImageID: redisId, ImageRegistry: "docker.io", ImageRepository: "library/redis", ImageRepoTags: []string{"redis:3.0", "redis:latest"}},
+		"/docker/c": {ImageID: "sha256:fedcba9876543210", ImageRegistry: "docker.io", ImageRepository: "library/redis", ImageRepoTags: []string{"redis:2.8"}},
+		"/docker/d": {ImageID: "sha256:0000000000000000", ImageRegistry: "gcr.io", ImageRepository: "team/app", ImageRepoTags: []string{"gcr.io/team/app:1.0"}},
+		// Created since the last housekeeping, without samples yet.
+		"/docker/e": {ImageID: redisId, ImageRegistry: "docker.io", ImageRepository: "library/redis", ImageRepoTags: []string{"redis:3.0", "redis:latest"}},
+	}
+	memoryStorage := memory.New(time.Minute, nil, nil)
+	start := time.Now().Truncate(*HousekeepingInterval).Add(-10 * *HousekeepingInterval)
+	m := createManagerAndAddContainers(
+		memoryStorage,
+		&fakesysfs.FakeSysFs{},
+		[]string{"/docker/a", "/docker/b", "/docker/c", "/docker/d", "/docker/e"},
+		func(h *container.MockContainerHandler) {
+			h.On("GetSpec").Return(specs[h.Name], nil)
+			h.On("ListContainers", container.ListSelf).Return([]info.ContainerReference(nil), nil)
+			ref, _ := h.ContainerReference()
+			numStats := 3
+			interval := *HousekeepingInterval
+			// Offset of the samples from the housekeeping interval boundaries.
+			var jitter time.Duration
+			switch h.Name {
+			case "/docker/b":
+				// Sampled half as often as the other containers of the
+				// image and off the interval boundaries.
+				numStats = 2
+				interval *= 2
+				jitter = *HousekeepingInterval / 4
+			case "/docker/e":
+				numStats = 0
+			}
+			for i := 0; i < numStats; i++ {
+				stats := &info.ContainerStats{Timestamp: start.Add(time.Duration(i)*interval + jitter)}
+				stats.Cpu.Usage.Total = uint64(i+1) * 100
+				stats.Cpu.Usage.PerCpu = []uint64{uint64(i+1) * 100}
+				stats.Memory.Usage = 1000
+				stats.Memory.WorkingSet = 800
+				stats.Network.RxBytes = uint64(i+1) * 10
+				stats.Network.TxBytes = uint64(i+1) * 20
+				stats.Filesystem = []info.FsStats{
+					{Device: "/dev/sda1", Limit: 5000, Usage: 300},
+					{Device: "/dev/sdb1", Volume: "/data", Limit: 5000, Usage: 900},
+				}
+				if err := memoryStorage.AddStats(ref, stats); err != nil {
+					t.Fatal(err)
+				}
+			}
+		},
+		t,
+	)
+	query := &info.ContainerInfoRequest{NumStats: 60}
+
+	for image, expected := range map[string][]string{
+		"redis":                       {"/docker/a", "/docker/b", "/docker/c"},
+		"redis:3.0":                   {"/docker/a", "/docker/b"},
+		"docker.io/library/redis:2.8": {"/docker/c"},
+		"redis:2.6":                   {},
+		"redis@" + redisDigest:        {"/docker/a"},
+		"redis@" + redisId:            {},
+		redisId[:19]:                  {"/docker/a", "/docker/b"},
+		"gcr.io/team/app":             {"/docker/d"},
+		"team/app":                    {},
+	} {
+		imageStats, err := m.GetStatsByImage(image, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(imageStats.Containers, expected) || imageStats.NumContainers != len(expected) {
+			t.Errorf("expected containers %v running %q, got %v", expected, image, imageStats.Containers)
+		}
+	}
+
+	imageStats, err := m.GetStatsByImage("redis:3.0", query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each sample of /docker/a is summed with the most recent sample of
+	// /docker/b at or before it.
+	if len(imageStats.Stats) != 3 {
+		t.Fatalf("expected 3 samples, got %d", len(imageStats.Stats))
+	}
+	expected := &info.ContainerStats{Timestamp: start.Add(2 * *HousekeepingInterval)}
+	expected.Cpu.Usage.Total = 500
+	expected.Cpu.Usage.PerCpu = []uint64{500}
+	expected.Memory.Usage = 2000
+	expected.Memory.WorkingSet = 1600
+	expected.Network.RxBytes = 50
+	expected.Network.TxBytes = 100
+	expected.Filesystem = []info.FsStats{{Device: "/dev/sda1", Limit: 5000, Usage: 600}}
+	if latest := imageStats.Stats[2]; !reflect.DeepEqual(latest, expected) {
+		t.Errorf("expected the sum %+v, got %+v", expected, latest)
+	}
+	for i, total := range []uint64{200, 300} {
+		if s := imageStats.Stats[i]; s.Cpu.Usage.Total != total || !s.Timestamp.Equal(start.Add(time.Duration(i)**HousekeepingInterval)) {
+			t.Errorf("expected the CPU usage %d at %v, got %+v", total, start.Add(time.Duration(i)**HousekeepingInterval), s)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"flag"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var nameStripPrefix = flag.String("container_name_strip_prefix", "", "Prefix stripped from the names of the containers below it, e.g. /kubepods turns /kubepods/pod1 into /pod1. The original name is kept as an alias")
+var nameLowercase = flag.Bool("container_name_lowercase", false, "Whether to lowercase the names of containers. The original name is kept as an alias")
+var nameReplace = flag.String("container_name_replace", "", "<regexp>=<replacement> applied to the names of containers after the prefix is stripped and they are lowercased. The original name is kept as an alias")
+
+// Normalizes the names the containers get from their handlers so that names
+// formatted differently by runtimes give stable identities.
+type nameNormalizer struct {
+	// Stripped from the names below it, without trailing slash.
+	stripPrefix string
+
+	lowercase bool
+
+	// Replaced by replacement in names, nil if none.
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// Returns nil if no normalization is configured.
+func newNameNormalizer(stripPrefix string, lowercase bool, replace string) (*nameNormalizer, error) {
+	if stripPrefix == "" && !lowercase && replace == "" {
+		return nil, nil
+	}
+	ret := &nameNormalizer{
+		stripPrefix: strings.TrimSuffix(stripPrefix, "/"),
+		lowercase:   lowercase,
+	}
+	if replace != "" {
+		i := strings.LastIndex(replace, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid container name replacement %q, expected <regexp>=<replacement>", replace)
+		}
+		pattern, err := regexp.Compile(replace[:i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern in container name replacement %q: %v", replace, err)
+		}
+		ret.pattern = pattern
+		ret.replacement = replace[i+1:]
+	}
+	return ret, nil
+}
+
+// Returns the normalized name of the container. The root container is never
+// renamed and names stay absolute.
+func (self *nameNormalizer) normalize(name string) string {
+	if self == nil || name == "/" {
+		return name
+	}
+	if self.stripPrefix != "" && strings.HasPrefix(name, self.stripPrefix+"/") {
+		name = name[len(self.stripPrefix):]
+	}
+	if self.lowercase {
+		name = strings.ToLower(name)
+	}
+	if self.pattern != nil {
+		name = self.pattern.ReplaceAllString(name, self.replacement)
+	}
+	if !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+	return name
+}
+
+// Returns the name of the parent of the container with the absolute name and
+// its depth in the hierarchy of containers. The root container "/" has no
+// parent and is at depth 0.
+func parentAndDepth(name string) (string, int) {
+	name = path.Clean(name)
+	if name == "/" {
+		return "", 0
+	}
+	return path.Dir(name), strings.Count(name, "/")
+}
@@ -0,0 +1,125 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/cadvisor/info/v2"
+	"github.com/google/cadvisor/utils/procfs"
+)
+
+func (self *manager) GetProcessTree(containerName string) (*v2.ProcessTree, error) {
+	cont, err := self.getContainerData(containerName)
+	if err != nil {
+		return nil, err
+	}
+	cgroupPath, err := cont.handler.GetCgroupPath("cpu")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cgroup path of %q: %v", containerName, err)
+	}
+	pids, err := procfs.GetCgroupPids(cgroupPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes of %q: %v", containerName, err)
+	}
+	procs := make([]procfs.ProcessStat, 0, len(pids))
+	for _, pid := range pids {
+		// Processes may exit while we walk them, ignore those.
+		stat, err := procfs.GetProcessStat(pid)
+		if err != nil {
+			continue
+		}
+		procs = append(procs, stat)
+	}
+	tree, ok := buildProcessTree(procs)
+	if !ok {
+		return nil, fmt.Errorf("no processes in container %q", containerName)
+	}
+	return tree, nil
+}
+
+// Builds the tree of the processes of a container from their parent PIDs. The
+// tree is rooted at the init of the container, the oldest of the processes
+// whose parent is not in the container. The other such processes, e.g. those
+// orphaned without a PID namespace whose parent becomes PID 1 of the host, or
+// those run in the container from outside, are children of the init. Returns
+// false if there are no processes.
+func buildProcessTree(procs []procfs.ProcessStat) (*v2.ProcessTree, bool) {
+	if len(procs) == 0 {
+		return nil, false
+	}
+	inContainer := make(map[int]bool, len(procs))
+	for _, proc := range procs {
+		inContainer[proc.Pid] = true
+	}
+
+	// The oldest process, the lowest PID on ties, is the init.
+	var init *procfs.ProcessStat
+	for i := range procs {
+		proc := &procs[i]
+		if inContainer[proc.Ppid] && proc.Ppid != proc.Pid {
+			continue
+		}
+		if init == nil || proc.StartTime < init.StartTime || (proc.StartTime == init.StartTime && proc.Pid < init.Pid) {
+			init = proc
+		}
+	}
+	if init == nil {
+		// Every process has its parent in the container, which only a cycle
+		// of PIDs reused while we walked them could cause.
+		init = &procs[0]
+	}
+
+	children := make(map[int][]procfs.ProcessStat, len(procs))
+	for _, proc := range procs {
+		if proc.Pid == init.Pid {
+			continue
+		}
+		parent := proc.Ppid
+		if !inContainer[parent] || parent == proc.Pid {
+			parent = init.Pid
+		}
+		children[parent] = append(children[parent], proc)
+	}
+	seen := make(map[int]bool, len(procs))
+	return processTree(*init, children, seen), true
+}
+
+// Builds the subtree of a process. Processes already in the tree are skipped
+// so PIDs reused while we walked them cannot make us recurse forever.
+func processTree(proc procfs.ProcessStat, children map[int][]procfs.ProcessStat, seen map[int]bool) *v2.ProcessTree {
+	seen[proc.Pid] = true
+	tree := &v2.ProcessTree{
+		Pid:     proc.Pid,
+		Ppid:    proc.Ppid,
+		Command: proc.Comm,
+	}
+	procChildren := children[proc.Pid]
+	sort.Sort(byPid(procChildren))
+	for _, child := range procChildren {
+		if seen[child.Pid] {
+			continue
+		}
+		tree.Children = append(tree.Children, *processTree(child, children, seen))
+	}
+	return tree
+}
+
+type byPid []procfs.ProcessStat
+
+func (self byPid) Len() int           { return len(self) }
+func (self byPid) Swap(i, j int)      { self[i], self[j] = self[j], self[i] }
+func (self byPid) Less(i, j int) bool { return self[i].Pid < self[j].Pid }
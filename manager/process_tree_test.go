@@ -0,0 +1,84 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/cadvisor/info/v2"
+	"github.com/google/cadvisor/utils/procfs"
+)
+
+func TestBuildProcessTree(t *testing.T) {
+	procs := []procfs.ProcessStat{
+		// Started by the runtime, outside of the container.
+		{Pid: 100, Ppid: 90, Comm: "init", StartTime: 10},
+		{Pid: 120, Ppid: 100, Comm: "nginx", StartTime: 20},
+		{Pid: 121, Ppid: 120, Comm: "worker", StartTime: 21},
+		{Pid: 110, Ppid: 120, Comm: "worker", StartTime: 22},
+		// Orphaned, reparented to PID 1 of the host.
+		{Pid: 150, Ppid: 1, Comm: "daemon", StartTime: 30},
+		// Run in the container from outside.
+		{Pid: 160, Ppid: 95, Comm: "sh", StartTime: 40},
+		{Pid: 161, Ppid: 160, Comm: "ps", StartTime: 41},
+	}
+	tree, ok := buildProcessTree(procs)
+	if !ok {
+		t.Fatal("expected a process tree")
+	}
+	expected := &v2.ProcessTree{
+		Pid: 100, Ppid: 90, Command: "init",
+		Children: []v2.ProcessTree{
+			{
+				Pid: 120, Ppid: 100, Command: "nginx",
+				Children: []v2.ProcessTree{
+					{Pid: 110, Ppid: 120, Command: "worker"},
+					{Pid: 121, Ppid: 120, Command: "worker"},
+				},
+			},
+			{Pid: 150, Ppid: 1, Command: "daemon"},
+			{
+				Pid: 160, Ppid: 95, Command: "sh",
+				Children: []v2.ProcessTree{
+					{Pid: 161, Ppid: 160, Command: "ps"},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(tree, expected) {
+		t.Errorf("expected process tree %+v, got %+v", expected, tree)
+	}
+}
+
+func TestBuildProcessTreeInitByStartTime(t *testing.T) {
+	// The init has a higher PID than the process run in the container later,
+	// as PIDs wrapped around.
+	procs := []procfs.ProcessStat{
+		{Pid: 30, Ppid: 5, Comm: "bash", StartTime: 500},
+		{Pid: 32000, Ppid: 5, Comm: "init", StartTime: 100},
+	}
+	tree, ok := buildProcessTree(procs)
+	if !ok {
+		t.Fatal("expected a process tree")
+	}
+	if tree.Pid != 32000 || len(tree.Children) != 1 || tree.Children[0].Pid != 30 {
+		t.Errorf("expected the oldest process at the root, got %+v", tree)
+	}
+
+	if _, ok := buildProcessTree(nil); ok {
+		t.Errorf("expected no process tree without processes")
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/info/v2"
+)
+
+// Computes a usage metric from the most recent stats of a container, oldest
+// first. Returns false if the stats are not enough to compute the metric.
+type usageMetric func(stats []*info.ContainerStats) (float64, bool)
+
+// Usage metrics containers can be filtered on, keyed by name.
+var usageMetrics = map[string]usageMetric{
+	// CPU usage rate of the last sample as a percentage of one core, only
+	// known once the container has a previous sample.
+	"cpu_usage_percent": func(stats []*info.ContainerStats) (float64, bool) {
+		if len(stats) < 2 {
+			return 0, false
+		}
+		return cpuUsagePercent(&stats[len(stats)-1].Cpu), true
+	},
+	"memory_usage_bytes": func(stats []*info.ContainerStats) (float64, bool) {
+		if len(stats) == 0 {
+			return 0, false
+		}
+		return float64(stats[len(stats)-1].Memory.Usage), true
+	},
+	"memory_working_set_bytes": func(stats []*info.ContainerStats) (float64, bool) {
+		if len(stats) == 0 {
+			return 0, false
+		}
+		return float64(stats[len(stats)-1].Memory.WorkingSet), true
+	},
+}
+
+// Returns the names of the usage metrics, sorted.
+func usageMetricNames() []string {
+	names := make([]string, 0, len(usageMetrics))
+	for name := range usageMetrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (self *manager) GetContainersAboveThreshold(containerName string, metric string, threshold float64, options v2.RequestOptions) (map[string]float64, error) {
+	usage, ok := usageMetrics[metric]
+	if !ok {
+		return nil, fmt.Errorf("unknown usage metric %q, options are %v", metric, usageMetricNames())
+	}
+	containers, err := self.getRequestedContainers(containerName, options)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string]float64)
+	var empty time.Time
+	for name, cont := range containers {
+		stats, err := self.memoryStorage.RecentStats(cont.info.Name, empty, empty, 2)
+		if err != nil {
+			// Skip containers without stats, we try to degrade gracefully.
+			continue
+		}
+		value, ok := usage(stats)
+		if ok && value > threshold {
+			ret[name] = value
+		}
+	}
+	return ret, nil
+}
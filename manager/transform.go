@@ -0,0 +1,120 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+var statsTransformsFlag = flag.String("stats_transforms", "", "Comma-separated list of the stats transforms deriving metrics from the stats of each container. Options are: utilization, and any transform registered with manager.RegisterStatsTransform")
+
+// A StatsTransform derives metrics from the stats of a container after they are
+// collected and adds them to cur.DerivedMetrics. prev is the previous stats of
+// the container, nil for its first stats.
+type StatsTransform func(spec *info.ContainerSpec, prev, cur *info.ContainerStats)
+
+// Global registry of stats transforms, keyed by name.
+var (
+	statsTransforms     = make(map[string]StatsTransform)
+	statsTransformsLock sync.RWMutex
+)
+
+func init() {
+	RegisterStatsTransform("utilization", utilizationTransform)
+}
+
+// Register a stats transform under the specified name. Transforms are only
+// applied when enabled with --stats_transforms. Registering a name twice
+// replaces the previous transform.
+func RegisterStatsTransform(name string, transform StatsTransform) {
+	statsTransformsLock.Lock()
+	defer statsTransformsLock.Unlock()
+
+	statsTransforms[name] = transform
+}
+
+// Returns the transforms registered under the comma-separated names, in order.
+func getStatsTransforms(names string) ([]StatsTransform, error) {
+	statsTransformsLock.RLock()
+	defer statsTransformsLock.RUnlock()
+
+	var ret []StatsTransform
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		transform, ok := statsTransforms[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown stats transform %q, options are %v", name, listStatsTransforms())
+		}
+		ret = append(ret, transform)
+	}
+	return ret, nil
+}
+
+// Returns the names of the registered stats transforms, sorted. Must be called
+// with statsTransformsLock held.
+func listStatsTransforms() []string {
+	names := make([]string, 0, len(statsTransforms))
+	for name := range statsTransforms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Derives the CPU usage rate as a percentage of one core, and the working set
+// as a fraction of the memory limit.
+func utilizationTransform(spec *info.ContainerSpec, prev, cur *info.ContainerStats) {
+	if spec.HasCpu && prev != nil {
+		addDerivedMetric(cur, "cpu_usage_percent", cpuUsagePercent(&cur.Cpu))
+	}
+	if spec.HasMemory && spec.Memory.Limit > 0 {
+		addDerivedMetric(cur, "memory_utilization", float64(cur.Memory.WorkingSet)/float64(spec.Memory.Limit))
+	}
+}
+
+// Flags the container as IO-constrained in the io_constrained derived metric:
+// 1 when some of its tasks were stalled on IO for more than threshold percent
+// of the last minute, 0 otherwise. Left unset without IO PSI, e.g. on cgroup v1.
+func setIoConstrained(stats *info.ContainerStats, threshold float64) {
+	if !stats.DiskIo.HasPSI {
+		return
+	}
+	constrained := 0.0
+	if stats.DiskIo.PSI.Some.Avg60 > threshold {
+		constrained = 1
+	}
+	addDerivedMetric(stats, "io_constrained", constrained)
+}
+
+// Returns the CPU usage rate as a percentage of one core.
+func cpuUsagePercent(cpu *info.CpuStats) float64 {
+	return float64(cpu.UsageRate.Total) / 10
+}
+
+func addDerivedMetric(stats *info.ContainerStats, name string, value float64) {
+	if stats.DerivedMetrics == nil {
+		stats.DerivedMetrics = make(map[string]float64)
+	}
+	stats.DerivedMetrics[name] = value
+}
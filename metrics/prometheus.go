@@ -15,7 +15,10 @@
 package metrics
 
 import (
+	"flag"
 	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/golang/glog"
@@ -23,10 +26,27 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+var exportMountInfo = flag.Bool("prometheus_mount_info", false, "Whether to export the mounts of containers as container_mount_info. Adds a series per mount")
+var exportUlimits = flag.Bool("prometheus_ulimits", false, "Whether to export the ulimits of containers as container_ulimits_soft and container_ulimits_hard. Adds two series per ulimit")
+var metricPrefixes = flag.String("prometheus_metric_prefixes", "", "Comma-separated list of metric name prefixes, e.g. container_cpu_,container_memory_. Only the metrics whose name starts with one of them are exported to Prometheus, all are if empty. Stats are collected regardless")
+var exportDerivedMetrics = flag.Bool("prometheus_derived_metrics", false, "Whether to export the metrics derived by the stats transforms as container_derived_metric. Adds a series per derived metric")
+
 // This will usually be manager.Manager, but can be swapped out for testing.
 type subcontainersInfoProvider interface {
 	// Get information about all subcontainers of the specified container (includes self).
 	SubcontainersInfo(containerName string, query *info.ContainerInfoRequest) ([]*info.ContainerInfo, error)
+
+	// Get the network statistics of the physical network interfaces of the machine.
+	GetMachineNetworkStats() ([]info.InterfaceStats, error)
+
+	// Get information about the machine.
+	GetMachineInfo() (*info.MachineInfo, error)
+
+	// Get the most recent samples of the host-wide usage of the machine.
+	GetMachineStats(numStats int) ([]*info.MachineStats, error)
+
+	// Returns whether the storage backends fall behind.
+	CollectionBackpressure() bool
 }
 
 // metricValue describes a single metric value for a given set of label values
@@ -44,12 +64,73 @@ func fsValues(fsStats []info.FsStats, valueFn func(*info.FsStats) float64) metri
 	for _, stat := range fsStats {
 		values = append(values, metricValue{
 			value:  valueFn(&stat),
-			labels: []string{stat.Device},
+			labels: []string{stat.Device, stat.Volume},
 		})
 	}
 	return values
 }
 
+// protocolValues is a helper method for assembling per-protocol network stats.
+func protocolValues(protocolStats []info.ProtocolStats, valueFn func(*info.ProtocolStats) float64) metricValues {
+	values := make(metricValues, 0, len(protocolStats))
+	for _, stat := range protocolStats {
+		values = append(values, metricValue{
+			value:  valueFn(&stat),
+			labels: []string{stat.Protocol},
+		})
+	}
+	return values
+}
+
+// ioValues is a helper method for assembling per-device and per-operation
+// blkio counters, divided by scale, e.g. to convert nanoseconds to seconds.
+// The "Total" operation is skipped as it would be counted twice when summing
+// over the operations.
+func ioValues(diskStats []info.PerDiskStats, scale float64) metricValues {
+	values := make(metricValues, 0, len(diskStats))
+	for _, disk := range diskStats {
+		device := fmt.Sprintf("%d:%d", disk.Major, disk.Minor)
+		for op, value := range disk.Stats {
+			if op == "Total" {
+				continue
+			}
+			values = append(values, metricValue{
+				value:  float64(value) / scale,
+				labels: []string{device, op},
+			})
+		}
+	}
+	return values
+}
+
+// ioMaxValues is a helper method for assembling the per-device IO limits of a
+// container, skipping the devices on which the limit is not set.
+func ioMaxValues(limits []info.IoMaxLimit, valueFn func(*info.IoMaxLimit) uint64) metricValues {
+	values := make(metricValues, 0, len(limits))
+	for i := range limits {
+		value := valueFn(&limits[i])
+		if value == 0 {
+			continue
+		}
+		values = append(values, metricValue{
+			value:  float64(value),
+			labels: []string{fmt.Sprintf("%d:%d", limits[i].Major, limits[i].Minor)},
+		})
+	}
+	return values
+}
+
+// Labels of all container metrics. The pod, namespace and container labels hold
+// the Kubernetes metadata of the container and are empty for containers not
+// managed by Kubernetes.
+var containerLabels = []string{"name", "id", "pod", "namespace", "container"}
+
+// Returns the values of containerLabels for the specified container.
+func containerLabelValues(container *info.ContainerInfo) []string {
+	k8s := container.Spec.Kubernetes
+	return []string{container.CanonicalName(), container.Name, k8s.PodName, k8s.Namespace, k8s.ContainerName}
+}
+
 // A containerMetric describes a multi-dimensional metric used for exposing
 // a certain type of container statistic.
 type containerMetric struct {
@@ -61,14 +142,56 @@ type containerMetric struct {
 }
 
 func (cm *containerMetric) desc() *prometheus.Desc {
-	return prometheus.NewDesc(cm.name, cm.help, append([]string{"name", "id"}, cm.extraLabels...), nil)
+	return prometheus.NewDesc(cm.name, cm.help, append(append([]string{}, containerLabels...), cm.extraLabels...), nil)
+}
+
+// A containerSpecMetric describes a metric derived from the spec of a
+// container rather than from its latest stats.
+type containerSpecMetric struct {
+	name        string
+	help        string
+	valueType   prometheus.ValueType
+	extraLabels []string
+	getValues   func(s *info.ContainerSpec) metricValues
+}
+
+func (cm *containerSpecMetric) desc() *prometheus.Desc {
+	return prometheus.NewDesc(cm.name, cm.help, append(append([]string{}, containerLabels...), cm.extraLabels...), nil)
+}
+
+// A machineNetworkMetric describes a per-interface network statistic of the machine.
+type machineNetworkMetric struct {
+	name     string
+	help     string
+	getValue func(s *info.InterfaceStats) float64
+}
+
+func (mm *machineNetworkMetric) desc() *prometheus.Desc {
+	return prometheus.NewDesc(mm.name, mm.help, []string{"interface"}, nil)
+}
+
+// A machineMetric describes a host-wide usage statistic of the machine.
+type machineMetric struct {
+	name      string
+	help      string
+	valueType prometheus.ValueType
+	getValue  func(s *info.MachineStats) float64
+}
+
+func (mm *machineMetric) desc() *prometheus.Desc {
+	return prometheus.NewDesc(mm.name, mm.help, nil, nil)
 }
 
 // PrometheusCollector implements prometheus.Collector.
 type PrometheusCollector struct {
-	infoProvider     subcontainersInfoProvider
-	errors           prometheus.Gauge
-	containerMetrics []containerMetric
+	infoProvider          subcontainersInfoProvider
+	errors                prometheus.Gauge
+	containerMetrics      []containerMetric
+	containerSpecMetrics  []containerSpecMetric
+	machineNetworkMetrics []machineNetworkMetric
+	machineMetrics        []machineMetric
+	// Prefixes of the names of the exported metrics, all are if empty.
+	metricPrefixes []string
 }
 
 // NewPrometheusCollector returns a new PrometheusCollector.
@@ -117,6 +240,105 @@ func NewPrometheusCollector(infoProvider subcontainersInfoProvider) *PrometheusC
 					}
 					return values
 				},
+			}, {
+				name:      "container_cpu_usage_rate",
+				help:      "CPU usage of the container over the interval since its previous sample in cores. Zero for its first sample.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Cpu.UsageRate.Total) / 1000}}
+				},
+			}, {
+				name:      "container_cpu_schedstat_run_seconds_total",
+				help:      "Time duration the processes of the container have run on the CPU.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Cpu.Schedstat.RunTime) / float64(time.Second)}}
+				},
+			}, {
+				name:      "container_cpu_schedstat_runqueue_seconds_total",
+				help:      "Time duration processes of the container have been waiting on a runqueue.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Cpu.Schedstat.RunqueueTime) / float64(time.Second)}}
+				},
+			}, {
+				name:        "container_cpu_context_switches_total",
+				help:        "Cumulative count of context switches of the processes of the container.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"type"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{
+						{
+							value:  float64(s.Cpu.ContextSwitches.Voluntary),
+							labels: []string{"voluntary"},
+						},
+						{
+							value:  float64(s.Cpu.ContextSwitches.Involuntary),
+							labels: []string{"involuntary"},
+						},
+					}
+				},
+			}, {
+				name:      "container_cpu_cfs_bursts_total",
+				help:      "Number of periods in which the container used its CFS burst budget.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Cpu.CFS.BurstCount)}}
+				},
+			}, {
+				name:      "container_cpu_cfs_burst_seconds_total",
+				help:      "Cumulative time the container ran above its CFS quota using its burst budget.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Cpu.CFS.BurstTime) / float64(time.Second)}}
+				},
+			}, {
+				name:        "container_blkio_device_usage_total",
+				help:        "Cumulative bytes transferred by the IOs of the container per device and operation, including discards.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"device", "operation"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return ioValues(s.DiskIo.IoServiceBytes, 1)
+				},
+			}, {
+				name:        "container_blkio_device_operations_total",
+				help:        "Cumulative count of IOs of the container per device and operation, including discards.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"device", "operation"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return ioValues(s.DiskIo.IoServiced, 1)
+				},
+			}, {
+				name:        "container_blkio_io_service_time_seconds_total",
+				help:        "Cumulative time between request dispatch and request completion for the IOs of the container, as reported by the CFQ scheduler.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"device", "operation"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return ioValues(s.DiskIo.IoServiceTime, float64(time.Second))
+				},
+			}, {
+				name:        "container_blkio_io_wait_time_seconds_total",
+				help:        "Cumulative time the IOs of the container spent waiting in the scheduler queues, as reported by the CFQ scheduler.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"device", "operation"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return ioValues(s.DiskIo.IoWaitTime, float64(time.Second))
+				},
+			}, {
+				name:        "container_blkio_throttled_seconds_total",
+				help:        "Cumulative time IO of the container was delayed by io.latency throttling.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"device"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					values := make(metricValues, 0, len(s.DiskIo.IoLatencyThrottled))
+					for _, disk := range s.DiskIo.IoLatencyThrottled {
+						values = append(values, metricValue{
+							value:  float64(disk.Stats["Total"]) / float64(time.Second),
+							labels: []string{fmt.Sprintf("%d:%d", disk.Major, disk.Minor)},
+						})
+					}
+					return values
+				},
 			}, {
 				name:      "container_memory_usage_bytes",
 				help:      "Current memory usage in bytes.",
@@ -124,6 +346,121 @@ func NewPrometheusCollector(infoProvider subcontainersInfoProvider) *PrometheusC
 				getValues: func(s *info.ContainerStats) metricValues {
 					return metricValues{{value: float64(s.Memory.Usage)}}
 				},
+			}, {
+				name:      "container_memory_cache",
+				help:      "Number of bytes of page cache memory.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Memory.Cache)}}
+				},
+			}, {
+				name:      "container_memory_rss",
+				help:      "Size of RSS in bytes.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Memory.RSS)}}
+				},
+			}, {
+				name:      "container_memory_mapped_file",
+				help:      "Size of memory mapped files in bytes.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Memory.MappedFile)}}
+				},
+			}, {
+				name:      "container_memory_swap",
+				help:      "Container swap usage in bytes.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Memory.Swap)}}
+				},
+			}, {
+				name:      "container_memory_swap_in_total",
+				help:      "Cumulative count of pages swapped in by the container.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Memory.Pswpin)}}
+				},
+			}, {
+				name:      "container_memory_swap_out_total",
+				help:      "Cumulative count of pages swapped out by the container.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Memory.Pswpout)}}
+				},
+			}, {
+				name:      "container_memory_low_events_total",
+				help:      "Cumulative count of times the container was reclaimed below its low memory boundary.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Memory.Events.Low)}}
+				},
+			}, {
+				name:      "container_memory_high_events_total",
+				help:      "Cumulative count of times the container was throttled over its high memory boundary.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Memory.Events.High)}}
+				},
+			}, {
+				name:      "container_memory_max_events_total",
+				help:      "Cumulative count of times the memory usage of the container was about to go over its limit.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Memory.Events.Max)}}
+				},
+			}, {
+				name:      "container_memory_oom_events_total",
+				help:      "Cumulative count of times the memory usage of the container reached its limit and an allocation failed.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Memory.Events.Oom)}}
+				},
+			}, {
+				name:      "container_memory_oom_kill_events_total",
+				help:      "Cumulative count of processes of the container killed by the OOM killer.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Memory.Events.OomKill)}}
+				},
+			}, {
+				name:      "container_memory_swap_max_events_total",
+				help:      "Cumulative count of times the swap usage of the container was about to go over its limit and swapping out failed. Only reported on cgroup v2 hosts with swap accounting.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					if s.Memory.SwapEvents == nil {
+						return metricValues{}
+					}
+					return metricValues{{value: float64(s.Memory.SwapEvents.Max)}}
+				},
+			}, {
+				name:      "container_memory_workingset_refault_total",
+				help:      "Cumulative count of refaults of previously evicted pages of the container. Only reported on cgroup v2 hosts.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Memory.WorkingSetEvents.Refault)}}
+				},
+			}, {
+				name:      "container_memory_workingset_activate_total",
+				help:      "Cumulative count of refaulted pages of the container that were immediately activated. Only reported on cgroup v2 hosts.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Memory.WorkingSetEvents.Activate)}}
+				},
+			}, {
+				name:      "container_memory_workingset_restore_total",
+				help:      "Cumulative count of restored pages of the container that were part of the active working set before being reclaimed. Only reported on cgroup v2 hosts.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Memory.WorkingSetEvents.Restore)}}
+				},
+			}, {
+				name:      "container_memory_kernel_usage",
+				help:      "Kernel memory (slab, stacks, sockets) charged to the container in bytes.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Memory.KernelUsage)}}
+				},
 			}, {
 				name:      "container_memory_working_set_bytes",
 				help:      "Current working set in bytes.",
@@ -160,7 +497,7 @@ func NewPrometheusCollector(infoProvider subcontainersInfoProvider) *PrometheusC
 				name:        "container_fs_limit_bytes",
 				help:        "Number of bytes that can be consumed by the container on this filesystem.",
 				valueType:   prometheus.GaugeValue,
-				extraLabels: []string{"device"},
+				extraLabels: []string{"device", "volume"},
 				getValues: func(s *info.ContainerStats) metricValues {
 					return fsValues(s.Filesystem, func(fs *info.FsStats) float64 {
 						return float64(fs.Limit)
@@ -170,7 +507,7 @@ func NewPrometheusCollector(infoProvider subcontainersInfoProvider) *PrometheusC
 				name:        "container_fs_usage_bytes",
 				help:        "Number of bytes that are consumed by the container on this filesystem.",
 				valueType:   prometheus.GaugeValue,
-				extraLabels: []string{"device"},
+				extraLabels: []string{"device", "volume"},
 				getValues: func(s *info.ContainerStats) metricValues {
 					return fsValues(s.Filesystem, func(fs *info.FsStats) float64 {
 						return float64(fs.Usage)
@@ -180,7 +517,7 @@ func NewPrometheusCollector(infoProvider subcontainersInfoProvider) *PrometheusC
 				name:        "container_fs_reads_total",
 				help:        "Cumulative count of reads completed",
 				valueType:   prometheus.CounterValue,
-				extraLabels: []string{"device"},
+				extraLabels: []string{"device", "volume"},
 				getValues: func(s *info.ContainerStats) metricValues {
 					return fsValues(s.Filesystem, func(fs *info.FsStats) float64 {
 						return float64(fs.ReadsCompleted)
@@ -190,7 +527,7 @@ func NewPrometheusCollector(infoProvider subcontainersInfoProvider) *PrometheusC
 				name:        "container_fs_sector_reads_total",
 				help:        "Cumulative count of sector reads completed",
 				valueType:   prometheus.CounterValue,
-				extraLabels: []string{"device"},
+				extraLabels: []string{"device", "volume"},
 				getValues: func(s *info.ContainerStats) metricValues {
 					return fsValues(s.Filesystem, func(fs *info.FsStats) float64 {
 						return float64(fs.SectorsRead)
@@ -200,7 +537,7 @@ func NewPrometheusCollector(infoProvider subcontainersInfoProvider) *PrometheusC
 				name:        "container_fs_reads_merged_total",
 				help:        "Cumulative count of reads merged",
 				valueType:   prometheus.CounterValue,
-				extraLabels: []string{"device"},
+				extraLabels: []string{"device", "volume"},
 				getValues: func(s *info.ContainerStats) metricValues {
 					return fsValues(s.Filesystem, func(fs *info.FsStats) float64 {
 						return float64(fs.ReadsMerged)
@@ -210,7 +547,7 @@ func NewPrometheusCollector(infoProvider subcontainersInfoProvider) *PrometheusC
 				name:        "container_fs_read_seconds_total",
 				help:        "Cumulative count of seconds spent reading",
 				valueType:   prometheus.CounterValue,
-				extraLabels: []string{"device"},
+				extraLabels: []string{"device", "volume"},
 				getValues: func(s *info.ContainerStats) metricValues {
 					return fsValues(s.Filesystem, func(fs *info.FsStats) float64 {
 						return float64(fs.ReadTime) / float64(time.Second)
@@ -220,7 +557,7 @@ func NewPrometheusCollector(infoProvider subcontainersInfoProvider) *PrometheusC
 				name:        "container_fs_writes_total",
 				help:        "Cumulative count of writes completed",
 				valueType:   prometheus.CounterValue,
-				extraLabels: []string{"device"},
+				extraLabels: []string{"device", "volume"},
 				getValues: func(s *info.ContainerStats) metricValues {
 					return fsValues(s.Filesystem, func(fs *info.FsStats) float64 {
 						return float64(fs.WritesCompleted)
@@ -230,7 +567,7 @@ func NewPrometheusCollector(infoProvider subcontainersInfoProvider) *PrometheusC
 				name:        "container_fs_sector_writes_total",
 				help:        "Cumulative count of sector writes completed",
 				valueType:   prometheus.CounterValue,
-				extraLabels: []string{"device"},
+				extraLabels: []string{"device", "volume"},
 				getValues: func(s *info.ContainerStats) metricValues {
 					return fsValues(s.Filesystem, func(fs *info.FsStats) float64 {
 						return float64(fs.SectorsWritten)
@@ -240,7 +577,7 @@ func NewPrometheusCollector(infoProvider subcontainersInfoProvider) *PrometheusC
 				name:        "container_fs_writes_merged_total",
 				help:        "Cumulative count of writes merged",
 				valueType:   prometheus.CounterValue,
-				extraLabels: []string{"device"},
+				extraLabels: []string{"device", "volume"},
 				getValues: func(s *info.ContainerStats) metricValues {
 					return fsValues(s.Filesystem, func(fs *info.FsStats) float64 {
 						return float64(fs.WritesMerged)
@@ -250,7 +587,7 @@ func NewPrometheusCollector(infoProvider subcontainersInfoProvider) *PrometheusC
 				name:        "container_fs_write_seconds_total",
 				help:        "Cumulative count of seconds spent writing",
 				valueType:   prometheus.CounterValue,
-				extraLabels: []string{"device"},
+				extraLabels: []string{"device", "volume"},
 				getValues: func(s *info.ContainerStats) metricValues {
 					return fsValues(s.Filesystem, func(fs *info.FsStats) float64 {
 						return float64(fs.WriteTime) / float64(time.Second)
@@ -260,7 +597,7 @@ func NewPrometheusCollector(infoProvider subcontainersInfoProvider) *PrometheusC
 				name:        "container_fs_io_current",
 				help:        "Number of I/Os currently in progress",
 				valueType:   prometheus.GaugeValue,
-				extraLabels: []string{"device"},
+				extraLabels: []string{"device", "volume"},
 				getValues: func(s *info.ContainerStats) metricValues {
 					return fsValues(s.Filesystem, func(fs *info.FsStats) float64 {
 						return float64(fs.IoInProgress)
@@ -270,7 +607,7 @@ func NewPrometheusCollector(infoProvider subcontainersInfoProvider) *PrometheusC
 				name:        "container_fs_io_time_seconds_total",
 				help:        "Cumulative count of seconds spent doing I/Os",
 				valueType:   prometheus.CounterValue,
-				extraLabels: []string{"device"},
+				extraLabels: []string{"device", "volume"},
 				getValues: func(s *info.ContainerStats) metricValues {
 					return fsValues(s.Filesystem, func(fs *info.FsStats) float64 {
 						return float64(float64(fs.IoTime) / float64(time.Second))
@@ -280,7 +617,7 @@ func NewPrometheusCollector(infoProvider subcontainersInfoProvider) *PrometheusC
 				name:        "container_fs_io_time_weighted_seconds_total",
 				help:        "Cumulative weighted I/O time in seconds",
 				valueType:   prometheus.CounterValue,
-				extraLabels: []string{"device"},
+				extraLabels: []string{"device", "volume"},
 				getValues: func(s *info.ContainerStats) metricValues {
 					return fsValues(s.Filesystem, func(fs *info.FsStats) float64 {
 						return float64(fs.WeightedIoTime) / float64(time.Second)
@@ -342,6 +679,89 @@ func NewPrometheusCollector(infoProvider subcontainersInfoProvider) *PrometheusC
 				getValues: func(s *info.ContainerStats) metricValues {
 					return metricValues{{value: float64(s.Network.TxErrors)}}
 				},
+			}, {
+				name:      "container_network_tcp_memory_bytes",
+				help:      "Memory used by TCP socket buffers in the network namespace of the container in bytes",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Network.TcpMemUsage)}}
+				},
+			}, {
+				name:      "container_network_conntrack_entries",
+				help:      "Number of connections tracked by netfilter in the network namespace of the container",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Network.ConntrackCount)}}
+				},
+			}, {
+				name:      "container_network_conntrack_limit",
+				help:      "Number of connections netfilter tracks before dropping new ones",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Network.ConntrackLimit)}}
+				},
+			}, {
+				name:        "container_network_protocol_receive_bytes_total",
+				help:        "Cumulative count of bytes received by the network namespace of the container per IP version",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"proto"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return protocolValues(s.Network.Protocols, func(p *info.ProtocolStats) float64 {
+						return float64(p.RxBytes)
+					})
+				},
+			}, {
+				name:        "container_network_protocol_receive_packets_total",
+				help:        "Cumulative count of datagrams received by the network namespace of the container per IP version",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"proto"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return protocolValues(s.Network.Protocols, func(p *info.ProtocolStats) float64 {
+						return float64(p.RxPackets)
+					})
+				},
+			}, {
+				name:        "container_network_protocol_transmit_bytes_total",
+				help:        "Cumulative count of bytes transmitted by the network namespace of the container per IP version",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"proto"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return protocolValues(s.Network.Protocols, func(p *info.ProtocolStats) float64 {
+						return float64(p.TxBytes)
+					})
+				},
+			}, {
+				name:        "container_network_protocol_transmit_packets_total",
+				help:        "Cumulative count of datagrams transmitted by the network namespace of the container per IP version",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"proto"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return protocolValues(s.Network.Protocols, func(p *info.ProtocolStats) float64 {
+						return float64(p.TxPackets)
+					})
+				},
+			}, {
+				name:        "container_network_interface_speed_bytes",
+				help:        "Link speed of the network interface of the container in bytes per second. Zero for interfaces not reporting it.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"interface"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					if s.Network.Interface == "" {
+						return nil
+					}
+					return metricValues{{value: float64(s.Network.Speed), labels: []string{s.Network.Interface}}}
+				},
+			}, {
+				name:        "container_network_interface_mtu",
+				help:        "Maximum transmission unit of the network interface of the container in bytes.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"interface"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					if s.Network.Interface == "" {
+						return nil
+					}
+					return metricValues{{value: float64(s.Network.Mtu), labels: []string{s.Network.Interface}}}
+				},
 			}, {
 				name:        "container_tasks_state",
 				help:        "Number of tasks in given state",
@@ -371,24 +791,535 @@ func NewPrometheusCollector(infoProvider subcontainersInfoProvider) *PrometheusC
 						},
 					}
 				},
+			}, {
+				name:      "container_file_descriptors",
+				help:      "Number of open file descriptors in the container.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Processes.OpenFds)}}
+				},
+			}, {
+				name:      "container_processes",
+				help:      "Number of processes in the container. Only reported when PID tracking is enabled.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Processes.PidCount)}}
+				},
+			}, {
+				name:      "container_threads",
+				help:      "Number of threads in the container.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Processes.ThreadCount)}}
+				},
+			}, {
+				name:        "container_misc_current",
+				help:        "Number of units of a misc controller resource in use by the container.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"resource"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					values := make(metricValues, 0, len(s.MiscStats))
+					for resource, stats := range s.MiscStats {
+						values = append(values, metricValue{value: float64(stats.Current), labels: []string{resource}})
+					}
+					return values
+				},
+			}, {
+				name:        "container_misc_max",
+				help:        "Maximum number of units of a misc controller resource the container may use. Not reported when unlimited.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"resource"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					values := make(metricValues, 0, len(s.MiscStats))
+					for resource, stats := range s.MiscStats {
+						if stats.Max == math.MaxUint64 {
+							continue
+						}
+						values = append(values, metricValue{value: float64(stats.Max), labels: []string{resource}})
+					}
+					return values
+				},
+			}, {
+				name:      "container_io_constrained",
+				help:      "Whether some tasks of the container were stalled on IO for more than the configured share of the last minute (1) or not (0). Only reported when --io_constrained_threshold is set, on hosts with IO PSI.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					constrained, ok := s.DerivedMetrics["io_constrained"]
+					if !ok {
+						return nil
+					}
+					return metricValues{{value: constrained}}
+				},
+			}, {
+				name:        "container_processes_churn_total",
+				help:        "Cumulative count of processes that appeared in or disappeared from the container. Only reported when PID tracking is enabled.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"direction"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{
+						{value: float64(s.Processes.PidsAdded), labels: []string{"added"}},
+						{value: float64(s.Processes.PidsRemoved), labels: []string{"removed"}},
+					}
+				},
+			}, {
+				name:      "container_oom_events_total",
+				help:      "Cumulative count of out of memory kills in the container.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.OomEvents)}}
+				},
+			}, {
+				name:      "container_dns_queries_total",
+				help:      "Cumulative count of DNS queries sent by the container.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					if s.Dns == nil {
+						return nil
+					}
+					return metricValues{{value: float64(s.Dns.Queries)}}
+				},
+			}, {
+				name:      "container_dns_failures_total",
+				help:      "Cumulative count of DNS queries sent by the container that failed or were left unanswered.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					if s.Dns == nil {
+						return nil
+					}
+					return metricValues{{value: float64(s.Dns.Failures)}}
+				},
+			}, {
+				name:      "container_dns_latency_seconds_total",
+				help:      "Cumulative time spent waiting for the answers to the DNS queries of the container in seconds.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					if s.Dns == nil {
+						return nil
+					}
+					return metricValues{{value: float64(s.Dns.LatencyTotal) / float64(time.Second)}}
+				},
+			},
+		},
+		containerSpecMetrics: []containerSpecMetric{
+			{
+				name:      "container_last_exit_code",
+				help:      "Exit code of the last run of the container, 0 if it never exited.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerSpec) metricValues {
+					return metricValues{{value: float64(s.LastExitCode)}}
+				},
+			}, {
+				name:      "container_spec_cpu_burst",
+				help:      "CPU time in microseconds the container may run past its quota in a period, 0 if CPU bursting is not supported.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerSpec) metricValues {
+					return metricValues{{value: float64(s.Cpu.Burst)}}
+				},
+			}, {
+				name:      "container_spec_cpu_idle",
+				help:      "Whether the container is scheduled as idle, i.e. only runs when nothing else would, 1 if so and 0 otherwise.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerSpec) metricValues {
+					if s.Cpu.Idle {
+						return metricValues{{value: 1}}
+					}
+					return metricValues{{value: 0}}
+				},
+			}, {
+				name:      "container_cpuset_cpus_count",
+				help:      "Number of CPUs the processes of the container may run on according to its cpuset.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerSpec) metricValues {
+					if len(s.AllowedCpus) == 0 {
+						return metricValues{}
+					}
+					return metricValues{{value: float64(len(s.AllowedCpus))}}
+				},
+			}, {
+				name:        "container_spec_blkio_read_bps_device",
+				help:        "Limit of the bytes per second the container may read from the device.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"device"},
+				getValues: func(s *info.ContainerSpec) metricValues {
+					return ioMaxValues(s.IoMax, func(l *info.IoMaxLimit) uint64 { return l.ReadBps })
+				},
+			}, {
+				name:        "container_spec_blkio_write_bps_device",
+				help:        "Limit of the bytes per second the container may write to the device.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"device"},
+				getValues: func(s *info.ContainerSpec) metricValues {
+					return ioMaxValues(s.IoMax, func(l *info.IoMaxLimit) uint64 { return l.WriteBps })
+				},
+			}, {
+				name:        "container_spec_blkio_read_iops_device",
+				help:        "Limit of the read operations per second the container may issue to the device.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"device"},
+				getValues: func(s *info.ContainerSpec) metricValues {
+					return ioMaxValues(s.IoMax, func(l *info.IoMaxLimit) uint64 { return l.ReadIops })
+				},
+			}, {
+				name:        "container_spec_blkio_write_iops_device",
+				help:        "Limit of the write operations per second the container may issue to the device.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"device"},
+				getValues: func(s *info.ContainerSpec) metricValues {
+					return ioMaxValues(s.IoMax, func(l *info.IoMaxLimit) uint64 { return l.WriteIops })
+				},
+			}, {
+				name:      "container_spec_device_access_rules",
+				help:      "Number of rules granting the container access to devices, a rule of type a grants access to all devices.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerSpec) metricValues {
+					if len(s.DeviceAccess) == 0 {
+						return metricValues{}
+					}
+					return metricValues{{value: float64(len(s.DeviceAccess))}}
+				},
+			}, {
+				name:      "container_network_class_id",
+				help:      "Class ID tagging the network packets of the container from its net_cls cgroup.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerSpec) metricValues {
+					if s.NetClsId == 0 {
+						return metricValues{}
+					}
+					return metricValues{{value: float64(s.NetClsId)}}
+				},
+			}, {
+				name:      "container_oom_score_adj",
+				help:      "Adjustment of the OOM killer score of the init process of the container, from -1000 to 1000.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerSpec) metricValues {
+					if s.OomScoreAdj == nil {
+						return metricValues{}
+					}
+					return metricValues{{value: float64(*s.OomScoreAdj)}}
+				},
+			}, {
+				name:        "container_controllers_info",
+				help:        "Cgroup controllers present for the container, the value is always 1. Stats of the missing ones are not collected.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"controller"},
+				getValues: func(s *info.ContainerSpec) metricValues {
+					values := make(metricValues, 0, len(s.ControllersAvailable))
+					for _, controller := range s.ControllersAvailable {
+						values = append(values, metricValue{value: 1, labels: []string{controller}})
+					}
+					return values
+				},
+			}, {
+				name:        "container_image_info",
+				help:        "Information about the image of the container, the value is always 1.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"image_id", "registry", "repository"},
+				getValues: func(s *info.ContainerSpec) metricValues {
+					if s.ImageID == "" && s.ImageRepository == "" {
+						return metricValues{}
+					}
+					return metricValues{{value: 1, labels: []string{s.ImageID, s.ImageRegistry, s.ImageRepository}}}
+				},
+			},
+		},
+		machineMetrics: []machineMetric{
+			{
+				name:      "machine_cpu_usage_seconds_total",
+				help:      "Cumulative CPU time of all the cores of the machine spent running tasks in seconds.",
+				valueType: prometheus.CounterValue,
+				getValue:  func(s *info.MachineStats) float64 { return float64(s.Cpu.Total) / float64(time.Second) },
+			}, {
+				name:      "machine_cpu_user_seconds_total",
+				help:      "Cumulative CPU time of all the cores of the machine spent in user mode in seconds.",
+				valueType: prometheus.CounterValue,
+				getValue:  func(s *info.MachineStats) float64 { return float64(s.Cpu.User) / float64(time.Second) },
+			}, {
+				name:      "machine_cpu_system_seconds_total",
+				help:      "Cumulative CPU time of all the cores of the machine spent in system mode in seconds.",
+				valueType: prometheus.CounterValue,
+				getValue:  func(s *info.MachineStats) float64 { return float64(s.Cpu.System) / float64(time.Second) },
+			}, {
+				name:      "machine_cpu_steal_seconds_total",
+				help:      "Cumulative CPU time of all the cores of the machine stolen by the hypervisor in seconds, not counted as usage.",
+				valueType: prometheus.CounterValue,
+				getValue:  func(s *info.MachineStats) float64 { return float64(s.Cpu.Steal) / float64(time.Second) },
+			}, {
+				name:      "machine_memory_usage_bytes",
+				help:      "Memory of the machine in use in bytes, i.e. not available without swapping.",
+				valueType: prometheus.GaugeValue,
+				getValue:  func(s *info.MachineStats) float64 { return float64(s.Memory.Usage) },
+			}, {
+				name:      "machine_memory_available_bytes",
+				help:      "Memory of the machine available to new applications without swapping in bytes.",
+				valueType: prometheus.GaugeValue,
+				getValue:  func(s *info.MachineStats) float64 { return float64(s.Memory.Available) },
+			}, {
+				name:      "machine_memory_free_bytes",
+				help:      "Memory of the machine not used at all in bytes.",
+				valueType: prometheus.GaugeValue,
+				getValue:  func(s *info.MachineStats) float64 { return float64(s.Memory.Free) },
+			}, {
+				name:      "machine_memory_buffers_bytes",
+				help:      "Memory of the machine used by the buffers of block devices in bytes.",
+				valueType: prometheus.GaugeValue,
+				getValue:  func(s *info.MachineStats) float64 { return float64(s.Memory.Buffers) },
+			}, {
+				name:      "machine_memory_cached_bytes",
+				help:      "Memory of the machine used by the page cache in bytes.",
+				valueType: prometheus.GaugeValue,
+				getValue:  func(s *info.MachineStats) float64 { return float64(s.Memory.Cached) },
+			}, {
+				name:      "machine_memory_slab_reclaimable_bytes",
+				help:      "Slab memory of the machine that can be reclaimed in bytes.",
+				valueType: prometheus.GaugeValue,
+				getValue:  func(s *info.MachineStats) float64 { return float64(s.Memory.SlabReclaimable) },
+			}, {
+				name:      "machine_memory_swap_total_bytes",
+				help:      "Swap space of the machine in bytes.",
+				valueType: prometheus.GaugeValue,
+				getValue:  func(s *info.MachineStats) float64 { return float64(s.Memory.SwapTotal) },
+			}, {
+				name:      "machine_memory_swap_free_bytes",
+				help:      "Unused swap space of the machine in bytes.",
+				valueType: prometheus.GaugeValue,
+				getValue:  func(s *info.MachineStats) float64 { return float64(s.Memory.SwapFree) },
+			}, {
+				name:      "machine_entropy_available_bits",
+				help:      "Entropy available in the random number pool of the kernel in bits.",
+				valueType: prometheus.GaugeValue,
+				getValue:  func(s *info.MachineStats) float64 { return float64(s.AvailableEntropy) },
+			}, {
+				name:      "machine_tasks_running",
+				help:      "Number of runnable tasks of the machine.",
+				valueType: prometheus.GaugeValue,
+				getValue:  func(s *info.MachineStats) float64 { return float64(s.Load.RunningTasks) },
+			}, {
+				name:      "machine_tasks",
+				help:      "Number of tasks of the machine, threads included.",
+				valueType: prometheus.GaugeValue,
+				getValue:  func(s *info.MachineStats) float64 { return float64(s.Load.TotalTasks) },
+			},
+		},
+		machineNetworkMetrics: []machineNetworkMetric{
+			{
+				name:     "machine_network_receive_bytes_total",
+				help:     "Cumulative count of bytes received by the machine",
+				getValue: func(s *info.InterfaceStats) float64 { return float64(s.RxBytes) },
+			}, {
+				name:     "machine_network_receive_packets_total",
+				help:     "Cumulative count of packets received by the machine",
+				getValue: func(s *info.InterfaceStats) float64 { return float64(s.RxPackets) },
+			}, {
+				name:     "machine_network_receive_packets_dropped_total",
+				help:     "Cumulative count of packets dropped while receiving by the machine",
+				getValue: func(s *info.InterfaceStats) float64 { return float64(s.RxDropped) },
+			}, {
+				name:     "machine_network_receive_errors_total",
+				help:     "Cumulative count of errors encountered while receiving by the machine",
+				getValue: func(s *info.InterfaceStats) float64 { return float64(s.RxErrors) },
+			}, {
+				name:     "machine_network_transmit_bytes_total",
+				help:     "Cumulative count of bytes transmitted by the machine",
+				getValue: func(s *info.InterfaceStats) float64 { return float64(s.TxBytes) },
+			}, {
+				name:     "machine_network_transmit_packets_total",
+				help:     "Cumulative count of packets transmitted by the machine",
+				getValue: func(s *info.InterfaceStats) float64 { return float64(s.TxPackets) },
+			}, {
+				name:     "machine_network_transmit_packets_dropped_total",
+				help:     "Cumulative count of packets dropped while transmitting by the machine",
+				getValue: func(s *info.InterfaceStats) float64 { return float64(s.TxDropped) },
+			}, {
+				name:     "machine_network_transmit_errors_total",
+				help:     "Cumulative count of errors encountered while transmitting by the machine",
+				getValue: func(s *info.InterfaceStats) float64 { return float64(s.TxErrors) },
 			},
 		},
 	}
+	if *exportDerivedMetrics {
+		c.containerMetrics = append(c.containerMetrics, containerMetric{
+			name:        "container_derived_metric",
+			help:        "Value of a metric derived from the stats of the container by a stats transform.",
+			valueType:   prometheus.GaugeValue,
+			extraLabels: []string{"metric"},
+			getValues: func(s *info.ContainerStats) metricValues {
+				values := make(metricValues, 0, len(s.DerivedMetrics))
+				for name, value := range s.DerivedMetrics {
+					values = append(values, metricValue{
+						value:  value,
+						labels: []string{name},
+					})
+				}
+				return values
+			},
+		})
+	}
+	if *exportMountInfo {
+		c.containerSpecMetrics = append(c.containerSpecMetrics, containerSpecMetric{
+			name:        "container_mount_info",
+			help:        "Information about a mount of the container, the value is always 1.",
+			valueType:   prometheus.GaugeValue,
+			extraLabels: []string{"destination", "type"},
+			getValues: func(s *info.ContainerSpec) metricValues {
+				values := make(metricValues, 0, len(s.Mounts))
+				for _, mount := range s.Mounts {
+					values = append(values, metricValue{
+						value:  1,
+						labels: []string{mount.Destination, mount.Type},
+					})
+				}
+				return values
+			},
+		})
+	}
+	if *exportUlimits {
+		c.containerSpecMetrics = append(c.containerSpecMetrics, containerSpecMetric{
+			name:        "container_ulimits_soft",
+			help:        "Soft limit of a resource of the processes of the container, -1 if unlimited.",
+			valueType:   prometheus.GaugeValue,
+			extraLabels: []string{"ulimit"},
+			getValues: func(s *info.ContainerSpec) metricValues {
+				values := make(metricValues, 0, len(s.Ulimits))
+				for _, ulimit := range s.Ulimits {
+					values = append(values, metricValue{
+						value:  float64(ulimit.SoftLimit),
+						labels: []string{ulimit.Name},
+					})
+				}
+				return values
+			},
+		}, containerSpecMetric{
+			name:        "container_ulimits_hard",
+			help:        "Hard limit of a resource of the processes of the container, -1 if unlimited.",
+			valueType:   prometheus.GaugeValue,
+			extraLabels: []string{"ulimit"},
+			getValues: func(s *info.ContainerSpec) metricValues {
+				values := make(metricValues, 0, len(s.Ulimits))
+				for _, ulimit := range s.Ulimits {
+					values = append(values, metricValue{
+						value:  float64(ulimit.HardLimit),
+						labels: []string{ulimit.Name},
+					})
+				}
+				return values
+			},
+		})
+	}
+	c.setMetricPrefixes(*metricPrefixes)
 	return c
 }
 
+// Restricts the exported metrics to those whose name starts with one of the
+// comma-separated prefixes. All metrics are exported if there are none.
+func (c *PrometheusCollector) setMetricPrefixes(prefixes string) {
+	c.metricPrefixes = nil
+	for _, prefix := range strings.Split(prefixes, ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			c.metricPrefixes = append(c.metricPrefixes, prefix)
+		}
+	}
+	if len(c.metricPrefixes) == 0 {
+		return
+	}
+
+	containerMetrics := c.containerMetrics[:0]
+	for _, cm := range c.containerMetrics {
+		if c.exported(cm.name) {
+			containerMetrics = append(containerMetrics, cm)
+		}
+	}
+	c.containerMetrics = containerMetrics
+	containerSpecMetrics := c.containerSpecMetrics[:0]
+	for _, cm := range c.containerSpecMetrics {
+		if c.exported(cm.name) {
+			containerSpecMetrics = append(containerSpecMetrics, cm)
+		}
+	}
+	c.containerSpecMetrics = containerSpecMetrics
+	machineNetworkMetrics := c.machineNetworkMetrics[:0]
+	for _, mm := range c.machineNetworkMetrics {
+		if c.exported(mm.name) {
+			machineNetworkMetrics = append(machineNetworkMetrics, mm)
+		}
+	}
+	c.machineNetworkMetrics = machineNetworkMetrics
+	machineMetrics := c.machineMetrics[:0]
+	for _, mm := range c.machineMetrics {
+		if c.exported(mm.name) {
+			machineMetrics = append(machineMetrics, mm)
+		}
+	}
+	c.machineMetrics = machineMetrics
+}
+
+// Returns whether the metric with the specified name is exported.
+func (c *PrometheusCollector) exported(name string) bool {
+	if len(c.metricPrefixes) == 0 {
+		return true
+	}
+	for _, prefix := range c.metricPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 // Describe describes all the metrics ever exported by cadvisor. It
 // implements prometheus.PrometheusCollector.
 func (c *PrometheusCollector) Describe(ch chan<- *prometheus.Desc) {
-	c.errors.Describe(ch)
+	if c.exported(scrapeErrorName) {
+		c.errors.Describe(ch)
+	}
 	for _, cm := range c.containerMetrics {
 		ch <- cm.desc()
 	}
+	for _, cm := range c.containerSpecMetrics {
+		ch <- cm.desc()
+	}
+	for _, mm := range c.machineNetworkMetrics {
+		ch <- mm.desc()
+	}
+	for _, mm := range c.machineMetrics {
+		ch <- mm.desc()
+	}
+	if c.exported(machineBootTimeName) {
+		ch <- machineBootTimeDesc
+	}
+	if c.exported(machineLoadAverageName) {
+		ch <- machineLoadAverageDesc
+	}
+	if c.exported(machineDiskSizeName) {
+		ch <- machineDiskSizeDesc
+	}
+	if c.exported(backpressureName) {
+		ch <- backpressureDesc
+	}
 }
 
 // Collect fetches the stats from all containers and delivers them as
 // Prometheus metrics. It implements prometheus.PrometheusCollector.
 func (c *PrometheusCollector) Collect(ch chan<- prometheus.Metric) {
+	c.collectContainers(ch)
+	c.collectMachineNetworkStats(ch)
+	c.collectMachineStats(ch)
+	c.collectMachineInfo(ch)
+	if c.exported(backpressureName) {
+		var backpressure float64
+		if c.infoProvider.CollectionBackpressure() {
+			backpressure = 1
+		}
+		ch <- prometheus.MustNewConstMetric(backpressureDesc, prometheus.GaugeValue, backpressure)
+	}
+	if c.exported(scrapeErrorName) {
+		c.errors.Collect(ch)
+	}
+}
+
+func (c *PrometheusCollector) collectContainers(ch chan<- prometheus.Metric) {
+	if len(c.containerMetrics) == 0 && len(c.containerSpecMetrics) == 0 {
+		return
+	}
 	containers, err := c.infoProvider.SubcontainersInfo("/", &info.ContainerInfoRequest{NumStats: 1})
 	if err != nil {
 		c.errors.Set(1)
@@ -396,19 +1327,97 @@ func (c *PrometheusCollector) Collect(ch chan<- prometheus.Metric) {
 		return
 	}
 	for _, container := range containers {
-		id := container.Name
-		name := id
-		if len(container.Aliases) > 0 {
-			name = container.Aliases[0]
-		}
+		labelValues := containerLabelValues(container)
 		stats := container.Stats[0]
 
 		for _, cm := range c.containerMetrics {
 			desc := cm.desc()
 			for _, metricValue := range cm.getValues(stats) {
-				ch <- prometheus.MustNewConstMetric(desc, cm.valueType, float64(metricValue.value), append([]string{name, id}, metricValue.labels...)...)
+				ch <- prometheus.MustNewConstMetric(desc, cm.valueType, float64(metricValue.value), append(labelValues, metricValue.labels...)...)
+			}
+		}
+		for _, cm := range c.containerSpecMetrics {
+			desc := cm.desc()
+			for _, metricValue := range cm.getValues(&container.Spec) {
+				ch <- prometheus.MustNewConstMetric(desc, cm.valueType, float64(metricValue.value), append(labelValues, metricValue.labels...)...)
 			}
 		}
 	}
-	c.errors.Collect(ch)
+}
+
+// Names of the metrics not described by a containerMetric, containerSpecMetric,
+// machineNetworkMetric or machineMetric.
+const (
+	scrapeErrorName        = "container_scrape_error"
+	machineBootTimeName    = "machine_boot_time_seconds"
+	machineLoadAverageName = "machine_load_average"
+	machineDiskSizeName    = "machine_disk_size_bytes"
+	backpressureName       = "cadvisor_collection_backpressure"
+)
+
+var machineBootTimeDesc = prometheus.NewDesc(machineBootTimeName, "Time the machine booted in seconds since the epoch.", nil, nil)
+
+var machineLoadAverageDesc = prometheus.NewDesc(machineLoadAverageName, "Average number of runnable or uninterruptible tasks of the machine over the period.", []string{"period"}, nil)
+
+var machineDiskSizeDesc = prometheus.NewDesc(machineDiskSizeName, "Size of a block device of the machine in bytes.", []string{"device"}, nil)
+
+var backpressureDesc = prometheus.NewDesc(backpressureName, "Whether the storage backends fall behind and the housekeeping of the containers is slowed down, 1 if so and 0 otherwise.", nil, nil)
+
+func (c *PrometheusCollector) collectMachineInfo(ch chan<- prometheus.Metric) {
+	if !c.exported(machineBootTimeName) && !c.exported(machineDiskSizeName) {
+		return
+	}
+	machineInfo, err := c.infoProvider.GetMachineInfo()
+	if err != nil {
+		c.errors.Set(1)
+		glog.Warningf("Couldn't get machine info: %v", err)
+		return
+	}
+	if c.exported(machineBootTimeName) && !machineInfo.BootTime.IsZero() {
+		ch <- prometheus.MustNewConstMetric(machineBootTimeDesc, prometheus.GaugeValue, float64(machineInfo.BootTime.Unix()))
+	}
+	if c.exported(machineDiskSizeName) {
+		for _, disk := range machineInfo.DiskMap {
+			ch <- prometheus.MustNewConstMetric(machineDiskSizeDesc, prometheus.GaugeValue, float64(disk.Size), disk.Name)
+		}
+	}
+}
+
+func (c *PrometheusCollector) collectMachineStats(ch chan<- prometheus.Metric) {
+	if len(c.machineMetrics) == 0 && !c.exported(machineLoadAverageName) {
+		return
+	}
+	// Not an error as the collection of the host-wide usage may be disabled.
+	stats, err := c.infoProvider.GetMachineStats(1)
+	if err != nil || len(stats) == 0 {
+		glog.V(4).Infof("No machine stats to export: %v", err)
+		return
+	}
+	for _, mm := range c.machineMetrics {
+		ch <- prometheus.MustNewConstMetric(mm.desc(), mm.valueType, mm.getValue(stats[0]))
+	}
+	if c.exported(machineLoadAverageName) {
+		load := stats[0].Load
+		ch <- prometheus.MustNewConstMetric(machineLoadAverageDesc, prometheus.GaugeValue, load.LoadAverage1, "1m")
+		ch <- prometheus.MustNewConstMetric(machineLoadAverageDesc, prometheus.GaugeValue, load.LoadAverage5, "5m")
+		ch <- prometheus.MustNewConstMetric(machineLoadAverageDesc, prometheus.GaugeValue, load.LoadAverage15, "15m")
+	}
+}
+
+func (c *PrometheusCollector) collectMachineNetworkStats(ch chan<- prometheus.Metric) {
+	if len(c.machineNetworkMetrics) == 0 {
+		return
+	}
+	interfaces, err := c.infoProvider.GetMachineNetworkStats()
+	if err != nil {
+		c.errors.Set(1)
+		glog.Warningf("Couldn't get machine network stats: %v", err)
+		return
+	}
+	for _, mm := range c.machineNetworkMetrics {
+		desc := mm.desc()
+		for i := range interfaces {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, mm.getValue(&interfaces[i]), interfaces[i].Name)
+		}
+	}
 }
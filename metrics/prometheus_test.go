@@ -16,11 +16,13 @@ package metrics
 
 import (
 	"io/ioutil"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	info "github.com/google/cadvisor/info/v1"
 	"github.com/prometheus/client_golang/prometheus"
@@ -29,11 +31,56 @@ import (
 type testSubcontainersInfoProvider struct{}
 
 func (p testSubcontainersInfoProvider) SubcontainersInfo(string, *info.ContainerInfoRequest) ([]*info.ContainerInfo, error) {
+	oomScoreAdj := -146
 	return []*info.ContainerInfo{
 		{
 			ContainerReference: info.ContainerReference{
 				Name: "testcontainer",
 			},
+			Spec: info.ContainerSpec{
+				LastExitCode: 55,
+				Cpu: info.CpuSpec{
+					Burst: 138,
+					Idle:  true,
+				},
+				OomScoreAdj: &oomScoreAdj,
+				AllowedCpus: []int{0, 1, 2, 3, 8, 10, 11},
+				AllowedMems: []int{0},
+				DeviceAccess: []info.DeviceAccessRule{
+					{Type: "c", Major: 1, Minor: 3, Permissions: "rwm"},
+					{Type: "c", Major: 136, Minor: info.DeviceWildcard, Permissions: "rwm"},
+				},
+				NetClsId:             166,
+				ControllersAvailable: []string{"cpu", "cpuacct", "memory"},
+				IoMax: []info.IoMaxLimit{
+					{Major: 8, Minor: 0, ReadBps: 157, WriteBps: 158, ReadIops: 159},
+					{Major: 8, Minor: 16, WriteIops: 160},
+				},
+				Ulimits: []info.Ulimit{
+					{
+						Name:      "nofile",
+						SoftLimit: 139,
+						HardLimit: 140,
+					},
+				},
+				Kubernetes: info.KubernetesMetadata{
+					PodName:       "testpod",
+					Namespace:     "testnamespace",
+					PodUID:        "testuid",
+					ContainerName: "testcontainer",
+				},
+				ImageID:         "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+				ImageRegistry:   "registry.example.com:5000",
+				ImageRepository: "team/testimage",
+				Mounts: []info.Mount{
+					{
+						Source:      "/var/lib/docker/volumes/data",
+						Destination: "/data",
+						Mode:        "rw",
+						Type:        "volume",
+					},
+				},
+			},
 			Stats: []*info.ContainerStats{
 				{
 					Cpu: info.CpuStats{
@@ -43,10 +90,57 @@ func (p testSubcontainersInfoProvider) SubcontainersInfo(string, *info.Container
 							User:   6,
 							System: 7,
 						},
+						Schedstat: info.CpuSchedstat{
+							NrThrottled:  63,
+							RunTime:      64,
+							RunqueueTime: 65,
+							RunPeriods:   66,
+						},
+						ContextSwitches: info.CpuContextSwitches{
+							Voluntary:   120,
+							Involuntary: 121,
+						},
+						CFS: info.CpuCFS{
+							BurstCount: 177,
+							BurstTime:  178e9,
+						},
+						UsageRate: info.CpuUsageRate{
+							Total:  1890,
+							User:   1200,
+							System: 690,
+						},
 					},
 					Memory: info.MemoryStats{
-						Usage:      8,
-						WorkingSet: 9,
+						Usage:          8,
+						WorkingSet:     9,
+						Cache:          59,
+						RSS:            60,
+						MappedFile:     61,
+						Swap:           62,
+						Pgpgin:         132,
+						Pgpgout:        133,
+						Pswpin:         134,
+						Pswpout:        135,
+						KernelUsage:    109,
+						KernelLimit:    110,
+						KernelTCPUsage: 111,
+						KernelTCPLimit: 112,
+						Events: info.MemoryEvents{
+							Low:     115,
+							High:    116,
+							Max:     117,
+							Oom:     118,
+							OomKill: 119,
+						},
+						WorkingSetEvents: info.WorkingSetEvents{
+							Refault:  148,
+							Activate: 149,
+							Restore:  150,
+						},
+						SwapEvents: &info.SwapEvents{
+							High: 179,
+							Max:  180,
+						},
 						ContainerData: info.MemoryStatsMemoryData{
 							Pgfault:    10,
 							Pgmajfault: 11,
@@ -56,15 +150,62 @@ func (p testSubcontainersInfoProvider) SubcontainersInfo(string, *info.Container
 							Pgmajfault: 13,
 						},
 					},
+					DiskIo: info.DiskIoStats{
+						IoServiceBytes: []info.PerDiskStats{{
+							Major: 8,
+							Minor: 0,
+							Stats: map[string]uint64{"Read": 189, "Write": 190, "Discard": 191, "Total": 570},
+						}},
+						IoServiced: []info.PerDiskStats{{
+							Major: 8,
+							Minor: 0,
+							Stats: map[string]uint64{"Read": 192, "Write": 193, "Discard": 194, "Total": 579},
+						}},
+						IoServiceTime: []info.PerDiskStats{{
+							Major: 8,
+							Minor: 0,
+							Stats: map[string]uint64{"Read": 125 * uint64(time.Second), "Write": 126 * uint64(time.Second)},
+						}},
+						IoWaitTime: []info.PerDiskStats{{
+							Major: 8,
+							Minor: 0,
+							Stats: map[string]uint64{"Read": 127 * uint64(time.Second), "Write": 128 * uint64(time.Second)},
+						}},
+						IoLatencyThrottled: []info.PerDiskStats{{
+							Major: 8,
+							Minor: 0,
+							Stats: map[string]uint64{"Total": 122 * uint64(time.Second)},
+						}},
+					},
+					MiscStats: map[string]info.MiscResourceStats{
+						"sev":    {Current: 171, Max: 172},
+						"sev_es": {Current: 173, Max: math.MaxUint64},
+						"tdx":    {Current: 0, Max: 0},
+					},
+					DerivedMetrics: map[string]float64{
+						"memory_utilization": 0.123,
+						"io_constrained":     1,
+					},
 					Network: info.NetworkStats{
-						RxBytes:   14,
-						RxPackets: 15,
-						RxErrors:  16,
-						RxDropped: 17,
-						TxBytes:   18,
-						TxPackets: 19,
-						TxErrors:  20,
-						TxDropped: 21,
+						Interface:      "eth0",
+						Speed:          136,
+						Mtu:            137,
+						RxBytes:        14,
+						RxPackets:      15,
+						RxErrors:       16,
+						RxDropped:      17,
+						TxBytes:        18,
+						TxPackets:      19,
+						TxErrors:       20,
+						TxDropped:      21,
+						TcpMemUsage:    113,
+						TcpMemLimit:    114,
+						ConntrackCount: 123,
+						ConntrackLimit: 124,
+						Protocols: []info.ProtocolStats{
+							{Protocol: "ipv4", RxBytes: 181, RxPackets: 182, TxBytes: 183, TxPackets: 184},
+							{Protocol: "ipv6", RxBytes: 185, RxPackets: 186, TxBytes: 187, TxPackets: 188},
+						},
 					},
 					Filesystem: []info.FsStats{
 						{
@@ -99,6 +240,12 @@ func (p testSubcontainersInfoProvider) SubcontainersInfo(string, *info.Container
 							IoTime:          48,
 							WeightedIoTime:  49,
 						},
+						{
+							Device: "sda1",
+							Volume: "/data",
+							Limit:  45,
+							Usage:  46,
+						},
 					},
 					TaskStats: info.LoadStats{
 						NrSleeping:        50,
@@ -107,13 +254,93 @@ func (p testSubcontainersInfoProvider) SubcontainersInfo(string, *info.Container
 						NrUninterruptible: 53,
 						NrIoWait:          54,
 					},
+					OomEvents: 56,
+					Dns: &info.DnsStats{
+						Queries:      174,
+						Failures:     175,
+						LatencyTotal: 176000000000,
+					},
+					Processes: info.ProcessStats{
+						OpenFds:      57,
+						OpenFdsLimit: 58,
+						PidCount:     129,
+						PidsAdded:    130,
+						PidsRemoved:  131,
+						ThreadCount:  167,
+					},
 				},
 			},
 		},
 	}, nil
 }
 
+func (p testSubcontainersInfoProvider) GetMachineNetworkStats() ([]info.InterfaceStats, error) {
+	return []info.InterfaceStats{
+		{
+			Name: "eth0",
+			NetworkStats: info.NetworkStats{
+				RxBytes:   101,
+				RxPackets: 102,
+				RxErrors:  103,
+				RxDropped: 104,
+				TxBytes:   105,
+				TxPackets: 106,
+				TxErrors:  107,
+				TxDropped: 108,
+			},
+		},
+	}, nil
+}
+
+func (p testSubcontainersInfoProvider) GetMachineInfo() (*info.MachineInfo, error) {
+	return &info.MachineInfo{
+		BootTime: time.Unix(1420070400, 0),
+		DiskMap: map[string]info.DiskInfo{
+			"8:0":  {Name: "sda", Major: 8, Minor: 0, Size: 168},
+			"8:16": {Name: "sdb", Major: 8, Minor: 16, Size: 169},
+		},
+	}, nil
+}
+
+func (p testSubcontainersInfoProvider) CollectionBackpressure() bool {
+	return true
+}
+
+func (p testSubcontainersInfoProvider) GetMachineStats(numStats int) ([]*info.MachineStats, error) {
+	return []*info.MachineStats{
+		{
+			Cpu: info.MachineCpuStats{
+				Total:  141000000000,
+				User:   142000000000,
+				System: 143000000000,
+				Steal:  146000000000,
+			},
+			Memory: info.MachineMemoryStats{
+				Usage:           144,
+				Available:       145,
+				Free:            151,
+				Buffers:         152,
+				Cached:          153,
+				SlabReclaimable: 154,
+				SwapTotal:       155,
+				SwapFree:        156,
+			},
+			AvailableEntropy: 147,
+			Load: info.MachineLoadStats{
+				LoadAverage1:  1.61,
+				LoadAverage5:  1.62,
+				LoadAverage15: 1.63,
+				RunningTasks:  164,
+				TotalTasks:    165,
+			},
+		},
+	}, nil
+}
+
 func TestPrometheusCollector(t *testing.T) {
+	*exportMountInfo = true
+	*exportDerivedMetrics = true
+	*exportUlimits = true
 	prometheus.MustRegister(NewPrometheusCollector(testSubcontainersInfoProvider{}))
 
 	rw := httptest.NewRecorder()
@@ -132,7 +359,7 @@ func TestPrometheusCollector(t *testing.T) {
 	// (https://github.com/prometheus/client_golang/issues/58), we simply compare
 	// verbatim text-format metrics outputs, but ignore certain metric lines
 	// whose value depends on the current time or local circumstances.
-	includeRe := regexp.MustCompile("^(# HELP |# TYPE |)container_")
+	includeRe := regexp.MustCompile("^(# HELP |# TYPE |)(container|machine)_")
 	ignoreRe := regexp.MustCompile("^container_last_seen{")
 	for i, want := range wantLines {
 		if !includeRe.MatchString(want) || ignoreRe.MatchString(want) {
@@ -143,3 +370,41 @@ func TestPrometheusCollector(t *testing.T) {
 		}
 	}
 }
+
+func TestPrometheusCollectorMetricPrefixes(t *testing.T) {
+	c := NewPrometheusCollector(testSubcontainersInfoProvider{})
+	c.setMetricPrefixes("container_cpu_, machine_load")
+
+	nameRe := regexp.MustCompile(`fqName: "([^"]+)"`)
+	descs := make(chan *prometheus.Desc)
+	go func() {
+		c.Describe(descs)
+		close(descs)
+	}()
+	described := make(map[string]bool)
+	for desc := range descs {
+		described[nameRe.FindStringSubmatch(desc.String())[1]] = true
+	}
+	metrics := make(chan prometheus.Metric)
+	go func() {
+		c.Collect(metrics)
+		close(metrics)
+	}()
+	collected := make(map[string]bool)
+	for metric := range metrics {
+		collected[nameRe.FindStringSubmatch(metric.Desc().String())[1]] = true
+	}
+
+	for _, names := range []map[string]bool{described, collected} {
+		for name := range names {
+			if !strings.HasPrefix(name, "container_cpu_") && !strings.HasPrefix(name, "machine_load") {
+				t.Errorf("unexpected metric %q not matching the prefixes", name)
+			}
+		}
+		for _, name := range []string{"container_cpu_usage_seconds_total", "machine_load_average"} {
+			if !names[name] {
+				t.Errorf("expected metric %q matching the prefixes, got %v", name, names)
+			}
+		}
+	}
+}
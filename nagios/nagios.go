@@ -0,0 +1,219 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package nagios serves the current state of a container in the output format
+// of Nagios and Icinga checks, so that check_http-style plugins can consume
+// cAdvisor directly.
+package nagios
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	httpMux "github.com/google/cadvisor/http/mux"
+	info "github.com/google/cadvisor/info/v1"
+)
+
+const NagiosPage = "/nagios/"
+
+// Header carrying the exit code of the check, as a Nagios plugin would exit.
+const statusHeader = "X-Nagios-Status"
+
+// Status of a check, ordered by severity. The values are the exit codes of
+// Nagios plugins.
+type status int
+
+const (
+	statusOk       status = 0
+	statusWarning  status = 1
+	statusCritical status = 2
+	statusUnknown  status = 3
+)
+
+func (self status) String() string {
+	switch self {
+	case statusOk:
+		return "OK"
+	case statusWarning:
+		return "WARNING"
+	case statusCritical:
+		return "CRITICAL"
+	}
+	return "UNKNOWN"
+}
+
+// Returns the status of value against the warning and critical thresholds,
+// either of which is ignored if negative. Values at a threshold reach it.
+func checkThresholds(value, warning, critical float64) status {
+	if critical >= 0 && value >= critical {
+		return statusCritical
+	}
+	if warning >= 0 && value >= warning {
+		return statusWarning
+	}
+	return statusOk
+}
+
+type containerInfoProvider interface {
+	GetContainerInfo(containerName string, query *info.ContainerInfoRequest) (*info.ContainerInfo, error)
+}
+
+// Usage of a container checked against thresholds, as a percentage.
+type check struct {
+	name string
+	// Returns the usage, false if it cannot be computed from the container.
+	getValue func(cinfo *info.ContainerInfo) (float64, bool)
+}
+
+var checks = []check{
+	{
+		// CPU usage over the last two samples as a percentage of one core.
+		name: "cpu",
+		getValue: func(cinfo *info.ContainerInfo) (float64, bool) {
+			if len(cinfo.Stats) < 2 {
+				return 0, false
+			}
+			prev, cur := cinfo.Stats[len(cinfo.Stats)-2], cinfo.Stats[len(cinfo.Stats)-1]
+			if !cur.Timestamp.After(prev.Timestamp) || cur.Cpu.Usage.Total < prev.Cpu.Usage.Total {
+				return 0, false
+			}
+			usage := float64(cur.Cpu.Usage.Total - prev.Cpu.Usage.Total)
+			return usage / float64(cur.Timestamp.Sub(prev.Timestamp).Nanoseconds()) * 100, true
+		},
+	}, {
+		// Working set as a percentage of the memory limit.
+		name: "memory",
+		getValue: func(cinfo *info.ContainerInfo) (float64, bool) {
+			if len(cinfo.Stats) == 0 || !cinfo.Spec.HasMemory || cinfo.Spec.Memory.Limit == 0 {
+				return 0, false
+			}
+			workingSet := cinfo.Stats[len(cinfo.Stats)-1].Memory.WorkingSet
+			return float64(workingSet) / float64(cinfo.Spec.Memory.Limit) * 100, true
+		},
+	}, {
+		// Usage of the fullest filesystem as a percentage of its capacity.
+		name: "disk",
+		getValue: func(cinfo *info.ContainerInfo) (float64, bool) {
+			if len(cinfo.Stats) == 0 {
+				return 0, false
+			}
+			found := false
+			var fullest float64
+			for _, fs := range cinfo.Stats[len(cinfo.Stats)-1].Filesystem {
+				if fs.Limit == 0 {
+					continue
+				}
+				found = true
+				if usage := float64(fs.Usage) / float64(fs.Limit) * 100; usage > fullest {
+					fullest = usage
+				}
+			}
+			return fullest, found
+		},
+	},
+}
+
+// Parses the <check>_warning and <check>_critical percentages of the request,
+// -1 if missing.
+func parseThresholds(r *http.Request, name string) (float64, float64, error) {
+	thresholds := []float64{-1, -1}
+	for i, suffix := range []string{"_warning", "_critical"} {
+		val := r.URL.Query().Get(name + suffix)
+		if val == "" {
+			continue
+		}
+		threshold, err := strconv.ParseFloat(val, 64)
+		if err != nil || threshold < 0 {
+			return 0, 0, fmt.Errorf("invalid %s%s threshold %q", name, suffix, val)
+		}
+		thresholds[i] = threshold
+	}
+	return thresholds[0], thresholds[1], nil
+}
+
+// Formats a threshold of the perfdata, empty if missing.
+func formatThreshold(threshold float64) string {
+	if threshold < 0 {
+		return ""
+	}
+	return strconv.FormatFloat(threshold, 'f', -1, 64)
+}
+
+// Returns the status of the container and the output of the check: a line
+// with the status and the usage followed by the perfdata.
+func checkContainer(cinfo *info.ContainerInfo, r *http.Request) (status, string, error) {
+	worst := statusOk
+	var summaries, perfdata []string
+	for _, c := range checks {
+		warning, critical, err := parseThresholds(r, c.name)
+		if err != nil {
+			return statusUnknown, "", err
+		}
+		value, ok := c.getValue(cinfo)
+		if !ok {
+			continue
+		}
+		checkStatus := checkThresholds(value, warning, critical)
+		if checkStatus > worst {
+			worst = checkStatus
+		}
+		summary := fmt.Sprintf("%s %.1f%%", c.name, value)
+		if checkStatus != statusOk {
+			summary += " " + checkStatus.String()
+		}
+		summaries = append(summaries, summary)
+		perfdata = append(perfdata, fmt.Sprintf("%s=%.1f%%;%s;%s;0;", c.name, value, formatThreshold(warning), formatThreshold(critical)))
+	}
+	if len(summaries) == 0 {
+		return statusUnknown, fmt.Sprintf("CADVISOR UNKNOWN - no usage of %q to check", cinfo.Name), nil
+	}
+	return worst, fmt.Sprintf("CADVISOR %s - %s | %s", worst, strings.Join(summaries, ", "), strings.Join(perfdata, " ")), nil
+}
+
+// Writes the output of the check along with its status as the exit code in
+// the status header. Errors of the check itself are UNKNOWN.
+func writeCheck(w http.ResponseWriter, checkStatus status, output string, code int) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.Header().Set(statusHeader, strconv.Itoa(int(checkStatus)))
+	w.WriteHeader(code)
+	fmt.Fprintln(w, output)
+}
+
+func nagiosHandler(provider containerInfoProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		containerName := "/" + strings.TrimPrefix(r.URL.Path, NagiosPage)
+		// Two samples are needed for the CPU usage.
+		cinfo, err := provider.GetContainerInfo(containerName, &info.ContainerInfoRequest{NumStats: 2})
+		if err != nil {
+			writeCheck(w, statusUnknown, fmt.Sprintf("CADVISOR UNKNOWN - failed to get container %q: %v", containerName, err), http.StatusNotFound)
+			return
+		}
+		checkStatus, output, err := checkContainer(cinfo, r)
+		if err != nil {
+			writeCheck(w, statusUnknown, fmt.Sprintf("CADVISOR UNKNOWN - %v", err), http.StatusBadRequest)
+			return
+		}
+		writeCheck(w, checkStatus, output, http.StatusOK)
+	}
+}
+
+// Registers the /nagios/<container> handler checking the CPU, memory and disk
+// usage of the container against the thresholds of the query, e.g.
+// ?cpu_warning=80&cpu_critical=95.
+func RegisterHandler(mux httpMux.Mux, provider containerInfoProvider) error {
+	mux.HandleFunc(NagiosPage, nagiosHandler(provider))
+	return nil
+}
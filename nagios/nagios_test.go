@@ -0,0 +1,140 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nagios
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+func TestCheckThresholds(t *testing.T) {
+	cases := []struct {
+		value, warning, critical float64
+		expected                 string
+	}{
+		{50, 80, 90, "OK"},
+		{80, 80, 90, "WARNING"},
+		{85, 80, 90, "WARNING"},
+		{90, 80, 90, "CRITICAL"},
+		{99, 80, 90, "CRITICAL"},
+		{99, -1, -1, "OK"},
+		{85, -1, 90, "OK"},
+		{95, 80, -1, "WARNING"},
+	}
+	for _, c := range cases {
+		if status := checkThresholds(c.value, c.warning, c.critical).String(); status != c.expected {
+			t.Errorf("expected %v against %v/%v to be %s, got %s", c.value, c.warning, c.critical, c.expected, status)
+		}
+	}
+}
+
+type testProvider struct {
+	cinfo *info.ContainerInfo
+}
+
+func (self testProvider) GetContainerInfo(containerName string, query *info.ContainerInfoRequest) (*info.ContainerInfo, error) {
+	if self.cinfo == nil || containerName != self.cinfo.Name {
+		return nil, fmt.Errorf("unknown container %q", containerName)
+	}
+	return self.cinfo, nil
+}
+
+func newTestContainerInfo() *info.ContainerInfo {
+	start := time.Unix(1425000000, 0)
+	return &info.ContainerInfo{
+		ContainerReference: info.ContainerReference{Name: "/docker/abcd"},
+		Spec: info.ContainerSpec{
+			HasMemory: true,
+			Memory:    info.MemorySpec{Limit: 1000},
+		},
+		Stats: []*info.ContainerStats{
+			{
+				Timestamp: start,
+				Cpu:       info.CpuStats{Usage: info.CpuUsage{Total: 0}},
+			}, {
+				// Half a core over a second.
+				Timestamp: start.Add(time.Second),
+				Cpu:       info.CpuStats{Usage: info.CpuUsage{Total: 500000000}},
+				Memory:    info.MemoryStats{WorkingSet: 850},
+				Filesystem: []info.FsStats{
+					{Device: "/dev/sda1", Limit: 100, Usage: 20},
+					{Device: "/dev/sdb1", Limit: 100, Usage: 30},
+				},
+			},
+		},
+	}
+}
+
+func serveCheck(provider containerInfoProvider, url string) *httptest.ResponseRecorder {
+	rw := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", url, nil)
+	nagiosHandler(provider).ServeHTTP(rw, r)
+	return rw
+}
+
+func TestNagiosHandler(t *testing.T) {
+	provider := testProvider{newTestContainerInfo()}
+	cases := []struct {
+		query    string
+		status   string
+		output   string
+		perfdata string
+	}{
+		{"", "0", "CADVISOR OK - cpu 50.0%, memory 85.0%, disk 30.0%", "cpu=50.0%;;;0; memory=85.0%;;;0; disk=30.0%;;;0;"},
+		{"?memory_warning=80&memory_critical=90", "1", "CADVISOR WARNING - cpu 50.0%, memory 85.0% WARNING, disk 30.0%", "memory=85.0%;80;90;0;"},
+		{"?memory_warning=80&cpu_critical=40", "2", "CADVISOR CRITICAL - cpu 50.0% CRITICAL, memory 85.0% WARNING, disk 30.0%", "cpu=50.0%;;40;0;"},
+		{"?disk_warning=25.5", "1", "disk 30.0% WARNING", "disk=30.0%;25.5;;0;"},
+	}
+	for _, c := range cases {
+		rw := serveCheck(provider, "/nagios/docker/abcd"+c.query)
+		if rw.Code != http.StatusOK {
+			t.Errorf("%q: expected status code 200, got %d", c.query, rw.Code)
+		}
+		if status := rw.Header().Get(statusHeader); status != c.status {
+			t.Errorf("%q: expected exit status %s, got %s", c.query, c.status, status)
+		}
+		body := rw.Body.String()
+		if !strings.Contains(body, c.output) || !strings.Contains(body, c.perfdata) {
+			t.Errorf("%q: expected output %q with perfdata %q, got %q", c.query, c.output, c.perfdata, body)
+		}
+	}
+}
+
+func TestNagiosHandlerUnknown(t *testing.T) {
+	provider := testProvider{newTestContainerInfo()}
+
+	rw := serveCheck(provider, "/nagios/docker/missing")
+	if rw.Code != http.StatusNotFound || rw.Header().Get(statusHeader) != "3" || !strings.HasPrefix(rw.Body.String(), "CADVISOR UNKNOWN") {
+		t.Errorf("expected UNKNOWN for a missing container, got %d %q", rw.Code, rw.Body.String())
+	}
+
+	rw = serveCheck(provider, "/nagios/docker/abcd?cpu_warning=high")
+	if rw.Code != http.StatusBadRequest || rw.Header().Get(statusHeader) != "3" {
+		t.Errorf("expected UNKNOWN for an invalid threshold, got %d %q", rw.Code, rw.Body.String())
+	}
+
+	// Nothing to check without stats.
+	provider.cinfo.Stats = nil
+	rw = serveCheck(provider, "/nagios/docker/abcd")
+	if rw.Header().Get(statusHeader) != "3" || !strings.HasPrefix(rw.Body.String(), "CADVISOR UNKNOWN") {
+		t.Errorf("expected UNKNOWN without stats, got %q", rw.Body.String())
+	}
+}
@@ -25,9 +25,21 @@ import (
 	"github.com/google/cadvisor/storage/bigquery/client"
 )
 
+func init() {
+	storage.RegisterStorageDriver("bigquery", func(config storage.DriverConfig) (storage.StorageDriver, error) {
+		return New(
+			config.MachineName,
+			config.Table,
+			config.Database,
+			config.StableIDs,
+		)
+	})
+}
+
 type bigqueryStorage struct {
 	client      *client.Client
 	machineName string
+	stableIDs   bool
 }
 
 const (
@@ -190,11 +202,7 @@ func (self *bigqueryStorage) containerStatsToRows(
 	row[colMachineName] = self.machineName
 
 	// Container name
-	name := ref.Name
-	if len(ref.Aliases) > 0 {
-		name = ref.Aliases[0]
-	}
-	row[colContainerName] = name
+	row[colContainerName] = storage.SeriesName(ref, self.stableIDs)
 
 	// Cumulative Cpu Usage
 	row[colCpuCumulativeUsage] = stats.Cpu.Usage.Total
@@ -439,9 +447,11 @@ func (self *bigqueryStorage) Close() error {
 // machineName: A unique identifier to identify the host that current cAdvisor
 // instance is running on.
 // tableName: BigQuery table used for storing stats.
+// stableIDs: Whether to identify containers by their stable ID.
 func New(machineName,
 	datasetId,
 	tableName string,
+	stableIDs bool,
 ) (storage.StorageDriver, error) {
 	bqClient, err := client.NewClient()
 	if err != nil {
@@ -455,6 +465,7 @@ func New(machineName,
 	ret := &bigqueryStorage{
 		client:      bqClient,
 		machineName: machineName,
+		stableIDs:   stableIDs,
 	}
 	schema := ret.GetSchema()
 	err = bqClient.CreateTable(tableName, schema)
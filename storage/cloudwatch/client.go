@@ -0,0 +1,319 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudwatch
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AWS credentials the requests are signed with.
+type Credentials struct {
+	AccessKeyId     string
+	SecretAccessKey string
+	// Only set for temporary credentials, e.g. those of the role of an EC2
+	// instance.
+	SessionToken string
+}
+
+// Returns the credentials to sign the next request with.
+type CredentialsProvider func() (Credentials, error)
+
+// Returns the credentials of the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN environment variables when set, those of the role of the
+// EC2 instance otherwise.
+func DefaultCredentialsProvider() CredentialsProvider {
+	if accessKeyId := os.Getenv("AWS_ACCESS_KEY_ID"); accessKeyId != "" {
+		creds := Credentials{
+			AccessKeyId:     accessKeyId,
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+		return func() (Credentials, error) {
+			return creds, nil
+		}
+	}
+	return (&instanceRoleCredentials{metadata: newMetadataClient()}).get
+}
+
+// Client of the EC2 instance metadata service.
+type metadataClient struct {
+	endpoint string
+	client   *http.Client
+}
+
+func newMetadataClient() *metadataClient {
+	return &metadataClient{
+		endpoint: "http://169.254.169.254/latest",
+		client:   &http.Client{Timeout: 2 * time.Second},
+	}
+}
+
+// Returns the metadata at the specified path, e.g. meta-data/instance-id.
+// A session token is used when the service requires one.
+func (self *metadataClient) get(path string) (string, error) {
+	req, err := http.NewRequest("GET", self.endpoint+"/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	if token, err := self.token(); err == nil {
+		req.Header.Set("X-aws-ec2-metadata-token", token)
+	}
+	resp, err := self.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get instance metadata %q: %s", path, resp.Status)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+func (self *metadataClient) token() (string, error) {
+	req, err := http.NewRequest("PUT", self.endpoint+"/api/token", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "300")
+	resp, err := self.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get instance metadata token: %s", resp.Status)
+	}
+	return string(body), nil
+}
+
+// Returns the region of the EC2 instance.
+func (self *metadataClient) region() (string, error) {
+	zone, err := self.get("meta-data/placement/availability-zone")
+	if err != nil {
+		return "", err
+	}
+	if len(zone) < 2 {
+		return "", fmt.Errorf("invalid availability zone %q", zone)
+	}
+	// The zone is the region followed by a letter, e.g. us-east-1a.
+	return zone[:len(zone)-1], nil
+}
+
+// Temporary credentials of the role of the EC2 instance, refreshed before they
+// expire.
+type instanceRoleCredentials struct {
+	metadata *metadataClient
+
+	lock       sync.Mutex
+	creds      Credentials
+	expiration time.Time
+}
+
+func (self *instanceRoleCredentials) get() (Credentials, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if time.Now().Add(5 * time.Minute).Before(self.expiration) {
+		return self.creds, nil
+	}
+	role, err := self.metadata.get("meta-data/iam/security-credentials/")
+	if err != nil {
+		return Credentials{}, fmt.Errorf("failed to get the role of the instance: %v", err)
+	}
+	// Instances have at most one role.
+	role = strings.SplitN(role, "\n", 2)[0]
+	out, err := self.metadata.get("meta-data/iam/security-credentials/" + role)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("failed to get the credentials of role %q: %v", role, err)
+	}
+	var creds struct {
+		AccessKeyId     string
+		SecretAccessKey string
+		Token           string
+		Expiration      time.Time
+	}
+	if err := json.Unmarshal([]byte(out), &creds); err != nil {
+		return Credentials{}, fmt.Errorf("invalid credentials of role %q: %v", role, err)
+	}
+	self.creds = Credentials{
+		AccessKeyId:     creds.AccessKeyId,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.Token,
+	}
+	self.expiration = creds.Expiration
+	return self.creds, nil
+}
+
+// Returns the endpoint of CloudWatch in the region.
+func endpoint(region string) string {
+	ret := "https://monitoring." + region + ".amazonaws.com/"
+	if strings.HasPrefix(region, "cn-") {
+		ret = "https://monitoring." + region + ".amazonaws.com.cn/"
+	}
+	return ret
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Signs the request with AWS Signature Version 4. All the headers of the
+// request are signed along with its host.
+func sign(req *http.Request, body []byte, creds Credentials, region, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders string
+	for _, name := range names {
+		canonicalHeaders += name + ":" + headers[name] + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	uri := req.URL.Path
+	if uri == "" {
+		uri = "/"
+	}
+	// The query must be encoded with %20 rather than + for spaces.
+	query := strings.Replace(req.URL.Query().Encode(), "+", "%20", -1)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uri,
+		query,
+		canonicalHeaders,
+		signedHeaders,
+		sha256Hex(body),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+	key := hmacSha256([]byte("AWS4"+creds.SecretAccessKey), date)
+	key = hmacSha256(key, region)
+	key = hmacSha256(key, service)
+	key = hmacSha256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSha256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", creds.AccessKeyId, scope, signedHeaders, signature))
+}
+
+type dimension struct {
+	name  string
+	value string
+}
+
+// A value of a metric.
+type datum struct {
+	name       string
+	value      float64
+	unit       string
+	timestamp  time.Time
+	dimensions []dimension
+}
+
+// Returns the form of a PutMetricData request of the datums.
+func putMetricDataForm(namespace string, datums []datum) url.Values {
+	form := url.Values{}
+	form.Set("Action", "PutMetricData")
+	form.Set("Version", "2010-08-01")
+	form.Set("Namespace", namespace)
+	for i, d := range datums {
+		prefix := fmt.Sprintf("MetricData.member.%d.", i+1)
+		form.Set(prefix+"MetricName", d.name)
+		form.Set(prefix+"Value", strconv.FormatFloat(d.value, 'f', -1, 64))
+		form.Set(prefix+"Unit", d.unit)
+		form.Set(prefix+"Timestamp", d.timestamp.UTC().Format(time.RFC3339))
+		for j, dim := range d.dimensions {
+			dimPrefix := fmt.Sprintf("%sDimensions.member.%d.", prefix, j+1)
+			form.Set(dimPrefix+"Name", dim.name)
+			form.Set(dimPrefix+"Value", dim.value)
+		}
+	}
+	return form
+}
+
+// Error returned by CloudWatch.
+type apiError struct {
+	statusCode int
+	Code       string `xml:"Error>Code"`
+	Message    string `xml:"Error>Message"`
+}
+
+func (self *apiError) Error() string {
+	return fmt.Sprintf("CloudWatch error %d %s: %s", self.statusCode, self.Code, self.Message)
+}
+
+// Whether the request was rejected because of the rate limits or a transient
+// failure of CloudWatch, and may succeed later.
+func (self *apiError) retryable() bool {
+	switch self.Code {
+	case "Throttling", "ThrottlingException", "RequestLimitExceeded":
+		return true
+	}
+	return self.statusCode == http.StatusTooManyRequests || self.statusCode >= 500
+}
+
+func parseError(resp *http.Response) error {
+	body, _ := ioutil.ReadAll(resp.Body)
+	ret := &apiError{statusCode: resp.StatusCode}
+	if err := xml.Unmarshal(body, ret); err != nil {
+		ret.Message = string(body)
+	}
+	return ret
+}
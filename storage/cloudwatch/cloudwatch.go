@@ -0,0 +1,369 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cloudwatch provides a storage driver that puts the stats of
+// containers to Amazon CloudWatch as metric data.
+package cloudwatch
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/storage"
+)
+
+const (
+	// Limits of CloudWatch on the metric data of a PutMetricData request and
+	// on the size of its payload.
+	MaxBatchSize   = 20
+	maxPayloadSize = 150 * 1024
+
+	// Number of metric data buffered, after which the oldest are dropped.
+	maxBufferedDatums = 10000
+
+	// Number of attempts at each request rejected by the rate limits of
+	// CloudWatch, with an exponential backoff between them.
+	maxAttempts = 5
+	minBackoff  = 100 * time.Millisecond
+
+	// Samples of containers older than this are forgotten, so that the
+	// containers that went away are not tracked forever.
+	maxSampleAge = 10 * time.Minute
+)
+
+func init() {
+	storage.RegisterStorageDriver("cloudwatch", func(config storage.DriverConfig) (storage.StorageDriver, error) {
+		metadata := newMetadataClient()
+		instanceId, err := metadata.get("meta-data/instance-id")
+		if err != nil {
+			glog.Warningf("Failed to get the EC2 instance ID, using the machine name %q instead: %v", config.MachineName, err)
+			instanceId = config.MachineName
+		}
+		region := os.Getenv("AWS_REGION")
+		if region == "" {
+			region = os.Getenv("AWS_DEFAULT_REGION")
+		}
+		if region == "" {
+			region, err = metadata.region()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get the AWS region, set AWS_REGION: %v", err)
+			}
+		}
+		return New(
+			instanceId,
+			config.Database,
+			region,
+			DefaultCredentialsProvider(),
+			MaxBatchSize,
+			config.BufferDuration,
+			config.StableIDs,
+		)
+	})
+}
+
+type cloudWatchStorage struct {
+	instanceId string
+	namespace  string
+	region     string
+	endpoint   string
+	stableIDs  bool
+
+	credentials CredentialsProvider
+	client      *http.Client
+
+	batchSize      int
+	maxPayloadSize int
+	minBackoff     time.Duration
+
+	// Guards pending and lastStats.
+	lock sync.Mutex
+	// Metric data waiting to be put.
+	pending []datum
+	// Last sample of each container, from which the rates are computed.
+	lastStats map[string]*info.ContainerStats
+
+	// Signaled when a full batch is pending.
+	flushes chan struct{}
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (self *cloudWatchStorage) AddStats(ref info.ContainerReference, stats *info.ContainerStats) error {
+	if stats == nil {
+		return nil
+	}
+	select {
+	case <-self.stop:
+		return fmt.Errorf("cloudwatch storage is closed")
+	default:
+	}
+	name := storage.SeriesName(ref, self.stableIDs)
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	datums := self.datums(name, stats, self.lastStats[name])
+	self.lastStats[name] = stats
+	self.pending = append(self.pending, datums...)
+	if dropped := len(self.pending) - maxBufferedDatums; dropped > 0 {
+		glog.Warningf("CloudWatch buffer is full, dropped %d metric data", dropped)
+		self.pending = self.pending[dropped:]
+	}
+	if len(self.pending) >= self.batchSize {
+		select {
+		case self.flushes <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Returns the rate per second of a counter between two samples, false if the
+// counter was reset.
+func rate(cur, prev uint64, elapsed time.Duration) (float64, bool) {
+	if cur < prev {
+		return 0, false
+	}
+	return float64(cur-prev) / elapsed.Seconds(), true
+}
+
+// Maps the sample of the container to metric data. Cumulative counters are
+// put as rates since the previous sample, if any.
+func (self *cloudWatchStorage) datums(containerName string, stats, prev *info.ContainerStats) []datum {
+	dimensions := []dimension{
+		{"InstanceId", self.instanceId},
+		{"ContainerName", containerName},
+	}
+	var ret []datum
+	add := func(name string, value float64, unit string, dims []dimension) {
+		ret = append(ret, datum{
+			name:       name,
+			value:      value,
+			unit:       unit,
+			timestamp:  stats.Timestamp,
+			dimensions: dims,
+		})
+	}
+
+	add("MemoryUsage", float64(stats.Memory.Usage), "Bytes", dimensions)
+	add("MemoryWorkingSet", float64(stats.Memory.WorkingSet), "Bytes", dimensions)
+	for _, fs := range stats.Filesystem {
+		fsDimensions := append([]dimension{{"Device", fs.Device}}, dimensions...)
+		add("FilesystemUsage", float64(fs.Usage), "Bytes", fsDimensions)
+		if fs.Limit > 0 {
+			add("FilesystemUtilization", float64(fs.Usage)/float64(fs.Limit)*100, "Percent", fsDimensions)
+		}
+	}
+
+	if prev == nil {
+		return ret
+	}
+	elapsed := stats.Timestamp.Sub(prev.Timestamp)
+	if elapsed <= 0 {
+		return ret
+	}
+	// Percentage of a single core.
+	add("CpuUtilization", float64(stats.Cpu.UsageRate.Total)/10, "Percent", dimensions)
+	if v, ok := rate(stats.Network.RxBytes, prev.Network.RxBytes, elapsed); ok {
+		add("NetworkRxBytes", v, "Bytes/Second", dimensions)
+	}
+	if v, ok := rate(stats.Network.TxBytes, prev.Network.TxBytes, elapsed); ok {
+		add("NetworkTxBytes", v, "Bytes/Second", dimensions)
+	}
+	if v, ok := rate(stats.Network.RxErrors+stats.Network.TxErrors, prev.Network.RxErrors+prev.Network.TxErrors, elapsed); ok {
+		add("NetworkErrors", v, "Count/Second", dimensions)
+	}
+	return ret
+}
+
+// The storage does not keep any stats.
+func (self *cloudWatchStorage) RecentStats(containerName string, numStats int) ([]*info.ContainerStats, error) {
+	return nil, nil
+}
+
+// Stops putting metric data once those pending are put.
+func (self *cloudWatchStorage) Close() error {
+	self.closeOnce.Do(func() {
+		close(self.stop)
+	})
+	<-self.done
+	return nil
+}
+
+// Puts the pending metric data every flush interval, or as soon as a full batch
+// is pending, until the storage is closed.
+func (self *cloudWatchStorage) run(flushInterval time.Duration) {
+	defer close(self.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			self.flush(true)
+			self.forgetStaleStats()
+		case <-self.flushes:
+			self.flush(false)
+		case <-self.stop:
+			self.flush(true)
+			return
+		}
+	}
+}
+
+// Puts the pending metric data in batches. Unless all is set, the last batch
+// is kept pending if it is not full.
+func (self *cloudWatchStorage) flush(all bool) {
+	self.lock.Lock()
+	pending := self.pending
+	n := len(pending)
+	if !all {
+		n -= n % self.batchSize
+	}
+	self.pending = pending[n:]
+	self.lock.Unlock()
+
+	for start := 0; start < n; start += self.batchSize {
+		end := start + self.batchSize
+		if end > n {
+			end = n
+		}
+		if err := self.put(pending[start:end]); err != nil {
+			glog.Warningf("Failed to put %d metric data to CloudWatch: %v", end-start, err)
+		}
+	}
+}
+
+func (self *cloudWatchStorage) forgetStaleStats() {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	for name, stats := range self.lastStats {
+		if time.Since(stats.Timestamp) > maxSampleAge {
+			delete(self.lastStats, name)
+		}
+	}
+}
+
+// Puts the metric data in a single request, or in several ones if its payload
+// would be too large. Requests rejected by the rate limits are retried.
+func (self *cloudWatchStorage) put(datums []datum) error {
+	body := []byte(putMetricDataForm(self.namespace, datums).Encode())
+	if len(body) > self.maxPayloadSize && len(datums) > 1 {
+		if err := self.put(datums[:len(datums)/2]); err != nil {
+			return err
+		}
+		return self.put(datums[len(datums)/2:])
+	}
+
+	backoff := self.minBackoff
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		err = self.putMetricData(body)
+		if apiErr, ok := err.(*apiError); !ok || !apiErr.retryable() {
+			return err
+		}
+		if attempt == maxAttempts {
+			break
+		}
+		glog.V(2).Infof("CloudWatch request throttled, retrying in %v: %v", backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-self.stop:
+			// Keep retrying the last requests while closing, without waiting.
+		}
+		backoff *= 2
+	}
+	return err
+}
+
+func (self *cloudWatchStorage) putMetricData(body []byte) error {
+	creds, err := self.credentials()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", self.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	sign(req, body, creds, self.region, "monitoring", time.Now())
+	resp, err := self.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return parseError(resp)
+	}
+	return nil
+}
+
+// instanceId: ID of the EC2 instance, added as the InstanceId dimension of all
+// the metrics.
+// namespace: Namespace of the metrics in CloudWatch.
+// region: AWS region of CloudWatch, e.g. us-east-1.
+// batchSize: Number of metric data put per request, at most MaxBatchSize.
+// flushInterval: Interval at which the pending metric data are put even if
+// they do not fill a batch.
+// stableIDs: Whether to identify containers by their stable ID.
+func New(instanceId,
+	namespace,
+	region string,
+	credentials CredentialsProvider,
+	batchSize int,
+	flushInterval time.Duration,
+	stableIDs bool,
+) (*cloudWatchStorage, error) {
+	if namespace == "" {
+		return nil, fmt.Errorf("missing CloudWatch namespace")
+	}
+	if region == "" {
+		return nil, fmt.Errorf("missing CloudWatch region")
+	}
+	if batchSize <= 0 || batchSize > MaxBatchSize {
+		return nil, fmt.Errorf("invalid CloudWatch batch size %d, must be between 1 and %d", batchSize, MaxBatchSize)
+	}
+	if flushInterval <= 0 {
+		return nil, fmt.Errorf("invalid CloudWatch flush interval %v, must be positive", flushInterval)
+	}
+	ret := newStorage(instanceId, namespace, region, endpoint(region), credentials, batchSize, flushInterval)
+	ret.stableIDs = stableIDs
+	return ret, nil
+}
+
+func newStorage(instanceId, namespace, region, endpoint string, credentials CredentialsProvider, batchSize int, flushInterval time.Duration) *cloudWatchStorage {
+	ret := &cloudWatchStorage{
+		instanceId:     instanceId,
+		namespace:      namespace,
+		region:         region,
+		endpoint:       endpoint,
+		credentials:    credentials,
+		client:         &http.Client{Timeout: 30 * time.Second},
+		batchSize:      batchSize,
+		maxPayloadSize: maxPayloadSize,
+		minBackoff:     minBackoff,
+		lastStats:      make(map[string]*info.ContainerStats),
+		flushes:        make(chan struct{}, 1),
+		stop:           make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+	go ret.run(flushInterval)
+	return ret
+}
@@ -0,0 +1,194 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudwatch
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+var testCredentials = func() (Credentials, error) {
+	return Credentials{AccessKeyId: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}, nil
+}
+
+// Example of the documentation of AWS Signature Version 4.
+func TestSign(t *testing.T) {
+	req, err := http.NewRequest("GET", "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	creds, _ := testCredentials()
+	sign(req, nil, creds, "us-east-1", "iam", time.Date(2015, time.August, 30, 12, 36, 0, 0, time.UTC))
+
+	expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+	if auth := req.Header.Get("Authorization"); auth != expected {
+		t.Errorf("expected authorization %q, got %q", expected, auth)
+	}
+}
+
+func TestDatums(t *testing.T) {
+	s := &cloudWatchStorage{instanceId: "i-1234"}
+	start := time.Now()
+	prev := &info.ContainerStats{Timestamp: start}
+	prev.Cpu.Usage.Total = uint64(time.Second)
+	prev.Network.RxBytes = 1000
+	stats := &info.ContainerStats{
+		Timestamp:  start.Add(10 * time.Second),
+		Filesystem: []info.FsStats{{Device: "/dev/sda1", Limit: 1000, Usage: 250}},
+	}
+	stats.Cpu.Usage.Total = uint64(6 * time.Second)
+	stats.Cpu.UsageRate.Total = 500
+	stats.Memory.Usage = 2048
+	stats.Network.RxBytes = 6000
+
+	values := make(map[string]float64)
+	for _, d := range s.datums("/docker/web", stats, prev) {
+		values[d.name] = d.value
+		if d.dimensions[len(d.dimensions)-2] != (dimension{"InstanceId", "i-1234"}) || d.dimensions[len(d.dimensions)-1] != (dimension{"ContainerName", "/docker/web"}) {
+			t.Errorf("expected the instance and container dimensions, got %+v", d.dimensions)
+		}
+	}
+	expected := map[string]float64{
+		"MemoryUsage":           2048,
+		"MemoryWorkingSet":      0,
+		"FilesystemUsage":       250,
+		"FilesystemUtilization": 25,
+		"CpuUtilization":        50,
+		"NetworkRxBytes":        500,
+		"NetworkTxBytes":        0,
+		"NetworkErrors":         0,
+	}
+	if len(values) != len(expected) {
+		t.Errorf("expected metrics %v, got %v", expected, values)
+	}
+	for name, value := range expected {
+		if v, ok := values[name]; !ok || v != value {
+			t.Errorf("expected %s %v, got %v", name, value, v)
+		}
+	}
+
+	// Rates need a previous sample.
+	if datums := s.datums("/docker/web", stats, nil); len(datums) != 4 {
+		t.Errorf("expected only the gauges without previous sample, got %+v", datums)
+	}
+}
+
+// Fake CloudWatch recording the number of metric data of each request, and
+// throttling the first ones.
+type fakeCloudWatch struct {
+	lock      sync.Mutex
+	requests  []int
+	throttled int
+}
+
+func (self *fakeCloudWatch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if err := r.ParseForm(); err != nil || r.Form.Get("Action") != "PutMetricData" || r.Form.Get("Namespace") != "cadvisor" || r.Header.Get("Authorization") == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if self.throttled > 0 {
+		self.throttled--
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, "<ErrorResponse><Error><Type>Sender</Type><Code>Throttling</Code><Message>Rate exceeded</Message></Error></ErrorResponse>")
+		return
+	}
+	n := 0
+	for r.Form.Get(fmt.Sprintf("MetricData.member.%d.MetricName", n+1)) != "" {
+		n++
+	}
+	self.requests = append(self.requests, n)
+}
+
+func addContainers(t *testing.T, s *cloudWatchStorage, n int) {
+	for i := 0; i < n; i++ {
+		ref := info.ContainerReference{Name: fmt.Sprintf("/container%d", i)}
+		if err := s.AddStats(ref, &info.ContainerStats{Timestamp: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func checkRequests(t *testing.T, fake *fakeCloudWatch, batchSize, total int) {
+	sum := 0
+	for _, n := range fake.requests {
+		if n > batchSize {
+			t.Errorf("expected at most %d metric data per request, got %d", batchSize, n)
+		}
+		sum += n
+	}
+	if sum != total {
+		t.Errorf("expected %d metric data to be put, got %d in requests %v", total, sum, fake.requests)
+	}
+}
+
+func TestBatches(t *testing.T) {
+	fake := &fakeCloudWatch{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	s := newStorage("i-1234", "cadvisor", "us-east-1", server.URL, testCredentials, MaxBatchSize, time.Hour)
+
+	// 2 metric data per container without previous sample nor filesystem.
+	addContainers(t, s, 23)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	checkRequests(t, fake, MaxBatchSize, 46)
+	if len(fake.requests) != 3 {
+		t.Errorf("expected 3 requests, got %v", fake.requests)
+	}
+}
+
+func TestPayloadSplit(t *testing.T) {
+	fake := &fakeCloudWatch{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	s := newStorage("i-1234", "cadvisor", "us-east-1", server.URL, testCredentials, MaxBatchSize, time.Hour)
+	s.maxPayloadSize = 2000
+
+	addContainers(t, s, 10)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	checkRequests(t, fake, MaxBatchSize, 20)
+	if len(fake.requests) < 2 {
+		t.Errorf("expected the payload to be split, got requests %v", fake.requests)
+	}
+}
+
+func TestThrottling(t *testing.T) {
+	fake := &fakeCloudWatch{throttled: 2}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	s := newStorage("i-1234", "cadvisor", "us-east-1", server.URL, testCredentials, MaxBatchSize, time.Hour)
+	s.minBackoff = time.Millisecond
+
+	addContainers(t, s, 1)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	checkRequests(t, fake, MaxBatchSize, 2)
+	if fake.throttled != 0 || len(fake.requests) != 1 {
+		t.Errorf("expected the throttled request to be retried, got requests %v", fake.requests)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package compression provides a storage driver that suppresses samples which
+// carry no new information before handing them to another storage driver.
+package compression
+
+import (
+	"math"
+	"reflect"
+	"sync"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/storage"
+)
+
+type compressedStorage struct {
+	backend storage.StorageDriver
+
+	// Relative difference under which two gauge values are considered identical.
+	tolerance float64
+
+	// Last sample written to the backend, keyed by container name.
+	lastWritten map[string]*info.ContainerStats
+	lock        sync.Mutex
+}
+
+// Returns the gauges of the sample, as integers and floats, along with a copy
+// of the sample in which those gauges, the timestamps and the sequence number
+// are cleared so only the counters remain.
+func splitStats(stats *info.ContainerStats) (info.ContainerStats, []uint64, []float64) {
+	counters := stats.GaugeCopy()
+	counters.Timestamp = time.Time{}
+	counters.CollectionTime = nil
+	counters.SequenceNumber = 0
+	var gauges []uint64
+	counters.ForEachGauge(func(value *uint64, limit bool) {
+		gauges = append(gauges, *value)
+		*value = 0
+	})
+	var floatGauges []float64
+	counters.ForEachFloatGauge(func(value *float64) {
+		floatGauges = append(floatGauges, *value)
+		*value = 0
+	})
+	return *counters, gauges, floatGauges
+}
+
+func (self *compressedStorage) withinTolerance(a, b float64) bool {
+	if a == b {
+		return true
+	}
+	diff := math.Abs(a - b)
+	return diff <= self.tolerance*math.Max(math.Abs(a), math.Abs(b))
+}
+
+// Whether the sample carries no new information over the one previously written.
+// Samples in which any counter changed are never considered redundant.
+func (self *compressedStorage) redundant(prev, cur *info.ContainerStats) bool {
+	prevCounters, prevGauges, prevFloatGauges := splitStats(prev)
+	curCounters, curGauges, curFloatGauges := splitStats(cur)
+	if !reflect.DeepEqual(prevCounters, curCounters) || len(prevGauges) != len(curGauges) {
+		return false
+	}
+	for i := range curGauges {
+		if !self.withinTolerance(float64(prevGauges[i]), float64(curGauges[i])) {
+			return false
+		}
+	}
+	for i := range curFloatGauges {
+		if !self.withinTolerance(prevFloatGauges[i], curFloatGauges[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (self *compressedStorage) AddStats(ref info.ContainerReference, stats *info.ContainerStats) error {
+	self.lock.Lock()
+	prev, ok := self.lastWritten[ref.Name]
+	if ok && self.redundant(prev, stats) {
+		self.lock.Unlock()
+		return nil
+	}
+	self.lastWritten[ref.Name] = stats
+	self.lock.Unlock()
+
+	return self.backend.AddStats(ref, stats)
+}
+
+func (self *compressedStorage) RemoveContainer(ref info.ContainerReference) error {
+	self.lock.Lock()
+	delete(self.lastWritten, ref.Name)
+	self.lock.Unlock()
+	return storage.RemoveContainer(self.backend, ref)
+}
+
+func (self *compressedStorage) AddSpec(ref info.ContainerReference, spec info.ContainerSpec) error {
+	return storage.AddSpec(self.backend, ref, spec)
+}
+
+func (self *compressedStorage) RecentStats(containerName string, numStats int) ([]*info.ContainerStats, error) {
+	return self.backend.RecentStats(containerName, numStats)
+}
+
+func (self *compressedStorage) Close() error {
+	self.lock.Lock()
+	self.lastWritten = make(map[string]*info.ContainerStats)
+	self.lock.Unlock()
+	return self.backend.Close()
+}
+
+// Wraps the backend so that samples whose counters did not change and whose
+// gauges are within the relative tolerance of the previously written sample of
+// the same container are dropped instead of written.
+func New(backend storage.StorageDriver, tolerance float64) storage.StorageDriver {
+	return &compressedStorage{
+		backend:     backend,
+		tolerance:   tolerance,
+		lastWritten: make(map[string]*info.ContainerStats),
+	}
+}
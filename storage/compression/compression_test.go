@@ -0,0 +1,166 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compression
+
+import (
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/storage/test"
+)
+
+var containerRef = info.ContainerReference{Name: "/container"}
+
+func makeStat(i int, cpuTotal, memoryUsage uint64) *info.ContainerStats {
+	var zero time.Time
+	return &info.ContainerStats{
+		Timestamp: zero.Add(time.Duration(i) * time.Second),
+		Cpu: info.CpuStats{
+			Usage: info.CpuUsage{
+				Total: cpuTotal,
+			},
+		},
+		Memory: info.MemoryStats{
+			Usage: memoryUsage,
+		},
+	}
+}
+
+func TestIdenticalGaugesAreCoalesced(t *testing.T) {
+	backend := &test.MockStorageDriver{}
+	driver := New(backend, 0.01)
+
+	first := makeStat(0, 100, 1000)
+	backend.On("AddStats", containerRef, first).Return(nil)
+	// Gauges within 1% and unchanged counters are dropped.
+	second := makeStat(1, 100, 1005)
+	// Large enough gauge changes are written.
+	third := makeStat(2, 100, 2000)
+	backend.On("AddStats", containerRef, third).Return(nil)
+
+	for _, stats := range []*info.ContainerStats{first, second, third} {
+		if err := driver.AddStats(containerRef, stats); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backend.AssertExpectations(t)
+	backend.AssertNumberOfCalls(t, "AddStats", 2)
+}
+
+func TestCountersAlwaysPassThrough(t *testing.T) {
+	backend := &test.MockStorageDriver{}
+	driver := New(backend, 0.01)
+
+	statsList := []*info.ContainerStats{
+		makeStat(0, 100, 1000),
+		makeStat(1, 101, 1000),
+		makeStat(2, 102, 1000),
+	}
+	for _, stats := range statsList {
+		backend.On("AddStats", containerRef, stats).Return(nil)
+		if err := driver.AddStats(containerRef, stats); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backend.AssertExpectations(t)
+	backend.AssertNumberOfCalls(t, "AddStats", len(statsList))
+}
+
+func TestSequenceNumbersAreIgnored(t *testing.T) {
+	backend := &test.MockStorageDriver{}
+	driver := New(backend, 0.01)
+
+	first := makeStat(0, 100, 1000)
+	backend.On("AddStats", containerRef, first).Return(nil)
+	for i, stats := range []*info.ContainerStats{first, makeStat(1, 100, 1000), makeStat(2, 100, 1000)} {
+		stats.SequenceNumber = uint64(i + 1)
+		if err := driver.AddStats(containerRef, stats); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backend.AssertExpectations(t)
+	backend.AssertNumberOfCalls(t, "AddStats", 1)
+}
+
+func TestCollectionTimesAreIgnored(t *testing.T) {
+	backend := &test.MockStorageDriver{}
+	driver := New(backend, 0.01)
+
+	first := makeStat(0, 100, 1000)
+	backend.On("AddStats", containerRef, first).Return(nil)
+	for i, stats := range []*info.ContainerStats{first, makeStat(1, 100, 1000), makeStat(2, 100, 1000)} {
+		// Aligned timestamps keep the actual time of collection.
+		collectionTime := stats.Timestamp.Add(time.Duration(i) * time.Millisecond)
+		stats.CollectionTime = &collectionTime
+		if err := driver.AddStats(containerRef, stats); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backend.AssertExpectations(t)
+	backend.AssertNumberOfCalls(t, "AddStats", 1)
+}
+
+func TestProcessCountersPassThrough(t *testing.T) {
+	backend := &test.MockStorageDriver{}
+	driver := New(backend, 0.01)
+
+	first := makeStat(0, 100, 1000)
+	first.Processes = info.ProcessStats{PidCount: 100, PidsAdded: 10}
+	backend.On("AddStats", containerRef, first).Return(nil)
+	// The process count is a gauge within the tolerance.
+	second := makeStat(1, 100, 1000)
+	second.Processes = info.ProcessStats{PidCount: 101, PidsAdded: 10}
+	// Processes came and went.
+	third := makeStat(2, 100, 1000)
+	third.Processes = info.ProcessStats{PidCount: 101, PidsAdded: 12, PidsRemoved: 2}
+	backend.On("AddStats", containerRef, third).Return(nil)
+
+	for _, stats := range []*info.ContainerStats{first, second, third} {
+		if err := driver.AddStats(containerRef, stats); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backend.AssertExpectations(t)
+	backend.AssertNumberOfCalls(t, "AddStats", 2)
+}
+
+func TestPressureAveragesAreGauges(t *testing.T) {
+	backend := &test.MockStorageDriver{}
+	driver := New(backend, 0.01)
+
+	first := makeStat(0, 100, 1000)
+	first.DiskIo.PSI.Some = info.PSIData{Avg10: 50.0, Total: 1000}
+	backend.On("AddStats", containerRef, first).Return(nil)
+	// The averages moved within the tolerance.
+	second := makeStat(1, 100, 1000)
+	second.DiskIo.PSI.Some = info.PSIData{Avg10: 50.2, Total: 1000}
+	// The averages moved beyond the tolerance.
+	third := makeStat(2, 100, 1000)
+	third.DiskIo.PSI.Some = info.PSIData{Avg10: 55.0, Total: 1000}
+	backend.On("AddStats", containerRef, third).Return(nil)
+	// The stall time is a counter.
+	fourth := makeStat(3, 100, 1000)
+	fourth.DiskIo.PSI.Some = info.PSIData{Avg10: 55.0, Total: 1001}
+	backend.On("AddStats", containerRef, fourth).Return(nil)
+
+	for _, stats := range []*info.ContainerStats{first, second, third, fourth} {
+		if err := driver.AddStats(containerRef, stats); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backend.AssertExpectations(t)
+	backend.AssertNumberOfCalls(t, "AddStats", 3)
+}
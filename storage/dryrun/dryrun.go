@@ -0,0 +1,82 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dryrun provides a storage driver that logs the samples it would
+// write to another storage driver, optionally without writing them.
+package dryrun
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/golang/glog"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/storage"
+)
+
+type dryRunStorage struct {
+	backend storage.StorageDriver
+
+	// Whether samples are also written to the backend.
+	forward bool
+
+	// Verbosity at which samples are logged.
+	logLevel glog.Level
+}
+
+func (self *dryRunStorage) AddStats(ref info.ContainerReference, stats *info.ContainerStats) error {
+	if glog.V(self.logLevel) {
+		out, err := json.Marshal(stats)
+		if err != nil {
+			return fmt.Errorf("failed to serialize stats of container %q: %v", ref.Name, err)
+		}
+		glog.Infof("Storage sample for %q: %s", ref.Name, out)
+	}
+	if !self.forward {
+		return nil
+	}
+	return self.backend.AddStats(ref, stats)
+}
+
+func (self *dryRunStorage) AddSpec(ref info.ContainerReference, spec info.ContainerSpec) error {
+	if !self.forward {
+		return nil
+	}
+	return storage.AddSpec(self.backend, ref, spec)
+}
+
+func (self *dryRunStorage) RemoveContainer(ref info.ContainerReference) error {
+	if !self.forward {
+		return nil
+	}
+	return storage.RemoveContainer(self.backend, ref)
+}
+
+func (self *dryRunStorage) RecentStats(containerName string, numStats int) ([]*info.ContainerStats, error) {
+	return self.backend.RecentStats(containerName, numStats)
+}
+
+func (self *dryRunStorage) Close() error {
+	return self.backend.Close()
+}
+
+// Wraps the backend so that every sample is logged at the specified verbosity.
+// Samples are only written to the backend if forward is true.
+func New(backend storage.StorageDriver, forward bool, logLevel glog.Level) storage.StorageDriver {
+	return &dryRunStorage{
+		backend:  backend,
+		forward:  forward,
+		logLevel: logLevel,
+	}
+}
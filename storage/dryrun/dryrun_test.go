@@ -0,0 +1,48 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dryrun
+
+import (
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/storage/test"
+)
+
+var containerRef = info.ContainerReference{Name: "/container"}
+
+func TestDryRunDoesNotWrite(t *testing.T) {
+	backend := &test.MockStorageDriver{}
+	driver := New(backend, false, 0)
+
+	err := driver.AddStats(containerRef, &info.ContainerStats{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.AssertNotCalled(t, "AddStats", containerRef, &info.ContainerStats{})
+}
+
+func TestForwardWrites(t *testing.T) {
+	backend := &test.MockStorageDriver{}
+	driver := New(backend, true, 0)
+
+	stats := &info.ContainerStats{}
+	backend.On("AddStats", containerRef, stats).Return(nil)
+	err := driver.AddStats(containerRef, stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.AssertExpectations(t)
+}
@@ -0,0 +1,100 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Configuration of the backend storage, common to all storage drivers. Drivers
+// ignore the options they do not use.
+type DriverConfig struct {
+	// A unique identifier of the host the cAdvisor instance is running on.
+	MachineName string
+
+	// Host (host:port) of the database.
+	Host string
+	// Name of the database.
+	Database string
+	// Name of the table stats are written to.
+	Table string
+	// Credentials of the database.
+	User     string
+	Password string
+	// Whether to use a secure connection to the database.
+	Secure bool
+
+	// Duration for which writes are buffered before being committed.
+	BufferDuration time.Duration
+
+	// Directory of file based storages.
+	Directory string
+
+	// Quality of service of message based storages, e.g. 1 for at least once
+	// delivery.
+	Qos int
+
+	// Whether containers are identified by their stable ID, if they have one,
+	// rather than by their canonical name.
+	StableIDs bool
+}
+
+// Creates a storage driver from the configuration.
+type StorageDriverFactory func(config DriverConfig) (StorageDriver, error)
+
+// Global registry of storage driver factories, keyed by driver name.
+var (
+	driverFactories     = make(map[string]StorageDriverFactory)
+	driverFactoriesLock sync.RWMutex
+)
+
+// Register a storage driver under the specified name. Drivers are expected to
+// register themselves from an init() function so that importing their package
+// is enough to make them available. Registering a name twice replaces the
+// previous driver.
+func RegisterStorageDriver(name string, factory StorageDriverFactory) {
+	driverFactoriesLock.Lock()
+	defer driverFactoriesLock.Unlock()
+
+	driverFactories[name] = factory
+}
+
+// Create the storage driver registered under the specified name.
+func NewStorageDriver(name string, config DriverConfig) (StorageDriver, error) {
+	driverFactoriesLock.RLock()
+	factory, ok := driverFactories[name]
+	driverFactoriesLock.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown backend storage driver: %v", name)
+	}
+	return factory(config)
+}
+
+// Returns the names of the registered storage drivers, sorted.
+func ListStorageDrivers() []string {
+	driverFactoriesLock.RLock()
+	defer driverFactoriesLock.RUnlock()
+
+	names := make([]string, 0, len(driverFactories))
+	for name := range driverFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,81 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"reflect"
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+type fakeStorageDriver struct {
+	config DriverConfig
+}
+
+func (self *fakeStorageDriver) AddStats(ref info.ContainerReference, stats *info.ContainerStats) error {
+	return nil
+}
+
+func (self *fakeStorageDriver) RecentStats(containerName string, numStats int) ([]*info.ContainerStats, error) {
+	return nil, nil
+}
+
+func (self *fakeStorageDriver) Close() error {
+	return nil
+}
+
+func TestRegisterStorageDriver(t *testing.T) {
+	RegisterStorageDriver("fake", func(config DriverConfig) (StorageDriver, error) {
+		return &fakeStorageDriver{config: config}, nil
+	})
+	defer func() {
+		driverFactoriesLock.Lock()
+		delete(driverFactories, "fake")
+		driverFactoriesLock.Unlock()
+	}()
+
+	found := false
+	for _, name := range ListStorageDrivers() {
+		if name == "fake" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected fake to be listed in %v", ListStorageDrivers())
+	}
+
+	config := DriverConfig{
+		MachineName: "machine",
+		Host:        "localhost:1234",
+		Database:    "db",
+	}
+	driver, err := NewStorageDriver("fake", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake, ok := driver.(*fakeStorageDriver)
+	if !ok {
+		t.Fatalf("expected the fake driver, got %T", driver)
+	}
+	if !reflect.DeepEqual(fake.config, config) {
+		t.Errorf("expected the driver to be created with %+v, got %+v", config, fake.config)
+	}
+
+	_, err = NewStorageDriver("unknown", config)
+	if err == nil {
+		t.Errorf("expected error for an unregistered driver")
+	}
+}
@@ -20,13 +20,31 @@ import (
 	"time"
 
 	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/storage"
 	influxdb "github.com/influxdb/influxdb/client"
 )
 
+func init() {
+	storage.RegisterStorageDriver("influxdb", func(config storage.DriverConfig) (storage.StorageDriver, error) {
+		return New(
+			config.MachineName,
+			config.Table,
+			config.Database,
+			config.User,
+			config.Password,
+			config.Host,
+			config.Secure,
+			config.BufferDuration,
+			config.StableIDs,
+		)
+	})
+}
+
 type influxdbStorage struct {
 	client         *influxdb.Client
 	machineName    string
 	tableName      string
+	stableIDs      bool
 	bufferDuration time.Duration
 	lastWrite      time.Time
 	series         []*influxdb.Series
@@ -74,11 +92,7 @@ func (self *influxdbStorage) getSeriesDefaultValues(
 
 	// Container name
 	*columns = append(*columns, colContainerName)
-	if len(ref.Aliases) > 0 {
-		*values = append(*values, ref.Aliases[0])
-	} else {
-		*values = append(*values, ref.Name)
-	}
+	*values = append(*values, storage.SeriesName(ref, self.stableIDs))
 }
 
 // In order to maintain a fixed column format, we add a new series for each filesystem partition.
@@ -339,6 +353,7 @@ func (self *influxdbStorage) newSeries(columns []string, points []interface{}) *
 // machineName: A unique identifier to identify the host that current cAdvisor
 // instance is running on.
 // influxdbHost: The host which runs influxdb.
+// stableIDs: Whether to identify containers by their stable ID.
 func New(machineName,
 	tablename,
 	database,
@@ -347,6 +362,7 @@ func New(machineName,
 	influxdbHost string,
 	isSecure bool,
 	bufferDuration time.Duration,
+	stableIDs bool,
 ) (*influxdbStorage, error) {
 	config := &influxdb.ClientConfig{
 		Host:     influxdbHost,
@@ -366,6 +382,7 @@ func New(machineName,
 		client:         client,
 		machineName:    machineName,
 		tableName:      tablename,
+		stableIDs:      stableIDs,
 		bufferDuration: bufferDuration,
 		lastWrite:      time.Now(),
 		series:         make([]*influxdb.Series, 0),
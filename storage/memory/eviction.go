@@ -0,0 +1,106 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"unsafe"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+// Decides which stats of a container the in-memory storage evicts, on top of
+// those older than its max age. Called each time stats are added to a
+// container.
+type EvictionStrategy interface {
+	// Returns how many of the stats of the container, oldest first, to evict.
+	Evict(stats []*info.ContainerStats) int
+}
+
+type countEviction struct {
+	maxStats int
+}
+
+// Keeps at most maxStats stats per container.
+func NewCountEviction(maxStats int) EvictionStrategy {
+	return &countEviction{maxStats}
+}
+
+func (self *countEviction) Evict(stats []*info.ContainerStats) int {
+	if len(stats) <= self.maxStats {
+		return 0
+	}
+	return len(stats) - self.maxStats
+}
+
+// Splits the eviction strategy into the number of stats the timed store of a
+// container caps itself at, -1 if none, and the strategy left to evict the
+// others, nil if none. This spares copying the stats out of the store on
+// each add when only their number is capped.
+func splitEviction(eviction EvictionStrategy) (int, EvictionStrategy) {
+	if count, ok := eviction.(*countEviction); ok {
+		return count.maxStats, nil
+	}
+	return -1, eviction
+}
+
+type sizeEviction struct {
+	maxBytes int
+}
+
+// Keeps the stats of each container within maxBytes of memory. The latest
+// stats are always kept.
+func NewSizeEviction(maxBytes int) EvictionStrategy {
+	return &sizeEviction{maxBytes}
+}
+
+func (self *sizeEviction) Evict(stats []*info.ContainerStats) int {
+	total := 0
+	for i := len(stats) - 1; i >= 0; i-- {
+		total += statsSize(stats[i])
+		if total > self.maxBytes && i < len(stats)-1 {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// Approximate memory used by the stats, including the elements of their slices
+// and maps but not the strings they reference.
+func statsSize(stats *info.ContainerStats) int {
+	size := int(unsafe.Sizeof(*stats))
+	size += len(stats.Cpu.Usage.PerCpu) * int(unsafe.Sizeof(uint64(0)))
+	size += len(stats.Filesystem) * int(unsafe.Sizeof(info.FsStats{}))
+	size += len(stats.Network.Interfaces) * int(unsafe.Sizeof(info.InterfaceStats{}))
+	for _, disks := range [][]info.PerDiskStats{
+		stats.DiskIo.IoServiceBytes,
+		stats.DiskIo.IoServiced,
+		stats.DiskIo.IoQueued,
+		stats.DiskIo.Sectors,
+		stats.DiskIo.IoServiceTime,
+		stats.DiskIo.IoWaitTime,
+		stats.DiskIo.IoMerged,
+		stats.DiskIo.IoTime,
+		stats.DiskIo.IoLatencyThrottled,
+	} {
+		for _, disk := range disks {
+			size += int(unsafe.Sizeof(disk)) + len(disk.Stats)*mapEntrySize
+		}
+	}
+	size += len(stats.DerivedMetrics) * mapEntrySize
+	return size
+}
+
+// Approximate memory used by an entry of a map of strings to numbers.
+const mapEntrySize = 32
@@ -0,0 +1,84 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func makeStats(n int) []*info.ContainerStats {
+	stats := make([]*info.ContainerStats, n)
+	for i := range stats {
+		stats[i] = makeStat(i)
+	}
+	return stats
+}
+
+func TestCountEviction(t *testing.T) {
+	eviction := NewCountEviction(3)
+	assert.Equal(t, 0, eviction.Evict(makeStats(2)))
+	assert.Equal(t, 0, eviction.Evict(makeStats(3)))
+	assert.Equal(t, 2, eviction.Evict(makeStats(5)))
+}
+
+func TestSizeEviction(t *testing.T) {
+	size := statsSize(makeStat(0))
+	eviction := NewSizeEviction(3*size + size/2)
+	assert.Equal(t, 0, eviction.Evict(makeStats(3)))
+	assert.Equal(t, 2, eviction.Evict(makeStats(5)))
+
+	// Larger stats take more room.
+	large := makeStats(5)
+	large[4].Filesystem = make([]info.FsStats, 20)
+	assert.True(t, statsSize(large[4]) > 2*size)
+	eviction = NewSizeEviction(statsSize(large[4]) + size + size/2)
+	assert.Equal(t, 3, eviction.Evict(large))
+
+	// The latest stats are kept even when larger than the budget.
+	assert.Equal(t, 4, NewSizeEviction(1).Evict(makeStats(5)))
+}
+
+func TestAddStatsWithEviction(t *testing.T) {
+	memoryStorage := New(time.Minute, nil, NewCountEviction(2))
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, memoryStorage.AddStats(containerRef, makeStat(i)))
+	}
+	stats := getRecentStats(t, memoryStorage, -1)
+	assert.Len(t, stats, 2)
+	assert.Equal(t, makeStat(3), stats[0])
+	assert.Equal(t, makeStat(4), stats[1])
+
+	// The cap holds after the max age of the container changes.
+	memoryStorage.SetMaxAge(containerRef, 2*time.Minute)
+	assert.Nil(t, memoryStorage.AddStats(containerRef, makeStat(5)))
+	stats = getRecentStats(t, memoryStorage, -1)
+	assert.Len(t, stats, 2)
+	assert.Equal(t, makeStat(5), stats[1])
+}
+
+func TestAddStatsWithSizeEviction(t *testing.T) {
+	size := statsSize(makeStat(0))
+	memoryStorage := New(time.Minute, nil, NewSizeEviction(2*size+size/2))
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, memoryStorage.AddStats(containerRef, makeStat(i)))
+	}
+	stats := getRecentStats(t, memoryStorage, -1)
+	assert.Len(t, stats, 2)
+	assert.Equal(t, makeStat(4), stats[1])
+}
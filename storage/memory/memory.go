@@ -31,7 +31,12 @@ type containerStorage struct {
 	ref         info.ContainerReference
 	recentStats *utils.TimedStore
 	maxAge      time.Duration
-	lock        sync.RWMutex
+	// Maximum number of stats kept, -1 if unlimited.
+	maxStats int
+	// Evicts stats on top of those older than maxAge or past maxStats, nil if
+	// none.
+	eviction EvictionStrategy
+	lock     sync.RWMutex
 }
 
 func (self *containerStorage) AddStats(stats *info.ContainerStats) error {
@@ -40,6 +45,15 @@ func (self *containerStorage) AddStats(stats *info.ContainerStats) error {
 
 	// Add the stat to storage.
 	self.recentStats.Add(stats.Timestamp, stats)
+	if self.eviction != nil {
+		var empty time.Time
+		result := self.recentStats.InTimeRange(empty, empty, -1)
+		all := make([]*info.ContainerStats, len(result))
+		for i, el := range result {
+			all[i] = el.(*info.ContainerStats)
+		}
+		self.recentStats.RemoveOldest(self.eviction.Evict(all))
+	}
 	return nil
 }
 
@@ -54,11 +68,28 @@ func (self *containerStorage) RecentStats(start, end time.Time, maxStats int) ([
 	return converted, nil
 }
 
-func newContainerStore(ref info.ContainerReference, maxAge time.Duration) *containerStorage {
+// Returns the bounds and number of the retained stats.
+func (self *containerStorage) Retention() (oldest, newest time.Time, numSamples int) {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	numSamples = self.recentStats.Size()
+	if numSamples == 0 {
+		return
+	}
+	// The store returns the newest stats first.
+	newest = self.recentStats.Get(0).(*info.ContainerStats).Timestamp
+	oldest = self.recentStats.Get(numSamples - 1).(*info.ContainerStats).Timestamp
+	return
+}
+
+func newContainerStore(ref info.ContainerReference, maxAge time.Duration, eviction EvictionStrategy) *containerStorage {
+	maxStats, eviction := splitEviction(eviction)
 	return &containerStorage{
 		ref:         ref,
-		recentStats: utils.NewTimedStore(maxAge),
+		recentStats: utils.NewTimedStore(maxAge, maxStats),
 		maxAge:      maxAge,
+		maxStats:    maxStats,
+		eviction:    eviction,
 	}
 }
 
@@ -66,6 +97,7 @@ type InMemoryStorage struct {
 	lock                sync.RWMutex
 	containerStorageMap map[string]*containerStorage
 	maxAge              time.Duration
+	eviction            EvictionStrategy
 	backend             storage.StorageDriver
 }
 
@@ -77,7 +109,7 @@ func (self *InMemoryStorage) AddStats(ref info.ContainerReference, stats *info.C
 		self.lock.Lock()
 		defer self.lock.Unlock()
 		if cstore, ok = self.containerStorageMap[ref.Name]; !ok {
-			cstore = newContainerStore(ref, self.maxAge)
+			cstore = newContainerStore(ref, self.maxAge, self.eviction)
 			self.containerStorageMap[ref.Name] = cstore
 		}
 	}()
@@ -93,6 +125,33 @@ func (self *InMemoryStorage) AddStats(ref info.ContainerReference, stats *info.C
 	return cstore.AddStats(stats)
 }
 
+// Forwards the spec of the container to the backend storage if it stores specs.
+// Specs are not kept in memory.
+func (self *InMemoryStorage) AddSpec(ref info.ContainerReference, spec info.ContainerSpec) error {
+	return storage.AddSpec(self.backend, ref, spec)
+}
+
+// Tells the backend storage the container was removed. The stats of the
+// container kept in memory are still evicted as they age.
+func (self *InMemoryStorage) RemoveContainer(ref info.ContainerReference) error {
+	return storage.RemoveContainer(self.backend, ref)
+}
+
+// Returns whether the backend storage falls behind, in which case samples
+// should be added less often.
+func (self *InMemoryStorage) Backpressure() bool {
+	return storage.Backpressure(self.backend)
+}
+
+// Returns whether stats of the specified container were stored. Containers
+// created since the last housekeeping have none yet.
+func (self *InMemoryStorage) HasStats(name string) bool {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	_, ok := self.containerStorageMap[name]
+	return ok
+}
+
 func (self *InMemoryStorage) RecentStats(name string, start, end time.Time, maxStats int) ([]*info.ContainerStats, error) {
 	var cstore *containerStorage
 	var ok bool
@@ -111,20 +170,71 @@ func (self *InMemoryStorage) RecentStats(name string, start, end time.Time, maxS
 	return cstore.RecentStats(start, end, maxStats)
 }
 
+// Returns the span of the stats retained for the specified container.
+// Containers without stored stats have no samples.
+func (self *InMemoryStorage) Retention(name string) info.ContainerRetention {
+	self.lock.RLock()
+	cstore, ok := self.containerStorageMap[name]
+	self.lock.RUnlock()
+	ret := info.ContainerRetention{
+		ContainerReference: info.ContainerReference{Name: name},
+	}
+	if ok {
+		ret.ContainerReference = cstore.ref
+		ret.Oldest, ret.Newest, ret.NumSamples = cstore.Retention()
+	}
+	return ret
+}
+
+// Returns how long stats are kept by default.
+func (self *InMemoryStorage) MaxAge() time.Duration {
+	return self.maxAge
+}
+
+// Sets how long stats of the specified container are kept, overriding the
+// default maxAge of the storage. Stats already stored are kept.
+func (self *InMemoryStorage) SetMaxAge(ref info.ContainerReference, maxAge time.Duration) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	cstore, ok := self.containerStorageMap[ref.Name]
+	if !ok {
+		self.containerStorageMap[ref.Name] = newContainerStore(ref, maxAge, self.eviction)
+		return
+	}
+
+	cstore.lock.Lock()
+	defer cstore.lock.Unlock()
+	var empty time.Time
+	existing := cstore.recentStats.InTimeRange(empty, empty, -1)
+	cstore.maxAge = maxAge
+	cstore.recentStats = utils.NewTimedStore(maxAge, cstore.maxStats)
+	for _, el := range existing {
+		stats := el.(*info.ContainerStats)
+		cstore.recentStats.Add(stats.Timestamp, stats)
+	}
+}
+
 func (self *InMemoryStorage) Close() error {
 	self.lock.Lock()
 	self.containerStorageMap = make(map[string]*containerStorage, 32)
 	self.lock.Unlock()
+	if self.backend != nil {
+		return self.backend.Close()
+	}
 	return nil
 }
 
+// Stats are kept for maxAge, and further evicted by the eviction strategy if
+// not nil.
 func New(
 	maxAge time.Duration,
 	backend storage.StorageDriver,
+	eviction EvictionStrategy,
 ) *InMemoryStorage {
 	ret := &InMemoryStorage{
 		containerStorageMap: make(map[string]*containerStorage, 32),
 		maxAge:              maxAge,
+		eviction:            eviction,
 		backend:             backend,
 	}
 	return ret
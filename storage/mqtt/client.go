@@ -0,0 +1,264 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mqtt
+
+// A minimal MQTT 3.1.1 client, only publishing messages.
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// Types of control packets, in the high nibble of their first byte.
+const (
+	packetConnect    byte = 1
+	packetConnack    byte = 2
+	packetPublish    byte = 3
+	packetPuback     byte = 4
+	packetPubrec     byte = 5
+	packetPubrel     byte = 6
+	packetPubcomp    byte = 7
+	packetPingreq    byte = 12
+	packetPingresp   byte = 13
+	packetDisconnect byte = 14
+)
+
+// Largest remaining length of a packet allowed by the protocol.
+const maxRemainingLength = 268435455
+
+// A control packet. The flags are the low nibble of the first byte.
+type packet struct {
+	kind  byte
+	flags byte
+	body  []byte
+}
+
+func writePacket(w io.Writer, p packet) error {
+	if len(p.body) > maxRemainingLength {
+		return fmt.Errorf("packet of %d bytes is too large", len(p.body))
+	}
+	buf := make([]byte, 0, len(p.body)+5)
+	buf = append(buf, p.kind<<4|p.flags)
+	// The remaining length is encoded 7 bits at a time, least significant first.
+	n := len(p.body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 128
+		}
+		buf = append(buf, b)
+		if n == 0 {
+			break
+		}
+	}
+	buf = append(buf, p.body...)
+	_, err := w.Write(buf)
+	return err
+}
+
+func readPacket(r *bufio.Reader) (packet, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+	length := 0
+	for multiplier := 1; ; multiplier *= 128 {
+		b, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+		length += int(b&127) * multiplier
+		if b&128 == 0 {
+			break
+		}
+		if multiplier > 128*128*128 {
+			return packet{}, errors.New("malformed remaining length")
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return packet{}, err
+	}
+	return packet{kind: first >> 4, flags: first & 15, body: body}, nil
+}
+
+// Appends a string prefixed with its 2 bytes length.
+func appendString(buf []byte, s string) []byte {
+	buf = append(buf, byte(len(s)>>8), byte(len(s)))
+	return append(buf, s...)
+}
+
+func appendUint16(buf []byte, v uint16) []byte {
+	return append(buf, byte(v>>8), byte(v))
+}
+
+// Options of the connection to the broker.
+type connectOptions struct {
+	clientId  string
+	username  string
+	password  string
+	keepAlive time.Duration
+	timeout   time.Duration
+}
+
+// Return codes of CONNACK packets refusing the connection.
+var connackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// A connection to the broker. Not safe for concurrent use.
+type client struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+
+	// Identifier of the last packet published with a QoS above 0.
+	packetId uint16
+}
+
+// Sends the CONNECT packet over conn and waits for the broker to accept it.
+func connect(conn net.Conn, options connectOptions) (*client, error) {
+	c := &client{
+		conn:    conn,
+		reader:  bufio.NewReader(conn),
+		timeout: options.timeout,
+	}
+
+	// Clean session, since the messages are only published.
+	flags := byte(0x02)
+	if options.username != "" {
+		flags |= 0x80
+		if options.password != "" {
+			flags |= 0x40
+		}
+	}
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags)
+	body = appendUint16(body, uint16(options.keepAlive/time.Second))
+	body = appendString(body, options.clientId)
+	if flags&0x80 != 0 {
+		body = appendString(body, options.username)
+	}
+	if flags&0x40 != 0 {
+		body = appendString(body, options.password)
+	}
+	if err := c.write(packet{kind: packetConnect, body: body}); err != nil {
+		return nil, err
+	}
+
+	p, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+	if p.kind != packetConnack || len(p.body) != 2 {
+		return nil, fmt.Errorf("expected CONNACK from the broker, got packet of type %d", p.kind)
+	}
+	if code := p.body[1]; code != 0 {
+		reason, ok := connackErrors[code]
+		if !ok {
+			reason = fmt.Sprintf("return code %d", code)
+		}
+		return nil, fmt.Errorf("connection refused by the broker: %s", reason)
+	}
+	return c, nil
+}
+
+func (self *client) write(p packet) error {
+	self.conn.SetWriteDeadline(time.Now().Add(self.timeout))
+	return writePacket(self.conn, p)
+}
+
+func (self *client) read() (packet, error) {
+	self.conn.SetReadDeadline(time.Now().Add(self.timeout))
+	return readPacket(self.reader)
+}
+
+// Reads packets until one of the specified kind acknowledging packetId.
+// Unexpected acknowledgements, e.g. of messages published before a timeout,
+// are skipped.
+func (self *client) waitFor(kind byte, packetId uint16) error {
+	for {
+		p, err := self.read()
+		if err != nil {
+			return err
+		}
+		if p.kind == kind && len(p.body) >= 2 && binary.BigEndian.Uint16(p.body) == packetId {
+			return nil
+		}
+	}
+}
+
+// Publishes the message and, for a QoS above 0, waits for the broker to
+// acknowledge it.
+func (self *client) publish(topic string, payload []byte, qos byte) error {
+	body := appendString(nil, topic)
+	if qos > 0 {
+		self.packetId++
+		if self.packetId == 0 {
+			self.packetId = 1
+		}
+		body = appendUint16(body, self.packetId)
+	}
+	body = append(body, payload...)
+	if err := self.write(packet{kind: packetPublish, flags: qos << 1, body: body}); err != nil {
+		return err
+	}
+
+	switch qos {
+	case 1:
+		return self.waitFor(packetPuback, self.packetId)
+	case 2:
+		if err := self.waitFor(packetPubrec, self.packetId); err != nil {
+			return err
+		}
+		if err := self.write(packet{kind: packetPubrel, flags: 0x02, body: appendUint16(nil, self.packetId)}); err != nil {
+			return err
+		}
+		return self.waitFor(packetPubcomp, self.packetId)
+	}
+	return nil
+}
+
+// Checks that the broker is still reachable and keeps the connection alive.
+func (self *client) ping() error {
+	if err := self.write(packet{kind: packetPingreq}); err != nil {
+		return err
+	}
+	for {
+		p, err := self.read()
+		if err != nil {
+			return err
+		}
+		if p.kind == packetPingresp {
+			return nil
+		}
+	}
+}
+
+// Disconnects cleanly from the broker and closes the connection.
+func (self *client) close() error {
+	self.write(packet{kind: packetDisconnect})
+	return self.conn.Close()
+}
@@ -0,0 +1,345 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mqtt provides a storage driver that publishes the stats of
+// containers to an MQTT broker, e.g. for edge devices reporting telemetry.
+package mqtt
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/storage"
+)
+
+const (
+	// Number of messages buffered while the broker is unreachable, after which
+	// the oldest ones are dropped.
+	DefaultBufferSize = 1000
+
+	keepAlive = time.Minute
+	// Timeout of the network operations with the broker.
+	timeout = 10 * time.Second
+
+	minRetryInterval = time.Second
+	maxRetryInterval = time.Minute
+)
+
+func init() {
+	storage.RegisterStorageDriver("mqtt", func(config storage.DriverConfig) (storage.StorageDriver, error) {
+		return New(
+			config.MachineName,
+			config.Host,
+			config.Table,
+			config.Qos,
+			config.User,
+			config.Password,
+			config.Secure,
+			DefaultBufferSize,
+			config.StableIDs,
+		)
+	})
+}
+
+// Payload of the messages, one per sample.
+type sample struct {
+	MachineName   string               `json:"machine"`
+	ContainerName string               `json:"container_name"`
+	Stats         *info.ContainerStats `json:"stats"`
+}
+
+type message struct {
+	topic   string
+	payload []byte
+}
+
+type mqttStorage struct {
+	machineName string
+	topicPrefix string
+	qos         byte
+	broker      string
+	stableIDs   bool
+
+	dial    func() (net.Conn, error)
+	options connectOptions
+
+	// Messages waiting to be published.
+	queue   chan *message
+	dropped uint64
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (self *mqttStorage) AddStats(ref info.ContainerReference, stats *info.ContainerStats) error {
+	if stats == nil {
+		return nil
+	}
+	select {
+	case <-self.stop:
+		return fmt.Errorf("mqtt storage is closed")
+	default:
+	}
+	name := storage.SeriesName(ref, self.stableIDs)
+	payload, err := json.Marshal(sample{
+		MachineName:   self.machineName,
+		ContainerName: name,
+		Stats:         stats,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to serialize stats of container %q: %v", ref.Name, err)
+	}
+	self.enqueue(&message{
+		topic:   self.topic(name),
+		payload: payload,
+	})
+	return nil
+}
+
+// Percent-encodes the MQTT wildcards "+" and "#", which may not appear in the
+// topics messages are published to, and "%" itself so that names stay
+// distinct.
+var topicEscaper = strings.NewReplacer("%", "%25", "+", "%2B", "#", "%23")
+
+// Topic the stats of the container are published to: the prefix followed by
+// the escaped name of the container.
+func (self *mqttStorage) topic(containerName string) string {
+	return self.topicPrefix + "/" + topicEscaper.Replace(strings.TrimPrefix(containerName, "/"))
+}
+
+// Queues the message for publishing without blocking, dropping the oldest
+// queued message when the buffer is full.
+func (self *mqttStorage) enqueue(m *message) {
+	for {
+		select {
+		case self.queue <- m:
+			return
+		default:
+		}
+		select {
+		case <-self.queue:
+			if dropped := atomic.AddUint64(&self.dropped, 1); dropped%uint64(cap(self.queue)) == 1 {
+				glog.Warningf("MQTT buffer is full, dropped %d messages so far", dropped)
+			}
+		default:
+		}
+	}
+}
+
+// The storage does not keep any stats.
+func (self *mqttStorage) RecentStats(containerName string, numStats int) ([]*info.ContainerStats, error) {
+	return nil, nil
+}
+
+// Stops publishing and disconnects from the broker. The messages still queued
+// are published first if the broker is connected.
+func (self *mqttStorage) Close() error {
+	self.closeOnce.Do(func() {
+		close(self.stop)
+	})
+	<-self.done
+	return nil
+}
+
+func (self *mqttStorage) connect() (*client, error) {
+	conn, err := self.dial()
+	if err != nil {
+		return nil, err
+	}
+	c, err := connect(conn, self.options)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Publishes the queued messages until the storage is closed, reconnecting to
+// the broker with an exponential backoff whenever the connection is lost.
+func (self *mqttStorage) run() {
+	defer close(self.done)
+
+	var c *client
+	// Message that failed to be published and is retried once reconnected.
+	var pending *message
+	retryInterval := minRetryInterval
+	pingTicker := time.NewTicker(keepAlive / 2)
+	defer pingTicker.Stop()
+	for {
+		if c == nil {
+			var err error
+			c, err = self.connect()
+			if err != nil {
+				glog.Warningf("Failed to connect to MQTT broker %q, retrying in %v: %v", self.broker, retryInterval, err)
+				select {
+				case <-self.stop:
+					return
+				case <-time.After(retryInterval):
+				}
+				retryInterval *= 2
+				if retryInterval > maxRetryInterval {
+					retryInterval = maxRetryInterval
+				}
+				continue
+			}
+			glog.Infof("Connected to MQTT broker %q", self.broker)
+			retryInterval = minRetryInterval
+		}
+
+		if pending == nil {
+			select {
+			case pending = <-self.queue:
+			case <-pingTicker.C:
+				if err := c.ping(); err != nil {
+					glog.Warningf("Lost connection to MQTT broker %q: %v", self.broker, err)
+					c.conn.Close()
+					c = nil
+				}
+				continue
+			case <-self.stop:
+				self.flush(c)
+				c.close()
+				return
+			}
+		}
+		if err := c.publish(pending.topic, pending.payload, self.qos); err != nil {
+			glog.Warningf("Failed to publish to MQTT broker %q, reconnecting: %v", self.broker, err)
+			c.conn.Close()
+			c = nil
+			continue
+		}
+		pending = nil
+	}
+}
+
+// Publishes the queued messages until the queue is empty or publishing fails.
+func (self *mqttStorage) flush(c *client) {
+	for {
+		select {
+		case m := <-self.queue:
+			if err := c.publish(m.topic, m.payload, self.qos); err != nil {
+				glog.Warningf("Failed to publish to MQTT broker %q, dropping %d queued messages: %v", self.broker, len(self.queue)+1, err)
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// Returns the address of the broker and whether to connect to it over TLS.
+// The broker is either host:port or a URL with one of the tcp, mqtt, ssl, tls
+// or mqtts schemes. The port defaults to 1883, or 8883 over TLS.
+func parseBroker(broker string, secure bool) (string, bool, error) {
+	host := broker
+	if strings.Contains(broker, "://") {
+		u, err := url.Parse(broker)
+		if err != nil {
+			return "", false, fmt.Errorf("invalid MQTT broker %q: %v", broker, err)
+		}
+		switch u.Scheme {
+		case "tcp", "mqtt":
+		case "ssl", "tls", "mqtts":
+			secure = true
+		default:
+			return "", false, fmt.Errorf("invalid MQTT broker %q: unsupported scheme %q", broker, u.Scheme)
+		}
+		host = u.Host
+	}
+	if host == "" {
+		return "", false, fmt.Errorf("invalid MQTT broker %q: missing host", broker)
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		port := "1883"
+		if secure {
+			port = "8883"
+		}
+		host = net.JoinHostPort(strings.Trim(host, "[]"), port)
+	}
+	return host, secure, nil
+}
+
+// machineName: A unique identifier of the host the cAdvisor instance is
+// running on, included in every message.
+// broker: Address of the MQTT broker, host:port or a URL such as
+// tcp://host:1883 or ssl://host:8883.
+// topicPrefix: Prefix of the topics, followed by the name of the container.
+// qos: Quality of service the messages are published with, 0, 1 or 2.
+// bufferSize: Number of messages buffered while the broker is unreachable.
+// stableIDs: Whether to identify containers by their stable ID.
+func New(machineName,
+	broker,
+	topicPrefix string,
+	qos int,
+	username,
+	password string,
+	secure bool,
+	bufferSize int,
+	stableIDs bool,
+) (*mqttStorage, error) {
+	if qos < 0 || qos > 2 {
+		return nil, fmt.Errorf("invalid MQTT QoS %d, must be 0, 1 or 2", qos)
+	}
+	if bufferSize <= 0 {
+		return nil, fmt.Errorf("invalid MQTT buffer size %d, must be positive", bufferSize)
+	}
+	address, useTls, err := parseBroker(broker, secure)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	dial := func() (net.Conn, error) {
+		return dialer.Dial("tcp", address)
+	}
+	if useTls {
+		dial = func() (net.Conn, error) {
+			return tls.DialWithDialer(dialer, "tcp", address, nil)
+		}
+	}
+	ret := newStorage(machineName, broker, topicPrefix, byte(qos), connectOptions{
+		clientId:  "cadvisor-" + machineName,
+		username:  username,
+		password:  password,
+		keepAlive: keepAlive,
+		timeout:   timeout,
+	}, bufferSize, dial)
+	ret.stableIDs = stableIDs
+	return ret, nil
+}
+
+func newStorage(machineName, broker, topicPrefix string, qos byte, options connectOptions, bufferSize int, dial func() (net.Conn, error)) *mqttStorage {
+	ret := &mqttStorage{
+		machineName: machineName,
+		topicPrefix: strings.TrimSuffix(topicPrefix, "/"),
+		qos:         qos,
+		broker:      broker,
+		dial:        dial,
+		options:     options,
+		queue:       make(chan *message, bufferSize),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go ret.run()
+	return ret
+}
@@ -0,0 +1,258 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+// A broker reached through in-memory connections.
+type fakeBroker struct {
+	conns chan net.Conn
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{conns: make(chan net.Conn, 10)}
+}
+
+func (self *fakeBroker) dial() (net.Conn, error) {
+	client, server := net.Pipe()
+	self.conns <- server
+	return client, nil
+}
+
+// Waits for a client to connect, checks its CONNECT packet and accepts it.
+func (self *fakeBroker) accept(t *testing.T) (net.Conn, *bufio.Reader) {
+	var conn net.Conn
+	select {
+	case conn = <-self.conns:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a connection")
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	p, err := readPacket(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.kind != packetConnect {
+		t.Fatalf("expected CONNECT, got packet of type %d", p.kind)
+	}
+	// Protocol name, level, flags and keep alive, then the client identifier,
+	// the user name and the password.
+	if flags := p.body[7]; flags != 0xc2 {
+		t.Errorf("expected clean session with credentials, got flags %#x", flags)
+	}
+	if err := writePacket(conn, packet{kind: packetConnack, body: []byte{0, 0}}); err != nil {
+		t.Fatal(err)
+	}
+	return conn, r
+}
+
+// Reads a PUBLISH packet and returns its topic, packet identifier and payload.
+func readPublish(t *testing.T, r *bufio.Reader, qos byte) (string, uint16, []byte) {
+	p, err := readPacket(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.kind != packetPublish || p.flags != qos<<1 {
+		t.Fatalf("expected PUBLISH with QoS %d, got packet of type %d with flags %#x", qos, p.kind, p.flags)
+	}
+	n := int(binary.BigEndian.Uint16(p.body))
+	topic := string(p.body[2 : 2+n])
+	body := p.body[2+n:]
+	var id uint16
+	if qos > 0 {
+		id = binary.BigEndian.Uint16(body)
+		body = body[2:]
+	}
+	return topic, id, body
+}
+
+func newTestStorage(dial func() (net.Conn, error), qos byte, bufferSize int) *mqttStorage {
+	return newStorage("machine", "broker", "cadvisor/", qos, connectOptions{
+		clientId:  "cadvisor-machine",
+		username:  "user",
+		password:  "secret",
+		keepAlive: keepAlive,
+		timeout:   5 * time.Second,
+	}, bufferSize, dial)
+}
+
+func newTestStats(seconds int) *info.ContainerStats {
+	stats := &info.ContainerStats{Timestamp: time.Unix(int64(seconds), 0).UTC()}
+	stats.Cpu.Usage.Total = uint64(seconds)
+	return stats
+}
+
+var ref = info.ContainerReference{Name: "/docker/abcd"}
+
+func TestPublish(t *testing.T) {
+	broker := newFakeBroker()
+	driver := newTestStorage(broker.dial, 1, DefaultBufferSize)
+	if err := driver.AddStats(ref, newTestStats(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, r := broker.accept(t)
+	topic, id, payload := readPublish(t, r, 1)
+	if topic != "cadvisor/docker/abcd" {
+		t.Errorf("expected topic %q, got %q", "cadvisor/docker/abcd", topic)
+	}
+	var s sample
+	if err := json.Unmarshal(payload, &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.MachineName != "machine" || s.ContainerName != "/docker/abcd" || s.Stats.Cpu.Usage.Total != 1 {
+		t.Errorf("unexpected sample %+v", s)
+	}
+	if err := writePacket(conn, packet{kind: packetPuback, body: appendUint16(nil, id)}); err != nil {
+		t.Fatal(err)
+	}
+
+	closed := make(chan error)
+	go func() {
+		closed <- driver.Close()
+	}()
+	if p, err := readPacket(r); err != nil || p.kind != packetDisconnect {
+		t.Errorf("expected DISCONNECT on close, got packet of type %d: %v", p.kind, err)
+	}
+	if err := <-closed; err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.AddStats(ref, newTestStats(2)); err == nil {
+		t.Errorf("expected an error adding stats to a closed storage")
+	}
+}
+
+func TestRepublishAfterReconnect(t *testing.T) {
+	broker := newFakeBroker()
+	driver := newTestStorage(broker.dial, 1, DefaultBufferSize)
+	if err := driver.AddStats(ref, newTestStats(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The connection is lost before the message is acknowledged.
+	conn, r := broker.accept(t)
+	readPublish(t, r, 1)
+	conn.Close()
+
+	conn, r = broker.accept(t)
+	_, id, payload := readPublish(t, r, 1)
+	var s sample
+	if err := json.Unmarshal(payload, &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Stats.Cpu.Usage.Total != 1 {
+		t.Errorf("expected the unacknowledged message to be published again, got %+v", s)
+	}
+	if err := writePacket(conn, packet{kind: packetPuback, body: appendUint16(nil, id)}); err != nil {
+		t.Fatal(err)
+	}
+
+	go driver.Close()
+	readPacket(r)
+	<-driver.done
+}
+
+func TestOfflineBuffer(t *testing.T) {
+	driver := newTestStorage(func() (net.Conn, error) {
+		return nil, errors.New("broker unreachable")
+	}, 0, 2)
+	defer driver.Close()
+
+	for i := 1; i <= 3; i++ {
+		if err := driver.AddStats(ref, newTestStats(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The oldest message was dropped.
+	if len(driver.queue) != 2 || driver.dropped != 1 {
+		t.Fatalf("expected 2 buffered messages and 1 dropped, got %d and %d", len(driver.queue), driver.dropped)
+	}
+	for i := 2; i <= 3; i++ {
+		var s sample
+		if err := json.Unmarshal((<-driver.queue).payload, &s); err != nil {
+			t.Fatal(err)
+		}
+		if s.Stats.Cpu.Usage.Total != uint64(i) {
+			t.Errorf("expected buffered stats %d, got %d", i, s.Stats.Cpu.Usage.Total)
+		}
+	}
+}
+
+func TestTopic(t *testing.T) {
+	storage := newTestStorage(nil, 0, 1)
+	cases := map[string]string{
+		"/":                  "cadvisor/",
+		"/docker/abcd":       "cadvisor/docker/abcd",
+		"/system.slice/a+b":  "cadvisor/system.slice/a%2Bb",
+		"/system.slice/#1":   "cadvisor/system.slice/%231",
+		"/system.slice/100%": "cadvisor/system.slice/100%25",
+	}
+	for name, expected := range cases {
+		if topic := storage.topic(name); topic != expected {
+			t.Errorf("expected topic %q for container %q, got %q", expected, name, topic)
+		}
+	}
+}
+
+func TestParseBroker(t *testing.T) {
+	cases := []struct {
+		broker  string
+		secure  bool
+		address string
+		useTls  bool
+	}{
+		{"localhost:1884", false, "localhost:1884", false},
+		{"localhost", false, "localhost:1883", false},
+		{"localhost", true, "localhost:8883", true},
+		{"tcp://broker.example.com", false, "broker.example.com:1883", false},
+		{"ssl://broker.example.com", false, "broker.example.com:8883", true},
+		{"mqtts://10.0.0.1:8884", false, "10.0.0.1:8884", true},
+		{"tcp://[::1]", false, "[::1]:1883", false},
+	}
+	for _, c := range cases {
+		address, useTls, err := parseBroker(c.broker, c.secure)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", c.broker, err)
+			continue
+		}
+		if address != c.address || useTls != c.useTls {
+			t.Errorf("expected %q to be %q with TLS %v, got %q with TLS %v", c.broker, c.address, c.useTls, address, useTls)
+		}
+	}
+
+	for _, broker := range []string{"", "http://localhost", "tcp://"} {
+		if _, _, err := parseBroker(broker, false); err == nil {
+			t.Errorf("expected an error for broker %q", broker)
+		}
+	}
+}
+
+func TestNewInvalidQos(t *testing.T) {
+	if _, err := New("machine", "localhost", "cadvisor", 3, "", "", false, DefaultBufferSize, false); err == nil {
+		t.Errorf("expected an error for QoS 3")
+	}
+}
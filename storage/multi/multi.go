@@ -0,0 +1,147 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package multi provides a storage driver that writes samples to several
+// storage drivers, each at its own interval.
+package multi
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/storage"
+)
+
+// A storage driver written to by the multi storage.
+type Backend struct {
+	// Name of the backend, used in errors.
+	Name   string
+	Driver storage.StorageDriver
+
+	// Minimum interval between the samples of a container written to the
+	// backend. Samples closer to the last written one are skipped. All the
+	// samples are written if zero.
+	Interval time.Duration
+}
+
+type backend struct {
+	Backend
+
+	// Timestamp of the last sample written to the backend, keyed by container
+	// name.
+	lastWritten map[string]time.Time
+	lock        sync.Mutex
+}
+
+// Whether the sample is due for the backend. Records it as written if so.
+func (self *backend) due(containerName string, timestamp time.Time) bool {
+	if self.Interval <= 0 {
+		return true
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	last, ok := self.lastWritten[containerName]
+	if ok && timestamp.Sub(last) < self.Interval {
+		return false
+	}
+	self.lastWritten[containerName] = timestamp
+	return true
+}
+
+// Forgets the last sample written for the container.
+func (self *backend) remove(containerName string) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	delete(self.lastWritten, containerName)
+}
+
+type multiStorage struct {
+	backends []*backend
+}
+
+// Collects the errors of the backends into a single error.
+func combineErrors(errs []string) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(errs, "; "))
+}
+
+func (self *multiStorage) AddStats(ref info.ContainerReference, stats *info.ContainerStats) error {
+	var errs []string
+	for _, b := range self.backends {
+		if !b.due(ref.Name, stats.Timestamp) {
+			continue
+		}
+		if err := b.Driver.AddStats(ref, stats); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", b.Name, err))
+		}
+	}
+	return combineErrors(errs)
+}
+
+func (self *multiStorage) AddSpec(ref info.ContainerReference, spec info.ContainerSpec) error {
+	var errs []string
+	for _, b := range self.backends {
+		if err := storage.AddSpec(b.Driver, ref, spec); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", b.Name, err))
+		}
+	}
+	return combineErrors(errs)
+}
+
+func (self *multiStorage) RemoveContainer(ref info.ContainerReference) error {
+	var errs []string
+	for _, b := range self.backends {
+		b.remove(ref.Name)
+		if err := storage.RemoveContainer(b.Driver, ref); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", b.Name, err))
+		}
+	}
+	return combineErrors(errs)
+}
+
+// Reads the stats from the first backend.
+func (self *multiStorage) RecentStats(containerName string, numStats int) ([]*info.ContainerStats, error) {
+	return self.backends[0].Driver.RecentStats(containerName, numStats)
+}
+
+func (self *multiStorage) Close() error {
+	var errs []string
+	for _, b := range self.backends {
+		if err := b.Driver.Close(); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", b.Name, err))
+		}
+	}
+	return combineErrors(errs)
+}
+
+// Writes every sample to each of the backends, skipping the samples of a
+// container closer than the interval of the backend to the last one written.
+func New(backends []Backend) (storage.StorageDriver, error) {
+	if len(backends) == 0 {
+		return nil, fmt.Errorf("no backend storage to write to")
+	}
+	ret := &multiStorage{}
+	for _, b := range backends {
+		ret.backends = append(ret.backends, &backend{
+			Backend:     b,
+			lastWritten: make(map[string]time.Time),
+		})
+	}
+	return ret, nil
+}
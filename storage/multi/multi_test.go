@@ -0,0 +1,144 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multi
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/storage"
+)
+
+// Counts the samples written to it for each container.
+type countingStorage struct {
+	counts  map[string]int
+	removed map[string]bool
+	err     error
+}
+
+func newCountingStorage() *countingStorage {
+	return &countingStorage{counts: make(map[string]int), removed: make(map[string]bool)}
+}
+
+func (self *countingStorage) RemoveContainer(ref info.ContainerReference) error {
+	self.removed[ref.Name] = true
+	return nil
+}
+
+func (self *countingStorage) AddStats(ref info.ContainerReference, stats *info.ContainerStats) error {
+	self.counts[ref.Name]++
+	return self.err
+}
+
+func (self *countingStorage) RecentStats(containerName string, numStats int) ([]*info.ContainerStats, error) {
+	return nil, nil
+}
+
+func (self *countingStorage) Close() error {
+	return nil
+}
+
+func TestIntervals(t *testing.T) {
+	local := newCountingStorage()
+	cloud := newCountingStorage()
+	driver, err := New([]Backend{
+		{Name: "local", Driver: local},
+		{Name: "cloud", Driver: cloud, Interval: 5 * time.Second},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2015, time.March, 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 12; i++ {
+		for _, name := range []string{"/a", "/b"} {
+			stats := &info.ContainerStats{Timestamp: start.Add(time.Duration(i) * time.Second)}
+			if err := driver.AddStats(info.ContainerReference{Name: name}, stats); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// The cloud backend only receives the samples at 0s, 5s and 10s.
+	for _, name := range []string{"/a", "/b"} {
+		if local.counts[name] != 12 {
+			t.Errorf("expected 12 samples of %q written locally, got %d", name, local.counts[name])
+		}
+		if cloud.counts[name] != 3 {
+			t.Errorf("expected 3 samples of %q written to the cloud, got %d", name, cloud.counts[name])
+		}
+	}
+}
+
+func TestBackendErrors(t *testing.T) {
+	failing := newCountingStorage()
+	failing.err = errors.New("unavailable")
+	working := newCountingStorage()
+	driver, err := New([]Backend{
+		{Name: "failing", Driver: failing},
+		{Name: "working", Driver: working},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref := info.ContainerReference{Name: "/a"}
+	if err := driver.AddStats(ref, &info.ContainerStats{}); err == nil || err.Error() != "failing: unavailable" {
+		t.Errorf("expected the error of the failing backend, got %v", err)
+	}
+	if working.counts["/a"] != 1 {
+		t.Errorf("expected the sample to be written to the working backend, got %d samples", working.counts["/a"])
+	}
+}
+
+func TestRemoveContainer(t *testing.T) {
+	cloud := newCountingStorage()
+	driver, err := New([]Backend{
+		{Name: "cloud", Driver: cloud, Interval: 5 * time.Second},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref := info.ContainerReference{Name: "/a"}
+	start := time.Date(2015, time.March, 1, 10, 0, 0, 0, time.UTC)
+	if err := driver.AddStats(ref, &info.ContainerStats{Timestamp: start}); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.RemoveContainer(driver, ref); err != nil {
+		t.Fatal(err)
+	}
+	if !cloud.removed["/a"] {
+		t.Errorf("expected the removal to be forwarded to the backend")
+	}
+	if n := len(driver.(*multiStorage).backends[0].lastWritten); n != 0 {
+		t.Errorf("expected the removed container to be forgotten, got %d containers", n)
+	}
+	// A new container with the same name is written to right away.
+	if err := driver.AddStats(ref, &info.ContainerStats{Timestamp: start.Add(time.Second)}); err != nil {
+		t.Fatal(err)
+	}
+	if cloud.counts["/a"] != 2 {
+		t.Errorf("expected 2 samples written to the cloud, got %d", cloud.counts["/a"])
+	}
+}
+
+func TestNoBackends(t *testing.T) {
+	if _, err := New(nil); err == nil {
+		t.Errorf("expected an error without backends")
+	}
+}
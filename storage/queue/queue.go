@@ -0,0 +1,165 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package queue provides a storage driver that writes samples to another
+// storage driver in the background so that a slow backend does not stall
+// collection, and that signals backpressure while the backend falls behind.
+package queue
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/golang/glog"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/storage"
+)
+
+// Sample, spec or removal of a container waiting to be written to the backend.
+type queuedWrite struct {
+	ref     info.ContainerReference
+	stats   *info.ContainerStats
+	spec    *info.ContainerSpec
+	removed bool
+}
+
+type queuedStorage struct {
+	backend storage.StorageDriver
+	writes  chan queuedWrite
+
+	// Number of queued writes at and above which backpressure is signalled,
+	// and at and below which it is cleared again.
+	highWatermark int
+	lowWatermark  int
+
+	backpressure bool
+	lock         sync.Mutex
+
+	// Held for reading while queueing so that writes are never queued once
+	// the queue is closed.
+	closeLock sync.RWMutex
+	closed    bool
+
+	// Closed once every queued write has reached the backend.
+	drained chan struct{}
+}
+
+func (self *queuedStorage) AddStats(ref info.ContainerReference, stats *info.ContainerStats) error {
+	return self.queue(queuedWrite{ref: ref, stats: stats})
+}
+
+func (self *queuedStorage) AddSpec(ref info.ContainerReference, spec info.ContainerSpec) error {
+	return self.queue(queuedWrite{ref: ref, spec: &spec})
+}
+
+// Queues the removal so that it reaches the backend after the samples of the
+// container still queued.
+func (self *queuedStorage) RemoveContainer(ref info.ContainerReference) error {
+	return self.queue(queuedWrite{ref: ref, removed: true})
+}
+
+// Queues the write, waiting for room in the queue if it is full.
+func (self *queuedStorage) queue(write queuedWrite) error {
+	self.closeLock.RLock()
+	defer self.closeLock.RUnlock()
+	if self.closed {
+		return fmt.Errorf("storage queue is closed")
+	}
+	self.writes <- write
+	self.updateBackpressure()
+	return nil
+}
+
+// Signals backpressure once the queue fills up to the high watermark and
+// clears it once the queue drains down to the low watermark.
+func (self *queuedStorage) updateBackpressure() {
+	queued := len(self.writes)
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if !self.backpressure && queued >= self.highWatermark {
+		glog.Warningf("Storage backend is falling behind with %d writes queued, signalling backpressure", queued)
+		self.backpressure = true
+	} else if self.backpressure && queued <= self.lowWatermark {
+		glog.Infof("Storage backend caught up with %d writes queued, clearing backpressure", queued)
+		self.backpressure = false
+	}
+}
+
+func (self *queuedStorage) Backpressure() bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.backpressure
+}
+
+// Writes the queued samples, specs and removals to the backend until the queue is closed.
+func (self *queuedStorage) write() {
+	defer close(self.drained)
+	for write := range self.writes {
+		var err error
+		if write.removed {
+			err = storage.RemoveContainer(self.backend, write.ref)
+		} else if write.spec != nil {
+			err = storage.AddSpec(self.backend, write.ref, *write.spec)
+		} else {
+			err = self.backend.AddStats(write.ref, write.stats)
+		}
+		if err != nil {
+			glog.Errorf("Failed to write to the storage backend for container %q: %v", write.ref.Name, err)
+		}
+		self.updateBackpressure()
+	}
+}
+
+func (self *queuedStorage) RecentStats(containerName string, numStats int) ([]*info.ContainerStats, error) {
+	return self.backend.RecentStats(containerName, numStats)
+}
+
+// Writes the writes still queued to the backend before closing it.
+func (self *queuedStorage) Close() error {
+	self.closeLock.Lock()
+	if self.closed {
+		self.closeLock.Unlock()
+		return nil
+	}
+	self.closed = true
+	close(self.writes)
+	self.closeLock.Unlock()
+	<-self.drained
+	return self.backend.Close()
+}
+
+// Wraps the backend so that samples and specs are queued and written to it in
+// the background, queueing at most size of them before callers wait. Once
+// threshold, between 0 and 1, of the queue is used backpressure is signalled
+// until the queue drains to half that.
+func New(backend storage.StorageDriver, size int, threshold float64) storage.BackpressureStorageDriver {
+	if size < 1 {
+		size = 1
+	}
+	highWatermark := int(threshold * float64(size))
+	if highWatermark < 1 {
+		highWatermark = 1
+	} else if highWatermark > size {
+		highWatermark = size
+	}
+	self := &queuedStorage{
+		backend:       backend,
+		writes:        make(chan queuedWrite, size),
+		highWatermark: highWatermark,
+		lowWatermark:  highWatermark / 2,
+		drained:       make(chan struct{}),
+	}
+	go self.write()
+	return self
+}
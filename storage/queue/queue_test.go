@@ -0,0 +1,111 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+var containerRef = info.ContainerReference{Name: "/container"}
+
+// Backend that only writes samples once released.
+type slowStorage struct {
+	release chan struct{}
+	lock    sync.Mutex
+	written int
+}
+
+func (self *slowStorage) AddStats(ref info.ContainerReference, stats *info.ContainerStats) error {
+	<-self.release
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.written++
+	return nil
+}
+
+func (self *slowStorage) RecentStats(containerName string, numStats int) ([]*info.ContainerStats, error) {
+	return nil, nil
+}
+
+func (self *slowStorage) Close() error {
+	return nil
+}
+
+func (self *slowStorage) numWritten() int {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.written
+}
+
+// Waits up to a second for the condition to hold.
+func waitFor(condition func() bool) bool {
+	for i := 0; i < 100; i++ {
+		if condition() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return condition()
+}
+
+func TestSlowBackendSignalsBackpressure(t *testing.T) {
+	backend := &slowStorage{release: make(chan struct{})}
+	driver := New(backend, 10, 0.5)
+
+	// The first sample is taken off the queue by the stuck write, the next
+	// five fill the queue up to the high watermark.
+	for i := 0; i < 6; i++ {
+		if driver.Backpressure() {
+			t.Fatalf("backpressure signalled after %d samples", i)
+		}
+		if err := driver.AddStats(containerRef, &info.ContainerStats{}); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 && !waitFor(func() bool { return len(driver.(*queuedStorage).writes) == 0 }) {
+			t.Fatal("first sample was not taken off the queue")
+		}
+	}
+	if !driver.Backpressure() {
+		t.Fatal("backpressure not signalled with the queue at the high watermark")
+	}
+
+	// Backpressure holds until the queue drains down to the low watermark.
+	backend.release <- struct{}{}
+	backend.release <- struct{}{}
+	if !waitFor(func() bool { return backend.numWritten() == 2 }) {
+		t.Fatal("released samples were not written")
+	}
+	if !driver.Backpressure() {
+		t.Fatal("backpressure cleared above the low watermark")
+	}
+	close(backend.release)
+	if !waitFor(func() bool { return !driver.Backpressure() }) {
+		t.Fatal("backpressure not cleared once the queue drained")
+	}
+
+	if err := driver.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if backend.numWritten() != 6 {
+		t.Errorf("expected all 6 samples to be written on close, got %d", backend.numWritten())
+	}
+	if err := driver.AddStats(containerRef, &info.ContainerStats{}); err == nil {
+		t.Error("expected an error adding stats once closed")
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rounding provides a storage driver that rounds the gauges of samples
+// to a number of significant figures before handing them to another storage
+// driver, which compresses better in backends storing floats.
+package rounding
+
+import (
+	"math"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/storage"
+)
+
+type roundedStorage struct {
+	backend storage.StorageDriver
+
+	// Number of significant figures the gauges are rounded to.
+	significantFigures int
+}
+
+// Rounds the value to the nearest number with the specified significant
+// figures. The largest value, used for unlimited limits, is kept as is.
+func round(v uint64, significantFigures int) uint64 {
+	if v == math.MaxUint64 {
+		return v
+	}
+	digits := int(math.Log10(float64(v))) + 1
+	if v == 0 || digits <= significantFigures {
+		return v
+	}
+	factor := uint64(math.Pow10(digits - significantFigures))
+	if v > math.MaxUint64-factor/2 {
+		return v / factor * factor
+	}
+	return (v + factor/2) / factor * factor
+}
+
+func (self *roundedStorage) AddStats(ref info.ContainerReference, stats *info.ContainerStats) error {
+	if stats == nil {
+		return self.backend.AddStats(ref, stats)
+	}
+	// The sample is shared with the in-memory storage, so a copy is rounded.
+	rounded := stats.GaugeCopy()
+	// Counters, e.g. the CPU usage, are left out so that they stay monotonic,
+	// and limits so that they stay exact.
+	rounded.ForEachGauge(func(value *uint64, limit bool) {
+		if !limit {
+			*value = round(*value, self.significantFigures)
+		}
+	})
+	return self.backend.AddStats(ref, rounded)
+}
+
+func (self *roundedStorage) AddSpec(ref info.ContainerReference, spec info.ContainerSpec) error {
+	return storage.AddSpec(self.backend, ref, spec)
+}
+
+func (self *roundedStorage) RemoveContainer(ref info.ContainerReference) error {
+	return storage.RemoveContainer(self.backend, ref)
+}
+
+func (self *roundedStorage) RecentStats(containerName string, numStats int) ([]*info.ContainerStats, error) {
+	return self.backend.RecentStats(containerName, numStats)
+}
+
+func (self *roundedStorage) Close() error {
+	return self.backend.Close()
+}
+
+// Wraps the backend so that the gauges of every sample are rounded to the
+// specified number of significant figures. Counters are written unchanged.
+func New(backend storage.StorageDriver, significantFigures int) storage.StorageDriver {
+	return &roundedStorage{
+		backend:            backend,
+		significantFigures: significantFigures,
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rounding
+
+import (
+	"math"
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/storage/test"
+	"github.com/stretchr/testify/mock"
+)
+
+var containerRef = info.ContainerReference{Name: "/container"}
+
+func TestRound(t *testing.T) {
+	cases := []struct {
+		value    uint64
+		expected uint64
+	}{
+		{0, 0},
+		{7, 7},
+		{123, 123},
+		{1234, 1230},
+		{1235, 1240},
+		{987654321, 988000000},
+		{999999, 1000000},
+		{math.MaxUint64, math.MaxUint64},
+		{math.MaxUint64 - 1, 18400000000000000000},
+	}
+	for _, c := range cases {
+		if rounded := round(c.value, 3); rounded != c.expected {
+			t.Errorf("expected %d rounded to 3 significant figures to be %d, got %d", c.value, c.expected, rounded)
+		}
+	}
+}
+
+func TestGaugesAreRounded(t *testing.T) {
+	backend := &test.MockStorageDriver{}
+	backend.On("AddStats", containerRef, mock.Anything).Return(nil)
+	driver := New(backend, 2)
+
+	stats := &info.ContainerStats{
+		Filesystem: []info.FsStats{{Limit: 123456, Usage: 54321, IoInProgress: 345}},
+	}
+	stats.Cpu.LoadAverage = 1234
+	stats.Cpu.Usage.Total = 123456789
+	stats.Memory.Usage = 123456789
+	stats.Memory.WorkingSet = 98765
+	if err := driver.AddStats(containerRef, stats); err != nil {
+		t.Fatal(err)
+	}
+
+	written := backend.Calls[0].Arguments.Get(1).(*info.ContainerStats)
+	if written.Memory.Usage != 120000000 || written.Memory.WorkingSet != 99000 || written.Filesystem[0].Usage != 54000 {
+		t.Errorf("expected the gauges to be rounded to 2 significant figures, got %+v", written)
+	}
+	if written.Cpu.LoadAverage != 1200 || written.Filesystem[0].IoInProgress != 350 {
+		t.Errorf("expected the load average and IOs in progress to be rounded, got %+v", written)
+	}
+	// Counters and limits are written unchanged.
+	if written.Cpu.Usage.Total != 123456789 || written.Filesystem[0].Limit != 123456 {
+		t.Errorf("expected the counters and limits to be unchanged, got %+v", written)
+	}
+	// The sample given to the driver is not modified.
+	if stats.Memory.Usage != 123456789 || stats.Filesystem[0].Usage != 54321 {
+		t.Errorf("expected the original sample to be unchanged, got %+v", stats)
+	}
+}
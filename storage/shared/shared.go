@@ -0,0 +1,257 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package shared provides a storage driver that writes the recent stats and
+// the specs of containers to a directory, from which another cAdvisor instance
+// can serve them read-only.
+package shared
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/storage"
+)
+
+const (
+	// Number of recent stats kept for each container.
+	DefaultMaxStats = 10
+
+	// Containers not written for this long are considered gone. Longer than the
+	// largest housekeeping interval so that idle containers are kept.
+	DefaultMaxAge = 2 * time.Minute
+
+	fileSuffix = ".json"
+)
+
+func init() {
+	storage.RegisterStorageDriver("shared", func(config storage.DriverConfig) (storage.StorageDriver, error) {
+		return New(config.Directory, DefaultMaxStats, DefaultMaxAge)
+	})
+}
+
+// Contents of the file of a container.
+type record struct {
+	Reference info.ContainerReference `json:"reference"`
+	Spec      *info.ContainerSpec     `json:"spec,omitempty"`
+	// Sorted from oldest to most recent.
+	Stats []*info.ContainerStats `json:"stats"`
+}
+
+// Store keeps one file per container in a directory. Writers add stats and
+// specs through the StorageDriver interface, readers list the containers and
+// read them back.
+type Store struct {
+	dir      string
+	maxStats int
+	maxAge   time.Duration
+
+	// Records written by this store, keyed by container name.
+	lock      sync.Mutex
+	records   map[string]*record
+	lastPrune time.Time
+}
+
+func (self *Store) AddStats(ref info.ContainerReference, stats *info.ContainerStats) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.pruneIfNeeded()
+	r := self.getRecord(ref)
+	r.Stats = append(r.Stats, stats)
+	if len(r.Stats) > self.maxStats {
+		r.Stats = r.Stats[len(r.Stats)-self.maxStats:]
+	}
+	return self.write(r)
+}
+
+func (self *Store) AddSpec(ref info.ContainerReference, spec info.ContainerSpec) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	r := self.getRecord(ref)
+	if r.Spec != nil && reflect.DeepEqual(*r.Spec, spec) {
+		return nil
+	}
+	r.Spec = &spec
+	return self.write(r)
+}
+
+// Returns the record written by this store for the container, creating it if
+// needed. Must be called with lock held.
+func (self *Store) getRecord(ref info.ContainerReference) *record {
+	r, ok := self.records[ref.Name]
+	if !ok {
+		r = &record{}
+		self.records[ref.Name] = r
+	}
+	r.Reference = ref
+	return r
+}
+
+// Atomically replaces the file of the record.
+func (self *Store) write(r *record) error {
+	out, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to serialize container %q: %v", r.Reference.Name, err)
+	}
+	file := self.path(r.Reference.Name)
+	tmp := file + ".tmp"
+	if err := ioutil.WriteFile(tmp, out, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// Removes the files of the containers that were not written for maxAge, at
+// most once per maxAge. Files left by previous writers are removed as well.
+// Must be called with lock held.
+func (self *Store) pruneIfNeeded() {
+	if time.Since(self.lastPrune) < self.maxAge {
+		return
+	}
+	self.lastPrune = time.Now()
+	entries, err := ioutil.ReadDir(self.dir)
+	if err != nil {
+		glog.Warningf("Failed to list the shared storage in %q: %v", self.dir, err)
+		return
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), fileSuffix) || time.Since(entry.ModTime()) < self.maxAge {
+			continue
+		}
+		os.Remove(filepath.Join(self.dir, entry.Name()))
+		if name, err := url.QueryUnescape(strings.TrimSuffix(entry.Name(), fileSuffix)); err == nil {
+			delete(self.records, name)
+		}
+	}
+}
+
+func (self *Store) path(containerName string) string {
+	return filepath.Join(self.dir, url.QueryEscape(containerName)+fileSuffix)
+}
+
+// Reads the record of the container. Containers not written for maxAge are
+// reported as not found.
+func (self *Store) read(containerName string) (*record, error) {
+	file := self.path(containerName)
+	fi, err := os.Stat(file)
+	if err != nil || time.Since(fi.ModTime()) >= self.maxAge {
+		return nil, fmt.Errorf("unable to find data for container %v", containerName)
+	}
+	out, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var r record
+	if err := json.Unmarshal(out, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse the data of container %q: %v", containerName, err)
+	}
+	return &r, nil
+}
+
+func (self *Store) RecentStats(containerName string, numStats int) ([]*info.ContainerStats, error) {
+	r, err := self.read(containerName)
+	if err != nil {
+		return nil, err
+	}
+	stats := r.Stats
+	if numStats >= 0 && len(stats) > numStats {
+		stats = stats[len(stats)-numStats:]
+	}
+	return stats, nil
+}
+
+// Returns the spec of the container. Fails if it was not written yet.
+func (self *Store) GetSpec(containerName string) (info.ContainerSpec, error) {
+	r, err := self.read(containerName)
+	if err != nil {
+		return info.ContainerSpec{}, err
+	}
+	if r.Spec == nil {
+		return info.ContainerSpec{}, fmt.Errorf("no spec stored for container %q", containerName)
+	}
+	return *r.Spec, nil
+}
+
+// Returns the reference of the container.
+func (self *Store) ContainerReference(containerName string) (info.ContainerReference, error) {
+	r, err := self.read(containerName)
+	if err != nil {
+		return info.ContainerReference{}, err
+	}
+	return r.Reference, nil
+}
+
+// Returns whether the container was written within maxAge.
+func (self *Store) Exists(containerName string) bool {
+	fi, err := os.Stat(self.path(containerName))
+	return err == nil && time.Since(fi.ModTime()) < self.maxAge
+}
+
+// Returns the names of the containers written within maxAge, sorted.
+func (self *Store) ListContainers() ([]string, error) {
+	entries, err := ioutil.ReadDir(self.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), fileSuffix) || time.Since(entry.ModTime()) >= self.maxAge {
+			continue
+		}
+		name, err := url.QueryUnescape(strings.TrimSuffix(entry.Name(), fileSuffix))
+		if err != nil {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (self *Store) Close() error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.records = make(map[string]*record)
+	return nil
+}
+
+// Creates a store in the specified directory keeping the last maxStats stats
+// of each container. Containers not written for maxAge are considered gone.
+func New(dir string, maxStats int, maxAge time.Duration) (*Store, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("shared storage requires a directory")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Store{
+		dir:      dir,
+		maxStats: maxStats,
+		maxAge:   maxAge,
+		records:  make(map[string]*record),
+	}, nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "cadvisor-shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestRoundTrip(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	writer, err := New(dir, 2, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := New(dir, 2, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref := info.ContainerReference{
+		Name:      "/docker/abcd",
+		Aliases:   []string{"web"},
+		Namespace: "docker",
+	}
+	spec := info.ContainerSpec{
+		HasCpu: true,
+		Labels: map[string]string{"app": "web"},
+	}
+	if err := writer.AddSpec(ref, spec); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2015, time.March, 1, 10, 0, 0, 0, time.UTC)
+	var written []*info.ContainerStats
+	for i := 0; i < 3; i++ {
+		stats := &info.ContainerStats{Timestamp: start.Add(time.Duration(i) * time.Second)}
+		stats.Cpu.Usage.Total = uint64(i)
+		written = append(written, stats)
+		if err := writer.AddStats(ref, stats); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.AddStats(info.ContainerReference{Name: "/"}, written[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := reader.ListContainers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"/", "/docker/abcd"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected containers %v, got %v", expected, names)
+	}
+	readRef, err := reader.ContainerReference(ref.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(readRef, ref) {
+		t.Errorf("expected reference %+v, got %+v", ref, readRef)
+	}
+	readSpec, err := reader.GetSpec(ref.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(readSpec, spec) {
+		t.Errorf("expected spec %+v, got %+v", spec, readSpec)
+	}
+	if _, err := reader.GetSpec("/"); err == nil {
+		t.Errorf("expected an error for a container without spec")
+	}
+
+	// Only the last 2 stats are kept.
+	stats, err := reader.RecentStats(ref.Name, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 || !stats[0].Eq(written[1]) || !stats[1].Eq(written[2]) {
+		t.Errorf("expected stats %+v, got %+v", written[1:], stats)
+	}
+	stats, err = reader.RecentStats(ref.Name, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || !stats[0].Eq(written[2]) {
+		t.Errorf("expected stats %+v, got %+v", written[2:], stats)
+	}
+	if _, err := reader.RecentStats("/unknown", -1); err == nil {
+		t.Errorf("expected an error for an unknown container")
+	}
+}
+
+func TestStaleContainers(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	store, err := New(dir, 2, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := "/stale"
+	if err := store.AddStats(info.ContainerReference{Name: name}, &info.ContainerStats{}); err != nil {
+		t.Fatal(err)
+	}
+	if !store.Exists(name) {
+		t.Fatalf("expected %q to exist", name)
+	}
+
+	old := time.Now().Add(-2 * time.Minute)
+	if err := os.Chtimes(store.path(name), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if store.Exists(name) {
+		t.Errorf("expected %q to be stale", name)
+	}
+	names, err := store.ListContainers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 0 {
+		t.Errorf("expected no containers, got %v", names)
+	}
+
+	// Writing another container prunes the stale one.
+	store.lastPrune = time.Time{}
+	if err := store.AddStats(info.ContainerReference{Name: "/"}, &info.ContainerStats{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(store.path(name)); !os.IsNotExist(err) {
+		t.Errorf("expected the file of %q to be removed, got %v", name, err)
+	}
+}
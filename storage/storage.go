@@ -31,3 +31,66 @@ type StorageDriver interface {
 	// on the implementation of the storage driver.
 	Close() error
 }
+
+// Implemented by storage drivers that also store the specs of containers, for
+// example to serve them from another cAdvisor instance.
+type SpecStorageDriver interface {
+	StorageDriver
+
+	// Stores the spec of the container, replacing any previous spec.
+	AddSpec(ref info.ContainerReference, spec info.ContainerSpec) error
+}
+
+// Returns the name of the series of samples of the container: its stable ID if
+// stableIDs is set and it has one, so that the series continues across
+// restarts of the container, or else its canonical name.
+func SeriesName(ref info.ContainerReference, stableIDs bool) string {
+	if stableIDs {
+		return ref.SeriesID()
+	}
+	return ref.CanonicalName()
+}
+
+// Stores the spec of the container in the driver if it supports it.
+func AddSpec(driver StorageDriver, ref info.ContainerReference, spec info.ContainerSpec) error {
+	if specDriver, ok := driver.(SpecStorageDriver); ok {
+		return specDriver.AddSpec(ref, spec)
+	}
+	return nil
+}
+
+// Implemented by storage drivers that keep state about each container, so that
+// it is dropped once the container is removed.
+type RemovableStorageDriver interface {
+	StorageDriver
+
+	// Drops the state kept about the container, which was removed.
+	RemoveContainer(ref info.ContainerReference) error
+}
+
+// Tells the driver the container was removed if it keeps state about
+// containers.
+func RemoveContainer(driver StorageDriver, ref info.ContainerReference) error {
+	if removableDriver, ok := driver.(RemovableStorageDriver); ok {
+		return removableDriver.RemoveContainer(ref)
+	}
+	return nil
+}
+
+// Implemented by storage drivers that write samples in the background and can
+// tell when they are added faster than they are written.
+type BackpressureStorageDriver interface {
+	StorageDriver
+
+	// Returns whether writes are backing up, in which case callers should
+	// slow down adding samples until it returns false again.
+	Backpressure() bool
+}
+
+// Returns whether the driver signals backpressure, false if it does not support it.
+func Backpressure(driver StorageDriver) bool {
+	if backpressureDriver, ok := driver.(BackpressureStorageDriver); ok {
+		return backpressureDriver.Backpressure()
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+func TestSeriesName(t *testing.T) {
+	ref := info.ContainerReference{
+		Name:     "/docker/2c4dee605d22",
+		Aliases:  []string{"web", "2c4dee605d22"},
+		StableID: "0f8e1d62/nginx",
+	}
+	if name := SeriesName(ref, false); name != "web" {
+		t.Errorf("expected the first alias unless stable IDs are enabled, got %q", name)
+	}
+	if name := SeriesName(ref, true); name != "0f8e1d62/nginx" {
+		t.Errorf("expected the stable ID, got %q", name)
+	}
+	ref.StableID = ""
+	if name := SeriesName(ref, true); name != "web" {
+		t.Errorf("expected the first alias without a stable ID, got %q", name)
+	}
+}
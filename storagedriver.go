@@ -17,14 +17,25 @@ package main
 import (
 	"flag"
 	"fmt"
-	"os"
+	"strings"
 	"time"
 
 	"github.com/golang/glog"
+	"github.com/google/cadvisor/manager"
 	"github.com/google/cadvisor/storage"
-	"github.com/google/cadvisor/storage/bigquery"
-	"github.com/google/cadvisor/storage/influxdb"
+	"github.com/google/cadvisor/storage/compression"
+	"github.com/google/cadvisor/storage/dryrun"
 	"github.com/google/cadvisor/storage/memory"
+	"github.com/google/cadvisor/storage/multi"
+	"github.com/google/cadvisor/storage/queue"
+	"github.com/google/cadvisor/storage/rounding"
+
+	// Register the storage drivers.
+	_ "github.com/google/cadvisor/storage/bigquery"
+	_ "github.com/google/cadvisor/storage/cloudwatch"
+	_ "github.com/google/cadvisor/storage/influxdb"
+	_ "github.com/google/cadvisor/storage/mqtt"
+	_ "github.com/google/cadvisor/storage/shared"
 )
 
 var argDbUsername = flag.String("storage_driver_user", "root", "database username")
@@ -33,57 +44,176 @@ var argDbHost = flag.String("storage_driver_host", "localhost:8086", "database h
 var argDbName = flag.String("storage_driver_db", "cadvisor", "database name")
 var argDbTable = flag.String("storage_driver_table", "stats", "table name")
 var argDbIsSecure = flag.Bool("storage_driver_secure", false, "use secure connection with database")
+var argDbDir = flag.String("storage_driver_dir", "/var/run/cadvisor/shared", "directory of file based storage drivers, e.g. shared")
+var argDbStableIds = flag.Bool("storage_driver_stable_ids", false, "Identify containers in the storage drivers by their stable ID, which persists across their restarts, rather than by their first alias. Containers without one keep their first alias")
+var argDbQos = flag.Int("storage_driver_qos", 0, "quality of service of message based storage drivers, e.g. mqtt. 0 is at most once, 1 at least once and 2 exactly once delivery")
 var argDbBufferDuration = flag.Duration("storage_driver_buffer_duration", 60*time.Second, "Writes in the storage driver will be buffered for this duration, and committed to the non memory backends as a single transaction")
+var argDbCompress = flag.Bool("storage_driver_compress", false, "Skip writing samples to the non memory backends when nothing but gauges within storage_driver_compression_tolerance changed since the last written sample")
+var argDbCompressionTolerance = flag.Float64("storage_driver_compression_tolerance", 0.0, "Relative difference under which gauges are considered unchanged when compressing samples")
+var argDbSignificantFigures = flag.Int("storage_driver_significant_figures", 0, "Round the gauges of the samples written to the non memory backends to this number of significant figures. 0 disables rounding")
+var argDbDryRun = flag.Bool("storage_driver_dry_run", false, "Log the samples that would be written to the non memory backends instead of writing them")
+var argDbLogSamples = flag.Bool("storage_driver_log_samples", false, "Log the samples written to the non memory backends while still writing them. Ignored in dry-run mode, which logs them already")
+var argDbDryRunLogLevel = flag.Int("storage_driver_dry_run_log_level", 0, "Verbosity at which samples are logged in dry-run mode or with storage_driver_log_samples")
+var argDbQueueSize = flag.Int("storage_driver_queue_size", 0, "Write samples to the non memory backends in the background, queueing at most this number of them. 0 writes them during housekeeping")
+var argDbBackpressureThreshold = flag.Float64("storage_driver_backpressure_threshold", 0.8, "Fraction of storage_driver_queue_size queued at which the storage backends are considered to fall behind and backpressure is signalled, until the queue drains to half of it")
+var argDbIntervals = flag.String("storage_driver_intervals", "", "Comma-separated list of <driver>=<duration> setting the minimum interval between the samples of a container written to the storage driver. Samples are written at every housekeeping to the other drivers")
 var storageDuration = flag.Duration("storage_duration", 2*time.Minute, "How long to keep data stored (Default: 2min).")
+var storageEviction = flag.String("storage_eviction", "age", "Strategy evicting the stats of each container kept in memory: age only keeps them for storage_duration, count also keeps at most storage_max_stats of them and size also keeps them within storage_max_bytes")
+var storageMaxStats = flag.Int("storage_max_stats", 120, "Number of stats of each container kept in memory with the count eviction strategy")
+var storageMaxBytes = flag.Int("storage_max_bytes", 1<<20, "Approximate bytes of memory used by the stats of each container kept in memory with the size eviction strategy")
 
 // Creates a memory storage with an optional backend storage option.
 func NewMemoryStorage(backendStorageName string) (*memory.InMemoryStorage, error) {
 	var storageDriver *memory.InMemoryStorage
 	var backendStorage storage.StorageDriver
-	var err error
-	switch backendStorageName {
-	case "":
-		backendStorage = nil
-	case "influxdb":
-		var hostname string
-		hostname, err = os.Hostname()
-		if err != nil {
-			return nil, err
-		}
-
-		backendStorage, err = influxdb.New(
-			hostname,
-			*argDbTable,
-			*argDbName,
-			*argDbUsername,
-			*argDbPassword,
-			*argDbHost,
-			*argDbIsSecure,
-			*argDbBufferDuration,
-		)
-	case "bigquery":
+	// Checked before the backend storage is created so that it is not left
+	// open.
+	eviction, err := newEvictionStrategy(*storageEviction)
+	if err != nil {
+		return nil, err
+	}
+	if backendStorageName != "" {
 		var hostname string
-		hostname, err = os.Hostname()
+		hostname, err = manager.Hostname()
 		if err != nil {
 			return nil, err
 		}
-		backendStorage, err = bigquery.New(
-			hostname,
-			*argDbTable,
-			*argDbName,
-		)
-	default:
-		err = fmt.Errorf("unknown backend storage driver: %v", *argDbDriver)
+		backendStorage, err = newBackendStorage(backendStorageName, storage.DriverConfig{
+			MachineName:    hostname,
+			Host:           *argDbHost,
+			Database:       *argDbName,
+			Table:          *argDbTable,
+			User:           *argDbUsername,
+			Password:       *argDbPassword,
+			Secure:         *argDbIsSecure,
+			BufferDuration: *argDbBufferDuration,
+			Directory:      *argDbDir,
+			Qos:            *argDbQos,
+			StableIDs:      *argDbStableIds,
+		})
 	}
 	if err != nil {
 		return nil, err
 	}
+	if backendStorage != nil && *argDbDryRun {
+		glog.Infof("Storage driver dry-run enabled, samples will be logged at level %d and not written", *argDbDryRunLogLevel)
+		backendStorage = dryrun.New(backendStorage, false, glog.Level(*argDbDryRunLogLevel))
+	} else if backendStorage != nil && *argDbLogSamples {
+		glog.Infof("Logging the samples written to the storage driver at level %d", *argDbDryRunLogLevel)
+		backendStorage = dryrun.New(backendStorage, true, glog.Level(*argDbDryRunLogLevel))
+	}
+	if backendStorage != nil && *argDbSignificantFigures > 0 {
+		glog.Infof("Rounding gauges to %d significant figures", *argDbSignificantFigures)
+		backendStorage = rounding.New(backendStorage, *argDbSignificantFigures)
+	}
+	if backendStorage != nil && *argDbCompress {
+		glog.Infof("Compressing samples with a tolerance of %v", *argDbCompressionTolerance)
+		backendStorage = compression.New(backendStorage, *argDbCompressionTolerance)
+	}
+	if backendStorage != nil && *argDbQueueSize > 0 {
+		glog.Infof("Queueing up to %d samples for the backend storage", *argDbQueueSize)
+		backendStorage = queue.New(backendStorage, *argDbQueueSize, *argDbBackpressureThreshold)
+	}
 	if backendStorageName != "" {
 		glog.Infof("Using backend storage type %q", backendStorageName)
 	} else {
 		glog.Infof("No backend storage selected")
 	}
 	glog.Infof("Caching stats in memory for %v", *storageDuration)
-	storageDriver = memory.New(*storageDuration, backendStorage)
+	storageDriver = memory.New(*storageDuration, backendStorage, eviction)
 	return storageDriver, nil
 }
+
+// Returns the strategy evicting stats from memory on top of those older than
+// storage_duration, nil if none.
+func newEvictionStrategy(name string) (memory.EvictionStrategy, error) {
+	switch name {
+	case "age":
+		return nil, nil
+	case "count":
+		if *storageMaxStats <= 0 {
+			return nil, fmt.Errorf("invalid --storage_max_stats %d, must be positive", *storageMaxStats)
+		}
+		glog.Infof("Caching at most %d stats per container in memory", *storageMaxStats)
+		return memory.NewCountEviction(*storageMaxStats), nil
+	case "size":
+		if *storageMaxBytes <= 0 {
+			return nil, fmt.Errorf("invalid --storage_max_bytes %d, must be positive", *storageMaxBytes)
+		}
+		glog.Infof("Caching at most %d bytes of stats per container in memory", *storageMaxBytes)
+		return memory.NewSizeEviction(*storageMaxBytes), nil
+	}
+	return nil, fmt.Errorf("unknown --storage_eviction %q, must be age, count or size", name)
+}
+
+// Creates the storage drivers in the comma-separated list of names. Several
+// drivers, or drivers written to at an interval, are wrapped in a multi storage.
+func newBackendStorage(names string, config storage.DriverConfig) (storage.StorageDriver, error) {
+	intervals, err := parseStorageIntervals(*argDbIntervals)
+	if err != nil {
+		return nil, err
+	}
+	nameList := strings.Split(names, ",")
+	for name := range intervals {
+		if !containsString(nameList, name) {
+			return nil, fmt.Errorf("interval set for storage driver %q which is not used", name)
+		}
+	}
+	var backends []multi.Backend
+	for _, name := range nameList {
+		driver, err := storage.NewStorageDriver(name, config)
+		if err != nil {
+			closeBackends(backends)
+			return nil, err
+		}
+		if interval, ok := intervals[name]; ok {
+			glog.Infof("Writing samples to backend storage %q at most every %v", name, interval)
+		}
+		backends = append(backends, multi.Backend{
+			Name:     name,
+			Driver:   driver,
+			Interval: intervals[name],
+		})
+	}
+	if len(backends) == 1 && backends[0].Interval == 0 {
+		return backends[0].Driver, nil
+	}
+	return multi.New(backends)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Closes the drivers created before a later one failed.
+func closeBackends(backends []multi.Backend) {
+	for _, b := range backends {
+		if err := b.Driver.Close(); err != nil {
+			glog.Warningf("Failed to close storage driver %q: %v", b.Name, err)
+		}
+	}
+}
+
+func parseStorageIntervals(intervals string) (map[string]time.Duration, error) {
+	ret := make(map[string]time.Duration)
+	if intervals == "" {
+		return ret, nil
+	}
+	for _, interval := range strings.Split(intervals, ",") {
+		i := strings.Index(interval, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid storage driver interval %q, expected <driver>=<duration>", interval)
+		}
+		duration, err := time.ParseDuration(interval[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration in storage driver interval %q: %v", interval, err)
+		}
+		ret[interval[:i]] = duration
+	}
+	return ret, nil
+}
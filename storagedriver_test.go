@@ -0,0 +1,98 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/storage"
+)
+
+// Records the configuration it was created with and the samples written to it.
+type recordingStorage struct {
+	config storage.DriverConfig
+	refs   []info.ContainerReference
+}
+
+func (self *recordingStorage) AddStats(ref info.ContainerReference, stats *info.ContainerStats) error {
+	self.refs = append(self.refs, ref)
+	return nil
+}
+
+func (self *recordingStorage) RecentStats(containerName string, numStats int) ([]*info.ContainerStats, error) {
+	return nil, nil
+}
+
+func (self *recordingStorage) Close() error {
+	return nil
+}
+
+func TestBackendStorageTaggedWithHostnameOverride(t *testing.T) {
+	var backend *recordingStorage
+	storage.RegisterStorageDriver("recording", func(config storage.DriverConfig) (storage.StorageDriver, error) {
+		backend = &recordingStorage{config: config}
+		return backend, nil
+	})
+	if err := flag.Set("hostname_override", "node-from-metadata"); err != nil {
+		t.Fatal(err)
+	}
+	defer flag.Set("hostname_override", "")
+
+	memoryStorage, err := NewMemoryStorage("recording")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer memoryStorage.Close()
+	ref := info.ContainerReference{Name: "/a"}
+	if err := memoryStorage.AddStats(ref, &info.ContainerStats{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if backend == nil {
+		t.Fatal("expected the backend storage to be created")
+	}
+	if backend.config.MachineName != "node-from-metadata" {
+		t.Errorf("expected the backend storage to tag samples with the overridden hostname, got %q", backend.config.MachineName)
+	}
+	if len(backend.refs) != 1 || backend.refs[0].Name != "/a" {
+		t.Errorf("expected the sample of /a to reach the backend storage, got %+v", backend.refs)
+	}
+}
+
+func TestLogSamplesStillWrites(t *testing.T) {
+	var backend *recordingStorage
+	storage.RegisterStorageDriver("recording", func(config storage.DriverConfig) (storage.StorageDriver, error) {
+		backend = &recordingStorage{config: config}
+		return backend, nil
+	})
+	if err := flag.Set("storage_driver_log_samples", "true"); err != nil {
+		t.Fatal(err)
+	}
+	defer flag.Set("storage_driver_log_samples", "false")
+
+	memoryStorage, err := NewMemoryStorage("recording")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer memoryStorage.Close()
+	if err := memoryStorage.AddStats(info.ContainerReference{Name: "/a"}, &info.ContainerStats{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(backend.refs) != 1 {
+		t.Errorf("expected the logged sample to be written to the backend storage, got %d samples", len(backend.refs))
+	}
+}
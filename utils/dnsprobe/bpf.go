@@ -0,0 +1,86 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsprobe
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// Commands of the bpf syscall.
+const (
+	bpfMapLookupElem = 1
+	bpfObjGet        = 7
+)
+
+// Attributes of BPF_OBJ_GET.
+type objGetAttr struct {
+	pathname  uint64
+	bpfFd     uint32
+	fileFlags uint32
+}
+
+// Attributes of BPF_MAP_LOOKUP_ELEM.
+type mapLookupAttr struct {
+	mapFd uint32
+	_     uint32
+	key   uint64
+	value uint64
+	flags uint64
+}
+
+func bpf(cmd int, attr unsafe.Pointer, size uintptr) (uintptr, error) {
+	if sysBpf == 0 {
+		return 0, fmt.Errorf("eBPF is not supported on this architecture")
+	}
+	ret, _, errno := syscall.Syscall(sysBpf, uintptr(cmd), uintptr(attr), size)
+	if errno != 0 {
+		return 0, errno
+	}
+	return ret, nil
+}
+
+// Returns a file descriptor of the eBPF object pinned at path.
+func objGet(path string) (int, error) {
+	pathname, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	attr := objGetAttr{pathname: uint64(uintptr(unsafe.Pointer(pathname)))}
+	fd, err := bpf(bpfObjGet, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	// The kernel reads the path through a pointer the garbage collector does
+	// not know about.
+	runtime.KeepAlive(pathname)
+	if err != nil {
+		return 0, err
+	}
+	return int(fd), nil
+}
+
+// Reads the value of key in the map into value, which must be as large as the
+// values of the map. Returns ENOENT if the key is not in the map.
+func mapLookup(mapFd int, key, value []byte) error {
+	attr := mapLookupAttr{
+		mapFd: uint32(mapFd),
+		key:   uint64(uintptr(unsafe.Pointer(&key[0]))),
+		value: uint64(uintptr(unsafe.Pointer(&value[0]))),
+	}
+	_, err := bpf(bpfMapLookupElem, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(key)
+	runtime.KeepAlive(value)
+	return err
+}
@@ -0,0 +1,114 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dnsprobe reads the DNS query stats of containers counted by an eBPF
+// probe on their DNS traffic.
+//
+// cAdvisor does not ship the probe: it is provided and loaded by the operator,
+// e.g. as cgroup skb programs attached to the root of the cgroup v2 hierarchy
+// that match the DNS queries and answers of each cgroup. It pins a hash map
+// keyed by the cgroup v2 ID of each container, i.e. the inode number of its
+// cgroup directory, as a little-endian uint64. Its values hold three
+// little-endian uint64 counters: the DNS queries sent, those that failed and
+// the nanoseconds spent waiting for their answers.
+package dnsprobe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"syscall"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+// Size of the values of the map pinned by the probe.
+const valueSize = 3 * 8
+
+type Reader struct {
+	// File descriptor of the map pinned by the probe.
+	mapFd int
+}
+
+// Opens the map pinned by the probe at mapPath. Fails if eBPF is not supported
+// or allowed, e.g. without CAP_SYS_ADMIN, or if the probe is not loaded.
+func New(mapPath string) (*Reader, error) {
+	if _, err := os.Stat(mapPath); err != nil {
+		return nil, fmt.Errorf("DNS probe map not found, is the probe loaded? %v", err)
+	}
+	fd, err := objGet(mapPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DNS probe map %q: %v", mapPath, err)
+	}
+	return &Reader{mapFd: fd}, nil
+}
+
+// Returns the DNS stats of the container with the cgroup at cgroupPath, which
+// are zero if the probe saw no DNS traffic from it yet.
+func (self *Reader) Stats(cgroupPath string) (info.DnsStats, error) {
+	id, err := cgroupID(cgroupPath)
+	if err != nil {
+		return info.DnsStats{}, err
+	}
+	key := make([]byte, 8)
+	binary.LittleEndian.PutUint64(key, id)
+	value := make([]byte, valueSize)
+	err = mapLookup(self.mapFd, key, value)
+	if err == syscall.ENOENT {
+		return info.DnsStats{}, nil
+	} else if err != nil {
+		return info.DnsStats{}, fmt.Errorf("failed to look up DNS stats of cgroup %q: %v", cgroupPath, err)
+	}
+	return parseDnsStats(value), nil
+}
+
+func (self *Reader) Close() error {
+	return syscall.Close(self.mapFd)
+}
+
+// Magic number of the cgroup v2 filesystem.
+const cgroup2SuperMagic = 0x63677270
+
+// Whether the cgroup is on the cgroup v2 unified hierarchy, the one whose
+// cgroup IDs the probe counts the queries by.
+func IsUnifiedCgroup(cgroupPath string) bool {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(cgroupPath, &fs); err != nil {
+		return false
+	}
+	return int64(fs.Type) == cgroup2SuperMagic
+}
+
+// Returns the cgroup v2 ID of the cgroup at cgroupPath.
+func cgroupID(cgroupPath string) (uint64, error) {
+	fileInfo, err := os.Stat(cgroupPath)
+	if err != nil {
+		return 0, err
+	}
+	stat, ok := fileInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("failed to get the inode of %q", cgroupPath)
+	}
+	return stat.Ino, nil
+}
+
+// Decodes a value of the map pinned by the probe. Only little-endian
+// architectures are supported.
+func parseDnsStats(value []byte) info.DnsStats {
+	return info.DnsStats{
+		Queries:      binary.LittleEndian.Uint64(value[0:8]),
+		Failures:     binary.LittleEndian.Uint64(value[8:16]),
+		LatencyTotal: binary.LittleEndian.Uint64(value[16:24]),
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsprobe
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+func TestParseDnsStats(t *testing.T) {
+	value := []byte{
+		10, 0, 0, 0, 0, 0, 0, 0,
+		2, 0, 0, 0, 0, 0, 0, 0,
+		0x00, 0xe1, 0xf5, 0x05, 0, 0, 0, 0,
+	}
+	expected := info.DnsStats{
+		Queries:      10,
+		Failures:     2,
+		LatencyTotal: 100000000,
+	}
+	if stats := parseDnsStats(value); stats != expected {
+		t.Errorf("expected %+v, got %+v", expected, stats)
+	}
+}
+
+func TestCgroupID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dnsprobe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var stat syscall.Stat_t
+	if err := syscall.Stat(dir, &stat); err != nil {
+		t.Fatal(err)
+	}
+	id, err := cgroupID(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != stat.Ino {
+		t.Errorf("expected the inode number %d as cgroup ID, got %d", stat.Ino, id)
+	}
+
+	if _, err := cgroupID(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error for a missing cgroup")
+	}
+}
+
+func TestNewWithoutProbe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dnsprobe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Without the probe loaded there is nothing to report.
+	if reader, err := New(filepath.Join(dir, "dns_stats")); err == nil {
+		reader.Close()
+		t.Error("expected an error without the map of the probe")
+	}
+	// Regular files are not eBPF maps.
+	path := filepath.Join(dir, "not_a_map")
+	if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if reader, err := New(path); err == nil {
+		reader.Close()
+		t.Error("expected an error for a file that is not an eBPF map")
+	}
+}
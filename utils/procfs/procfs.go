@@ -0,0 +1,752 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package procfs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Returns the PIDs of the processes in the cgroup at the specified path.
+func GetCgroupPids(cgroupPath string) ([]int, error) {
+	return readCgroupIds(path.Join(cgroupPath, "cgroup.procs"))
+}
+
+// Returns the IDs of the threads in the cgroup at the specified path.
+func GetCgroupTasks(cgroupPath string) ([]int, error) {
+	return readCgroupIds(path.Join(cgroupPath, "tasks"))
+}
+
+// Returns the IDs of the threads in the cgroup v2 cgroup at the specified path.
+func GetCgroupThreads(cgroupPath string) ([]int, error) {
+	return readCgroupIds(path.Join(cgroupPath, "cgroup.threads"))
+}
+
+func readCgroupIds(file string) ([]int, error) {
+	out, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		pid, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id %q in %q: %v", line, file, err)
+		}
+		pids = append(pids, pid)
+	}
+	return pids, nil
+}
+
+// Returns the inode of the network namespace of the specified process, which
+// identifies the namespace.
+func GetNetnsInode(pid int) (uint64, error) {
+	fi, err := os.Stat(fmt.Sprintf("/proc/%d/ns/net", pid))
+	if err != nil {
+		return 0, err
+	}
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("unable to get the inode of the network namespace of process %d", pid)
+	}
+	return stat.Ino, nil
+}
+
+// Returns the number of file descriptors the specified process has open.
+func GetOpenFds(pid int) (uint64, error) {
+	dir, err := os.Open(fmt.Sprintf("/proc/%d/fd", pid))
+	if err != nil {
+		return 0, err
+	}
+	defer dir.Close()
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(len(names)), nil
+}
+
+// Returns the soft limit on open files of the specified process.
+// Unlimited processes report 0.
+func GetOpenFdsLimit(pid int) (uint64, error) {
+	out, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/limits", pid))
+	if err != nil {
+		return 0, err
+	}
+	return parseOpenFdsLimit(string(out))
+}
+
+func parseOpenFdsLimit(limits string) (uint64, error) {
+	const prefix = "Max open files"
+	for _, line := range strings.Split(limits, "\n") {
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, prefix))
+		if len(fields) < 1 {
+			break
+		}
+		if fields[0] == "unlimited" {
+			return 0, nil
+		}
+		return strconv.ParseUint(fields[0], 10, 64)
+	}
+	return 0, fmt.Errorf("no open files limit found")
+}
+
+// CPU time of all the cores of the machine as reported by the first line of
+// /proc/stat.
+type CpuTimes struct {
+	User    time.Duration
+	Nice    time.Duration
+	System  time.Duration
+	Idle    time.Duration
+	Iowait  time.Duration
+	Irq     time.Duration
+	Softirq time.Duration
+	Steal   time.Duration
+}
+
+// Returns the CPU time of all the cores of the machine.
+func GetCpuTimes() (CpuTimes, error) {
+	out, err := ioutil.ReadFile("/proc/stat")
+	if err != nil {
+		return CpuTimes{}, err
+	}
+	return parseCpuTimes(string(out))
+}
+
+// Returns the CPU time of all the cores of the machine, and of each core
+// indexed by its ID. Offline cores have no time.
+func GetAllCpuTimes() (CpuTimes, []CpuTimes, error) {
+	out, err := ioutil.ReadFile("/proc/stat")
+	if err != nil {
+		return CpuTimes{}, nil, err
+	}
+	return parseAllCpuTimes(string(out))
+}
+
+func parseCpuTimes(stat string) (CpuTimes, error) {
+	times, _, err := parseAllCpuTimes(stat)
+	return times, err
+}
+
+func parseAllCpuTimes(stat string) (CpuTimes, []CpuTimes, error) {
+	var total CpuTimes
+	var perCpu []CpuTimes
+	found := false
+	for _, line := range strings.Split(stat, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+		times, err := parseCpuLine(line, fields)
+		if err != nil {
+			return CpuTimes{}, nil, err
+		}
+		if fields[0] == "cpu" {
+			total = times
+			found = true
+			continue
+		}
+		cpu, err := strconv.Atoi(strings.TrimPrefix(fields[0], "cpu"))
+		if err != nil || cpu < 0 {
+			return CpuTimes{}, nil, fmt.Errorf("malformed cpu line %q", line)
+		}
+		for len(perCpu) <= cpu {
+			perCpu = append(perCpu, CpuTimes{})
+		}
+		perCpu[cpu] = times
+	}
+	if !found {
+		return CpuTimes{}, nil, fmt.Errorf("no cpu line found")
+	}
+	return total, perCpu, nil
+}
+
+// Parses the fields of a "cpu" or "cpu<N>" line of /proc/stat.
+func parseCpuLine(line string, fields []string) (CpuTimes, error) {
+	// Older kernels do not report the steal time.
+	if len(fields) < 8 {
+		return CpuTimes{}, fmt.Errorf("malformed cpu line %q", line)
+	}
+	var values [8]time.Duration
+	for i := range values {
+		if i+1 >= len(fields) {
+			break
+		}
+		v, err := strconv.ParseUint(fields[i+1], 10, 64)
+		if err != nil {
+			return CpuTimes{}, fmt.Errorf("malformed cpu line %q: %v", line, err)
+		}
+		values[i] = JiffiesToDuration(v)
+	}
+	return CpuTimes{
+		User:    values[0],
+		Nice:    values[1],
+		System:  values[2],
+		Idle:    values[3],
+		Iowait:  values[4],
+		Irq:     values[5],
+		Softirq: values[6],
+		Steal:   values[7],
+	}, nil
+}
+
+// Memory of the machine as reported by /proc/meminfo, in bytes.
+type MemInfo struct {
+	Total     uint64
+	Free      uint64
+	Available uint64
+	Buffers   uint64
+	Cached    uint64
+	SwapTotal uint64
+	SwapFree  uint64
+	// Slab memory that can be reclaimed, e.g. the dentry and inode caches.
+	SReclaimable uint64
+	// Whether MemAvailable is reported, which kernels before 3.14 do not.
+	HasAvailable bool
+}
+
+// Returns the memory of the machine.
+func GetMemInfo() (MemInfo, error) {
+	out, err := ioutil.ReadFile("/proc/meminfo")
+	if err != nil {
+		return MemInfo{}, err
+	}
+	return parseMemInfo(string(out))
+}
+
+// Returns the bits of entropy available in the random number pool of the kernel.
+func GetEntropyAvail() (uint64, error) {
+	out, err := ioutil.ReadFile("/proc/sys/kernel/random/entropy_avail")
+	if err != nil {
+		return 0, err
+	}
+	return parseEntropyAvail(string(out))
+}
+
+func parseEntropyAvail(entropyAvail string) (uint64, error) {
+	bits, err := strconv.ParseUint(strings.TrimSpace(entropyAvail), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid entropy_avail %q: %v", entropyAvail, err)
+	}
+	return bits, nil
+}
+
+// Load averages and task counts of the machine.
+type LoadAvg struct {
+	// Average number of runnable or uninterruptible tasks over the last 1, 5
+	// and 15 minutes.
+	Load1  float64
+	Load5  float64
+	Load15 float64
+
+	// Number of runnable tasks and of all the tasks of the machine.
+	RunningTasks uint64
+	TotalTasks   uint64
+}
+
+// Returns the load averages and task counts of the machine.
+func GetLoadAvg() (LoadAvg, error) {
+	out, err := ioutil.ReadFile("/proc/loadavg")
+	if err != nil {
+		return LoadAvg{}, err
+	}
+	return parseLoadAvg(string(out))
+}
+
+func parseLoadAvg(loadavg string) (LoadAvg, error) {
+	var ret LoadAvg
+	var lastPid int
+	_, err := fmt.Sscanf(loadavg, "%f %f %f %d/%d %d", &ret.Load1, &ret.Load5, &ret.Load15, &ret.RunningTasks, &ret.TotalTasks, &lastPid)
+	if err != nil {
+		return LoadAvg{}, fmt.Errorf("invalid loadavg %q: %v", loadavg, err)
+	}
+	return ret, nil
+}
+
+func parseMemInfo(meminfo string) (MemInfo, error) {
+	var ret MemInfo
+	hasTotal := false
+	for _, line := range strings.Split(meminfo, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		var dest *uint64
+		switch fields[0] {
+		case "MemTotal:":
+			dest = &ret.Total
+			hasTotal = true
+		case "MemFree:":
+			dest = &ret.Free
+		case "MemAvailable:":
+			dest = &ret.Available
+			ret.HasAvailable = true
+		case "Buffers:":
+			dest = &ret.Buffers
+		case "Cached:":
+			dest = &ret.Cached
+		case "SwapTotal:":
+			dest = &ret.SwapTotal
+		case "SwapFree:":
+			dest = &ret.SwapFree
+		case "SReclaimable:":
+			dest = &ret.SReclaimable
+		default:
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return MemInfo{}, fmt.Errorf("malformed meminfo line %q: %v", line, err)
+		}
+		// Values are in kB.
+		*dest = v * 1024
+	}
+	if !hasTotal {
+		return MemInfo{}, fmt.Errorf("no MemTotal found")
+	}
+	return ret, nil
+}
+
+// Resource limit of a process as reported by /proc/<pid>/limits.
+type Limit struct {
+	// Name of the resource as used by ulimit, e.g. "nofile".
+	Name string
+	// Soft and hard limits, -1 if unlimited.
+	Soft int64
+	Hard int64
+}
+
+// Names used by ulimit of the resources listed in /proc/<pid>/limits.
+var limitNames = []struct {
+	prefix string
+	name   string
+}{
+	{"Max cpu time", "cpu"},
+	{"Max file size", "fsize"},
+	{"Max data size", "data"},
+	{"Max stack size", "stack"},
+	{"Max core file size", "core"},
+	{"Max resident set", "rss"},
+	{"Max processes", "nproc"},
+	{"Max open files", "nofile"},
+	{"Max locked memory", "memlock"},
+	{"Max address space", "as"},
+	{"Max file locks", "locks"},
+	{"Max pending signals", "sigpending"},
+	{"Max msgqueue size", "msgqueue"},
+	{"Max nice priority", "nice"},
+	{"Max realtime priority", "rtprio"},
+	{"Max realtime timeout", "rttime"},
+}
+
+// Identity and lineage of a process, from /proc/<pid>/stat.
+type ProcessStat struct {
+	Pid int
+	// PID of the parent of the process.
+	Ppid int
+	// Name of the executable of the process, truncated to 15 characters.
+	Comm string
+	// Time the process started after system boot.
+	// Units: clock ticks.
+	StartTime uint64
+}
+
+// Returns the identity and lineage of the specified process.
+func GetProcessStat(pid int) (ProcessStat, error) {
+	out, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return ProcessStat{}, err
+	}
+	return parseProcessStat(string(out))
+}
+
+func parseProcessStat(stat string) (ProcessStat, error) {
+	// The name is in parentheses and may hold spaces and parentheses itself, so
+	// the fields following it are found from the last ")".
+	start := strings.Index(stat, "(")
+	end := strings.LastIndex(stat, ")")
+	if start < 0 || end < start {
+		return ProcessStat{}, fmt.Errorf("invalid process stat %q", stat)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(stat[:start]))
+	if err != nil {
+		return ProcessStat{}, fmt.Errorf("invalid pid in process stat %q: %v", stat, err)
+	}
+	// Fields after the name, starting with the state. The parent PID is the
+	// 4th field of the stat and the start time the 22nd.
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 20 {
+		return ProcessStat{}, fmt.Errorf("too few fields in process stat %q", stat)
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return ProcessStat{}, fmt.Errorf("invalid parent pid in process stat %q: %v", stat, err)
+	}
+	startTime, err := strconv.ParseUint(fields[19], 10, 64)
+	if err != nil {
+		return ProcessStat{}, fmt.Errorf("invalid start time in process stat %q: %v", stat, err)
+	}
+	return ProcessStat{
+		Pid:       pid,
+		Ppid:      ppid,
+		Comm:      stat[start+1 : end],
+		StartTime: startTime,
+	}, nil
+}
+
+// Returns the adjustment of the OOM killer score of the specified process.
+func GetOomScoreAdj(pid int) (int, error) {
+	out, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/oom_score_adj", pid))
+	if err != nil {
+		return 0, err
+	}
+	return parseOomScoreAdj(string(out))
+}
+
+func parseOomScoreAdj(oomScoreAdj string) (int, error) {
+	adj, err := strconv.Atoi(strings.TrimSpace(oomScoreAdj))
+	if err != nil {
+		return 0, fmt.Errorf("invalid oom_score_adj %q: %v", oomScoreAdj, err)
+	}
+	return adj, nil
+}
+
+// Returns the resource limits of the specified process.
+func GetLimits(pid int) ([]Limit, error) {
+	out, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/limits", pid))
+	if err != nil {
+		return nil, err
+	}
+	return parseLimits(string(out))
+}
+
+func parseLimits(limits string) ([]Limit, error) {
+	ret := []Limit{}
+	for _, line := range strings.Split(limits, "\n") {
+		for _, limitName := range limitNames {
+			if !strings.HasPrefix(line, limitName.prefix) {
+				continue
+			}
+			fields := strings.Fields(strings.TrimPrefix(line, limitName.prefix))
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("malformed limit %q", line)
+			}
+			soft, err := parseLimit(fields[0])
+			if err != nil {
+				return nil, err
+			}
+			hard, err := parseLimit(fields[1])
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, Limit{Name: limitName.name, Soft: soft, Hard: hard})
+			break
+		}
+	}
+	return ret, nil
+}
+
+func parseLimit(value string) (int64, error) {
+	if value == "unlimited" {
+		return -1, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// Scheduler statistics of a thread as reported by /proc/<tid>/schedstat.
+type Schedstat struct {
+	// Time spent on the cpu in nanoseconds.
+	RunTime uint64
+	// Time spent waiting on a runqueue in nanoseconds.
+	RunqueueTime uint64
+	// Number of timeslices run on this cpu.
+	RunPeriods uint64
+}
+
+// Returns the scheduler statistics of the specified thread.
+func GetSchedstat(tid int) (Schedstat, error) {
+	out, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/schedstat", tid))
+	if err != nil {
+		return Schedstat{}, err
+	}
+	return parseSchedstat(string(out))
+}
+
+func parseSchedstat(schedstat string) (Schedstat, error) {
+	fields := strings.Fields(schedstat)
+	if len(fields) != 3 {
+		return Schedstat{}, fmt.Errorf("expected 3 fields in schedstat, found %d", len(fields))
+	}
+	var values [3]uint64
+	for i, field := range fields {
+		v, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return Schedstat{}, fmt.Errorf("invalid schedstat value %q: %v", field, err)
+		}
+		values[i] = v
+	}
+	return Schedstat{
+		RunTime:      values[0],
+		RunqueueTime: values[1],
+		RunPeriods:   values[2],
+	}, nil
+}
+
+// Context switches of a thread as reported by /proc/<tid>/status.
+type ContextSwitches struct {
+	// Switches made because the thread blocked, e.g. waiting on IO.
+	Voluntary uint64
+	// Switches forced by the scheduler, e.g. at the end of a timeslice.
+	Involuntary uint64
+}
+
+// Returns the context switches of the specified thread.
+func GetContextSwitches(tid int) (ContextSwitches, error) {
+	out, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/status", tid))
+	if err != nil {
+		return ContextSwitches{}, err
+	}
+	return parseContextSwitches(string(out))
+}
+
+func parseContextSwitches(status string) (ContextSwitches, error) {
+	var ret ContextSwitches
+	found := 0
+	for _, line := range strings.Split(status, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		var dest *uint64
+		switch fields[0] {
+		case "voluntary_ctxt_switches:":
+			dest = &ret.Voluntary
+		case "nonvoluntary_ctxt_switches:":
+			dest = &ret.Involuntary
+		default:
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return ContextSwitches{}, fmt.Errorf("invalid context switches value %q: %v", fields[1], err)
+		}
+		*dest = v
+		found++
+	}
+	if found != 2 {
+		return ContextSwitches{}, fmt.Errorf("no context switches found in status")
+	}
+	return ret, nil
+}
+
+// Returns the memory used by TCP socket buffers in the network namespace of the
+// specified process, in bytes.
+func GetTcpMemUsage(pid int) (uint64, error) {
+	out, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/net/sockstat", pid))
+	if err != nil {
+		return 0, err
+	}
+	pages, err := parseSockstatTcpMem(string(out))
+	if err != nil {
+		return 0, err
+	}
+	return pages * uint64(os.Getpagesize()), nil
+}
+
+// Returns the number of pages used by TCP sockets from the content of sockstat.
+func parseSockstatTcpMem(sockstat string) (uint64, error) {
+	for _, line := range strings.Split(sockstat, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != "TCP:" {
+			continue
+		}
+		// Fields after the protocol are pairs of name and value.
+		for i := 1; i+1 < len(fields); i += 2 {
+			if fields[i] == "mem" {
+				return strconv.ParseUint(fields[i+1], 10, 64)
+			}
+		}
+		break
+	}
+	return 0, fmt.Errorf("no TCP memory usage found in sockstat")
+}
+
+// Returns the memory TCP sockets may use before the kernel starts dropping
+// packets (the max of net.ipv4.tcp_mem), in bytes.
+func GetTcpMemLimit() (uint64, error) {
+	out, err := ioutil.ReadFile("/proc/sys/net/ipv4/tcp_mem")
+	if err != nil {
+		return 0, err
+	}
+	pages, err := parseTcpMemLimit(string(out))
+	if err != nil {
+		return 0, err
+	}
+	return pages * uint64(os.Getpagesize()), nil
+}
+
+func parseTcpMemLimit(tcpMem string) (uint64, error) {
+	fields := strings.Fields(tcpMem)
+	if len(fields) != 3 {
+		return 0, fmt.Errorf("expected 3 fields in tcp_mem, found %d", len(fields))
+	}
+	return strconv.ParseUint(fields[2], 10, 64)
+}
+
+// Returns the number of connections tracked by netfilter in the network
+// namespace of the specified process.
+func GetConntrackCount(pid int) (uint64, error) {
+	out, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/net/stat/nf_conntrack", pid))
+	if err != nil {
+		return 0, err
+	}
+	return parseConntrackStat(string(out))
+}
+
+// Returns the number of entries from the content of net/stat/nf_conntrack. The
+// file has a header and a row of hexadecimal counters per cpu, the first of
+// which is the number of entries of the whole table.
+func parseConntrackStat(stat string) (uint64, error) {
+	lines := strings.Split(stat, "\n")
+	if len(lines) < 2 {
+		return 0, fmt.Errorf("no rows in conntrack stats")
+	}
+	header, row := strings.Fields(lines[0]), strings.Fields(lines[1])
+	if len(header) == 0 || header[0] != "entries" || len(row) == 0 {
+		return 0, fmt.Errorf("no entries in conntrack stats")
+	}
+	return strconv.ParseUint(row[0], 16, 64)
+}
+
+// IP traffic of a network namespace, in datagrams and bytes.
+type IpTraffic struct {
+	InPackets  uint64
+	OutPackets uint64
+	InBytes    uint64
+	OutBytes   uint64
+}
+
+// Returns the IPv4 traffic of the network namespace of the specified process.
+// The datagrams are read from net/snmp and the bytes from net/netstat.
+func GetIpv4Traffic(pid int) (IpTraffic, error) {
+	snmp, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/net/snmp", pid))
+	if err != nil {
+		return IpTraffic{}, err
+	}
+	netstat, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/net/netstat", pid))
+	if err != nil {
+		return IpTraffic{}, err
+	}
+	return parseIpv4Traffic(string(snmp), string(netstat))
+}
+
+func parseIpv4Traffic(snmp, netstat string) (IpTraffic, error) {
+	ip, err := parseSnmpTable(snmp, "Ip:")
+	if err != nil {
+		return IpTraffic{}, err
+	}
+	ipExt, err := parseSnmpTable(netstat, "IpExt:")
+	if err != nil {
+		return IpTraffic{}, err
+	}
+	return IpTraffic{
+		InPackets:  ip["InReceives"],
+		OutPackets: ip["OutRequests"],
+		InBytes:    ipExt["InOctets"],
+		OutBytes:   ipExt["OutOctets"],
+	}, nil
+}
+
+// Returns the counters of a table of net/snmp or net/netstat, which has a line
+// of names followed by a line of values, both starting with the table prefix.
+// Counters that are not unsigned integers are skipped.
+func parseSnmpTable(content, prefix string) (map[string]uint64, error) {
+	var names []string
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != prefix {
+			continue
+		}
+		if names == nil {
+			names = fields[1:]
+			continue
+		}
+		if len(fields)-1 != len(names) {
+			return nil, fmt.Errorf("expected %d values in %s, found %d", len(names), prefix, len(fields)-1)
+		}
+		table := make(map[string]uint64, len(names))
+		for i, name := range names {
+			if v, err := strconv.ParseUint(fields[i+1], 10, 64); err == nil {
+				table[name] = v
+			}
+		}
+		return table, nil
+	}
+	return nil, fmt.Errorf("no %s values found", prefix)
+}
+
+// Returns the IPv6 traffic of the network namespace of the specified process
+// from net/snmp6, which does not exist when IPv6 is disabled.
+func GetIpv6Traffic(pid int) (IpTraffic, error) {
+	out, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/net/snmp6", pid))
+	if err != nil {
+		return IpTraffic{}, err
+	}
+	return parseIpv6Traffic(string(out))
+}
+
+func parseIpv6Traffic(snmp6 string) (IpTraffic, error) {
+	counters := make(map[string]uint64)
+	for _, line := range strings.Split(snmp6, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if v, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			counters[fields[0]] = v
+		}
+	}
+	if _, ok := counters["Ip6InReceives"]; !ok {
+		return IpTraffic{}, fmt.Errorf("no Ip6InReceives found in snmp6")
+	}
+	return IpTraffic{
+		InPackets:  counters["Ip6InReceives"],
+		OutPackets: counters["Ip6OutRequests"],
+		InBytes:    counters["Ip6InOctets"],
+		OutBytes:   counters["Ip6OutOctets"],
+	}, nil
+}
+
+// Returns the maximum number of connections tracked by netfilter.
+func GetConntrackLimit() (uint64, error) {
+	out, err := ioutil.ReadFile("/proc/sys/net/netfilter/nf_conntrack_max")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(out)), 10, 64)
+}
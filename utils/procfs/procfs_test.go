@@ -0,0 +1,384 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package procfs
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testLimits = `Limit                     Soft Limit           Hard Limit           Units
+Max cpu time              unlimited            unlimited            seconds
+Max file size             unlimited            unlimited            bytes
+Max open files            1024                 4096                 files
+Max locked memory         65536                65536                bytes
+`
+
+func TestParseOpenFdsLimit(t *testing.T) {
+	limit, err := parseOpenFdsLimit(testLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limit != 1024 {
+		t.Errorf("expected soft limit of 1024, got %d", limit)
+	}
+
+	_, err = parseOpenFdsLimit("Max cpu time unlimited unlimited seconds\n")
+	if err == nil {
+		t.Errorf("expected error when the open files limit is missing")
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	limits, err := parseLimits(testLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Limit{
+		{Name: "cpu", Soft: -1, Hard: -1},
+		{Name: "fsize", Soft: -1, Hard: -1},
+		{Name: "nofile", Soft: 1024, Hard: 4096},
+		{Name: "memlock", Soft: 65536, Hard: 65536},
+	}
+	if !reflect.DeepEqual(limits, expected) {
+		t.Errorf("expected %+v, got %+v", expected, limits)
+	}
+
+	if _, err := parseLimits("Max open files            1024\n"); err == nil {
+		t.Errorf("expected error when the hard limit is missing")
+	}
+}
+
+const testStatus = `Name:	nginx
+State:	S (sleeping)
+Threads:	1
+voluntary_ctxt_switches:	1520
+nonvoluntary_ctxt_switches:	37
+`
+
+func TestParseOomScoreAdj(t *testing.T) {
+	adj, err := parseOomScoreAdj("-999\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if adj != -999 {
+		t.Errorf("expected oom_score_adj -999, got %d", adj)
+	}
+	if _, err := parseOomScoreAdj("unlimited"); err == nil {
+		t.Errorf("expected error parsing invalid oom_score_adj")
+	}
+}
+
+func TestParseProcessStat(t *testing.T) {
+	stat, err := parseProcessStat("4242 (my (odd) app) S 4200 4242 4242 0 -1 4194560 1 0 0 0 3 1 0 0 20 0 1 0 98765 1000 100 18446744073709551615\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := ProcessStat{Pid: 4242, Ppid: 4200, Comm: "my (odd) app", StartTime: 98765}
+	if stat != expected {
+		t.Errorf("expected %+v, got %+v", expected, stat)
+	}
+	if _, err := parseProcessStat("4242 (app) S 4200"); err == nil {
+		t.Errorf("expected error parsing truncated process stat")
+	}
+}
+
+func TestParseEntropyAvail(t *testing.T) {
+	bits, err := parseEntropyAvail("3754\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bits != 3754 {
+		t.Errorf("expected 3754 bits of entropy, got %d", bits)
+	}
+	if _, err := parseEntropyAvail(""); err == nil {
+		t.Errorf("expected error parsing empty entropy_avail")
+	}
+}
+
+func TestParseLoadAvg(t *testing.T) {
+	loadavg, err := parseLoadAvg("0.20 0.18 1.12 3/816 11206\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := LoadAvg{Load1: 0.2, Load5: 0.18, Load15: 1.12, RunningTasks: 3, TotalTasks: 816}
+	if loadavg != expected {
+		t.Errorf("expected %+v, got %+v", expected, loadavg)
+	}
+	if _, err := parseLoadAvg("0.20 0.18"); err == nil {
+		t.Errorf("expected error parsing truncated loadavg")
+	}
+}
+
+func TestParseContextSwitches(t *testing.T) {
+	switches, err := parseContextSwitches(testStatus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := ContextSwitches{
+		Voluntary:   1520,
+		Involuntary: 37,
+	}
+	if switches != expected {
+		t.Errorf("expected %+v, got %+v", expected, switches)
+	}
+
+	_, err = parseContextSwitches("Name:\tnginx\n")
+	if err == nil {
+		t.Errorf("expected error when the context switches are missing")
+	}
+}
+
+func TestParseSchedstat(t *testing.T) {
+	schedstat, err := parseSchedstat("4107467 1234567 89\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Schedstat{
+		RunTime:      4107467,
+		RunqueueTime: 1234567,
+		RunPeriods:   89,
+	}
+	if schedstat != expected {
+		t.Errorf("expected %+v, got %+v", expected, schedstat)
+	}
+
+	_, err = parseSchedstat("1 2")
+	if err == nil {
+		t.Errorf("expected error on truncated schedstat")
+	}
+}
+
+const testSockstat = `sockets: used 290
+TCP: inuse 5 orphan 0 tw 2 alloc 7 mem 3
+UDP: inuse 1 mem 1
+UDPLITE: inuse 0
+RAW: inuse 0
+FRAG: inuse 0 memory 0
+`
+
+func TestParseSockstatTcpMem(t *testing.T) {
+	pages, err := parseSockstatTcpMem(testSockstat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pages != 3 {
+		t.Errorf("expected 3 pages of TCP memory, got %d", pages)
+	}
+
+	_, err = parseSockstatTcpMem("sockets: used 290\nUDP: inuse 1 mem 1\n")
+	if err == nil {
+		t.Errorf("expected error when the TCP line is missing")
+	}
+}
+
+func TestParseTcpMemLimit(t *testing.T) {
+	pages, err := parseTcpMemLimit("188565\t251421\t377130\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pages != 377130 {
+		t.Errorf("expected a limit of 377130 pages, got %d", pages)
+	}
+
+	_, err = parseTcpMemLimit("188565 251421")
+	if err == nil {
+		t.Errorf("expected error on truncated tcp_mem")
+	}
+}
+
+const testConntrackStat = `entries  searched found new invalid ignore delete delete_list insert insert_failed drop early_drop icmp_error  expect_new expect_create expect_delete search_restart
+0000012c  00000000 00000000 00000000 00000007 00000c5e 00000000 00000000 00000000 00000000 00000000 00000000 00000000  00000000 00000000 00000000 00000010
+0000012c  00000000 00000000 00000000 00000002 00000a1b 00000000 00000000 00000000 00000000 00000000 00000000 00000000  00000000 00000000 00000000 00000004
+`
+
+func TestParseConntrackStat(t *testing.T) {
+	entries, err := parseConntrackStat(testConntrackStat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries != 300 {
+		t.Errorf("expected 300 entries, got %d", entries)
+	}
+
+	_, err = parseConntrackStat("entries searched\n")
+	if err == nil {
+		t.Errorf("expected error when there are no rows")
+	}
+}
+
+const testSnmp = `Ip: Forwarding DefaultTTL InReceives InHdrErrors InAddrErrors ForwDatagrams InUnknownProtos InDiscards InDelivers OutRequests OutDiscards OutNoRoutes ReasmTimeout ReasmReqds ReasmOKs ReasmFails FragOKs FragFails FragCreates
+Ip: 1 64 1500 0 0 0 0 0 1500 1200 0 0 0 0 0 0 0 0 0
+Icmp: InMsgs InErrors
+Icmp: 3 0
+Tcp: RtoAlgorithm RtoMin RtoMax MaxConn
+Tcp: 1 200 120000 -1
+`
+
+const testNetstat = `TcpExt: SyncookiesSent SyncookiesRecv
+TcpExt: 0 0
+IpExt: InNoRoutes InTruncatedPkts InMcastPkts OutMcastPkts InBcastPkts OutBcastPkts InOctets OutOctets
+IpExt: 0 0 0 0 0 0 900000 450000
+`
+
+func TestParseIpv4Traffic(t *testing.T) {
+	traffic, err := parseIpv4Traffic(testSnmp, testNetstat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := IpTraffic{InPackets: 1500, OutPackets: 1200, InBytes: 900000, OutBytes: 450000}
+	if traffic != expected {
+		t.Errorf("expected %+v, got %+v", expected, traffic)
+	}
+
+	_, err = parseIpv4Traffic(testSnmp, "TcpExt: SyncookiesSent\nTcpExt: 0\n")
+	if err == nil {
+		t.Errorf("expected error when the IpExt table is missing")
+	}
+	_, err = parseIpv4Traffic("Ip: Forwarding InReceives\nIp: 1\n", testNetstat)
+	if err == nil {
+		t.Errorf("expected error on a truncated Ip table")
+	}
+}
+
+const testSnmp6 = `Ip6InReceives                   	800
+Ip6InHdrErrors                  	0
+Ip6InOctets                     	640000
+Ip6OutRequests                  	700
+Ip6OutOctets                    	320000
+Icmp6InMsgs                     	12
+`
+
+func TestParseIpv6Traffic(t *testing.T) {
+	traffic, err := parseIpv6Traffic(testSnmp6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := IpTraffic{InPackets: 800, OutPackets: 700, InBytes: 640000, OutBytes: 320000}
+	if traffic != expected {
+		t.Errorf("expected %+v, got %+v", expected, traffic)
+	}
+
+	_, err = parseIpv6Traffic("Icmp6InMsgs 12\n")
+	if err == nil {
+		t.Errorf("expected error when the IPv6 counters are missing")
+	}
+}
+
+const testStat = `cpu  100 20 300 4000 50 6 7 8 0 0
+cpu0 50 10 150 2000 25 3 3 4 0 0
+intr 12345
+`
+
+func TestParseCpuTimes(t *testing.T) {
+	times, err := parseCpuTimes(testStat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := CpuTimes{
+		User:    JiffiesToDuration(100),
+		Nice:    JiffiesToDuration(20),
+		System:  JiffiesToDuration(300),
+		Idle:    JiffiesToDuration(4000),
+		Iowait:  JiffiesToDuration(50),
+		Irq:     JiffiesToDuration(6),
+		Softirq: JiffiesToDuration(7),
+		Steal:   JiffiesToDuration(8),
+	}
+	if times != expected {
+		t.Errorf("expected %+v, got %+v", expected, times)
+	}
+
+	// Older kernels do not report the steal time.
+	times, err = parseCpuTimes("cpu  100 20 300 4000 50 6 7\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if times.Steal != 0 || times.Softirq != JiffiesToDuration(7) {
+		t.Errorf("expected no steal time, got %+v", times)
+	}
+
+	_, err = parseCpuTimes("intr 12345\n")
+	if err == nil {
+		t.Errorf("expected error when the cpu line is missing")
+	}
+}
+
+func TestParseAllCpuTimes(t *testing.T) {
+	// cpu1 is offline.
+	total, perCpu, err := parseAllCpuTimes(testStat + "cpu2 10 0 20 300 4 0 1 2 0 0\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total.User != JiffiesToDuration(100) {
+		t.Errorf("expected the total user time of the machine, got %+v", total)
+	}
+	if len(perCpu) != 3 {
+		t.Fatalf("expected the times of 3 cores, got %+v", perCpu)
+	}
+	if perCpu[0].System != JiffiesToDuration(150) || perCpu[0].Steal != JiffiesToDuration(4) {
+		t.Errorf("unexpected times of cpu0: %+v", perCpu[0])
+	}
+	if perCpu[1] != (CpuTimes{}) {
+		t.Errorf("expected no time for the offline cpu1, got %+v", perCpu[1])
+	}
+	if perCpu[2].User != JiffiesToDuration(10) || perCpu[2].Softirq != JiffiesToDuration(1) {
+		t.Errorf("unexpected times of cpu2: %+v", perCpu[2])
+	}
+
+	_, _, err = parseAllCpuTimes("cpu  100 20 300 4000 50 6 7 8\ncpuX 1 2 3 4 5 6 7 8\n")
+	if err == nil {
+		t.Errorf("expected error for a malformed cpu ID")
+	}
+}
+
+const testMemInfo = `MemTotal:        8000000 kB
+MemFree:         1000000 kB
+MemAvailable:    5000000 kB
+Buffers:          200000 kB
+Cached:          3000000 kB
+SwapCached:            0 kB
+SwapTotal:       2000000 kB
+SwapFree:        1500000 kB
+Slab:             400000 kB
+SReclaimable:     300000 kB
+SUnreclaim:       100000 kB
+`
+
+func TestParseMemInfo(t *testing.T) {
+	memInfo, err := parseMemInfo(testMemInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := MemInfo{
+		Total:        8000000 * 1024,
+		Free:         1000000 * 1024,
+		Available:    5000000 * 1024,
+		Buffers:      200000 * 1024,
+		Cached:       3000000 * 1024,
+		SwapTotal:    2000000 * 1024,
+		SwapFree:     1500000 * 1024,
+		SReclaimable: 300000 * 1024,
+		HasAvailable: true,
+	}
+	if memInfo != expected {
+		t.Errorf("expected %+v, got %+v", expected, memInfo)
+	}
+
+	_, err = parseMemInfo("MemFree:         1000000 kB\n")
+	if err == nil {
+		t.Errorf("expected error when the total memory is missing")
+	}
+}
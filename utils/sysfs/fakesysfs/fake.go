@@ -50,26 +50,89 @@ func (self *FileInfo) Sys() interface{} {
 	return nil
 }
 
+// Contents of the sysfs files of a synthetic block device.
+type FakeBlockDevice struct {
+	Numbers    string
+	Size       string
+	Scheduler  string
+	Rotational string
+	Model      string
+}
+
 type FakeSysFs struct {
 	info  FileInfo
 	cache sysfs.CacheInfo
+	// Synthetic block devices by name, a single sda if nil.
+	blockDevices map[string]FakeBlockDevice
+}
+
+var defaultBlockDevice = FakeBlockDevice{
+	Numbers:    "8:0\n",
+	Size:       "1234567",
+	Scheduler:  "noop deadline [cfq]",
+	Rotational: "1\n",
+	Model:      "FAKE DISK\n",
 }
 
 func (self *FakeSysFs) GetBlockDevices() ([]os.FileInfo, error) {
-	self.info.EntryName = "sda"
-	return []os.FileInfo{&self.info}, nil
+	if self.blockDevices == nil {
+		self.info.EntryName = "sda"
+		return []os.FileInfo{&self.info}, nil
+	}
+	devices := make([]os.FileInfo, 0, len(self.blockDevices))
+	for name := range self.blockDevices {
+		devices = append(devices, &FileInfo{EntryName: name})
+	}
+	return devices, nil
+}
+
+func (self *FakeSysFs) getBlockDevice(name string) (FakeBlockDevice, error) {
+	if self.blockDevices == nil {
+		return defaultBlockDevice, nil
+	}
+	device, ok := self.blockDevices[name]
+	if !ok {
+		return FakeBlockDevice{}, os.ErrNotExist
+	}
+	return device, nil
+}
+
+// Returns the contents of a file of a block device, or an error if it is empty
+// as if the file did not exist.
+func (self *FakeSysFs) getBlockDeviceFile(name string, file func(FakeBlockDevice) string) (string, error) {
+	device, err := self.getBlockDevice(name)
+	if err != nil {
+		return "", err
+	}
+	if file(device) == "" {
+		return "", os.ErrNotExist
+	}
+	return file(device), nil
 }
 
 func (self *FakeSysFs) GetBlockDeviceSize(name string) (string, error) {
-	return "1234567", nil
+	return self.getBlockDeviceFile(name, func(d FakeBlockDevice) string { return d.Size })
 }
 
 func (self *FakeSysFs) GetBlockDeviceScheduler(name string) (string, error) {
-	return "noop deadline [cfq]", nil
+	return self.getBlockDeviceFile(name, func(d FakeBlockDevice) string { return d.Scheduler })
 }
 
 func (self *FakeSysFs) GetBlockDeviceNumbers(name string) (string, error) {
-	return "8:0\n", nil
+	return self.getBlockDeviceFile(name, func(d FakeBlockDevice) string { return d.Numbers })
+}
+
+func (self *FakeSysFs) GetBlockDeviceRotational(name string) (string, error) {
+	return self.getBlockDeviceFile(name, func(d FakeBlockDevice) string { return d.Rotational })
+}
+
+func (self *FakeSysFs) GetBlockDeviceModel(name string) (string, error) {
+	return self.getBlockDeviceFile(name, func(d FakeBlockDevice) string { return d.Model })
+}
+
+// Replaces the default sda with the specified synthetic block devices.
+func (self *FakeSysFs) SetBlockDevices(devices map[string]FakeBlockDevice) {
+	self.blockDevices = devices
 }
 
 func (self *FakeSysFs) GetNetworkDevices() ([]os.FileInfo, error) {
@@ -51,6 +51,10 @@ type SysFs interface {
 	GetBlockDeviceScheduler(string) (string, error)
 	// Get device major:minor number string.
 	GetBlockDeviceNumbers(string) (string, error)
+	// Get whether the block device is rotational, "1", or not, "0".
+	GetBlockDeviceRotational(string) (string, error)
+	// Get the model of the block device. Virtual devices have none.
+	GetBlockDeviceModel(string) (string, error)
 
 	GetNetworkDevices() ([]os.FileInfo, error)
 	GetNetworkAddress(string) (string, error)
@@ -100,6 +104,22 @@ func (self *realSysFs) GetBlockDeviceSize(name string) (string, error) {
 	return string(size), nil
 }
 
+func (self *realSysFs) GetBlockDeviceRotational(name string) (string, error) {
+	rotational, err := ioutil.ReadFile(path.Join(blockDir, name, "/queue/rotational"))
+	if err != nil {
+		return "", err
+	}
+	return string(rotational), nil
+}
+
+func (self *realSysFs) GetBlockDeviceModel(name string) (string, error) {
+	model, err := ioutil.ReadFile(path.Join(blockDir, name, "/device/model"))
+	if err != nil {
+		return "", err
+	}
+	return string(model), nil
+}
+
 func (self *realSysFs) GetNetworkDevices() ([]os.FileInfo, error) {
 	files, err := ioutil.ReadDir(netDir)
 	if err != nil {
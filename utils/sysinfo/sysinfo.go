@@ -77,12 +77,30 @@ func GetBlockDeviceInfo(sysfs sysfs.SysFs) (map[string]info.DiskInfo, error) {
 			}
 		}
 		disk_info.Scheduler = sched
+		if rotational, err := sysfs.GetBlockDeviceRotational(name); err == nil {
+			disk_info.Rotational = strings.TrimSpace(rotational) == "1"
+		}
+		if model, err := sysfs.GetBlockDeviceModel(name); err == nil {
+			disk_info.Model = strings.TrimSpace(model)
+		}
 		device := fmt.Sprintf("%d:%d", disk_info.Major, disk_info.Minor)
 		diskMap[device] = disk_info
 	}
 	return diskMap, nil
 }
 
+// Whether the network device is a loopback, veth or Docker bridge device,
+// which are not reported.
+func isIgnoredNetworkDevice(name string) bool {
+	ignoredDevices := []string{"lo", "veth", "docker"}
+	for _, prefix := range ignoredDevices {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 // Get information about network devices present on the system.
 func GetNetworkDevices(sysfs sysfs.SysFs) ([]info.NetInfo, error) {
 	devs, err := sysfs.GetNetworkDevices()
@@ -92,16 +110,7 @@ func GetNetworkDevices(sysfs sysfs.SysFs) ([]info.NetInfo, error) {
 	netDevices := []info.NetInfo{}
 	for _, dev := range devs {
 		name := dev.Name()
-		// Ignore docker, loopback, and veth devices.
-		ignoredDevices := []string{"lo", "veth", "docker"}
-		ignored := false
-		for _, prefix := range ignoredDevices {
-			if strings.HasPrefix(name, prefix) {
-				ignored = true
-				break
-			}
-		}
-		if ignored {
+		if isIgnoredNetworkDevice(name) {
 			continue
 		}
 		address, err := sysfs.GetNetworkAddress(name)
@@ -202,6 +211,46 @@ func getNetworkStats(name string, sysFs sysfs.SysFs) (info.NetworkStats, error)
 	if err != nil {
 		return stats, err
 	}
+	stats.Interface = name
+	setLinkStats(name, sysFs, &stats)
+	return stats, nil
+}
+
+// Fills in the link speed and MTU of the interface. Virtual interfaces report
+// no speed or a negative one, which is left at zero.
+func setLinkStats(name string, sysFs sysfs.SysFs, stats *info.NetworkStats) {
+	if mtu, err := sysFs.GetNetworkMtu(name); err == nil {
+		stats.Mtu, _ = strconv.ParseUint(strings.TrimSpace(mtu), 10, 64)
+	}
+	if speed, err := sysFs.GetNetworkSpeed(name); err == nil {
+		// Reported in Mbit/s.
+		if s, err := strconv.ParseInt(strings.TrimSpace(speed), 10, 64); err == nil && s > 0 {
+			stats.Speed = uint64(s) * 1000 * 1000 / 8
+		}
+	}
+}
+
+// Returns the network statistics of the physical network interfaces of the machine.
+func GetMachineNetworkStats(sysFs sysfs.SysFs) ([]info.InterfaceStats, error) {
+	devs, err := sysFs.GetNetworkDevices()
+	if err != nil {
+		return nil, err
+	}
+	stats := []info.InterfaceStats{}
+	for _, dev := range devs {
+		name := dev.Name()
+		if isIgnoredNetworkDevice(name) {
+			continue
+		}
+		netStats, err := getNetworkStats(name, sysFs)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, info.InterfaceStats{
+			Name:         name,
+			NetworkStats: netStats,
+		})
+	}
 	return stats, nil
 }
 
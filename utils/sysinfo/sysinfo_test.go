@@ -15,6 +15,7 @@
 package sysinfo
 
 import (
+	"reflect"
 	"testing"
 
 	info "github.com/google/cadvisor/info/v1"
@@ -46,6 +47,32 @@ func TestGetBlockDeviceInfo(t *testing.T) {
 	if disk.Scheduler != "cfq" {
 		t.Errorf("expected to get scheduler type of cfq. Got %q", disk.Scheduler)
 	}
+	if !disk.Rotational {
+		t.Errorf("expected disk to be rotational")
+	}
+	if disk.Model != "FAKE DISK" {
+		t.Errorf("expected to get disk model FAKE DISK. Got %q", disk.Model)
+	}
+}
+
+func TestGetSyntheticBlockDeviceInfo(t *testing.T) {
+	fakeSys := fakesysfs.FakeSysFs{}
+	fakeSys.SetBlockDevices(map[string]fakesysfs.FakeBlockDevice{
+		"nvme0n1": {Numbers: "259:0\n", Size: "2000\n", Scheduler: "[none] mq-deadline\n", Rotational: "0\n", Model: "FAST SSD  \n"},
+		"dm-0":    {Numbers: "253:0\n", Size: "100\n", Rotational: "0\n"},
+		"loop0":   {Numbers: "7:0\n", Size: "8\n"},
+	})
+	disks, err := GetBlockDeviceInfo(&fakeSys)
+	if err != nil {
+		t.Fatalf("expected call to GetBlockDeviceInfo() to succeed. Failed with %s", err)
+	}
+	expected := map[string]info.DiskInfo{
+		"259:0": {Name: "nvme0n1", Major: 259, Minor: 0, Size: 2000 * 512, Scheduler: "none", Model: "FAST SSD"},
+		"253:0": {Name: "dm-0", Major: 253, Minor: 0, Size: 100 * 512, Scheduler: "none"},
+	}
+	if !reflect.DeepEqual(disks, expected) {
+		t.Errorf("expected disks %+v, got %+v", expected, disks)
+	}
 }
 
 func TestGetNetworkDevices(t *testing.T) {
@@ -111,6 +138,9 @@ func TestGetCacheInfo(t *testing.T) {
 
 func TestGetNetworkStats(t *testing.T) {
 	expected_stats := info.NetworkStats{
+		Interface: "eth0",
+		Speed:     125000000,
+		Mtu:       1024,
 		RxBytes:   1024,
 		RxPackets: 1024,
 		RxErrors:  1024,
@@ -125,7 +155,34 @@ func TestGetNetworkStats(t *testing.T) {
 	if err != nil {
 		t.Errorf("call to getNetworkStats() failed with %s", err)
 	}
-	if expected_stats != netStats {
+	if !reflect.DeepEqual(expected_stats, netStats) {
 		t.Errorf("expected to get stats %+v, got %+v", expected_stats, netStats)
 	}
 }
+
+func TestGetMachineNetworkStats(t *testing.T) {
+	fakeSys := &fakesysfs.FakeSysFs{}
+	fakeSys.SetEntryName("eth0")
+	stats, err := GetMachineNetworkStats(fakeSys)
+	if err != nil {
+		t.Errorf("call to GetMachineNetworkStats() failed with %s", err)
+	}
+	if len(stats) != 1 {
+		t.Fatalf("expected stats of one interface. Got %d", len(stats))
+	}
+	if stats[0].Name != "eth0" {
+		t.Errorf("expected stats of eth0. Got %q", stats[0].Name)
+	}
+	if stats[0].RxBytes != 1024 || stats[0].TxBytes != 1024 {
+		t.Errorf("expected 1024 bytes received and transmitted. Got %+v", stats[0].NetworkStats)
+	}
+
+	fakeSys.SetEntryName("veth1234")
+	stats, err = GetMachineNetworkStats(fakeSys)
+	if err != nil {
+		t.Errorf("call to GetMachineNetworkStats() failed with %s", err)
+	}
+	if len(stats) != 0 {
+		t.Errorf("expected veth1234 to be ignored, but got stats %+v", stats)
+	}
+}
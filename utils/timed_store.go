@@ -21,8 +21,9 @@ import (
 
 // A time-based buffer for ContainerStats. Holds information for a specific time period.
 type TimedStore struct {
-	buffer []timedStoreData
-	age    time.Duration
+	buffer   []timedStoreData
+	age      time.Duration
+	maxItems int
 }
 
 type timedStoreData struct {
@@ -30,11 +31,13 @@ type timedStoreData struct {
 	data      interface{}
 }
 
-// Returns a new thread-compatible TimedStore.
-func NewTimedStore(age time.Duration) *TimedStore {
+// Returns a new thread-compatible TimedStore keeping elements for the specified
+// age, and at most maxItems of them. maxItems of -1 means no limit.
+func NewTimedStore(age time.Duration, maxItems int) *TimedStore {
 	return &TimedStore{
-		buffer: make([]timedStoreData, 0),
-		age:    age,
+		buffer:   make([]timedStoreData, 0),
+		age:      age,
+		maxItems: maxItems,
 	}
 }
 
@@ -54,6 +57,11 @@ func (self *TimedStore) Add(timestamp time.Time, item interface{}) {
 		timestamp: timestamp,
 		data:      copied,
 	})
+
+	// Remove the oldest elements past maxItems.
+	if self.maxItems >= 0 && len(self.buffer) > self.maxItems {
+		self.buffer = self.buffer[len(self.buffer)-self.maxItems:]
+	}
 }
 
 // Returns up to maxResult elements in the specified time period (inclusive).
@@ -132,6 +140,16 @@ func (self *TimedStore) getData(index int) timedStoreData {
 	return self.buffer[len(self.buffer)-index-1]
 }
 
+// Removes the n oldest elements, or all of them if there are fewer.
+func (self *TimedStore) RemoveOldest(n int) {
+	if n > len(self.buffer) {
+		n = len(self.buffer)
+	}
+	if n > 0 {
+		self.buffer = self.buffer[n:]
+	}
+}
+
 func (self *TimedStore) Size() int {
 	return len(self.buffer)
 }
@@ -55,7 +55,7 @@ func expectElements(t *testing.T, actual []interface{}, expected []int) {
 }
 
 func TestAdd(t *testing.T) {
-	sb := NewTimedStore(5 * time.Second)
+	sb := NewTimedStore(5*time.Second, -1)
 
 	// Add 1.
 	sb.Add(createTime(0), 0)
@@ -83,8 +83,24 @@ func TestAdd(t *testing.T) {
 	expectAllElements(t, sb, []int{6, 7, 8, 9, 10})
 }
 
+func TestMaxItems(t *testing.T) {
+	sb := NewTimedStore(5*time.Second, 3)
+
+	// Add 1.
+	sb.Add(createTime(0), 0)
+	expectSize(t, sb, 1)
+	expectAllElements(t, sb, []int{0})
+
+	// Fill the buffer.
+	for i := 1; i <= 5; i++ {
+		sb.Add(createTime(i), i)
+	}
+	expectSize(t, sb, 3)
+	expectAllElements(t, sb, []int{3, 4, 5})
+}
+
 func TestGet(t *testing.T) {
-	sb := NewTimedStore(5 * time.Second)
+	sb := NewTimedStore(5*time.Second, -1)
 	sb.Add(createTime(1), 1)
 	sb.Add(createTime(2), 2)
 	sb.Add(createTime(3), 3)
@@ -97,7 +113,7 @@ func TestGet(t *testing.T) {
 }
 
 func TestInTimeRange(t *testing.T) {
-	sb := NewTimedStore(5 * time.Second)
+	sb := NewTimedStore(5*time.Second, -1)
 	assert := assert.New(t)
 
 	var empty time.Time
@@ -173,8 +189,23 @@ func TestInTimeRange(t *testing.T) {
 	assert.Empty(sb.InTimeRange(empty, createTime(0), 10))
 }
 
+func TestRemoveOldest(t *testing.T) {
+	sb := NewTimedStore(5*time.Second, -1)
+	sb.Add(createTime(1), 1)
+	sb.Add(createTime(2), 2)
+	sb.Add(createTime(3), 3)
+
+	var empty time.Time
+	sb.RemoveOldest(0)
+	expectElements(t, sb.InTimeRange(empty, empty, -1), []int{1, 2, 3})
+	sb.RemoveOldest(2)
+	expectElements(t, sb.InTimeRange(empty, empty, -1), []int{3})
+	sb.RemoveOldest(5)
+	expectSize(t, sb, 0)
+}
+
 func TestInTimeRangeWithLimit(t *testing.T) {
-	sb := NewTimedStore(5 * time.Second)
+	sb := NewTimedStore(5*time.Second, -1)
 	sb.Add(createTime(1), 1)
 	sb.Add(createTime(2), 2)
 	sb.Add(createTime(3), 3)
@@ -14,7 +14,12 @@
 
 package utils
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
 
 // Returns a mask of all cores on the machine if the passed-in mask is empty.
 func FixCpuMask(mask string, cores int) string {
@@ -27,3 +32,42 @@ func FixCpuMask(mask string, cores int) string {
 	}
 	return mask
 }
+
+// Parses a list of CPUs or memory nodes in the format of cpuset, e.g.
+// "0-3,8,10-11", into the sorted IDs it lists. IDs listed more than once are
+// only returned once.
+func ParseCpuList(list string) ([]int, error) {
+	ret := []int{}
+	list = strings.TrimSpace(list)
+	if list == "" {
+		return ret, nil
+	}
+	for _, r := range strings.Split(list, ",") {
+		bounds := strings.SplitN(r, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %q in list %q: %v", r, list, err)
+		}
+		last := first
+		if len(bounds) == 2 {
+			last, err = strconv.Atoi(bounds[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid range %q in list %q: %v", r, list, err)
+			}
+		}
+		if first > last {
+			return nil, fmt.Errorf("invalid range %q in list %q", r, list)
+		}
+		for id := first; id <= last; id++ {
+			ret = append(ret, id)
+		}
+	}
+	sort.Ints(ret)
+	unique := ret[:0]
+	for _, id := range ret {
+		if len(unique) == 0 || id != unique[len(unique)-1] {
+			unique = append(unique, id)
+		}
+	}
+	return unique, nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCpuList(t *testing.T) {
+	tests := map[string][]int{
+		"":            {},
+		"0":           {0},
+		"0-3,8,10-11": {0, 1, 2, 3, 8, 10, 11},
+		"2-2\n":       {2},
+		"8,0-3,2":     {0, 1, 2, 3, 8},
+	}
+	for list, expected := range tests {
+		ids, err := ParseCpuList(list)
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %v", list, err)
+			continue
+		}
+		if !reflect.DeepEqual(ids, expected) {
+			t.Errorf("expected %v for %q, got %v", expected, list, ids)
+		}
+	}
+	for _, list := range []string{"a", "3-1", "0-"} {
+		if _, err := ParseCpuList(list); err == nil {
+			t.Errorf("expected error parsing %q", list)
+		}
+	}
+}